@@ -54,13 +54,39 @@ Flags:
 		is "NewCodeReader" by default. --diag-format-call has no effect unless
 		--diag-format-pkg and --diag are also set.
 
+	--check-ambiguity N
+		Search every sentence of up to N tokens in the language for one that
+		has more than one parse tree, and give an "ambig-sentence" warning with
+		the sentence and two of its parse trees for each one found. Unlike the
+		conflicts given when creating an LR parser, each one found shows that
+		the grammar itself is ambiguous. The search takes longer the larger N
+		is, and it cannot show that a grammar is unambiguous; only that no
+		sentence of up to N tokens is ambiguous.
+
 	--clr
 		Generate a Canonical LR(k) parser. Mutually exclusive with --ll, --slr,
-		and --lalr.
+		--lalr, and --cyk.
 
 	-C, --command CODE
 		Read the FISHI markdown document in CODE before any other input is read.
 
+	--convert-ll
+		Rewrite the grammar so that it has no left recursion and is left
+		factored before creating an LL(1) parser from it, and rewrite the
+		actions so they give the same IR for the new grammar. The rewritten
+		actions use inherited attributes to pass values between the new rules.
+		Only left recursion where a production starts with its own head can be
+		removed. This is done with --ll whenever the grammar is not LL(1); give
+		--convert-ll to do it for every grammar. Implies --ll; mutually
+		exclusive with --clr, --slr, --lalr, and --cyk.
+
+	--cyk
+		Generate a CYK parser. It accepts any context-free grammar, including
+		ambiguous ones, by converting it to Chomsky normal form, but it takes
+		time proportional to the cube of the number of tokens in its input.
+		Mutually exclusive with --ll, --slr, --lalr, --clr, and
+		--static-tables.
+
 	-d, --diag FILE
 		Generate a diagnostics binary from the spec and output it to the path
 		FILE. This binary will contain a self-contained version of the generated
@@ -99,6 +125,15 @@ Flags:
 		Print a detailed representation of the DFA that is constructed for the
 		generated parser to stdout.
 
+	--diagnostics-format FORMAT
+		Set the format that errors and warnings are output in. FORMAT may be
+		one of "text" for the default human-readable messages printed as they
+		occur, "json" for a single JSON object with the file, span, severity,
+		warning type, and message of every error and warning, or "sarif" for a
+		SARIF 2.1.0 log of them. For "json" and "sarif", the output is written
+		to stderr once ictcc is done and no other error or warning messages are
+		printed.
+
 	--exp FEATURE
 		Enable experimental or untested feature FEATURE. The allowed values for
 		FEATURE are as follows for this version of ictcc: "inherited-attributes"
@@ -118,9 +153,17 @@ Flags:
 		encountered, ictcc will output it as though it were an error and
 		immediately halt. Valid values for WARNTYPE are "dupe-human",
 		"missing-human", "priority", "unused", "ambig", "validation", "import",
-		"val-args", "exp-inherited-attributes", and "all". This flag may be
-		specified multiple times and in conjunction with -S flags; if both -F
-		and -S are specified for a warning, -F takes precedence.
+		"val-args", "exp-inherited-attributes", "ambig-sentence", and "all".
+		This flag may be specified multiple times and in conjunction with -S
+		flags; if both -F and -S are specified for a warning, -F takes
+		precedence.
+
+	--fuzz-target
+		Also generate fuzz.ict_test.go in the frontend package. It has a Go fuzz
+		target, FuzzParse, that gives the generated lexer and parser random
+		sentences of the grammar, made from fake text for each token, and fails
+		if any of them are not accepted. Run it with "go test -fuzz FuzzParse"
+		in the frontend package directory.
 
 	--hooks PATH
 		Retrieve the hooks table binding translation scheme hooks to their
@@ -152,12 +195,13 @@ Flags:
 		The default value is "Unspecified".
 
 	--lalr
-		Generate an LALR(k) parser. Mutually exclusive with --ll, --slr, and
-		--clr.
+		Generate an LALR(k) parser. Mutually exclusive with --ll, --slr, --clr,
+		and --cyk.
 
 	--ll
-		Generate an LL(k) parser. Mutually exclusive with --lalr, --slr, and
-		--clr.
+		Generate an LL(k) parser. If the grammar is not LL(1), it is converted
+		first as with --convert-ll. Mutually exclusive with --lalr, --slr,
+		--clr, and --cyk.
 
 	-n, --no-gen
 		Do not output a Go package with source code files that contain the
@@ -194,6 +238,14 @@ Flags:
 		Output specifically requested via other flags or caused by warnings or
 		errors is not affected by this flag.
 
+	--recursive-descent
+		Generate the parser as a recursive-descent parser made of one Go
+		function per non-terminal in the grammar instead of embedding an
+		encoded table-driven parser in the generated frontend. This implies
+		--ll, and is mutually exclusive with --lalr, --slr, --clr, and --cyk. If
+		--tmpl-parser is given, it replaces the recursive-descent parser
+		template.
+
 	-s, --spec
 		Print a formatted listing of the complete spec out once it is read from
 		FISHI input files.
@@ -221,7 +273,13 @@ Flags:
 
 	--slr
 		Generate a Simple LR(k) parser. Mutually exclusive with --ll, --lalr,
-		and --clr.
+		--clr, and --cyk.
+
+	--static-tables
+		Generate the parsing table of the parser as Go composite literals in
+		the generated frontend that the parser is built from directly, instead
+		of embedding an encoded parser that must be decoded each time the
+		frontend is used. Mutually exclusive with --recursive-descent.
 
 	-S, --suppress WARNTYPE
 		Suppress the output of WARNTYPE warnings. If the specified type of
@@ -233,10 +291,20 @@ Flags:
 	-t, --tree
 		Print the parse tree of successfully parsed FISHI files to stdout.
 
+	--tree-format FORMAT
+		Set the format that parse trees are printed in by -t. FORMAT may be
+		one of "diagram" for the default ASCII diagram, "json" for a JSON
+		object with the symbol, token, and source position of every node,
+		"sexpr" for an S-expression, or "dot" for a Graphviz DOT digraph.
+
 	--tmpl-frontend FILE
 		Use the contents of FILE as the template to generate frontend.ict.go
 		with during codegen.
 
+	--tmpl-fuzz FILE
+		Use the contents of FILE as the template to generate fuzz.ict_test.go
+		with during codegen.
+
 	--tmpl-lexer FILE
 		Use the contents of FILE as the template to generate lexer.ict.go with
 		during codegen.
@@ -259,6 +327,8 @@ Flags:
 
 	-T, --parse-table
 		Print the parse table of the parser generated from the spec to stdout.
+		For LR parsers, this is followed by the size of the compressed table
+		that the parser actually stores compared to that of the full table.
 
 	-v, --lang-ver VERSION
 		Set the language version in the metadata of the generated frontend to
@@ -272,6 +342,7 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"go/build"
@@ -305,6 +376,8 @@ var (
 	flagNoGen      = pflag.BoolP("no-gen", "n", false, "Do not output generated frontend output files")
 	flagGenAST     = pflag.BoolP("ast", "a", false, "Print the AST of the analyzed fishi")
 	flagGenTree    = pflag.BoolP("tree", "t", false, "Print the parse trees of each analyzed fishi file")
+	flagTreeFormat = pflag.String("tree-format", "diagram", "The format to print parse trees in; one of diagram, json, sexpr, or dot")
+	flagDiagFormat = pflag.String("diagnostics-format", "text", "The format to output errors and warnings in; one of text, json, or sarif")
 	flagShowSpec   = pflag.BoolP("spec", "s", false, "Print the FISHI spec interpreted from the analyzed fishi")
 	flagLang       = pflag.StringP("lang", "l", "Unspecified", "The name of the languae being generated")
 	flagLangVer    = pflag.StringP("lang-ver", "v", "v0.0", "The version of the language to generate")
@@ -334,12 +407,19 @@ var (
 	flagTmplSDTS   = pflag.String("tmpl-sdts", "", "A template file to replace the embedded SDTS template with")
 	flagTmplFront  = pflag.String("tmpl-frontend", "", "A template file to replace the embedded frontend template with")
 	flagTmplMain   = pflag.String("tmpl-main", "", "A template file to replace the embedded main.go template with")
+	flagTmplFuzz   = pflag.String("tmpl-fuzz", "", "A template file to replace the embedded fuzz test template with")
 
 	flagParserLL      = pflag.Bool("ll", false, "Generate an LL(1) parser")
 	flagParserSLR     = pflag.Bool("slr", false, "Generate a simple LR(1) parser")
 	flagParserCLR     = pflag.Bool("clr", false, "Generate a canonical LR(1) parser")
 	flagParserLALR    = pflag.Bool("lalr", false, "Generate a canonical LR(1) parser")
+	flagParserCYK     = pflag.Bool("cyk", false, "Generate a CYK parser that accepts any context-free grammar")
 	flagParserNoAmbig = pflag.Bool("no-ambig", false, "Disallow ambiguity in grammar even if creating a parser that can auto-resolve it")
+	flagCheckAmbig    = pflag.Int("check-ambiguity", 0, "Search sentences of up to N tokens for ones that have more than one parse tree")
+	flagParserRD      = pflag.Bool("recursive-descent", false, "Generate the LL(1) parser as recursive-descent Go functions")
+	flagConvertLL     = pflag.Bool("convert-ll", false, "Rewrite the grammar and translation scheme to remove left recursion and left factor them before creating an LL(1) parser")
+	flagStaticTables  = pflag.Bool("static-tables", false, "Generate the parsing table as Go source instead of embedding an encoded parser")
+	flagFuzzTarget    = pflag.Bool("fuzz-target", false, "Generate a Go fuzz target that feeds the frontend random sentences of the grammar")
 
 	flagLexerTrace  = pflag.Bool("debug-lexer", false, "Print the lexer trace to stderr")
 	flagParserTrace = pflag.Bool("debug-parser", false, "Print the parser trace to stderr")
@@ -378,11 +458,36 @@ func main() {
 		return
 	}
 
+	diagFormat, err := fishi.ParseDiagnosticsFormat(*flagDiagFormat)
+	if err != nil {
+		errInvalidFlags("--diagnostics-format " + err.Error())
+		return
+	}
+	if diagFormat != fishi.DiagnosticsText {
+		// from here on, errors and warnings go into the report
+		report = &fishi.Report{Tool: "ictcc", Version: Version}
+		reportFormat = diagFormat
+	}
+
 	warnHandler, err := fishi.NewWarnHandlerFromCLI(*flagWarnSuppress, *flagWarnFatal)
 	if err != nil {
 		errInvalidFlags(err.Error())
 		return
 	}
+	warnHandler.Report = report
+
+	if *flagCheckAmbig < 0 {
+		errInvalidFlags(fmt.Sprintf("--check-ambiguity must be at least 0; not %d", *flagCheckAmbig))
+		return
+	}
+
+	switch strings.ToLower(*flagTreeFormat) {
+	case "diagram", "json", "sexpr", "dot":
+		// valid
+	default:
+		errInvalidFlags(fmt.Sprintf("--tree-format must be one of diagram, json, sexpr, or dot; not %q", *flagTreeFormat))
+		return
+	}
 
 	// mutually exclusive and required options for diagnostics bin generation.
 	if *flagDiagBin != "" {
@@ -456,6 +561,9 @@ func main() {
 		IRType:               *flagIRType,
 		TemplateFiles:        map[string]string{},
 		PreserveBinarySource: *flagPreserveBinSource,
+		RecursiveDescent:     *flagParserRD,
+		StaticTables:         *flagStaticTables,
+		FuzzTarget:           *flagFuzzTarget,
 	}
 	if *flagTmplTokens != "" {
 		cgOpts.TemplateFiles[fishi.ComponentTokens] = *flagTmplTokens
@@ -464,7 +572,11 @@ func main() {
 		cgOpts.TemplateFiles[fishi.ComponentLexer] = *flagTmplLexer
 	}
 	if *flagTmplParser != "" {
-		cgOpts.TemplateFiles[fishi.ComponentParser] = *flagTmplParser
+		if *flagParserRD {
+			cgOpts.TemplateFiles[fishi.ComponentParserRD] = *flagTmplParser
+		} else {
+			cgOpts.TemplateFiles[fishi.ComponentParser] = *flagTmplParser
+		}
 	}
 	if *flagTmplSDTS != "" {
 		cgOpts.TemplateFiles[fishi.ComponentSDTS] = *flagTmplSDTS
@@ -475,6 +587,9 @@ func main() {
 	if *flagTmplMain != "" {
 		cgOpts.TemplateFiles[fishi.ComponentMainFile] = *flagTmplMain
 	}
+	if *flagTmplFuzz != "" {
+		cgOpts.TemplateFiles[fishi.ComponentFuzzTest] = *flagTmplFuzz
+	}
 	if len(cgOpts.TemplateFiles) == 0 {
 		// just nil it
 		cgOpts.TemplateFiles = nil
@@ -515,7 +630,7 @@ func main() {
 		// parse tree is per-file, so we do this immediately even on error, as
 		// it may be useful
 		if cmdRes.Tree != nil && *flagGenTree {
-			fmt.Printf("%s\n", trans.Annotate(*cmdRes.Tree).String())
+			printTree(trans.Annotate(*cmdRes.Tree))
 		}
 
 		if cmdErr != nil {
@@ -524,10 +639,10 @@ func main() {
 				fmt.Printf("%s\n", cmdRes.AST.String())
 			}
 
-			if syntaxErr, ok := cmdErr.(*syntaxerr.Error); ok {
-				errSyntax("<COMMAND>", syntaxErr)
+			if _, ok := syntaxerr.AsDiagnostics(cmdErr); ok {
+				errSyntax("<COMMAND>", cmdErr)
 			} else {
-				errOther(fmt.Sprintf("%s: %s", "<COMMAND>", err.Error()))
+				errOther(fmt.Sprintf("%s: %s", "<COMMAND>", cmdErr.Error()))
 			}
 			return
 		}
@@ -593,7 +708,7 @@ func main() {
 			// parse tree is per-file, so we do this immediately even on error, as
 			// it may be useful
 			if res.Tree != nil && *flagGenTree {
-				fmt.Printf("%s\n", trans.Annotate(*res.Tree).String())
+				printTree(trans.Annotate(*res.Tree))
 			}
 
 			if err != nil {
@@ -602,8 +717,8 @@ func main() {
 					fmt.Printf("%s\n", res.AST.String())
 				}
 
-				if syntaxErr, ok := err.(*syntaxerr.Error); ok {
-					errSyntax(file, syntaxErr)
+				if _, ok := syntaxerr.AsDiagnostics(err); ok {
+					errSyntax(file, err)
 				} else {
 					errOther(fmt.Sprintf("%s: %s", file, err.Error()))
 				}
@@ -643,9 +758,11 @@ func main() {
 	}
 	// now check err
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "\n")
-		if syntaxErr, ok := err.(*syntaxerr.Error); ok {
-			errSyntax("", syntaxErr)
+		if report == nil {
+			fmt.Fprintf(os.Stderr, "\n")
+		}
+		if _, ok := syntaxerr.AsDiagnostics(err); ok {
+			errSyntax("", err)
 		} else {
 			errOther(err.Error())
 		}
@@ -655,11 +772,64 @@ func main() {
 		return
 	}
 
+	// rewrite it for LL(1) parsing if requested, or if an LL(1) parser was
+	// requested and the grammar needs it.
+	if *flagConvertLL {
+		if !*flagQuietMode {
+			fmt.Printf("Converting grammar and translation scheme for LL(1) parsing...\n")
+		}
+		spec, err = spec.ConvertToLL1()
+		if err != nil {
+			errOther(err.Error())
+			return
+		}
+	} else if (*flagParserLL || *flagParserRD) && !parse.IsLL1(spec.Grammar) {
+		if !*flagQuietMode {
+			fmt.Printf("Grammar is not LL(1); converting grammar and translation scheme for LL(1) parsing...\n")
+		}
+		spec, err = spec.ConvertToLL1()
+		if err != nil {
+			errParser(fmt.Sprintf("grammar is not LL(1) and cannot be converted: %s", err.Error()))
+			return
+		}
+	}
+
 	// we officially have a spec. try to print it if requested
 	if *flagShowSpec {
 		printSpec(spec)
 	}
 
+	// check the grammar itself for ambiguity if requested
+	if *flagCheckAmbig > 0 {
+		if !*flagQuietMode {
+			fmt.Printf("Checking sentences of up to %d tokens for ambiguity...\n", *flagCheckAmbig)
+		}
+		ambigWarns, err := spec.CheckAmbiguity(*flagCheckAmbig)
+		if err != nil {
+			errParser(err.Error())
+			return
+		}
+
+		var fatalAmbigWarn error
+		for _, warn := range ambigWarns {
+			if wErr := warnHandler.Handlef("%s\n\n", warn); wErr != nil {
+				fatalAmbigWarn = wErr
+			}
+		}
+		if fatalAmbigWarn != nil {
+			errFatalWarn("fatal warning(s) occured")
+			return
+		}
+
+		if !*flagQuietMode {
+			if len(ambigWarns) > 0 {
+				fmt.Printf("Found %d ambiguous sentence(s)\n", len(ambigWarns))
+			} else {
+				fmt.Printf("No ambiguous sentences found\n")
+			}
+		}
+	}
+
 	// if no-gen is set and diagnostics binary not requested and DFA not
 	// requested, we are done.
 	if *flagNoGen && *flagDiagBin == "" && !*flagDFA && !*flagParseTable {
@@ -714,6 +884,10 @@ func main() {
 	// output parse table if requested
 	if *flagParseTable {
 		fmt.Printf("%s\n", p.TableString())
+
+		if full, compressed, err := parse.LRTableSize(p); err == nil {
+			fmt.Printf("Compressed table: %d values stored for %d entries (ratio %.2f:1)\n", compressed, full, float64(full)/float64(compressed))
+		}
 	}
 
 	// create a test compiler and output it if either codegen or diagnostic bin
@@ -864,17 +1038,27 @@ func main() {
 		feImportPath = "FE_IMPORT_PATH"
 	}
 
-	err = fishi.GenerateFrontendGo(spec, md, *flagPkg, feDest, feImportPath, &cgOpts)
+	err = fishi.GenerateFrontendGo(spec, md, p, *flagPkg, feDest, feImportPath, &cgOpts)
 	if err != nil {
 		errGeneration(err.Error())
 		return
 	}
 
-	parserPath := filepath.Join(feDest, "parser.cff")
-	err = parse.WriteFile(p, parserPath)
-	if err != nil {
-		errGeneration(err.Error())
-		return
+	// same conditions GenerateFrontendGo uses to decide whether to output
+	// AnalyzeStream.
+	if !*flagQuietMode && !*flagParserRD && p.Type().IsLR() && spec.SAttributed() {
+		fmt.Printf("Spec is S-attributed; frontend includes AnalyzeStream for parsing without a tree\n")
+	}
+
+	// recursive-descent parsers and parsers with static tables are entirely in
+	// generated code and do not need the encoded parser.
+	if !*flagParserRD && !*flagStaticTables {
+		parserPath := filepath.Join(feDest, "parser.cff")
+		err = parse.WriteFile(p, parserPath)
+		if err != nil {
+			errGeneration(err.Error())
+			return
+		}
 	}
 }
 
@@ -1025,17 +1209,38 @@ func devModeInfoFromFlags() (DevModeInfo, error) {
 // err will be non-nil if there is an invalid combination of CLI flags.
 func parserSelectionFromFlags() (t *parse.Algorithm, allowAmbig bool, err error) {
 	// enforce mutual exclusion of cli args
-	if (*flagParserLL && (*flagParserCLR || *flagParserSLR || *flagParserLALR)) ||
-		(*flagParserCLR && (*flagParserSLR || *flagParserLALR)) ||
-		(*flagParserSLR && *flagParserLALR) {
+	if (*flagParserLL && (*flagParserCLR || *flagParserSLR || *flagParserLALR || *flagParserCYK)) ||
+		(*flagParserCLR && (*flagParserSLR || *flagParserLALR || *flagParserCYK)) ||
+		(*flagParserSLR && (*flagParserLALR || *flagParserCYK)) ||
+		(*flagParserLALR && *flagParserCYK) {
 
 		err = fmt.Errorf("cannot specify more than one parser type")
 		return
 	}
 
+	if *flagParserRD && (*flagParserCLR || *flagParserSLR || *flagParserLALR || *flagParserCYK) {
+		err = fmt.Errorf("--recursive-descent requires an LL(1) parser")
+		return
+	}
+
+	if *flagConvertLL && (*flagParserCLR || *flagParserSLR || *flagParserLALR || *flagParserCYK) {
+		err = fmt.Errorf("--convert-ll requires an LL(1) parser")
+		return
+	}
+
+	if *flagParserRD && *flagStaticTables {
+		err = fmt.Errorf("--recursive-descent and --static-tables cannot both be given")
+		return
+	}
+
+	if *flagParserCYK && *flagStaticTables {
+		err = fmt.Errorf("--cyk and --static-tables cannot both be given")
+		return
+	}
+
 	allowAmbig = !*flagParserNoAmbig
 
-	if *flagParserLL {
+	if *flagParserLL || *flagParserRD || *flagConvertLL {
 		t = new(parse.Algorithm)
 		*t = parse.LL1
 
@@ -1050,11 +1255,45 @@ func parserSelectionFromFlags() (t *parse.Algorithm, allowAmbig bool, err error)
 	} else if *flagParserLALR {
 		t = new(parse.Algorithm)
 		*t = parse.LALR1
+	} else if *flagParserCYK {
+		t = new(parse.Algorithm)
+		*t = parse.CYK
 	}
 
 	return
 }
 
+// printTree prints apt to stdout in the format given by --tree-format.
+func printTree(apt trans.AnnotatedTree) {
+	treeStr, err := formatTree(apt, *flagTreeFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERR: could not print parse tree: %s\n", err)
+		return
+	}
+	fmt.Printf("%s\n", treeStr)
+}
+
+// formatTree returns apt in the given format, which must be one of the values
+// accepted by --tree-format.
+func formatTree(apt trans.AnnotatedTree, format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "diagram":
+		return apt.String(), nil
+	case "json":
+		data, err := json.MarshalIndent(apt, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case "sexpr":
+		return apt.SExpr(), nil
+	case "dot":
+		return strings.TrimSuffix(apt.DOT(false), "\n"), nil
+	default:
+		return "", fmt.Errorf("unknown tree format %q", format)
+	}
+}
+
 func printSpec(spec fishi.Spec) {
 	// print tokens
 	fmt.Printf("Token Classes:\n")
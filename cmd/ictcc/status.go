@@ -1,9 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/dekarrin/ictiobus/fishi"
 	"github.com/dekarrin/ictiobus/syntaxerr"
 )
 
@@ -46,6 +48,14 @@ const (
 
 var (
 	exitStatus = ExitSuccess
+
+	// report collects errors and warnings to be written to stderr as a whole
+	// when ictcc exits. It is nil if they are to be output as text as they
+	// occur instead.
+	report *fishi.Report
+
+	// reportFormat is the format that report is written in.
+	reportFormat fishi.DiagnosticsFormat
 )
 
 // errNoFiles sets the exit status to ExitErrNoFiles and prints the given error
@@ -66,17 +76,30 @@ func errInvalidFlags(msg string) {
 	exitErr(ExitErrInvalidFlags, msg)
 }
 
-// errSyntax sets the exit status to ExitErrSyntax and prints an error message
-// given by the syntax error to stderr.
+// errSyntax sets the exit status to ExitErrSyntax and prints the diagnostics
+// of the given syntax error to stderr, rendered with source lines and colored
+// if stderr is a terminal. If filename is not empty, it is used as the file
+// of every location in the error.
 //
 // Caller is responsible for exiting main immediately after this function
 // returns.
-func errSyntax(filename string, synErr *syntaxerr.Error) {
-	if filename == "" {
-		filename = "<INPUT>"
+func errSyntax(filename string, synErr error) {
+	diags, ok := syntaxerr.AsDiagnostics(synErr)
+	if !ok {
+		errOther(synErr.Error())
+		return
+	}
+	if filename != "" {
+		diags = diags.WithFile(filename)
 	}
-	fmt.Fprintf(os.Stderr, "%s\n", synErr.MessageForFile(filename))
 	exitStatus = ExitErrSyntax
+
+	if report != nil {
+		report.AddDiagnostics(diags)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%s\n", syntaxerr.RendererFor(os.Stderr).RenderAll(diags))
 }
 
 // errParser sets the exit status to ExitErrParser and prints the given error
@@ -117,12 +140,18 @@ func errOther(msg string) {
 
 // exitErr sets the exit status and prints "ERROR: " followed by the given
 // error message to stderr. Automatically ends printed message with a newline.
+// If errors are being collected in report, the message is added to it instead
+// of being printed.
 //
 // Caller is responsible for exiting main immediately after this function
 // returns.
 func exitErr(statusCode int, msg string) {
-	fmt.Fprintf(os.Stderr, errorPrefix+"%s\n", msg)
 	exitStatus = statusCode
+	if report != nil {
+		report.AddError("", errors.New(msg))
+		return
+	}
+	fmt.Fprintf(os.Stderr, errorPrefix+"%s\n", msg)
 }
 
 // basic function to check if panic is happening and recover it while also
@@ -134,6 +163,11 @@ func preservePanicOrExitWithStatus() {
 		// we checked
 		panic("unrecoverable panic occured")
 	} else {
+		if report != nil {
+			if err := report.Write(os.Stderr, reportFormat); err != nil {
+				fmt.Fprintf(os.Stderr, errorPrefix+"write diagnostics: %s\n", err)
+			}
+		}
 		os.Exit(exitStatus)
 	}
 }
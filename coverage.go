@@ -0,0 +1,387 @@
+package ictiobus
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/trans"
+)
+
+// Coverage collects which rules and productions of a grammar and which
+// bindings of a translation scheme are used as input is analyzed. It gets them
+// from the events of a parser and an SDTS, so it sees every input that they
+// analyze; running a corpus of inputs through a Frontend that is tracked by a
+// Coverage shows which parts of the language the corpus never exercises.
+//
+// A Coverage is safe for concurrent use by multiple goroutines.
+type Coverage struct {
+	mu sync.Mutex
+
+	g        grammar.CFG
+	bindings []trans.Binding
+
+	// prodHits is the number of times each production was used, keyed by the
+	// head of its rule and then by its symbols joined with spaces.
+	prodHits map[string]map[string]int
+
+	// bindHits is the number of times each binding in bindings was executed,
+	// by its index.
+	bindHits []int
+
+	// bindIndex gives the index in bindings of each binding, keyed by
+	// bindingKey.
+	bindIndex map[string]int
+}
+
+// ProductionCoverage is how many times a production of a grammar was used.
+type ProductionCoverage struct {
+	// Head is the non-terminal at the head of the rule that the production
+	// is in.
+	Head string
+
+	// Production is the production.
+	Production grammar.Production
+
+	// Hits is the number of times the production was used.
+	Hits int
+}
+
+// String returns the string representation of the ProductionCoverage.
+func (pc ProductionCoverage) String() string {
+	return fmt.Sprintf("%s -> %s (%d)", pc.Head, pc.Production, pc.Hits)
+}
+
+// BindingCoverage is how many times a binding of a translation scheme was
+// executed.
+type BindingCoverage struct {
+	trans.Binding
+
+	// Hits is the number of times the hook of the binding was called.
+	Hits int
+}
+
+// String returns the string representation of the BindingCoverage.
+func (bc BindingCoverage) String() string {
+	return fmt.Sprintf("%s (%d)", bc.Binding, bc.Hits)
+}
+
+// NewCoverage returns a Coverage that reports on the rules and productions of
+// g and on the given bindings, such as those returned by trans.Bindings for
+// an SDTS. Its ParserEvent and SDTSEvent methods must be registered as
+// listeners for it to collect anything; Frontend.TrackCoverage does this for
+// the components of a Frontend.
+func NewCoverage(g grammar.CFG, bindings []trans.Binding) *Coverage {
+	c := &Coverage{
+		g:         g,
+		bindings:  make([]trans.Binding, len(bindings)),
+		prodHits:  map[string]map[string]int{},
+		bindHits:  make([]int, len(bindings)),
+		bindIndex: map[string]int{},
+	}
+	copy(c.bindings, bindings)
+
+	for _, nt := range g.NonTerminals() {
+		c.prodHits[nt] = map[string]int{}
+		for _, p := range g.Rule(nt).Productions {
+			c.prodHits[nt][strings.Join(p, " ")] = 0
+		}
+	}
+	for i, b := range c.bindings {
+		key := bindingKey(b.Head, b.Production, b.Dest, b.Hook)
+		if _, ok := c.bindIndex[key]; !ok {
+			c.bindIndex[key] = i
+		}
+	}
+
+	return c
+}
+
+// TrackCoverage returns a new Coverage for the grammar of fe's parser and the
+// bindings of fe's SDTS, and registers it as a listener on both so that it
+// collects coverage for all input that fe analyzes from then on. The listener
+// of the SDTS is replaced, so any tracing that was set up on it is removed; to
+// keep both, create the Coverage with NewCoverage and call its SDTSEvent
+// method from the tracing listener. Productions are only counted if fe's parser
+// is a parse.TraceEventSource, and bindings are only reported if fe's SDTS is a
+// trans.BindingLister; all parsers and SDTSs in ictiobus are.
+func (fe Frontend[E]) TrackCoverage() *Coverage {
+	c := NewCoverage(fe.Parser.Grammar(), trans.Bindings(fe.SDTS))
+	if src, ok := fe.Parser.(parse.TraceEventSource); ok {
+		src.RegisterTraceEventListener(c.ParserEvent)
+	}
+	fe.SDTS.RegisterListener(c.SDTSEvent)
+	return c
+}
+
+// ParserEvent records the production used by a TraceReduce or TracePredict
+// event. Other events are ignored. It is meant to be given to
+// RegisterTraceEventListener of a parse.TraceEventSource.
+func (c *Coverage) ParserEvent(ev parse.TraceEvent) {
+	if ev.Type != parse.TraceReduce && ev.Type != parse.TracePredict {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// rules not in the grammar, such as the augmented start rule of an LR
+	// parser, are not counted.
+	hits, ok := c.prodHits[ev.Symbol]
+	if !ok {
+		return
+	}
+	prodKey := strings.Join(ev.Production, " ")
+	if _, ok := hits[prodKey]; ok {
+		hits[prodKey]++
+	}
+}
+
+// SDTSEvent records the binding executed for an EventHookCall event. Other
+// events are ignored. It is meant to be given to RegisterListener of a
+// trans.SDTS.
+func (c *Coverage) SDTSEvent(e trans.Event) {
+	if e.Type != trans.EventHookCall || e.Hook == nil || e.Hook.Node == nil {
+		return
+	}
+
+	head, prod := e.Hook.Node.Rule()
+	key := bindingKey(head, prod, e.Hook.Target, e.Hook.Name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if idx, ok := c.bindIndex[key]; ok {
+		c.bindHits[idx]++
+	}
+}
+
+// Productions returns the coverage of every production of the grammar, in the
+// order that the rules and productions are in the grammar.
+func (c *Coverage) Productions() []ProductionCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var prods []ProductionCoverage
+	for _, nt := range c.g.NonTerminalsByPriority() {
+		for _, p := range c.g.Rule(nt).Productions {
+			prods = append(prods, ProductionCoverage{
+				Head:       nt,
+				Production: p.Copy(),
+				Hits:       c.prodHits[nt][strings.Join(p, " ")],
+			})
+		}
+	}
+	return prods
+}
+
+// Bindings returns the coverage of every binding, in the order they were given
+// to NewCoverage.
+func (c *Coverage) Bindings() []BindingCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	binds := make([]BindingCoverage, len(c.bindings))
+	for i := range c.bindings {
+		binds[i] = BindingCoverage{Binding: c.bindings[i], Hits: c.bindHits[i]}
+	}
+	return binds
+}
+
+// UncoveredRules returns the head of every rule of the grammar that none of
+// the productions of were ever used, in the order they are in the grammar.
+func (c *Coverage) UncoveredRules() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var rules []string
+	for _, nt := range c.g.NonTerminalsByPriority() {
+		used := false
+		for _, hits := range c.prodHits[nt] {
+			if hits > 0 {
+				used = true
+				break
+			}
+		}
+		if !used {
+			rules = append(rules, nt)
+		}
+	}
+	return rules
+}
+
+// UncoveredProductions returns every production of the grammar that was never
+// used, in the order they are in the grammar.
+func (c *Coverage) UncoveredProductions() []ProductionCoverage {
+	var prods []ProductionCoverage
+	for _, pc := range c.Productions() {
+		if pc.Hits == 0 {
+			prods = append(prods, pc)
+		}
+	}
+	return prods
+}
+
+// UncoveredBindings returns every binding that was never executed, in the
+// order they were given to NewCoverage.
+func (c *Coverage) UncoveredBindings() []BindingCoverage {
+	var binds []BindingCoverage
+	for _, bc := range c.Bindings() {
+		if bc.Hits == 0 {
+			binds = append(binds, bc)
+		}
+	}
+	return binds
+}
+
+// coverageReport is the data that a Coverage is output from.
+type coverageReport struct {
+	Rules       coverageTotal
+	Productions coverageTotal
+	Bindings    coverageTotal
+
+	AllProductions       []ProductionCoverage
+	AllBindings          []BindingCoverage
+	UncoveredRules       []string
+	UncoveredProductions []ProductionCoverage
+	UncoveredBindings    []BindingCoverage
+}
+
+// coverageTotal is how many of some part of a language were covered.
+type coverageTotal struct {
+	Covered int
+	Total   int
+}
+
+// Percent returns the percentage of the total that was covered. If the total
+// is 0, it is 100.
+func (ct coverageTotal) Percent() float64 {
+	if ct.Total == 0 {
+		return 100
+	}
+	return float64(ct.Covered) / float64(ct.Total) * 100
+}
+
+// String returns the string representation of the coverageTotal.
+func (ct coverageTotal) String() string {
+	return fmt.Sprintf("%d/%d (%.1f%%)", ct.Covered, ct.Total, ct.Percent())
+}
+
+// report returns the current coverage as a coverageReport.
+func (c *Coverage) report() coverageReport {
+	rep := coverageReport{
+		AllProductions:       c.Productions(),
+		AllBindings:          c.Bindings(),
+		UncoveredRules:       c.UncoveredRules(),
+		UncoveredProductions: c.UncoveredProductions(),
+		UncoveredBindings:    c.UncoveredBindings(),
+	}
+
+	rules := len(c.g.NonTerminalsByPriority())
+	rep.Rules = coverageTotal{Covered: rules - len(rep.UncoveredRules), Total: rules}
+	rep.Productions = coverageTotal{Covered: len(rep.AllProductions) - len(rep.UncoveredProductions), Total: len(rep.AllProductions)}
+	rep.Bindings = coverageTotal{Covered: len(rep.AllBindings) - len(rep.UncoveredBindings), Total: len(rep.AllBindings)}
+
+	return rep
+}
+
+// WriteText writes a human-readable report of the coverage to w. It gives how
+// many of the rules, productions, and bindings were covered, followed by a
+// list of each one that was not.
+func (c *Coverage) WriteText(w io.Writer) error {
+	rep := c.report()
+
+	var sb strings.Builder
+	sb.WriteString("Coverage:\n")
+	sb.WriteString(fmt.Sprintf("  Rules:       %s\n", rep.Rules))
+	sb.WriteString(fmt.Sprintf("  Productions: %s\n", rep.Productions))
+	sb.WriteString(fmt.Sprintf("  Bindings:    %s\n", rep.Bindings))
+
+	if len(rep.UncoveredRules) > 0 {
+		sb.WriteString("\nUncovered rules:\n")
+		for _, nt := range rep.UncoveredRules {
+			sb.WriteString(fmt.Sprintf("  %s\n", nt))
+		}
+	}
+	if len(rep.UncoveredProductions) > 0 {
+		sb.WriteString("\nUncovered productions:\n")
+		for _, pc := range rep.UncoveredProductions {
+			sb.WriteString(fmt.Sprintf("  %s -> %s\n", pc.Head, pc.Production))
+		}
+	}
+	if len(rep.UncoveredBindings) > 0 {
+		sb.WriteString("\nUncovered bindings:\n")
+		for _, bc := range rep.UncoveredBindings {
+			sb.WriteString(fmt.Sprintf("  %s\n", bc.Binding))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteHTML writes a report of the coverage to w as a standalone HTML page. It
+// gives how many of the rules, productions, and bindings were covered, and has
+// a table of every production and binding with the number of times it was
+// used, with the ones that were never used highlighted.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	return coverageHTMLTemplate.Execute(w, c.report())
+}
+
+// bindingKey returns the key that identifies the binding for the given rule,
+// destination attribute, and hook.
+func bindingKey(head string, prod []string, dest trans.AttrRef, hook string) string {
+	return head + "\x00" + strings.Join(prod, " ") + "\x00" + dest.String() + "\x00" + hook
+}
+
+var coverageHTMLTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.75em; text-align: left; }
+td.hits { text-align: right; }
+tr.uncovered { background-color: #fdd; }
+code { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>Coverage Report</h1>
+<table>
+<tr><th></th><th>Covered</th><th>Total</th><th>Percent</th></tr>
+<tr><td>Rules</td><td>{{ .Rules.Covered }}</td><td>{{ .Rules.Total }}</td><td>{{ printf "%.1f%%" .Rules.Percent }}</td></tr>
+<tr><td>Productions</td><td>{{ .Productions.Covered }}</td><td>{{ .Productions.Total }}</td><td>{{ printf "%.1f%%" .Productions.Percent }}</td></tr>
+<tr><td>Bindings</td><td>{{ .Bindings.Covered }}</td><td>{{ .Bindings.Total }}</td><td>{{ printf "%.1f%%" .Bindings.Percent }}</td></tr>
+</table>
+{{- if .UncoveredRules }}
+<h2>Uncovered Rules</h2>
+<ul>
+{{- range .UncoveredRules }}
+<li><code>{{ . }}</code></li>
+{{- end }}
+</ul>
+{{- end }}
+<h2>Productions</h2>
+<table>
+<tr><th>Rule</th><th>Production</th><th>Hits</th></tr>
+{{- range .AllProductions }}
+<tr{{ if eq .Hits 0 }} class="uncovered"{{ end }}><td><code>{{ .Head }}</code></td><td><code>{{ .Production }}</code></td><td class="hits">{{ .Hits }}</td></tr>
+{{- end }}
+</table>
+<h2>Bindings</h2>
+<table>
+<tr><th>Binding</th><th>Hits</th></tr>
+{{- range .AllBindings }}
+<tr{{ if eq .Hits 0 }} class="uncovered"{{ end }}><td><code>{{ .Binding }}</code></td><td class="hits">{{ .Hits }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
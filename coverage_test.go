@@ -0,0 +1,236 @@
+package ictiobus
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/trans"
+	"github.com/stretchr/testify/assert"
+)
+
+// noTraceParser is a parse.Parser that is not a parse.TraceEventSource.
+type noTraceParser struct {
+	parse.Parser
+}
+
+func Test_Frontend_TrackCoverage(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> int X ;
+		X -> plus int X | minus int X | ε ;
+	`)
+
+	ll, err := NewLLParser(g)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lalr, _, err := NewLALRParser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testCases := []struct {
+		name                string
+		parser              parse.Parser
+		inputs              []string
+		expectProds         []string
+		expectUncovRules    []string
+		expectUncovBindings []string
+	}{
+		{
+			name:   "LL(1), no input",
+			parser: ll,
+			expectProds: []string{
+				"S -> int X (0)",
+				"X -> plus int X (0)",
+				"X -> minus int X (0)",
+				"X -> ε (0)",
+			},
+			expectUncovRules: []string{"S", "X"},
+			expectUncovBindings: []string{
+				"S -> int X: {head symbol}.val = add({1st terminal symbol}.$text, {1st non-terminal symbol}.val) (0)",
+				"X -> ε: {head symbol}.val = zero() (0)",
+				"X -> minus int X: {head symbol}.val = sub({2nd terminal symbol}.$text, {1st non-terminal symbol}.val) (0)",
+				"X -> plus int X: {head symbol}.val = add({2nd terminal symbol}.$text, {1st non-terminal symbol}.val) (0)",
+			},
+		},
+		{
+			name:   "LL(1), aggregated across inputs",
+			parser: ll,
+			inputs: []string{"1 + 2", "3", "4 + 5 + 6"},
+			expectProds: []string{
+				"S -> int X (3)",
+				"X -> plus int X (3)",
+				"X -> minus int X (0)",
+				"X -> ε (3)",
+			},
+			expectUncovBindings: []string{
+				"X -> minus int X: {head symbol}.val = sub({2nd terminal symbol}.$text, {1st non-terminal symbol}.val) (0)",
+			},
+		},
+		{
+			name:   "LALR(1), aggregated across inputs",
+			parser: lalr,
+			inputs: []string{"1 + 2", "3", "4 + 5 + 6"},
+			expectProds: []string{
+				"S -> int X (3)",
+				"X -> plus int X (3)",
+				"X -> minus int X (0)",
+				"X -> ε (3)",
+			},
+			expectUncovBindings: []string{
+				"X -> minus int X: {head symbol}.val = sub({2nd terminal symbol}.$text, {1st non-terminal symbol}.val) (0)",
+			},
+		},
+		{
+			name:   "LALR(1), everything covered",
+			parser: lalr,
+			inputs: []string{"1 + 2 - 3"},
+			expectProds: []string{
+				"S -> int X (1)",
+				"X -> plus int X (1)",
+				"X -> minus int X (1)",
+				"X -> ε (1)",
+			},
+		},
+		{
+			name:   "parser without trace events counts only bindings",
+			parser: noTraceParser{ll},
+			inputs: []string{"1 + 2"},
+			expectProds: []string{
+				"S -> int X (0)",
+				"X -> plus int X (0)",
+				"X -> minus int X (0)",
+				"X -> ε (0)",
+			},
+			expectUncovRules: []string{"S", "X"},
+			expectUncovBindings: []string{
+				"X -> minus int X: {head symbol}.val = sub({2nd terminal symbol}.$text, {1st non-terminal symbol}.val) (0)",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			fe := Frontend[int]{
+				Lexer:       coverageTestLexer(),
+				Parser:      tc.parser,
+				SDTS:        coverageTestSDTS(),
+				IRAttribute: "val",
+			}
+			cov := fe.TrackCoverage()
+
+			for _, input := range tc.inputs {
+				_, _, err := fe.AnalyzeString(input)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			var actualProds []string
+			for _, pc := range cov.Productions() {
+				actualProds = append(actualProds, pc.String())
+			}
+			var actualUncovBindings []string
+			for _, bc := range cov.UncoveredBindings() {
+				actualUncovBindings = append(actualUncovBindings, bc.String())
+			}
+
+			assert.Equal(tc.expectProds, actualProds)
+			assert.Equal(tc.expectUncovRules, cov.UncoveredRules())
+			assert.Equal(tc.expectUncovBindings, actualUncovBindings)
+		})
+	}
+}
+
+func Test_Coverage_WriteText(t *testing.T) {
+	assert := assert.New(t)
+
+	g := grammar.MustParse(`
+		S -> int X ;
+		X -> plus int X | minus int X | ε ;
+	`)
+	lalr, _, err := NewLALRParser(g, false)
+	if !assert.NoError(err) {
+		return
+	}
+	fe := Frontend[int]{
+		Lexer:       coverageTestLexer(),
+		Parser:      lalr,
+		SDTS:        coverageTestSDTS(),
+		IRAttribute: "val",
+	}
+	cov := fe.TrackCoverage()
+
+	_, _, err = fe.AnalyzeString("1 + 2")
+	if !assert.NoError(err) {
+		return
+	}
+
+	expect := strings.Join([]string{
+		"Coverage:",
+		"  Rules:       2/2 (100.0%)",
+		"  Productions: 3/4 (75.0%)",
+		"  Bindings:    3/4 (75.0%)",
+		"",
+		"Uncovered productions:",
+		"  X -> minus int X",
+		"",
+		"Uncovered bindings:",
+		"  X -> minus int X: {head symbol}.val = sub({2nd terminal symbol}.$text, {1st non-terminal symbol}.val)",
+		"",
+	}, "\n")
+
+	var buf bytes.Buffer
+	err = cov.WriteText(&buf)
+
+	assert.NoError(err)
+	assert.Equal(expect, buf.String())
+
+	buf.Reset()
+	err = cov.WriteHTML(&buf)
+
+	assert.NoError(err)
+	assert.Contains(buf.String(), `<tr class="uncovered"><td><code>X</code></td><td><code>minus int X</code></td><td class="hits">0</td></tr>`)
+	assert.Contains(buf.String(), `<tr><td><code>X</code></td><td><code>plus int X</code></td><td class="hits">1</td></tr>`)
+}
+
+func coverageTestLexer() lex.Lexer {
+	lx := lex.NewLexer(false)
+	lx.RegisterClass(lex.NewTokenClass("int", "integer"), "")
+	lx.RegisterClass(lex.NewTokenClass("plus", "'+'"), "")
+	lx.RegisterClass(lex.NewTokenClass("minus", "'-'"), "")
+	lx.AddPattern(`[0-9]+`, lex.LexAs("int"), "", 0)
+	lx.AddPattern(`\+`, lex.LexAs("plus"), "", 0)
+	lx.AddPattern(`-`, lex.LexAs("minus"), "", 0)
+	lx.AddPattern(`\s+`, lex.Discard(), "", 0)
+	return lx
+}
+
+func coverageTestSDTS() trans.SDTS {
+	sdts := trans.NewSDTS()
+	sdts.SetHooks(trans.HookMap{
+		"add": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			n, err := strconv.Atoi(args[0].(string))
+			return n + args[1].(int), err
+		},
+		"sub": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			n, err := strconv.Atoi(args[0].(string))
+			return args[1].(int) - n, err
+		},
+		"zero": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return 0, nil
+		},
+	})
+	sdts.Bind("S", []string{"int", "X"}, "val", "add", []trans.AttrRef{{Rel: trans.NRTerminal(0), Name: "$text"}, {Rel: trans.NRNonTerminal(0), Name: "val"}})
+	sdts.Bind("X", []string{"plus", "int", "X"}, "val", "add", []trans.AttrRef{{Rel: trans.NRTerminal(1), Name: "$text"}, {Rel: trans.NRNonTerminal(0), Name: "val"}})
+	sdts.Bind("X", []string{"minus", "int", "X"}, "val", "sub", []trans.AttrRef{{Rel: trans.NRTerminal(1), Name: "$text"}, {Rel: trans.NRNonTerminal(0), Name: "val"}})
+	sdts.Bind("X", []string{""}, "val", "zero", nil)
+	return sdts
+}
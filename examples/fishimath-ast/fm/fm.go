@@ -3,6 +3,7 @@
 package fm
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
@@ -154,7 +155,8 @@ func (interp *Interpreter) Parse(code string) (ast fmhooks.AST, err error) {
 
 		// wrap syntax errors so user of the Interpreter doesn't have to check
 		// for a special syntax error just to get the detailed syntax err info
-		if synErr, ok := err.(*syntaxerr.Error); ok {
+		var synErr *syntaxerr.Error
+		if errors.As(err, &synErr) {
 			return ast, fmt.Errorf("%s", synErr.MessageForFile(interp.File))
 		}
 	}
@@ -173,7 +175,8 @@ func (interp *Interpreter) ParseReader(r io.Reader) (ast fmhooks.AST, err error)
 
 		// wrap syntax errors so user of the Interpreter doesn't have to check
 		// for a special syntax error just to get the detailed syntax err info
-		if synErr, ok := err.(*syntaxerr.Error); ok {
+		var synErr *syntaxerr.Error
+		if errors.As(err, &synErr) {
 			return ast, fmt.Errorf("%s", synErr.MessageForFile(interp.File))
 		}
 	}
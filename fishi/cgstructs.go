@@ -34,6 +34,23 @@ type CodegenOptions struct {
 	// compiled/executed. Normally, these files are removed, but preserving them
 	// allows for diagnostics on the generated source.
 	PreserveBinarySource bool
+
+	// RecursiveDescent is whether to generate the parser as a recursive-descent
+	// parser, with one Go function per non-terminal in the grammar, instead of
+	// embedding an encoded table-driven parser. The grammar must be LL(1) to
+	// use this option.
+	RecursiveDescent bool
+
+	// StaticTables is whether to generate the parsing table of the parser as
+	// Go composite literals that the parser is built from directly, instead of
+	// embedding an encoded parser that must be decoded at run time. Cannot be
+	// used with RecursiveDescent.
+	StaticTables bool
+
+	// FuzzTarget is whether to also generate a test file with a Go fuzz
+	// target, FuzzParse, in the frontend package. It feeds the lexer and
+	// parser random sentences of the grammar made by a parse.Generator.
+	FuzzTarget bool
 }
 
 // GeneratedCodeInfo contains information about the generated code.
@@ -222,7 +239,15 @@ type cgData struct {
 	Classes           []cgClass
 	Patterns          cgPatterns
 	Rules             []cgRule
+	EntryPoints       []string
 	Bindings          []cgBinding
+	RDParser          cgRDParser
+	StaticTables      cgStaticTables
+
+	// Streaming is whether the generated frontend can analyze input without
+	// building a parse tree, which it can when the parser is LR and every
+	// attribute in the translation scheme is synthesized.
+	Streaming bool
 }
 
 func (cgd cgData) String() string {
@@ -239,6 +264,7 @@ func (cgd cgData) String() string {
 	sb.WriteString(fmt.Sprintf("  FrontendPkgImport: %q\n", cgd.FrontendPkgImport))
 	sb.WriteString(fmt.Sprintf("  Command:           %q\n", cgd.Command))
 	sb.WriteString(fmt.Sprintf("  CommandArgs:       %q\n", cgd.CommandArgs))
+	sb.WriteString(fmt.Sprintf("  Streaming:         %t\n", cgd.Streaming))
 
 	// classes
 	sb.WriteString("  Classes:           [")
@@ -293,6 +319,8 @@ func (cgd cgData) String() string {
 		sb.WriteString("  ]\n")
 	}
 
+	sb.WriteString(fmt.Sprintf("  EntryPoints:       %q\n", cgd.EntryPoints))
+
 	// bindings
 	sb.WriteString("  Bindings:          [")
 	if len(cgd.Bindings) < 1 {
@@ -444,3 +472,81 @@ type cgClass struct {
 func (cgc cgClass) String() string {
 	return fmt.Sprintf("(%s %s %q)", cgc.Name, cgc.ID, cgc.Human)
 }
+
+// codegen data for template fill of a parser whose table is given as Go
+// composite literals. Exactly one of LRStates or LLRows will be set, depending
+// on whether Algorithm is an LR algorithm.
+type cgStaticTables struct {
+	// Algorithm is the Go expression for the parse.Algorithm of the parser.
+	Algorithm   string
+	StartSymbol string
+	LR          bool
+	Initial     int
+	LRStates    []cgLRState
+	LLRows      []cgLLRow
+}
+
+type cgLRState struct {
+	Index int
+
+	// Kernel is the kernel items of the state, for identifying it in comments.
+	Kernel  []string
+	Actions []cgTableCell
+	Gotos   []cgTableCell
+}
+
+type cgLLRow struct {
+	NonTerminal string
+	Cells       []cgTableCell
+}
+
+// cgTableCell is a single non-error entry in a parsing table. Value is Go
+// source code that evaluates to the entry.
+type cgTableCell struct {
+	Symbol string
+	Value  string
+}
+
+// codegen data for template fill of a recursive-descent parser.
+type cgRDParser struct {
+	StartSymbol string
+	TableString string
+	Entries     []cgRDEntry
+	Funcs       []cgRDFunc
+}
+
+// cgRDEntry is a non-terminal that a recursive-descent parser can parse input
+// as, along with the name of the parse function for it.
+type cgRDEntry struct {
+	Symbol string
+	Func   string
+}
+
+type cgRDFunc struct {
+	Name        string
+	NonTerminal string
+	Branches    []cgRDBranch
+}
+
+type cgRDBranch struct {
+	Lookaheads []string
+	Production string
+	Symbols    []cgRDSymbol
+}
+
+type cgRDSymbol struct {
+	Symbol   string
+	Terminal bool
+
+	// Func is the name of the parse function for the symbol. Only set when
+	// Terminal is false.
+	Func string
+
+	// Class is the name of the token class var for the symbol. Only set when
+	// Terminal is true.
+	Class string
+
+	// Expect is the message used when the symbol is not matched by input. Only
+	// set when Terminal is true.
+	Expect string
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/dekarrin/ictiobus/syntaxerr"
 	"github.com/dekarrin/rosed"
 )
 
@@ -44,6 +45,10 @@ func (wh WarnHandling) String() string {
 // WarnHandler handles warnings and can be configured by reading slices of names
 // of warnings. The zero-value is not ready to be used; call NewWarnHandler() or
 // NewWarnHandlerFromCLI to create one.
+//
+// ErrorPrefix and WarnPrefix are only used for warnings that do not have a
+// Diagnostic. A warning with a Diagnostic is rendered with its severity already
+// at the start, so no prefix is added to it.
 type WarnHandler struct {
 	h map[WarnType]WarnHandling
 
@@ -53,12 +58,17 @@ type WarnHandler struct {
 	Output io.Writer
 
 	// ErrorPrefix is the string to prepend error messages written to Output
-	// with.
+	// with. It is not used for warnings that have a Diagnostic.
 	ErrorPrefix string
 
 	// WarnPrefix is the string to prepend error messages written to Output
-	// with.
+	// with. It is not used for warnings that have a Diagnostic.
 	WarnPrefix string
+
+	// Report is where warnings are collected when they are to be output in a
+	// machine-readable format. If it is set, warnings that are not suppressed
+	// are added to it instead of being written to Output.
+	Report *Report
 }
 
 // HandlingType returns the WarnHandling configured for the given warning type.
@@ -95,8 +105,11 @@ func (wh *WarnHandler) Handle(w Warning) (fatal error) {
 }
 
 // Handlef is identical to Handle but allows a custom format string to be
-// supplied. It returns a non-nil error if and only if the warning is treated as
-// fatal.
+// supplied. A warning that has a Diagnostic is shown rendered with the source
+// lines it refers to, colored if Output is a terminal, and with the severity
+// of an error if it is treated as fatal; ErrorPrefix and WarnPrefix are not
+// added to it. It returns a non-nil error if and only if the warning is treated
+// as fatal.
 func (wh *WarnHandler) Handlef(fmtStr string, w Warning) (fatal error) {
 	var prefix string
 
@@ -114,8 +127,22 @@ func (wh *WarnHandler) Handlef(fmtStr string, w Warning) (fatal error) {
 		prefix = wh.WarnPrefix
 	}
 
-	msg := prefix + w.Message
 	// okay, not suppressed, so output the warning
+	if wh.Report != nil {
+		wh.Report.AddWarning(w, fatal != nil)
+		return fatal
+	}
+	if w.Diagnostic != nil {
+		// it already says whether it's a warning, so no need for a prefix
+		diag := *w.Diagnostic
+		if fatal != nil {
+			diag.Severity = syntaxerr.SeverityError
+		}
+		fmt.Fprintf(wh.Output, fmtStr, syntaxerr.RendererFor(wh.Output).Render(diag))
+		return fatal
+	}
+
+	msg := prefix + w.Message
 	if strings.Contains(msg, "\n") {
 		// rosed will help us here;
 
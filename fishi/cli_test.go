@@ -0,0 +1,100 @@
+package fishi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WarnHandler_Handle(t *testing.T) {
+	diag := syntaxerr.Diagnostic{
+		Severity:   syntaxerr.SeverityWarning,
+		Message:    "setting priority to 0 has no effect",
+		Span:       syntaxerr.Span{Start: syntaxerr.Position{Line: 2, Col: 18}, End: syntaxerr.Position{Line: 2, Col: 19}},
+		SourceLine: `\+ %token plus %priority 0`,
+	}
+
+	testCases := []struct {
+		name        string
+		fatal       bool
+		warn        Warning
+		expect      string
+		expectFatal bool
+	}{
+		{
+			name:   "message only",
+			warn:   Warning{Type: WarnPriorityZero, Message: "bad\nthing"},
+			expect: "WARN: bad\n      thing\n",
+		},
+		{
+			name: "diagnostic",
+			warn: Warning{Type: WarnPriorityZero, Message: "unused", Diagnostic: &diag},
+			expect: "warning: setting priority to 0 has no effect\n" +
+				" --> 2:18\n" +
+				"  |\n" +
+				"2 | \\+ %token plus %priority 0\n" +
+				"  |                  ^\n",
+		},
+		{
+			name:  "fatal diagnostic",
+			fatal: true,
+			warn:  Warning{Type: WarnPriorityZero, Message: "unused", Diagnostic: &diag},
+			expect: "error: setting priority to 0 has no effect\n" +
+				" --> 2:18\n" +
+				"  |\n" +
+				"2 | \\+ %token plus %priority 0\n" +
+				"  |                  ^\n",
+			expectFatal: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var out bytes.Buffer
+			wh := NewWarnHandler()
+			wh.Output = &out
+			if tc.fatal {
+				wh.Fatal(tc.warn.Type)
+			}
+
+			// execute
+			err := wh.Handle(tc.warn)
+
+			// assert
+			assert.Equal(tc.expect, out.String())
+			if tc.expectFatal {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func Test_WarnHandler_Handle_report(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	wh := NewWarnHandler()
+	wh.Output = &out
+	wh.Report = &Report{}
+	wh.Suppressed(WarnUnusedTerminal)
+	wh.Fatal(WarnPriorityZero)
+
+	// execute
+	errUnused := wh.Handle(Warning{Type: WarnUnusedTerminal, Message: "unused"})
+	errPriority := wh.Handle(Warning{Type: WarnPriorityZero, Message: "priority 0"})
+
+	// assert
+	assert.NoError(errUnused)
+	assert.Error(errPriority)
+	assert.Empty(out.String())
+	if assert.Len(wh.Report.Problems, 1) {
+		assert.Equal("priority", wh.Report.Problems[0].WarnType)
+		assert.Equal(syntaxerr.SeverityError, wh.Report.Problems[0].Severity)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	_ "embed"
+	"errors"
 	"fmt"
 	"go/format"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"text/template"
 	"unicode"
 
+	"github.com/dekarrin/ictiobus/grammar"
 	"github.com/dekarrin/ictiobus/internal/box"
 	"github.com/dekarrin/ictiobus/internal/shellout"
 	"github.com/dekarrin/ictiobus/internal/textfmt"
@@ -43,6 +45,12 @@ const (
 	ComponentSDTS     = "sdts"
 	ComponentFrontend = "frontend"
 	ComponentMainFile = "main"
+	ComponentFuzzTest = "fuzz"
+
+	// ComponentParserRD is the parser component when the frontend is generated
+	// with a recursive-descent parser. It is output to the same file that
+	// ComponentParser would be.
+	ComponentParserRD = "parser-rd"
 )
 
 // Names of each file that is generated.
@@ -53,6 +61,7 @@ const (
 	generatedSDTSFilename     = "sdts.ict.go"
 	generatedFrontendFilename = "frontend.ict.go"
 	generatedMainFilename     = "main.ict.go"
+	generatedFuzzTestFilename = "fuzz.ict_test.go"
 )
 
 // Default template strings for each component of the generated compiler.
@@ -66,6 +75,9 @@ var (
 	//go:embed templates/parser.go.tmpl
 	templateParser string
 
+	//go:embed templates/parser_rd.go.tmpl
+	templateParserRD string
+
 	//go:embed templates/sdts.go.tmpl
 	templateSDTS string
 
@@ -74,6 +86,9 @@ var (
 
 	//go:embed templates/main.go.tmpl
 	templateMainFile string
+
+	//go:embed templates/fuzz_test.go.tmpl
+	templateFuzzTest string
 )
 
 var (
@@ -88,18 +103,22 @@ var (
 		ComponentTokens,
 		ComponentLexer,
 		ComponentParser,
+		ComponentParserRD,
 		ComponentSDTS,
 		ComponentFrontend,
 		ComponentMainFile,
+		ComponentFuzzTest,
 	}
 
 	defaultTemplates = map[string]string{
 		ComponentTokens:   templateTokens,
 		ComponentLexer:    templateLexer,
 		ComponentParser:   templateParser,
+		ComponentParserRD: templateParserRD,
 		ComponentSDTS:     templateSDTS,
 		ComponentFrontend: templateFrontend,
 		ComponentMainFile: templateMainFile,
+		ComponentFuzzTest: templateFuzzTest,
 	}
 )
 
@@ -140,7 +159,19 @@ func executeTestCompiler(gci GeneratedCodeInfo, valOptions *trans.ValidationOpti
 	}
 
 	env := append(os.Environ(), "GOWORK=off")
-	return shellout.ExecFG(gci.Path, env, "go", args...)
+	if warnOpts.Report == nil {
+		return shellout.ExecFG(gci.Path, env, "go", args...)
+	}
+
+	// collect what the compiler reports instead of letting it print it
+	args = append(args, "--diagnostics-format", DiagnosticsJSON.String())
+	var stderr bytes.Buffer
+	runErr := shellout.ExecFGStderrTo(gci.Path, env, &stderr, "go", args...)
+	if err := warnOpts.Report.Merge(stderr.Bytes()); err != nil && stderr.Len() > 0 {
+		// not a report; likely the output of go build failing
+		warnOpts.Report.AddError("", errors.New(strings.TrimSpace(stderr.String())))
+	}
+	return runErr
 }
 
 // GenerateDiagnosticsBinary generates a binary that can read input written in
@@ -326,17 +357,21 @@ func generateBinaryMainGo(spec Spec, md SpecMetadata, params mainBinaryParams) (
 		feFQir := makeFQType(feIRPkg, irType)
 		feOpts.IRType = feFQir
 	}
-	err = GenerateFrontendGo(spec, md, params.FrontendPkgName, fePkgPath, fePkgImport, &feOpts)
+	err = GenerateFrontendGo(spec, md, params.Parser, params.FrontendPkgName, fePkgPath, fePkgImport, &feOpts)
 	if err != nil {
 		return gci, fmt.Errorf("generating compiler: %w", err)
 	}
 
 	// since GenerateCompilerGo ensures the directory exists, we can now copy
-	// the encoded parser into it as well.
-	parserPath := filepath.Join(fePkgPath, "parser.cff")
-	err = parse.WriteFile(params.Parser, parserPath)
-	if err != nil {
-		return gci, fmt.Errorf("writing parser: %w", err)
+	// the encoded parser into it as well. a recursive-descent parser or one
+	// with static tables is made entirely of generated code and has no need of
+	// it.
+	if !params.Opts.RecursiveDescent && !params.Opts.StaticTables {
+		parserPath := filepath.Join(fePkgPath, "parser.cff")
+		err = parse.WriteFile(params.Parser, parserPath)
+		if err != nil {
+			return gci, fmt.Errorf("writing parser: %w", err)
+		}
 	}
 
 	var irIsBuiltIn bool
@@ -460,8 +495,15 @@ func clearDirContents(dir string) error {
 // handle a fishi spec. The source code is placed in the given directory. This
 // does *not* copy the hooks package, it only outputs the frontend code.
 //
+// p is the parser that the frontend will use. When opts.StaticTables is set,
+// its parsing table is output as Go code; otherwise, the encoded parser must be
+// placed in the package directory separately. If p is an LR parser and the
+// spec is S-attributed, the frontend package also gets an AnalyzeStream
+// function that analyzes input without building a parse tree. p may be nil if
+// opts.StaticTables is not set, in which case no AnalyzeStream is generated.
+//
 // If opts is nil, the default options will be used.
-func GenerateFrontendGo(spec Spec, md SpecMetadata, pkgName, pkgDir string, pkgImport string, opts *CodegenOptions) error {
+func GenerateFrontendGo(spec Spec, md SpecMetadata, p parse.Parser, pkgName, pkgDir string, pkgImport string, opts *CodegenOptions) error {
 	if len(spec.Tokens) == 0 {
 		return fmt.Errorf("spec defines no tokens")
 	}
@@ -481,6 +523,30 @@ func GenerateFrontendGo(spec Spec, md SpecMetadata, pkgName, pkgDir string, pkgI
 
 	data := createTemplateFillData(spec, md, pkgName, opts.IRType, pkgImport)
 
+	parserComponent := ComponentParser
+	if opts.RecursiveDescent {
+		rdData, err := createRDParserFillData(spec)
+		if err != nil {
+			return fmt.Errorf("generating recursive-descent parser: %w", err)
+		}
+		data.RDParser = rdData
+		parserComponent = ComponentParserRD
+	}
+	if opts.StaticTables {
+		if opts.RecursiveDescent {
+			return fmt.Errorf("static tables cannot be generated for a recursive-descent parser")
+		}
+		if p == nil {
+			return fmt.Errorf("static tables require a parser")
+		}
+		tablesData, err := createStaticTablesFillData(p, spec.Grammar.StartSymbol())
+		if err != nil {
+			return fmt.Errorf("generating static parser tables: %w", err)
+		}
+		data.StaticTables = tablesData
+	}
+	data.Streaming = p != nil && !opts.RecursiveDescent && p.Type().IsLR() && spec.SAttributed()
+
 	err := os.MkdirAll(pkgDir, 0755)
 	if err != nil {
 		return fmt.Errorf("creating target dir: %w", err)
@@ -496,10 +562,13 @@ func GenerateFrontendGo(spec Spec, md SpecMetadata, pkgName, pkgDir string, pkgI
 	renderFiles := map[string]codegenTemplate{
 		ComponentTokens:   {nil, filepath.Join(pkgName+"token", generatedTokensFilename)},
 		ComponentLexer:    {nil, generatedLexerFilename},
-		ComponentParser:   {nil, generatedParserFilename},
+		parserComponent:   {nil, generatedParserFilename},
 		ComponentSDTS:     {nil, generatedSDTSFilename},
 		ComponentFrontend: {nil, generatedFrontendFilename},
 	}
+	if opts.FuzzTarget {
+		renderFiles[ComponentFuzzTest] = codegenTemplate{nil, generatedFuzzTestFilename}
+	}
 
 	// initialize templates
 	err = initTemplates(renderFiles, fnMap, opts.TemplateFiles)
@@ -731,6 +800,7 @@ func createTemplateFillData(spec Spec, md SpecMetadata, pkgName string, fqIRType
 
 		data.Rules = append(data.Rules, rData)
 	}
+	data.EntryPoints = spec.Grammar.EntryPoints
 
 	// fill bindings
 
@@ -786,6 +856,174 @@ func createTemplateFillData(spec Spec, md SpecMetadata, pkgName string, fqIRType
 	return data
 }
 
+// createStaticTablesFillData creates the template fill data for giving the
+// parsing table of p as Go composite literals.
+func createStaticTablesFillData(p parse.Parser, startSymbol string) (cgStaticTables, error) {
+	data := cgStaticTables{StartSymbol: startSymbol}
+
+	switch p.Type() {
+	case parse.LL1:
+		data.Algorithm = "parse.LL1"
+	case parse.SLR1:
+		data.Algorithm = "parse.SLR1"
+	case parse.LALR1:
+		data.Algorithm = "parse.LALR1"
+	case parse.CLR1:
+		data.Algorithm = "parse.CLR1"
+	default:
+		return data, fmt.Errorf("unsupported parser type: %s", p.Type())
+	}
+
+	if p.Type() == parse.LL1 {
+		preds, err := parse.LL1Predictions(p)
+		if err != nil {
+			return data, err
+		}
+
+		for _, A := range textfmt.OrderedKeys(preds) {
+			row := cgLLRow{NonTerminal: A}
+			for _, a := range textfmt.OrderedKeys(preds[A]) {
+				row.Cells = append(row.Cells, cgTableCell{
+					Symbol: a,
+					Value:  fmt.Sprintf("%#v", preds[A][a]),
+				})
+			}
+			data.LLRows = append(data.LLRows, row)
+		}
+
+		return data, nil
+	}
+
+	table, err := parse.LRTableOf(p)
+	if err != nil {
+		return data, err
+	}
+	kernels, err := parse.LRStateKernels(p)
+	if err != nil {
+		return data, err
+	}
+
+	data.LR = true
+	data.Initial = table.Initial
+	for i := range table.Action {
+		state := cgLRState{Index: i}
+		for _, item := range kernels[i] {
+			state.Kernel = append(state.Kernel, item.String())
+		}
+		for _, a := range textfmt.OrderedKeys(table.Action[i]) {
+			state.Actions = append(state.Actions, cgTableCell{
+				Symbol: a,
+				Value:  fmt.Sprintf("%#v", table.Action[i][a]),
+			})
+		}
+		for _, A := range textfmt.OrderedKeys(table.Goto[i]) {
+			state.Gotos = append(state.Gotos, cgTableCell{
+				Symbol: A,
+				Value:  fmt.Sprintf("%d", table.Goto[i][A]),
+			})
+		}
+		data.LRStates = append(data.LRStates, state)
+	}
+
+	return data, nil
+}
+
+// createRDParserFillData creates the template fill data for a recursive-descent
+// parser for the grammar in spec. The grammar must be LL(1); each non-terminal
+// gets its own parse function whose branches are selected by the lookaheads
+// from the LL(1) prediction table.
+func createRDParserFillData(spec Spec) (cgRDParser, error) {
+	p, _, err := spec.CreateParser(parse.LL1, false)
+	if err != nil {
+		return cgRDParser{}, err
+	}
+	preds, err := parse.LL1Predictions(p)
+	if err != nil {
+		return cgRDParser{}, err
+	}
+
+	g := spec.Grammar
+	nts := g.NonTerminalsByPriority()
+
+	// name the parse funcs first so they can be referred to by each other.
+	// different NT names can map to the same identifier (e.g. "A-B" and "A_B"),
+	// so number any that collide.
+	funcNames := map[string]string{}
+	usedNames := box.NewStringSet()
+	for _, nt := range nts {
+		name := "parse" + safeTCIdentifierName(nt)[2:]
+		candidate := name
+		for i := 2; usedNames.Has(candidate); i++ {
+			candidate = fmt.Sprintf("%s%d", name, i)
+		}
+		usedNames.Add(candidate)
+		funcNames[nt] = candidate
+	}
+
+	tokCgClasses := map[string]cgClass{}
+	for _, class := range spec.Tokens {
+		tokCgClasses[class.ID()] = cgClass{
+			Name:  safeTCIdentifierName(class.ID()),
+			ID:    class.ID(),
+			Human: class.Human(),
+		}
+	}
+
+	data := cgRDParser{
+		StartSymbol: g.StartSymbol(),
+		TableString: p.TableString(),
+	}
+
+	for _, nt := range g.AllEntryPoints() {
+		data.Entries = append(data.Entries, cgRDEntry{Symbol: nt, Func: funcNames[nt]})
+	}
+
+	for _, nt := range nts {
+		fn := cgRDFunc{
+			Name:        funcNames[nt],
+			NonTerminal: nt,
+		}
+
+		// one branch per production, in the order they are given in the rule,
+		// with every lookahead that predicts it.
+		ntPreds := preds[nt]
+		for _, prod := range g.Rule(nt).Productions {
+			branch := cgRDBranch{
+				Production: nt + " -> " + prod.String(),
+			}
+			for _, a := range textfmt.OrderedKeys(ntPreds) {
+				if ntPreds[a].Equal(prod) {
+					branch.Lookaheads = append(branch.Lookaheads, a)
+				}
+			}
+			if len(branch.Lookaheads) == 0 {
+				// never predicted, so can never be taken.
+				continue
+			}
+
+			if !prod.Equal(grammar.Epsilon) {
+				for _, sym := range prod {
+					symData := cgRDSymbol{Symbol: sym, Terminal: g.IsTerminal(sym)}
+					if symData.Terminal {
+						class := tokCgClasses[sym]
+						symData.Class = class.Name
+						symData.Expect = "expected " + textfmt.ArticleFor(class.Human, false) + " " + class.Human
+					} else {
+						symData.Func = funcNames[sym]
+					}
+					branch.Symbols = append(branch.Symbols, symData)
+				}
+			}
+
+			fn.Branches = append(fn.Branches, branch)
+		}
+
+		data.Funcs = append(data.Funcs, fn)
+	}
+
+	return data, nil
+}
+
 func safeTCIdentifierName(str string) string {
 	nameRunes := []rune{}
 
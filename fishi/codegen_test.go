@@ -0,0 +1,400 @@
+package fishi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_createRDParserFillData(t *testing.T) {
+	testCases := []struct {
+		name          string
+		tokens        []lex.TokenClass
+		grammar       string
+		entryPoints   []string
+		expect        []cgRDFunc
+		expectEntries []cgRDEntry
+		expectErr     bool
+	}{
+		{
+			name: "LL(1) expression grammar",
+			tokens: []lex.TokenClass{
+				lex.NewTokenClass("+", "plus sign"),
+				lex.NewTokenClass("int", "integer"),
+			},
+			grammar: `
+				E   -> int E-P ;
+				E-P -> + int E-P | ε ;
+			`,
+			expectEntries: []cgRDEntry{{Symbol: "E", Func: "parseE"}},
+			expect: []cgRDFunc{
+				{
+					Name:        "parseE",
+					NonTerminal: "E",
+					Branches: []cgRDBranch{
+						{
+							Lookaheads: []string{"int"},
+							Production: "E -> int E-P",
+							Symbols: []cgRDSymbol{
+								{Symbol: "int", Terminal: true, Class: "TCInt", Expect: "expected an integer"},
+								{Symbol: "E-P", Func: "parseEP"},
+							},
+						},
+					},
+				},
+				{
+					Name:        "parseEP",
+					NonTerminal: "E-P",
+					Branches: []cgRDBranch{
+						{
+							Lookaheads: []string{"+"},
+							Production: "E-P -> + int E-P",
+							Symbols: []cgRDSymbol{
+								{Symbol: "+", Terminal: true, Class: "TCPlusSign", Expect: "expected a plus sign"},
+								{Symbol: "int", Terminal: true, Class: "TCInt", Expect: "expected an integer"},
+								{Symbol: "E-P", Func: "parseEP"},
+							},
+						},
+						{
+							Lookaheads: []string{"$"},
+							Production: "E-P -> ε",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "colliding func names are numbered",
+			tokens: []lex.TokenClass{
+				lex.NewTokenClass("a", "a"),
+			},
+			grammar: `
+				S   -> A-B A_B ;
+				A-B -> a ;
+				A_B -> a ;
+			`,
+			expectEntries: []cgRDEntry{{Symbol: "S", Func: "parseS"}},
+			expect: []cgRDFunc{
+				{
+					Name:        "parseS",
+					NonTerminal: "S",
+					Branches: []cgRDBranch{
+						{
+							Lookaheads: []string{"a"},
+							Production: "S -> A-B A_B",
+							Symbols: []cgRDSymbol{
+								{Symbol: "A-B", Func: "parseAB"},
+								{Symbol: "A_B", Func: "parseAB2"},
+							},
+						},
+					},
+				},
+				{
+					Name:        "parseAB",
+					NonTerminal: "A-B",
+					Branches: []cgRDBranch{
+						{
+							Lookaheads: []string{"a"},
+							Production: "A-B -> a",
+							Symbols: []cgRDSymbol{
+								{Symbol: "a", Terminal: true, Class: "TCA", Expect: "expected an a"},
+							},
+						},
+					},
+				},
+				{
+					Name:        "parseAB2",
+					NonTerminal: "A_B",
+					Branches: []cgRDBranch{
+						{
+							Lookaheads: []string{"a"},
+							Production: "A_B -> a",
+							Symbols: []cgRDSymbol{
+								{Symbol: "a", Terminal: true, Class: "TCA", Expect: "expected an a"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "entry point can be followed by end of input",
+			tokens: []lex.TokenClass{
+				lex.NewTokenClass("a", "a"),
+				lex.NewTokenClass("b", "b"),
+			},
+			grammar: `
+				S -> A b ;
+				A -> a A | ε ;
+			`,
+			entryPoints: []string{"A"},
+			expectEntries: []cgRDEntry{
+				{Symbol: "S", Func: "parseS"},
+				{Symbol: "A", Func: "parseA"},
+			},
+			expect: []cgRDFunc{
+				{
+					Name:        "parseS",
+					NonTerminal: "S",
+					Branches: []cgRDBranch{
+						{
+							Lookaheads: []string{"a", "b"},
+							Production: "S -> A b",
+							Symbols: []cgRDSymbol{
+								{Symbol: "A", Func: "parseA"},
+								{Symbol: "b", Terminal: true, Class: "TCB", Expect: "expected a b"},
+							},
+						},
+					},
+				},
+				{
+					Name:        "parseA",
+					NonTerminal: "A",
+					Branches: []cgRDBranch{
+						{
+							Lookaheads: []string{"a"},
+							Production: "A -> a A",
+							Symbols: []cgRDSymbol{
+								{Symbol: "a", Terminal: true, Class: "TCA", Expect: "expected an a"},
+								{Symbol: "A", Func: "parseA"},
+							},
+						},
+						{
+							Lookaheads: []string{"$", "b"},
+							Production: "A -> ε",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "non-LL(1) grammar",
+			tokens: []lex.TokenClass{
+				lex.NewTokenClass("+", "plus sign"),
+				lex.NewTokenClass("int", "integer"),
+			},
+			grammar: `
+				E -> E + int | int ;
+			`,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			spec := Spec{
+				Tokens:  tc.tokens,
+				Grammar: grammar.MustParse(tc.grammar),
+			}
+			spec.Grammar.EntryPoints = tc.entryPoints
+
+			actual, err := createRDParserFillData(spec)
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(spec.Grammar.StartSymbol(), actual.StartSymbol)
+			assert.Equal(tc.expectEntries, actual.Entries)
+			assert.NotEmpty(actual.TableString)
+			assert.Equal(tc.expect, actual.Funcs)
+		})
+	}
+}
+
+func Test_createStaticTablesFillData(t *testing.T) {
+	testCases := []struct {
+		name    string
+		algo    parse.Algorithm
+		grammar string
+		expect  cgStaticTables
+	}{
+		{
+			name: "LL(1) parser",
+			algo: parse.LL1,
+			grammar: `
+				E   -> int E-P ;
+				E-P -> + int E-P | ε ;
+			`,
+			expect: cgStaticTables{
+				Algorithm:   "parse.LL1",
+				StartSymbol: "E",
+				LLRows: []cgLLRow{
+					{NonTerminal: "E", Cells: []cgTableCell{
+						{Symbol: "int", Value: `grammar.Production{"int", "E-P"}`},
+					}},
+					{NonTerminal: "E-P", Cells: []cgTableCell{
+						{Symbol: "$", Value: `grammar.Production{""}`},
+						{Symbol: "+", Value: `grammar.Production{"+", "int", "E-P"}`},
+					}},
+				},
+			},
+		},
+		{
+			name: "SLR(1) parser",
+			algo: parse.SLR1,
+			grammar: `
+				S -> a ;
+			`,
+			expect: cgStaticTables{
+				Algorithm:   "parse.SLR1",
+				StartSymbol: "S",
+				LR:          true,
+				LRStates: []cgLRState{
+					{
+						Index:   0,
+						Kernel:  []string{"S-P -> . S"},
+						Actions: []cgTableCell{{Symbol: "a", Value: "parse.Shift(1)"}},
+						Gotos:   []cgTableCell{{Symbol: "S", Value: "2"}},
+					},
+					{
+						Index:   1,
+						Kernel:  []string{"S -> a ."},
+						Actions: []cgTableCell{{Symbol: "$", Value: `parse.Reduce("S", "a")`}},
+					},
+					{
+						Index:   2,
+						Kernel:  []string{"S-P -> S ."},
+						Actions: []cgTableCell{{Symbol: "$", Value: "parse.Accept()"}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := grammar.MustParse(tc.grammar)
+			var p parse.Parser
+			var err error
+			if tc.algo == parse.LL1 {
+				p, err = parse.GenerateLL1Parser(g)
+			} else {
+				p, _, err = parse.GenerateSLR1Parser(g, false)
+			}
+			if !assert.NoError(err, "generating parser failed") {
+				return
+			}
+
+			actual, err := createStaticTablesFillData(p, g.StartSymbol())
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_GenerateFrontendGo_Streaming(t *testing.T) {
+	const specTemplate = `%%tokens
+\+        %token plus  %human '+'
+[0-9]+    %token int   %human 'integer'
+\s+       %discard
+
+%%grammar
+{S} = {S} plus int | int
+
+%%actions
+%symbol {S}
+-> {S} plus int : {^}.val = add({0}.val, {2}.$text)
+                  INHERITED
+-> int          : {^}.val = int({0}.$text)
+`
+
+	testCases := []struct {
+		name            string
+		inherited       bool
+		algo            parse.Algorithm
+		expectStreaming bool
+	}{
+		{
+			name:            "S-attributed with LR parser",
+			algo:            parse.SLR1,
+			expectStreaming: true,
+		},
+		{
+			name: "S-attributed with LL parser",
+			algo: parse.LL1,
+		},
+		{
+			name:      "inherited attribute with LR parser",
+			inherited: true,
+			algo:      parse.SLR1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			inhAction := ""
+			if tc.inherited {
+				inhAction = ": {0}.inh = int({2}.$text)"
+			}
+			res, err := Parse(strings.NewReader(strings.Replace(specTemplate, "INHERITED", inhAction, 1)), nil)
+			if !assert.NoError(err) {
+				return
+			}
+			spec, _, err := NewSpec(*res.AST)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(!tc.inherited, spec.SAttributed())
+
+			var p parse.Parser
+			if tc.algo == parse.LL1 {
+				// the grammar is left-recursive so it must be LR; it is only
+				// the algorithm that matters here.
+				p = mockLLParser{}
+			} else {
+				p, _, err = parse.GenerateSLR1Parser(spec.Grammar, false)
+				if !assert.NoError(err) {
+					return
+				}
+			}
+
+			dir := t.TempDir()
+			opts := &CodegenOptions{}
+			md := SpecMetadata{Language: "Test", Version: "1.0"}
+			err = GenerateFrontendGo(spec, md, p, "fe", dir, "example.com/fe", opts)
+			if !assert.NoError(err) {
+				return
+			}
+
+			code, err := os.ReadFile(filepath.Join(dir, generatedFrontendFilename))
+			if !assert.NoError(err) {
+				return
+			}
+
+			if tc.expectStreaming {
+				assert.Contains(string(code), "func AnalyzeStream[IRType any](ctx context.Context, r io.Reader, lim ictiobus.Limits, hooks trans.HookMap, opts *FrontendOptions) (IRType, error)")
+			} else {
+				assert.NotContains(string(code), "AnalyzeStream")
+			}
+		})
+	}
+}
+
+// mockLLParser is a parse.Parser that reports itself as an LL(1) parser and
+// can do nothing else.
+type mockLLParser struct {
+	parse.Parser
+}
+
+func (mockLLParser) Type() parse.Algorithm {
+	return parse.LL1
+}
@@ -0,0 +1,278 @@
+package fishi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/fishi/syntax"
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/slices"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/trans"
+)
+
+// ebnfAttribute is the name of the attribute that the actions generated for
+// EBNF operators and groups set.
+const ebnfAttribute = "value"
+
+// ebnfKind is what a generated rule was made for.
+type ebnfKind int
+
+const (
+	// ebnfGroup is a group. Its rule has one production for
+	// each alternative in the group.
+	ebnfGroup ebnfKind = iota
+
+	// ebnfOptional is the '?' operator. Its rule is H -> X | ε.
+	ebnfOptional
+
+	// ebnfStar is the '*' operator. Its rule is H -> R, where R is the
+	// ebnfRepeat rule for X. It puts the items that R gives back in order.
+	ebnfStar
+
+	// ebnfRepeat is the repetition used by ebnfStar. Its rule is H -> X H | ε,
+	// and it gives the items in reverse order so that each one can be appended
+	// to the list instead of copying the list to put it at the front.
+	ebnfRepeat
+
+	// ebnfPlus is the '+' operator. Its rule is H -> X S, where S is the rule
+	// for X*.
+	ebnfPlus
+)
+
+// ebnfRule is a rule for a non-terminal that was generated in place of an EBNF
+// operator or group in a production of a FISHI grammar.
+type ebnfRule struct {
+	head  string
+	kind  ebnfKind
+	prods []grammar.Production
+
+	// src is the token of the symbol or group the rule was made for.
+	src lex.Token
+}
+
+// ebnfDesugarer replaces EBNF operators and groups in the productions of a
+// grammar with generated non-terminals whose rules are plain BNF.
+type ebnfDesugarer struct {
+	// names has a rule for every non-terminal in the grammar, including the
+	// generated ones, so that new names can be generated that are not in use.
+	names grammar.CFG
+
+	// rules is the generated rules, in the order that their names were
+	// generated.
+	rules []ebnfRule
+
+	// plain converts a terminal or non-terminal as written in FISHI to the
+	// symbol in the grammar.
+	plain func(sym string, src lex.Token) (string, error)
+}
+
+// production returns prod with each of its symbols converted to a grammar
+// symbol. Any that have an EBNF operator or are a group are replaced with a
+// generated non-terminal named after head.
+func (d *ebnfDesugarer) production(head string, prod []syntax.GrammarSymbol, src lex.Token) (grammar.Production, error) {
+	newProd := grammar.Production{}
+	for _, sym := range prod {
+		converted, err := d.symbol(head, sym, src)
+		if err != nil {
+			return nil, err
+		}
+		newProd = append(newProd, converted)
+	}
+	return newProd, nil
+}
+
+// symbol returns the grammar symbol for sym, generating rules for it if it has
+// an EBNF operator or is a group.
+func (d *ebnfDesugarer) symbol(head string, sym syntax.GrammarSymbol, src lex.Token) (string, error) {
+	if sym.Src != nil {
+		src = sym.Src
+	}
+
+	// a group of a single plain symbol is only there to take an operator, so
+	// it does not need a rule of its own.
+	if len(sym.Group) == 1 && len(sym.Group[0]) == 1 && sym.Group[0][0].Plain() && sym.Group[0][0].Symbol != "" {
+		inner := sym.Group[0][0]
+		inner.Op = sym.Op
+		if inner.Src == nil {
+			inner.Src = src
+		}
+		sym = inner
+	}
+
+	var elem string
+	if sym.Group != nil {
+		idx := d.reserve(head, ebnfGroup, src)
+		for _, alt := range sym.Group {
+			p, err := d.production(head, alt, src)
+			if err != nil {
+				return "", err
+			}
+			d.rules[idx].prods = append(d.rules[idx].prods, p)
+		}
+		elem = d.rules[idx].head
+	} else {
+		var err error
+		elem, err = d.plain(sym.Symbol, src)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch sym.Op {
+	case "":
+		return elem, nil
+	case "?":
+		idx := d.reserve(head, ebnfOptional, src)
+		d.rules[idx].prods = []grammar.Production{{elem}, grammar.Epsilon.Copy()}
+		return d.rules[idx].head, nil
+	case "*":
+		return d.star(head, elem, src), nil
+	case "+":
+		idx := d.reserve(head, ebnfPlus, src)
+		star := d.star(head, elem, src)
+		d.rules[idx].prods = []grammar.Production{{elem, star}}
+		return d.rules[idx].head, nil
+	default:
+		return "", lex.NewSyntaxErrorFromToken(fmt.Sprintf("unknown EBNF operator %q", sym.Op), src)
+	}
+}
+
+// star generates the rules for zero or more of elem and returns the head of
+// the outermost one.
+func (d *ebnfDesugarer) star(head string, elem string, src lex.Token) string {
+	idx := d.reserve(head, ebnfStar, src)
+	repIdx := d.reserve(head, ebnfRepeat, src)
+	repHead := d.rules[repIdx].head
+
+	// right recursion keeps the rule usable by LL(1) parsers.
+	d.rules[repIdx].prods = []grammar.Production{{elem, repHead}, grammar.Epsilon.Copy()}
+	d.rules[idx].prods = []grammar.Production{{repHead}}
+	return d.rules[idx].head
+}
+
+// reserve adds a new rule of the given kind with a generated name based on head
+// and returns its index in d.rules.
+func (d *ebnfDesugarer) reserve(head string, kind ebnfKind, src lex.Token) int {
+	name := d.names.GenerateUniqueName(head)
+	d.names.AddRule(name, grammar.Epsilon)
+	d.rules = append(d.rules, ebnfRule{head: name, kind: kind, src: src})
+	return len(d.rules) - 1
+}
+
+// ebnfTranslationScheme returns the SDDs that set the value attribute of each
+// of the generated rules. Terminals give their lexed text, generated
+// non-terminals give their value, and other non-terminals give the attribute
+// that the actions in scheme set on them.
+//
+// The value is nil for ε and for a '?' that matched nothing, an []interface{}
+// of each item for '*' and '+', and for a group is the value of the only symbol
+// in the alternative that matched or an []interface{} of the value of each
+// symbol in it if there are several.
+func ebnfTranslationScheme(rules []ebnfRule, g grammar.CFG, scheme []SDD) ([]SDD, error) {
+	generated := map[string]bool{}
+	for _, r := range rules {
+		generated[r.head] = true
+	}
+
+	attrs := map[string][]string{}
+	for _, sdd := range scheme {
+		nt := sdd.Rule.NonTerminal
+		if sdd.Attribute.Rel.Type == trans.RelHead && !slices.In(sdd.Attribute.Name, attrs[nt]) {
+			attrs[nt] = append(attrs[nt], sdd.Attribute.Name)
+		}
+	}
+
+	valueOf := func(r ebnfRule, prod grammar.Production, i int) (trans.AttrRef, error) {
+		sym := prod[i]
+		ref := trans.AttrRef{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: i}}
+
+		if g.IsTerminal(sym) {
+			ref.Name = "$text"
+			return ref, nil
+		} else if generated[sym] {
+			ref.Name = ebnfAttribute
+			return ref, nil
+		}
+
+		names := attrs[sym]
+		if len(names) == 1 {
+			ref.Name = names[0]
+		} else if slices.In(ebnfAttribute, names) {
+			ref.Name = ebnfAttribute
+		} else {
+			var msg string
+			if len(names) == 0 {
+				msg = fmt.Sprintf("%s is used with an EBNF operator or in a group, but no action sets an attribute on it", sym)
+			} else {
+				msg = fmt.Sprintf("%s is used with an EBNF operator or in a group, but actions set more than one attribute on it (%s) and none are named %q", sym, strings.Join(names, ", "), ebnfAttribute)
+			}
+			if r.src == nil {
+				return ref, fmt.Errorf("%s", msg)
+			}
+			return ref, lex.NewSyntaxErrorFromToken(msg, r.src)
+		}
+		return ref, nil
+	}
+
+	var sdds []SDD
+	for _, r := range rules {
+		for i, p := range r.prods {
+			sdd := SDD{
+				Attribute: trans.AttrRef{Rel: trans.NodeRelation{Type: trans.RelHead}, Name: ebnfAttribute},
+				Rule:      grammar.Rule{NonTerminal: r.head, Productions: []grammar.Production{p.Copy()}},
+			}
+
+			// the items that the hook is called with, by index in p.
+			var items []int
+
+			switch r.kind {
+			case ebnfGroup:
+				if p.Equal(grammar.Epsilon) {
+					sdd.Hook = trans.HookNil
+				} else if len(p) == 1 {
+					sdd.Hook = trans.HookIdent
+					items = []int{0}
+				} else {
+					sdd.Hook = trans.HookTuple
+					for j := range p {
+						items = append(items, j)
+					}
+				}
+			case ebnfOptional:
+				if i == 0 {
+					sdd.Hook = trans.HookIdent
+					items = []int{0}
+				} else {
+					sdd.Hook = trans.HookNil
+				}
+			case ebnfStar:
+				sdd.Hook = trans.HookListReverse
+				items = []int{0}
+			case ebnfRepeat:
+				if p.Equal(grammar.Epsilon) {
+					sdd.Hook = trans.HookListEmpty
+				} else {
+					sdd.Hook = trans.HookListAppend
+					items = []int{1, 0}
+				}
+			case ebnfPlus:
+				// only done once for the first item, so the copy is fine.
+				sdd.Hook = trans.HookListPrepend
+				items = []int{0, 1}
+			}
+
+			for _, idx := range items {
+				ref, err := valueOf(r, p, idx)
+				if err != nil {
+					return nil, err
+				}
+				sdd.Args = append(sdd.Args, ref)
+			}
+
+			sdds = append(sdds, sdd)
+		}
+	}
+
+	return sdds, nil
+}
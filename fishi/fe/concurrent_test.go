@@ -0,0 +1,93 @@
+package fe_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dekarrin/ictiobus/fishi/fe"
+	"github.com/dekarrin/ictiobus/fishi/syntax"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/trans"
+)
+
+// Test_Frontend_Concurrent checks that a single frontend can be used by many
+// goroutines at once. It is most useful when run with the race detector:
+//
+//	go test -race -run Concurrent ./...
+func Test_Frontend_Concurrent(t *testing.T) {
+	const (
+		workers = 8
+		runs    = 4
+	)
+
+	testCases := []struct {
+		name string
+		opts *fe.FrontendOptions
+	}{
+		{name: "lazy lexer", opts: nil},
+		{name: "eager lexer", opts: &fe.FrontendOptions{LexerEager: true}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			frontend := fe.Frontend(syntax.HooksTable, tc.opts)
+
+			// each worker analyzes its own spec so that results getting mixed
+			// up between goroutines would be caught.
+			specFor := func(w int) string {
+				return fmt.Sprintf("%%%%tokens\n\n[a-z]+  %%token id%d\n\n%%%%grammar\n\n{S} = {S} id%d | id%d\n", w, w, w)
+			}
+
+			var wg sync.WaitGroup
+			errs := make([]error, workers)
+			tokens := make([][]string, workers)
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+
+					for i := 0; i < runs; i++ {
+						ast, _, err := frontend.AnalyzeString(specFor(w))
+						if err != nil {
+							errs[w] = err
+							return
+						}
+						tokens[w] = nil
+						for _, b := range ast.Nodes {
+							if b.Type() != syntax.BlockTypeTokens {
+								continue
+							}
+							for _, cont := range b.Tokens().Content {
+								for _, entry := range cont.Entries {
+									tokens[w] = append(tokens[w], entry.Token)
+								}
+							}
+						}
+					}
+				}(w)
+			}
+
+			// set listeners while analysis is running; they must not cause
+			// races with it.
+			frontend.Lexer.RegisterTraceListener(func(t lex.Token) {})
+			frontend.Parser.(parse.TraceEventSource).RegisterTraceEventListener(func(ev parse.TraceEvent) {})
+			frontend.SDTS.RegisterListener(func(e trans.Event) {})
+			frontend.SDTS.SetHooks(syntax.HooksTable)
+
+			wg.Wait()
+
+			for w := 0; w < workers; w++ {
+				if !assert.NoError(errs[w], "worker %d", w) {
+					continue
+				}
+				assert.Equal([]string{fmt.Sprintf("id%d", w)}, tokens[w], "worker %d", w)
+			}
+		})
+	}
+}
@@ -25,6 +25,9 @@ var (
 	// TCDirDiscard is the token class representing a %discard directive in FISHI.
 	TCDirDiscard = lex.NewTokenClass("dir-discard", "%discard directive")
 
+	// TCDirEntry is the token class representing a %entry directive in FISHI.
+	TCDirEntry = lex.NewTokenClass("dir-entry", "%entry directive")
+
 	// TCDirHook is the token class representing a %hook directive '=' in FISHI.
 	TCDirHook = lex.NewTokenClass("dir-hook", "%hook directive '='")
 
@@ -85,6 +88,9 @@ var (
 	// TCInt is the token class representing an integer literal in FISHI.
 	TCInt = lex.NewTokenClass("int", "integer literal")
 
+	// TCLparen is the token class representing a group start '{(' in FISHI.
+	TCLparen = lex.NewTokenClass("lparen", "group start '{('")
+
 	// TCNlEscseq is the token class representing an escape sequence in FISHI.
 	TCNlEscseq = lex.NewTokenClass("nl-escseq", "escape sequence")
 
@@ -97,6 +103,9 @@ var (
 	// TCNonterm is the token class representing a non-terminal symbol literal in FISHI.
 	TCNonterm = lex.NewTokenClass("nonterm", "non-terminal symbol literal")
 
+	// TCRparen is the token class representing a group end ')}' in FISHI.
+	TCRparen = lex.NewTokenClass("rparen", "group end ')}'")
+
 	// TCTerm is the token class representing a terminal symbol literal in FISHI.
 	TCTerm = lex.NewTokenClass("term", "terminal symbol literal")
 )
@@ -105,6 +114,7 @@ var all = map[string]lex.TokenClass{
 	"alt":              TCAlt,
 	"attr-ref":         TCAttrRef,
 	"dir-discard":      TCDirDiscard,
+	"dir-entry":        TCDirEntry,
 	"dir-hook":         TCDirHook,
 	"dir-human":        TCDirHuman,
 	"dir-index":        TCDirIndex,
@@ -125,10 +135,12 @@ var all = map[string]lex.TokenClass{
 	"hdr-tokens":       TCHdrTokens,
 	"id":               TCId,
 	"int":              TCInt,
+	"lparen":           TCLparen,
 	"nl-escseq":        TCNlEscseq,
 	"nl-freeform-text": TCNlFreeformText,
 	"nl-nonterm":       TCNlNonterm,
 	"nonterm":          TCNonterm,
+	"rparen":           TCRparen,
 	"term":             TCTerm,
 }
 
@@ -11,12 +11,15 @@ created by invoking ictiobus with the following command:
 */
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/dekarrin/ictiobus"
 	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
 	"github.com/dekarrin/ictiobus/trans"
 
 	"github.com/dekarrin/ictiobus/fishi/syntax"
@@ -49,6 +52,20 @@ type FrontendOptions struct {
 	// they occur. This includes operations such as parse tree annotation and
 	// hook execution.
 	SDTSTrace bool
+
+	// Coverage is where to record which rules, productions, and bindings of
+	// the language are used. If set, it is given the events of the parser and
+	// the translation scheme. It should be created with [NewCoverage]. If
+	// nil, coverage is not collected.
+	Coverage *ictiobus.Coverage
+}
+
+// NewCoverage returns an empty ictiobus.Coverage for the grammar and the
+// translation scheme of the FISHI language. It can be given to
+// [Frontend] in FrontendOptions.Coverage, including to more than one Frontend
+// to collect coverage from all of them.
+func NewCoverage() *ictiobus.Coverage {
+	return ictiobus.NewCoverage(Grammar(), trans.Bindings(SDTS()))
 }
 
 // Frontend returns the complete compiled frontend for the FISHI langauge.
@@ -85,8 +102,21 @@ func Frontend(hooks trans.HookMap, opts *FrontendOptions) ictiobus.Frontend[synt
 		})
 	}
 
-	if opts.SDTSTrace {
+	if opts.Coverage != nil {
+		if src, ok := fe.Parser.(parse.TraceEventSource); ok {
+			src.RegisterTraceEventListener(opts.Coverage.ParserEvent)
+		}
+	}
+
+	if opts.SDTSTrace || opts.Coverage != nil {
 		fe.SDTS.RegisterListener(func(e trans.Event) {
+			if opts.Coverage != nil {
+				opts.Coverage.SDTSEvent(e)
+			}
+			if !opts.SDTSTrace {
+				return
+			}
+
 			switch e.Type {
 			case trans.EventAnnotation:
 				fmt.Fprintf(os.Stderr, "SDTS: Annotated parse tree:\n%s\n", e.Tree)
@@ -124,3 +154,13 @@ func Frontend(hooks trans.HookMap, opts *FrontendOptions) ictiobus.Frontend[synt
 
 	return fe
 }
+
+// AnalyzeStream analyzes the FISHI code read from r without building a
+// parse tree. The translation scheme is applied as the input is parsed, which
+// uses far less memory than Analyze for large inputs; see
+// ictiobus.Frontend.AnalyzeStream. It stops when ctx is done or when the input
+// exceeds one of the limits in lim, and a limit of 0 means there is no limit.
+// The hooks and opts parameters are the same as for [Frontend].
+func AnalyzeStream(ctx context.Context, r io.Reader, lim ictiobus.Limits, hooks trans.HookMap, opts *FrontendOptions) (syntax.AST, error) {
+	return Frontend(hooks, opts).AnalyzeStreamContext(ctx, r, lim)
+}
@@ -50,9 +50,9 @@ func Lexer(lazy bool) lex.Lexer {
 	lx.RegisterClass(fetoken.TCTerm, "ACTIONS")
 
 	lx.AddPattern(`\s+`, lex.Discard(), "ACTIONS", 0)
-	lx.AddPattern(`(?:{(?:&|\.)(?:[0-9]+)?}|{[0-9]+}|{\^}|{[A-Za-z][^{}]*}|[^\s{}]+)\.[\$A-Za-z][\$A-Za-z0-9_]*`, lex.LexAs(fetoken.TCAttrRef.ID()), "ACTIONS", 0)
+	lx.AddPattern(`(?:{(?:&|\.)(?:[0-9]+)?}|{[0-9]+}|{\^}|{[A-Za-z](?:[^{}]|{(?:[^{}]|{[^{}]*})*})*}|[^\s{}]+)\.[\$A-Za-z][\$A-Za-z0-9_]*`, lex.LexAs(fetoken.TCAttrRef.ID()), "ACTIONS", 0)
 	lx.AddPattern(`[0-9]+`, lex.LexAs(fetoken.TCInt.ID()), "ACTIONS", 0)
-	lx.AddPattern(`{[A-Za-z][^}]*}`, lex.LexAs(fetoken.TCNonterm.ID()), "ACTIONS", 0)
+	lx.AddPattern(`{[A-Za-z](?:[^{}]|{(?:[^{}]|{[^{}]*})*})*}`, lex.LexAs(fetoken.TCNonterm.ID()), "ACTIONS", 0)
 	lx.AddPattern(`%[Ss][Tt][Aa][Tt][Ee]`, lex.LexAndSwapState(fetoken.TCDirState.ID(), "STATE-A"), "ACTIONS", 0)
 	lx.AddPattern(`%[Ss][Yy][Mm][Bb][Oo][Ll]`, lex.LexAs(fetoken.TCDirSymbol.ID()), "ACTIONS", 0)
 	lx.AddPattern(`(?:->|%[Pp][Rr][Oo][Dd])`, lex.LexAs(fetoken.TCDirProd.ID()), "ACTIONS", 0)
@@ -69,20 +69,26 @@ func Lexer(lazy bool) lex.Lexer {
 
 	// GRAMMAR state
 	lx.RegisterClass(fetoken.TCDirState, "GRAMMAR")
+	lx.RegisterClass(fetoken.TCDirEntry, "GRAMMAR")
 	lx.RegisterClass(fetoken.TCNlNonterm, "GRAMMAR")
 	lx.RegisterClass(fetoken.TCAlt, "GRAMMAR")
 	lx.RegisterClass(fetoken.TCEpsilon, "GRAMMAR")
+	lx.RegisterClass(fetoken.TCLparen, "GRAMMAR")
+	lx.RegisterClass(fetoken.TCRparen, "GRAMMAR")
 	lx.RegisterClass(fetoken.TCNonterm, "GRAMMAR")
 	lx.RegisterClass(fetoken.TCTerm, "GRAMMAR")
 	lx.RegisterClass(fetoken.TCEq, "GRAMMAR")
 
 	lx.AddPattern(`%[Ss][Tt][Aa][Tt][Ee]`, lex.LexAndSwapState(fetoken.TCDirState.ID(), "STATE-G"), "GRAMMAR", 0)
+	lx.AddPattern(`%[Ee][Nn][Tt][Rr][Yy]`, lex.LexAs(fetoken.TCDirEntry.ID()), "GRAMMAR", 0)
 	lx.AddPattern(`[^\S\n]+`, lex.Discard(), "GRAMMAR", 0)
-	lx.AddPattern(`\n\s*{[A-Za-z][^}]*}`, lex.LexAs(fetoken.TCNlNonterm.ID()), "GRAMMAR", 0)
+	lx.AddPattern(`\n\s*{[A-Za-z](?:[^{}]|{(?:[^{}]|{[^{}]*})*})*}`, lex.LexAs(fetoken.TCNlNonterm.ID()), "GRAMMAR", 0)
 	lx.AddPattern(`\n`, lex.Discard(), "GRAMMAR", 0)
 	lx.AddPattern(`\|`, lex.LexAs(fetoken.TCAlt.ID()), "GRAMMAR", 0)
 	lx.AddPattern(`{}`, lex.LexAs(fetoken.TCEpsilon.ID()), "GRAMMAR", 0)
-	lx.AddPattern(`{[A-Za-z][^}]*}`, lex.LexAs(fetoken.TCNonterm.ID()), "GRAMMAR", 0)
+	lx.AddPattern(`{\(`, lex.LexAs(fetoken.TCLparen.ID()), "GRAMMAR", 0)
+	lx.AddPattern(`\)}[?*+]?`, lex.LexAs(fetoken.TCRparen.ID()), "GRAMMAR", 0)
+	lx.AddPattern(`{[A-Za-z](?:[^{}]|{(?:[^{}]|{[^{}]*})*})*}[?*+]?`, lex.LexAs(fetoken.TCNonterm.ID()), "GRAMMAR", 0)
 	lx.AddPattern(`[^=\s]\S*|\S\S+`, lex.LexAs(fetoken.TCTerm.ID()), "GRAMMAR", 0)
 	lx.AddPattern(`=`, lex.LexAs(fetoken.TCEq.ID()), "GRAMMAR", 0)
 
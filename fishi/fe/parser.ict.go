@@ -30,6 +30,7 @@ func Grammar() grammar.CFG {
 	g.AddTerm(fetoken.TCAlt.ID(), fetoken.TCAlt)
 	g.AddTerm(fetoken.TCAttrRef.ID(), fetoken.TCAttrRef)
 	g.AddTerm(fetoken.TCDirDiscard.ID(), fetoken.TCDirDiscard)
+	g.AddTerm(fetoken.TCDirEntry.ID(), fetoken.TCDirEntry)
 	g.AddTerm(fetoken.TCDirHook.ID(), fetoken.TCDirHook)
 	g.AddTerm(fetoken.TCDirHuman.ID(), fetoken.TCDirHuman)
 	g.AddTerm(fetoken.TCDirIndex.ID(), fetoken.TCDirIndex)
@@ -50,10 +51,12 @@ func Grammar() grammar.CFG {
 	g.AddTerm(fetoken.TCHdrTokens.ID(), fetoken.TCHdrTokens)
 	g.AddTerm(fetoken.TCId.ID(), fetoken.TCId)
 	g.AddTerm(fetoken.TCInt.ID(), fetoken.TCInt)
+	g.AddTerm(fetoken.TCLparen.ID(), fetoken.TCLparen)
 	g.AddTerm(fetoken.TCNlEscseq.ID(), fetoken.TCNlEscseq)
 	g.AddTerm(fetoken.TCNlFreeformText.ID(), fetoken.TCNlFreeformText)
 	g.AddTerm(fetoken.TCNlNonterm.ID(), fetoken.TCNlNonterm)
 	g.AddTerm(fetoken.TCNonterm.ID(), fetoken.TCNonterm)
+	g.AddTerm(fetoken.TCRparen.ID(), fetoken.TCRparen)
 	g.AddTerm(fetoken.TCTerm.ID(), fetoken.TCTerm)
 
 	g.AddRule("FISHISPEC", []string{"BLOCKS"})
@@ -166,6 +169,7 @@ func Grammar() grammar.CFG {
 	g.AddRule("GRULE-LIST", []string{"GRULE"})
 
 	g.AddRule("GRULE", []string{"nl-nonterm", "eq", "ALTERNATIONS"})
+	g.AddRule("GRULE", []string{"dir-entry", "nonterm", "eq", "ALTERNATIONS"})
 
 	g.AddRule("ALTERNATIONS", []string{"GPRODUCTION"})
 	g.AddRule("ALTERNATIONS", []string{"ALTERNATIONS", "alt", "GPRODUCTION"})
@@ -178,6 +182,7 @@ func Grammar() grammar.CFG {
 
 	g.AddRule("GSYM", []string{"nonterm"})
 	g.AddRule("GSYM", []string{"term"})
+	g.AddRule("GSYM", []string{"lparen", "ALTERNATIONS", "rparen"})
 
 	g.AddRule("STATE-INS", []string{"dir-state", "ID-EXPR"})
 
@@ -948,6 +948,20 @@ func sdtsBindTCGrule(sdts trans.SDTS) {
 		prodStr := strings.Join([]string{"nl-nonterm", "eq", "ALTERNATIONS"}, " ")
 		panic(fmt.Sprintf("binding %s -> [%s]: %s", "GRULE", prodStr, err.Error()))
 	}
+
+	err = sdts.Bind(
+		"GRULE", []string{"dir-entry", "nonterm", "eq", "ALTERNATIONS"},
+		"value",
+		"make_entry_rule",
+		[]trans.AttrRef{
+			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 1}, Name: "$text"},
+			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 3}, Name: "value"},
+		},
+	)
+	if err != nil {
+		prodStr := strings.Join([]string{"dir-entry", "nonterm", "eq", "ALTERNATIONS"}, " ")
+		panic(fmt.Sprintf("binding %s -> [%s]: %s", "GRULE", prodStr, err.Error()))
+	}
 }
 
 func sdtsBindTCAlternations(sdts trans.SDTS) {
@@ -955,7 +969,7 @@ func sdtsBindTCAlternations(sdts trans.SDTS) {
 	err = sdts.Bind(
 		"ALTERNATIONS", []string{"GPRODUCTION"},
 		"value",
-		"string_list_list_start",
+		"production_list_start",
 		[]trans.AttrRef{
 			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "value"},
 		},
@@ -968,7 +982,7 @@ func sdtsBindTCAlternations(sdts trans.SDTS) {
 	err = sdts.Bind(
 		"ALTERNATIONS", []string{"ALTERNATIONS", "alt", "GPRODUCTION"},
 		"value",
-		"string_list_list_append",
+		"production_list_append",
 		[]trans.AttrRef{
 			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "value"},
 			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 2}, Name: "value"},
@@ -998,7 +1012,7 @@ func sdtsBindTCGproduction(sdts trans.SDTS) {
 	err = sdts.Bind(
 		"GPRODUCTION", []string{"epsilon"},
 		"value",
-		"epsilon_string_list",
+		"epsilon_grammar_symbol_list",
 		nil,
 	)
 	if err != nil {
@@ -1012,7 +1026,7 @@ func sdtsBindTCGsymList(sdts trans.SDTS) {
 	err = sdts.Bind(
 		"GSYM-LIST", []string{"GSYM-LIST", "GSYM"},
 		"value",
-		"string_list_append",
+		"grammar_symbol_list_append",
 		[]trans.AttrRef{
 			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "value"},
 			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 1}, Name: "value"},
@@ -1026,7 +1040,7 @@ func sdtsBindTCGsymList(sdts trans.SDTS) {
 	err = sdts.Bind(
 		"GSYM-LIST", []string{"GSYM"},
 		"value",
-		"string_list_start",
+		"grammar_symbol_list_start",
 		[]trans.AttrRef{
 			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "value"},
 		},
@@ -1218,9 +1232,10 @@ func sdtsBindTCGsym(sdts trans.SDTS) {
 	err = sdts.Bind(
 		"GSYM", []string{"nonterm"},
 		"value",
-		"get_nonterminal",
+		"make_grammar_symbol",
 		[]trans.AttrRef{
 			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "$text"},
+			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "$ft"},
 		},
 	)
 	if err != nil {
@@ -1231,15 +1246,31 @@ func sdtsBindTCGsym(sdts trans.SDTS) {
 	err = sdts.Bind(
 		"GSYM", []string{"term"},
 		"value",
-		"get_terminal",
+		"make_grammar_symbol",
 		[]trans.AttrRef{
 			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "$text"},
+			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "$ft"},
 		},
 	)
 	if err != nil {
 		prodStr := strings.Join([]string{"term"}, " ")
 		panic(fmt.Sprintf("binding %s -> [%s]: %s", "GSYM", prodStr, err.Error()))
 	}
+
+	err = sdts.Bind(
+		"GSYM", []string{"lparen", "ALTERNATIONS", "rparen"},
+		"value",
+		"make_grammar_group",
+		[]trans.AttrRef{
+			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 1}, Name: "value"},
+			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 2}, Name: "$text"},
+			{Rel: trans.NodeRelation{Type: trans.RelSymbol, Index: 0}, Name: "$ft"},
+		},
+	)
+	if err != nil {
+		prodStr := strings.Join([]string{"lparen", "ALTERNATIONS", "rparen"}, " ")
+		panic(fmt.Sprintf("binding %s -> [%s]: %s", "GSYM", prodStr, err.Error()))
+	}
 }
 
 func sdtsBindTCStateIns(sdts trans.SDTS) {
@@ -13,6 +13,7 @@ import (
 	"github.com/dekarrin/ictiobus/fishi/fe"
 	"github.com/dekarrin/ictiobus/fishi/format"
 	"github.com/dekarrin/ictiobus/fishi/syntax"
+	"github.com/dekarrin/ictiobus/lex"
 	"github.com/dekarrin/ictiobus/parse"
 )
 
@@ -143,13 +144,19 @@ func ParseMarkdownFile(filename string, opts *Options) (Results, error) {
 
 // ParseMarkdownFile parses a FISHI spec read from the named Reader containing
 // markdown-formatted text with fishi codeblocks. If opts is nil, it is treated
-// as a pointer to a zero-valued Options struct.
+// as a pointer to a zero-valued Options struct. If r has a Name method, such as
+// an *os.File, tokens and errors are given the name it returns as their file.
 func ParseMarkdown(r io.Reader, opts *Options) (Results, error) {
+	named, hasName := r.(interface{ Name() string })
+
 	bufF := bufio.NewReader(r)
 	r, err := format.NewCodeReader(bufF)
 	if err != nil {
 		return Results{}, err
 	}
+	if hasName {
+		r = lex.NamedReader(named.Name(), r)
+	}
 
 	res, err := Parse(r, opts)
 	if err != nil {
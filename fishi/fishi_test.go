@@ -2,6 +2,7 @@ package fishi
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -19,8 +20,8 @@ func Test_WithFakeInput(t *testing.T) {
 	assert.NoError(actual)
 
 	if actual != nil {
-		actualSynt, ok := actual.(*syntaxerr.Error)
-		if ok {
+		var actualSynt *syntaxerr.Error
+		if errors.As(actual, &actualSynt) {
 			fmt.Println(actualSynt.FullMessage())
 		}
 	}
@@ -37,8 +38,8 @@ func Test_SelfHostedMarkdown_Spec(t *testing.T) {
 	_, _, actualErr := NewSpec(*res.AST)
 
 	if actualErr != nil {
-		actualSynt, ok := actualErr.(*syntaxerr.Error)
-		if ok {
+		var actualSynt *syntaxerr.Error
+		if errors.As(actualErr, &actualSynt) {
 			fmt.Println(actualSynt.FullMessage())
 		}
 	}
@@ -52,8 +53,8 @@ func Test_SelfHostedMarkdown(t *testing.T) {
 	assert.NoError(actual)
 
 	if actual != nil {
-		actualSynt, ok := actual.(*syntaxerr.Error)
-		if ok {
+		var actualSynt *syntaxerr.Error
+		if errors.As(actual, &actualSynt) {
 			fmt.Println(actualSynt.FullMessage())
 		}
 	}
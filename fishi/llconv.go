@@ -0,0 +1,626 @@
+package fishi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/slices"
+	"github.com/dekarrin/ictiobus/trans"
+)
+
+// llAttrPrefix is the start of the name of every inherited attribute that is
+// made when converting a spec for LL(1) parsing. Attribute names in FISHI
+// cannot contain '-', so these never conflict with the attributes of a spec.
+const llAttrPrefix = "in-"
+
+// llProduction is a production of a grammar being converted for LL(1) parsing
+// along with the SDDs that are bound to it.
+type llProduction struct {
+	syms grammar.Production
+	sdds []SDD
+}
+
+// llRule is a rule of a grammar being converted for LL(1) parsing.
+type llRule struct {
+	head  string
+	prods []llProduction
+}
+
+// llConverter rewrites a grammar and the translation scheme on it together, so
+// that the translation scheme gives the same results on the new grammar as it
+// did on the old one.
+type llConverter struct {
+	rules []*llRule
+
+	// names has a rule for every non-terminal in the grammar, including the
+	// generated ones, so that new names can be generated that are not in use.
+	names grammar.CFG
+}
+
+// ConvertToLL1 returns a copy of spec with its grammar rewritten so that an
+// LL(1) parser can be created from it and its translation scheme rewritten so
+// that it gives the same results on the new grammar.
+//
+// Immediate left recursion is removed, and then the grammar is left factored.
+// Both add new non-terminals named after the one they were made from. When
+// left recursion is removed, the SDDs of the left-recursive productions are
+// moved to the new non-terminal, and the value that each attribute of the rule
+// has so far is passed down the parse tree to the rest of the input in an
+// inherited attribute. When left factoring, the SDDs of the factored
+// productions are moved to the new non-terminal, and the attributes of the
+// shared start of the productions that they use are passed down to it in
+// inherited attributes. The inherited attributes have names that start with
+// "in-". If a production does not set an attribute that other productions of
+// its rule do, the attribute is set to nil for it.
+//
+// An error is returned if the translation scheme of spec already has inherited
+// attributes, if the grammar has left recursion other than a production that
+// starts with its own head, or if an SDD uses the $id of a node that the
+// conversion removes. The returned spec is not guaranteed to be LL(1); if it is
+// not, creating an LL(1) parser for it will fail.
+func (spec Spec) ConvertToLL1() (Spec, error) {
+	for _, sdd := range spec.TranslationScheme {
+		if sdd.Attribute.Rel.Type != trans.RelHead {
+			return Spec{}, fmt.Errorf("SDTS rule for %s sets inherited attribute %s; translation schemes with inherited attributes cannot be converted", sdd.Rule.String(), sdd.Attribute)
+		}
+	}
+
+	conv := newLLConverter(spec.Grammar, spec.TranslationScheme)
+
+	// rules made for removed left recursion are inserted right after the rule
+	// they are for, and they are never left-recursive themselves.
+	for i := 0; i < len(conv.rules); i++ {
+		if err := conv.removeLeftRecursion(i); err != nil {
+			return Spec{}, err
+		}
+	}
+
+	if cycle := conv.leftRecursion(); cycle != nil {
+		return Spec{}, fmt.Errorf("%s is left-recursive through %s; only left recursion in productions that start with their own head can be removed", cycle[0], strings.Join(cycle, " -> "))
+	}
+
+	if err := conv.leftFactor(); err != nil {
+		return Spec{}, err
+	}
+
+	converted := Spec{
+		Tokens:   spec.Tokens,
+		Patterns: spec.Patterns,
+	}
+	converted.Grammar, converted.TranslationScheme = conv.result(spec.Grammar)
+
+	// the first SDD gives the IR attribute, so make sure it is still the
+	// same one.
+	if len(spec.TranslationScheme) > 0 {
+		first := spec.TranslationScheme[0]
+		for i, sdd := range converted.TranslationScheme {
+			if sdd.Rule.NonTerminal == first.Rule.NonTerminal && sdd.Attribute.Rel.Type == trans.RelHead && sdd.Attribute.Name == first.Attribute.Name {
+				scheme := []SDD{sdd}
+				scheme = append(scheme, converted.TranslationScheme[:i]...)
+				scheme = append(scheme, converted.TranslationScheme[i+1:]...)
+				converted.TranslationScheme = scheme
+				break
+			}
+		}
+	}
+
+	return converted, nil
+}
+
+// newLLConverter returns an llConverter for g with the SDDs of scheme bound to
+// its productions.
+func newLLConverter(g grammar.CFG, scheme []SDD) *llConverter {
+	bound := map[string][]SDD{}
+	for _, sdd := range scheme {
+		key := llKey(sdd.Rule.NonTerminal, sdd.Rule.Productions[0])
+		bound[key] = append(bound[key], sdd)
+	}
+
+	conv := &llConverter{names: g.Copy()}
+	for _, nt := range g.NonTerminalsByPriority() {
+		r := &llRule{head: nt}
+		for _, p := range g.Rule(nt).Productions {
+			r.prods = append(r.prods, llProduction{syms: p.Copy(), sdds: bound[llKey(nt, p)]})
+		}
+		conv.rules = append(conv.rules, r)
+	}
+
+	return conv
+}
+
+// result returns the grammar and translation scheme that c has. The terminals,
+// start symbol, and entry points of the grammar are taken from orig.
+func (c *llConverter) result(orig grammar.CFG) (grammar.CFG, []SDD) {
+	g := grammar.CFG{
+		Start:       orig.Start,
+		EntryPoints: append([]string(nil), orig.EntryPoints...),
+	}
+	for _, t := range orig.Terminals() {
+		g.AddTerm(t, orig.Term(t))
+	}
+
+	var scheme []SDD
+	for _, r := range c.rules {
+		for _, p := range r.prods {
+			g.AddRule(r.head, p.syms)
+			for _, sdd := range p.sdds {
+				sdd.Rule = grammar.Rule{NonTerminal: r.head, Productions: []grammar.Production{p.syms.Copy()}}
+				scheme = append(scheme, sdd)
+			}
+		}
+	}
+
+	return g, scheme
+}
+
+// removeLeftRecursion removes the immediate left recursion from the rule at
+// idx in c.rules, if it has any. The new rule is inserted right after it.
+//
+// This is the translation of Algorithm 4.19 from the purple dragon book for
+// when the grammar has an SDTS, as described in section 5.4.4, "Eliminating
+// Left Recursion From SDT's":
+//
+//	A  -> Aα | β
+//
+// becomes
+//
+//	A  -> βA'
+//	A' -> αA' | ε
+//
+// and each attribute x of A gets an inherited attribute in-x on A' for the
+// value of A.x of the input before A', which the ε production of A' passes
+// back up as A'.x.
+func (c *llConverter) removeLeftRecursion(idx int) error {
+	r := c.rules[idx]
+
+	var alphas, betas []llProduction
+	for _, p := range r.prods {
+		if p.syms[0] == r.head {
+			if len(p.syms) == 1 {
+				return fmt.Errorf("%s has a production that is only itself", r.head)
+			}
+			alphas = append(alphas, p)
+		} else {
+			betas = append(betas, p)
+		}
+	}
+	if len(alphas) == 0 {
+		return nil
+	}
+	if len(betas) == 0 {
+		return fmt.Errorf("every production of %s starts with %s, so it can never be derived", r.head, r.head)
+	}
+
+	// every attribute set on A by any production is passed down A'.
+	var attrs []string
+	for _, p := range r.prods {
+		for _, sdd := range p.sdds {
+			if !slices.In(sdd.Attribute.Name, attrs) {
+				attrs = append(attrs, sdd.Attribute.Name)
+			}
+		}
+	}
+
+	// the node for the A that starts a left-recursive production is removed,
+	// as is the node for its head, but the first token of both is the same as
+	// that of the A at the top, so that can be passed down too.
+	var passFirstToken bool
+	for _, p := range alphas {
+		for _, sdd := range p.sdds {
+			for _, arg := range sdd.Args {
+				if arg.Rel.Type != trans.RelHead && c.symbolIndex(arg.Rel, p.syms) != 0 {
+					continue
+				}
+				switch arg.Name {
+				case "$ft":
+					passFirstToken = true
+				case "$id":
+					return fmt.Errorf("SDTS rule for %s -> %s uses %s; it cannot be kept when left recursion is removed", r.head, p.syms, arg)
+				}
+			}
+		}
+	}
+
+	prime := c.newName(r.head)
+	primeRule := &llRule{head: prime}
+
+	var newProds []llProduction
+	for _, p := range betas {
+		beta := llSymbols(p.syms)
+		np := llProduction{syms: append(beta.Copy(), prime)}
+		rest := len(beta)
+
+		var set []string
+		for _, sdd := range p.sdds {
+			set = append(set, sdd.Attribute.Name)
+
+			// other attributes of the head are now given to A' instead.
+			args := make([]trans.AttrRef, len(sdd.Args))
+			for i, arg := range sdd.Args {
+				if arg.Rel.Type == trans.RelHead && !strings.HasPrefix(arg.Name, "$") {
+					arg = llSymbolRef(rest, llAttrPrefix+arg.Name)
+				}
+				args[i] = arg
+			}
+			np.sdds = append(np.sdds, SDD{Attribute: llSymbolRef(rest, llAttrPrefix+sdd.Attribute.Name), Hook: sdd.Hook, Args: args})
+		}
+		np.sdds = append(np.sdds, llNilSDDs(attrs, set, llSymbolRef(rest, ""), llAttrPrefix)...)
+		if passFirstToken {
+			np.sdds = append(np.sdds, SDD{Attribute: llSymbolRef(rest, llAttrPrefix+"$ft"), Hook: trans.HookIdent, Args: []trans.AttrRef{llHeadRef("$ft")}})
+		}
+		for _, x := range attrs {
+			np.sdds = append(np.sdds, SDD{Attribute: llHeadRef(x), Hook: trans.HookIdent, Args: []trans.AttrRef{llSymbolRef(rest, x)}})
+		}
+
+		newProds = append(newProds, np)
+	}
+
+	for _, p := range alphas {
+		alpha := p.syms[1:]
+		np := llProduction{syms: append(alpha.Copy(), prime)}
+		rest := len(alpha)
+
+		var set []string
+		for _, sdd := range p.sdds {
+			set = append(set, sdd.Attribute.Name)
+
+			args := make([]trans.AttrRef, len(sdd.Args))
+			for i, arg := range sdd.Args {
+				if arg.Rel.Type == trans.RelHead {
+					if arg.Name == "$ft" {
+						args[i] = llHeadRef(llAttrPrefix + arg.Name)
+					} else {
+						args[i] = llSymbolRef(rest, llAttrPrefix+arg.Name)
+					}
+				} else if pos := c.symbolIndex(arg.Rel, p.syms); pos == 0 {
+					args[i] = llHeadRef(llAttrPrefix + arg.Name)
+				} else {
+					args[i] = llSymbolRef(pos-1, arg.Name)
+				}
+			}
+			np.sdds = append(np.sdds, SDD{Attribute: llSymbolRef(rest, llAttrPrefix+sdd.Attribute.Name), Hook: sdd.Hook, Args: args})
+		}
+		np.sdds = append(np.sdds, llNilSDDs(attrs, set, llSymbolRef(rest, ""), llAttrPrefix)...)
+		if passFirstToken {
+			np.sdds = append(np.sdds, SDD{Attribute: llSymbolRef(rest, llAttrPrefix+"$ft"), Hook: trans.HookIdent, Args: []trans.AttrRef{llHeadRef(llAttrPrefix + "$ft")}})
+		}
+		for _, x := range attrs {
+			np.sdds = append(np.sdds, SDD{Attribute: llHeadRef(x), Hook: trans.HookIdent, Args: []trans.AttrRef{llSymbolRef(rest, x)}})
+		}
+
+		primeRule.prods = append(primeRule.prods, np)
+	}
+
+	epsilon := llProduction{syms: grammar.Epsilon.Copy()}
+	for _, x := range attrs {
+		epsilon.sdds = append(epsilon.sdds, SDD{Attribute: llHeadRef(x), Hook: trans.HookIdent, Args: []trans.AttrRef{llHeadRef(llAttrPrefix + x)}})
+	}
+	primeRule.prods = append(primeRule.prods, epsilon)
+
+	r.prods = newProds
+	c.insertRule(primeRule, idx+1)
+	return nil
+}
+
+// leftFactor left factors every rule in c until none of the productions of
+// any rule start with the same symbols.
+//
+// This is the translation of Algorithm 4.21 from the purple dragon book for
+// when the grammar has an SDTS:
+//
+//	A  -> αβ₁ | αβ₂ | γ
+//
+// becomes
+//
+//	A  -> αA' | γ
+//	A' -> β₁ | β₂
+//
+// and each attribute of α and of A that the SDDs of αβ₁ and αβ₂ use is given
+// to A' as an inherited attribute.
+func (c *llConverter) leftFactor() error {
+	changed := true
+	for changed {
+		changed = false
+		for i := 0; i < len(c.rules); i++ {
+			alpha := llCommonPrefix(c.rules[i].prods)
+			if len(alpha) == 0 {
+				continue
+			}
+			if err := c.factor(i, alpha); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+	return nil
+}
+
+// factor left factors the productions of the rule at idx in c.rules that
+// start with alpha. The new rule is inserted right after it.
+func (c *llConverter) factor(idx int, alpha grammar.Production) error {
+	r := c.rules[idx]
+	prime := c.newName(r.head)
+	primeRule := &llRule{head: prime}
+	m := len(alpha)
+
+	// the attributes given to A', in the order they are first used, and what
+	// gives each its value in A -> αA'.
+	var passed []string
+	passedFrom := map[string]trans.AttrRef{}
+	pass := func(name string, from trans.AttrRef) trans.AttrRef {
+		if _, ok := passedFrom[name]; !ok {
+			passed = append(passed, name)
+			passedFrom[name] = from
+		}
+		return llHeadRef(name)
+	}
+
+	var attrs []string
+	var sets [][]string
+	for _, p := range r.prods {
+		if !slices.HasPrefix(llSymbols(p.syms), alpha) {
+			continue
+		}
+
+		rest := p.syms[m:]
+		if len(rest) == 0 {
+			rest = grammar.Epsilon
+		}
+		np := llProduction{syms: rest.Copy()}
+
+		var set []string
+		for _, sdd := range p.sdds {
+			if sdd.Attribute.Rel.Type == trans.RelHead {
+				set = append(set, sdd.Attribute.Name)
+			}
+		}
+
+		remap := func(arg trans.AttrRef) trans.AttrRef {
+			if arg.Rel.Type == trans.RelHead {
+				if slices.In(arg.Name, set) {
+					return arg
+				}
+				return pass(llAttrPrefix+arg.Name, arg)
+			}
+			pos := c.symbolIndex(arg.Rel, p.syms)
+			if pos < m {
+				return pass(fmt.Sprintf("%s%d-%s", llAttrPrefix, pos, arg.Name), llSymbolRef(pos, arg.Name))
+			}
+			return llSymbolRef(pos-m, arg.Name)
+		}
+
+		for _, sdd := range p.sdds {
+			dest := sdd.Attribute
+			if dest.Rel.Type != trans.RelHead {
+				pos := c.symbolIndex(dest.Rel, p.syms)
+				if pos < m {
+					return fmt.Errorf("cannot left factor %s: SDTS rule for %s -> %s sets inherited attribute %s on a symbol in %s, which its other productions also start with", r.head, r.head, p.syms, dest, alpha)
+				}
+				dest = llSymbolRef(pos-m, dest.Name)
+			} else if !slices.In(dest.Name, attrs) {
+				attrs = append(attrs, dest.Name)
+			}
+
+			args := make([]trans.AttrRef, len(sdd.Args))
+			for i, arg := range sdd.Args {
+				args[i] = remap(arg)
+			}
+			np.sdds = append(np.sdds, SDD{Attribute: dest, Hook: sdd.Hook, Args: args})
+		}
+
+		primeRule.prods = append(primeRule.prods, np)
+		sets = append(sets, set)
+	}
+
+	for i := range primeRule.prods {
+		primeRule.prods[i].sdds = append(primeRule.prods[i].sdds, llNilSDDs(attrs, sets[i], llHeadRef(""), "")...)
+	}
+
+	factored := llProduction{syms: append(alpha.Copy(), prime)}
+	for _, name := range passed {
+		factored.sdds = append(factored.sdds, SDD{Attribute: llSymbolRef(m, name), Hook: trans.HookIdent, Args: []trans.AttrRef{passedFrom[name]}})
+	}
+	for _, x := range attrs {
+		factored.sdds = append(factored.sdds, SDD{Attribute: llHeadRef(x), Hook: trans.HookIdent, Args: []trans.AttrRef{llSymbolRef(m, x)}})
+	}
+
+	// the factored production takes the place of the first one it replaces.
+	var newProds []llProduction
+	var placed bool
+	for _, p := range r.prods {
+		if !slices.HasPrefix(llSymbols(p.syms), alpha) {
+			newProds = append(newProds, p)
+		} else if !placed {
+			newProds = append(newProds, factored)
+			placed = true
+		}
+	}
+
+	r.prods = newProds
+	c.insertRule(primeRule, idx+1)
+	return nil
+}
+
+// leftRecursion returns a cycle of non-terminals that are left-recursive
+// through each other, starting and ending with the same one. If there are
+// none, nil is returned.
+func (c *llConverter) leftRecursion() []string {
+	heads := map[string]bool{}
+	for _, r := range c.rules {
+		heads[r.head] = true
+	}
+
+	nullable := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, r := range c.rules {
+			if nullable[r.head] {
+				continue
+			}
+			for _, p := range r.prods {
+				allNullable := true
+				for _, sym := range llSymbols(p.syms) {
+					if !nullable[sym] {
+						allNullable = false
+						break
+					}
+				}
+				if allNullable {
+					nullable[r.head] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	// an edge A -> B means that a production of A can start with B.
+	edges := map[string][]string{}
+	for _, r := range c.rules {
+		for _, p := range r.prods {
+			for _, sym := range llSymbols(p.syms) {
+				if !heads[sym] {
+					break
+				}
+				if !slices.In(sym, edges[r.head]) {
+					edges[r.head] = append(edges[r.head], sym)
+				}
+				if !nullable[sym] {
+					break
+				}
+			}
+		}
+	}
+
+	visiting := map[string]bool{}
+	done := map[string]bool{}
+	var path []string
+	var visit func(nt string) []string
+	visit = func(nt string) []string {
+		if visiting[nt] {
+			start := len(path) - 1
+			for path[start] != nt {
+				start--
+			}
+			cycle := append([]string{}, path[start:]...)
+			return append(cycle, nt)
+		}
+		if done[nt] {
+			return nil
+		}
+		visiting[nt] = true
+		path = append(path, nt)
+		for _, next := range edges[nt] {
+			if cycle := visit(next); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		visiting[nt] = false
+		done[nt] = true
+		return nil
+	}
+
+	for _, r := range c.rules {
+		if cycle := visit(r.head); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// newName returns a new non-terminal name based on head that is not used in
+// the grammar.
+func (c *llConverter) newName(head string) string {
+	name := c.names.GenerateUniqueName(head)
+	c.names.AddRule(name, grammar.Epsilon)
+	return name
+}
+
+// insertRule inserts r into c.rules at idx.
+func (c *llConverter) insertRule(r *llRule, idx int) {
+	c.rules = append(c.rules, nil)
+	copy(c.rules[idx+1:], c.rules[idx:])
+	c.rules[idx] = r
+}
+
+// symbolIndex returns the index in prod of the symbol that rel refers to, or
+// -1 if it refers to the head.
+func (c *llConverter) symbolIndex(rel trans.NodeRelation, prod grammar.Production) int {
+	if rel.Type == trans.RelSymbol {
+		return rel.Index
+	} else if rel.Type == trans.RelHead {
+		return -1
+	}
+
+	// terminals and non-terminals are told apart the same way as in the
+	// SDTS.
+	wantTerm := rel.Type == trans.RelTerminal
+	var n int
+	for i, sym := range prod {
+		if (strings.ToLower(sym) == sym) != wantTerm {
+			continue
+		}
+		if n == rel.Index {
+			return i
+		}
+		n++
+	}
+	return -1
+}
+
+// llNilSDDs returns SDDs that set each attribute in attrs that is not in set to
+// nil. The attributes are set on the node that on refers to, with prefix added
+// to their names.
+func llNilSDDs(attrs []string, set []string, on trans.AttrRef, prefix string) []SDD {
+	var sdds []SDD
+	for _, x := range attrs {
+		if !slices.In(x, set) {
+			on.Name = prefix + x
+			sdds = append(sdds, SDD{Attribute: on, Hook: trans.HookNil})
+		}
+	}
+	return sdds
+}
+
+// llCommonPrefix returns the longest sequence of symbols that at least two of
+// prods start with.
+func llCommonPrefix(prods []llProduction) grammar.Production {
+	var longest grammar.Production
+	for i := range prods {
+		for j := i + 1; j < len(prods); j++ {
+			pref := slices.LongestCommonPrefix(llSymbols(prods[i].syms), llSymbols(prods[j].syms))
+			if len(pref) > len(longest) {
+				longest = pref
+			}
+		}
+	}
+	return longest
+}
+
+// llSymbols returns the symbols of p, which are none if it is the epsilon
+// production.
+func llSymbols(p grammar.Production) grammar.Production {
+	if p.Equal(grammar.Epsilon) {
+		return grammar.Production{}
+	}
+	return p
+}
+
+// llKey returns the key for the SDDs bound to the given production.
+func llKey(head string, prod grammar.Production) string {
+	return head + " -> " + strings.Join(prod, " ")
+}
+
+// llHeadRef returns a reference to the attribute of the head with the given
+// name.
+func llHeadRef(name string) trans.AttrRef {
+	return trans.AttrRef{Rel: trans.NRHead(), Name: name}
+}
+
+// llSymbolRef returns a reference to the attribute with the given name of the
+// symbol at idx in the production.
+func llSymbolRef(idx int, name string) trans.AttrRef {
+	return trans.AttrRef{Rel: trans.NRSymbol(idx), Name: name}
+}
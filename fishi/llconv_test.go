@@ -0,0 +1,259 @@
+package fishi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/ictiobus"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/trans"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Spec_ConvertToLL1(t *testing.T) {
+	const tokens = `%%tokens
+\d+    %token int    %human integer
+\+     %token plus   %human '+'
+-      %token minus  %human '-'
+\*     %token times  %human '*'
+\(     %token lp     %human '('
+\)     %token rp     %human ')'
+\s+    %discard
+`
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectRules []string
+		expectIR    map[string]int
+		expectErr   bool
+
+		// expectFirstTokens is the $ft given to each call of sub_at for an
+		// input of expectIR, as "lexeme@line:col".
+		expectFirstTokens map[string][]string
+	}{
+		{
+			name: "left-recursive expression grammar",
+			spec: `
+%%grammar
+{E} = {E} plus {T} | {E} minus {T} | {T}
+{T} = {T} times {F} | {F}
+{F} = lp {E} rp | int
+
+%%actions
+%symbol {E}
+-> {E} plus {T}:   {^}.value = add({0}.value, {2}.value)
+-> {E} minus {T}:  {^}.value = sub({0}.value, {2}.value)
+-> {T}:            {^}.value = ident({0}.value)
+%symbol {T}
+-> {T} times {F}:  {^}.value = mul({0}.value, {2}.value)
+-> {F}:            {^}.value = ident({0}.value)
+%symbol {F}
+-> lp {E} rp:      {^}.value = ident({1}.value)
+-> int:            {^}.value = int({0}.$text)
+`,
+			expectRules: []string{
+				"E -> T E-P",
+				"E-P -> plus T E-P | minus T E-P | ε",
+				"T -> F T-P",
+				"T-P -> times F T-P | ε",
+				"F -> lp E rp | int",
+			},
+			expectIR: map[string]int{
+				"10 - 3 - 2":      5,
+				"2 * (3 + 4) - 1": 13,
+				"8":               8,
+			},
+		},
+		{
+			name: "left recursion using first token",
+			spec: `
+%%grammar
+{E} = {E} minus int | int
+
+%%actions
+%symbol {E}
+-> {E} minus int:  {^}.value = sub_at({0}.value, {2}.$text, {^}.$ft)
+-> int:            {^}.value = int({0}.$text)
+`,
+			expectRules: []string{
+				"E -> int E-P",
+				"E-P -> minus int E-P | ε",
+			},
+			expectIR: map[string]int{
+				"10 - 3 - 2": 5,
+				"4 - 1":      3,
+			},
+
+			// $ft must stay the first token of the original E, not become
+			// the minus that starts each E-P.
+			expectFirstTokens: map[string][]string{
+				"10 - 3 - 2": {"10@1:1", "10@1:1"},
+				"4 - 1":      {"4@1:1"},
+			},
+		},
+		{
+			name: "common prefixes are left factored",
+			spec: `
+%%grammar
+{S} = int plus int | int minus int | int
+
+%%actions
+%symbol {S}
+-> int plus int:   {^}.value = add_text({0}.$text, {2}.$text)
+-> int minus int:  {^}.value = sub_text({0}.$text, {2}.$text)
+-> int:            {^}.value = int({0}.$text)
+`,
+			expectRules: []string{
+				"S -> int S-P",
+				"S-P -> plus int | minus int | ε",
+			},
+			expectIR: map[string]int{
+				"7 - 2": 5,
+				"7 + 2": 9,
+				"7":     7,
+			},
+		},
+		{
+			name: "grammar without translation scheme",
+			spec: `
+%%grammar
+{E} = {E} plus int | int
+`,
+			expectRules: []string{
+				"E -> int E-P",
+				"E-P -> plus int E-P | ε",
+			},
+		},
+		{
+			name: "indirect left recursion",
+			spec: `
+%%grammar
+{A} = {B} plus | int
+{B} = {A} minus | int
+`,
+			expectErr: true,
+		},
+		{
+			name: "id of removed node",
+			spec: `
+%%grammar
+{E} = {E} minus int | int
+
+%%actions
+%symbol {E}
+-> {E} minus int:  {^}.value = sub({0}.$id, {2}.$text)
+-> int:            {^}.value = int({0}.$text)
+`,
+			expectErr: true,
+		},
+	}
+
+	// firstTokens is the $ft given to each call of sub_at.
+	var firstTokens []string
+
+	hooks := trans.HookMap{
+		"int": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return strconv.Atoi(args[0].(string))
+		},
+		"ident": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return args[0], nil
+		},
+		"add": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return args[0].(int) + args[1].(int), nil
+		},
+		"sub": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return args[0].(int) - args[1].(int), nil
+		},
+		"mul": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return args[0].(int) * args[1].(int), nil
+		},
+		"sub_at": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			ft, ok := args[2].(lex.Token)
+			if !ok {
+				return nil, fmt.Errorf("$ft is not a token: %v", args[2])
+			}
+			firstTokens = append(firstTokens, fmt.Sprintf("%s@%d:%d", ft.Lexeme(), ft.Line(), ft.LinePos()))
+
+			n, err := strconv.Atoi(args[1].(string))
+			return args[0].(int) - n, err
+		},
+		"add_text": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			l, _ := strconv.Atoi(args[0].(string))
+			r, err := strconv.Atoi(args[1].(string))
+			return l + r, err
+		},
+		"sub_text": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			l, _ := strconv.Atoi(args[0].(string))
+			r, err := strconv.Atoi(args[1].(string))
+			return l - r, err
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			res, err := Parse(strings.NewReader(tokens+tc.spec), nil)
+			if !assert.NoError(err) {
+				return
+			}
+			spec, _, err := NewSpec(*res.AST)
+			if !assert.NoError(err) {
+				return
+			}
+
+			converted, err := spec.ConvertToLL1()
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			// the original grammar is not LL(1).
+			_, _, err = spec.CreateParser(parse.LL1, false)
+			assert.Error(err)
+
+			var actualRules []string
+			for _, nt := range converted.Grammar.NonTerminalsByPriority() {
+				actualRules = append(actualRules, converted.Grammar.Rule(nt).String())
+			}
+			assert.Equal(tc.expectRules, actualRules)
+
+			p, _, err := converted.CreateParser(parse.LL1, false)
+			if !assert.NoError(err) {
+				return
+			}
+			if len(tc.expectIR) == 0 {
+				return
+			}
+			lx, err := converted.CreateLexer(false)
+			if !assert.NoError(err) {
+				return
+			}
+			sdts, err := converted.CreateSDTS()
+			if !assert.NoError(err) {
+				return
+			}
+			sdts.SetHooks(hooks)
+
+			// the IR attribute must not change.
+			assert.Equal("value", converted.TranslationScheme[0].Attribute.Name)
+
+			fe := ictiobus.Frontend[int]{Lexer: lx, Parser: p, SDTS: sdts, IRAttribute: "value"}
+			for input, expect := range tc.expectIR {
+				firstTokens = nil
+				actual, _, err := fe.AnalyzeString(input)
+				if !assert.NoError(err, input) {
+					continue
+				}
+				assert.Equal(expect, actual, input)
+				assert.Equal(tc.expectFirstTokens[input], firstTokens, input)
+			}
+		})
+	}
+}
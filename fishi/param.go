@@ -0,0 +1,618 @@
+package fishi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/fishi/syntax"
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"golang.org/x/text/unicode/runenames"
+)
+
+// maxParamNesting is the deepest that uses of parameterized rules can be nested
+// in the arguments of a single use. A parameterized rule whose expansion goes
+// deeper than this almost certainly never stops making new rules.
+const maxParamNesting = 16
+
+// unsafeNonTermChars matches characters that cannot be in the name of a
+// non-terminal.
+var unsafeNonTermChars = regexp.MustCompile(`[^A-Z_-]`)
+
+// paramRule is a parameterized rule in a FISHI spec. It is a template for the
+// rules that are made for each different list of arguments it is used with.
+type paramRule struct {
+	name   string
+	params []string
+
+	// prods is the productions of every rule given for the parameterized
+	// non-terminal, in order.
+	prods [][]syntax.GrammarSymbol
+
+	// actions is the actions given for the parameterized non-terminal, and
+	// actionParams is the names that each of them uses for the parameters,
+	// which need not be the same as the ones in params.
+	actions      []syntax.SymbolActions
+	actionParams [][]string
+
+	src lex.Token
+}
+
+// paramExpander makes the rules and actions for each use of a parameterized
+// rule.
+type paramExpander struct {
+	templates map[string]*paramRule
+
+	// instances is the name of the non-terminal made for each use so far, by
+	// the name of the parameterized rule and its arguments as written.
+	instances map[string]string
+
+	// depth is how deeply uses of parameterized rules are nested in the
+	// arguments of each non-terminal made so far.
+	depth map[string]int
+
+	// names has a rule for every non-terminal in the spec and every one made
+	// so far, so that new names can be checked for conflicts.
+	names grammar.CFG
+
+	rules   []syntax.GrammarRule
+	actions []syntax.SymbolActions
+}
+
+// expandParameterizedRules replaces every use of a parameterized rule in the
+// given grammar and actions blocks with a non-terminal made for the arguments
+// it is used with, and removes the parameterized rules and their actions. The
+// rules and actions for the new non-terminals are added to the end of the last
+// grammar block and the last actions block.
+//
+// A parameterized rule is declared by giving a comma-separated list of
+// parameter names in parentheses after the name of its head symbol, such as
+// {SEP-LIST(S, X)}, and each parameter is used in its productions and actions
+// as if it were a non-terminal, such as {X}. It is used by giving terminals or
+// non-terminals as arguments in the same way, such as {SEP-LIST(comma, {EXPR})}.
+// The non-terminal made for that use would be named SEP-LIST_COMMA_EXPR.
+func expandParameterizedRules(grammarBlocks []syntax.GrammarContent, actionsBlocks []syntax.ActionsContent) ([]syntax.GrammarContent, []syntax.ActionsContent, error) {
+	e := &paramExpander{
+		templates: map[string]*paramRule{},
+		instances: map[string]string{},
+		depth:     map[string]int{},
+	}
+
+	// find all of the parameterized rules first so that they can be used
+	// before they are declared.
+	var templateNames []string
+	plainHeads := map[string]lex.Token{}
+	for _, gBl := range grammarBlocks {
+		for _, rule := range gBl.Rules {
+			head := rule.Rule.NonTerminal
+			name, params, err := splitParameterized(head[1 : len(head)-1])
+			if err != nil {
+				return nil, nil, lex.NewSyntaxErrorFromToken(err.Error(), rule.Src)
+			}
+			name = strings.ToUpper(name)
+
+			if params == nil {
+				plainHeads[name] = rule.Src
+				e.names.AddRule(name, grammar.Epsilon)
+				continue
+			}
+
+			if rule.Entry {
+				return nil, nil, lex.NewSyntaxErrorFromToken(fmt.Sprintf("parameterized rule %s cannot be an entry point", name), rule.Src)
+			}
+			for i := range params {
+				if strings.ContainsAny(params[i], "{}()") {
+					return nil, nil, lex.NewSyntaxErrorFromToken(fmt.Sprintf("parameter %q of %s is not a valid name", params[i], name), rule.Src)
+				}
+				params[i] = strings.ToUpper(params[i])
+				for j := 0; j < i; j++ {
+					if params[i] == params[j] {
+						return nil, nil, lex.NewSyntaxErrorFromToken(fmt.Sprintf("parameter %s of %s is given more than once", params[i], name), rule.Src)
+					}
+				}
+			}
+
+			t, ok := e.templates[name]
+			if !ok {
+				t = &paramRule{name: name, params: params, src: rule.Src}
+				e.templates[name] = t
+				templateNames = append(templateNames, name)
+			} else if len(t.params) != len(params) {
+				errMsg := fmt.Sprintf("%s is given with %d parameters here but %d elsewhere", name, len(params), len(t.params))
+				return nil, nil, lex.NewSyntaxErrorFromToken(errMsg, rule.Src)
+			}
+
+			prods := rule.Productions
+			if prods == nil {
+				prods = plainGrammarSymbols(rule.Rule.Productions)
+			}
+
+			// productions given in other rules for the same head may use
+			// different names for the parameters.
+			rename := map[string]syntax.GrammarSymbol{}
+			for i := range params {
+				rename[params[i]] = syntax.GrammarSymbol{Symbol: "{" + t.params[i] + "}"}
+			}
+			for _, prod := range prods {
+				renamed := make([]syntax.GrammarSymbol, len(prod))
+				for i := range prod {
+					renamed[i] = substituteParams(prod[i], rename)
+				}
+				t.prods = append(t.prods, renamed)
+			}
+		}
+	}
+
+	for _, name := range templateNames {
+		if tok, ok := plainHeads[name]; ok {
+			return nil, nil, lex.NewSyntaxErrorFromToken(fmt.Sprintf("%s is given as both a parameterized and a non-parameterized rule", name), tok)
+		}
+	}
+
+	// actions for a parameterized rule are kept with it so that they can be
+	// made for each use.
+	newActionsBlocks := make([]syntax.ActionsContent, len(actionsBlocks))
+	for i, actBl := range actionsBlocks {
+		newActionsBlocks[i] = actBl
+		newActionsBlocks[i].Actions = nil
+		for _, symAct := range actBl.Actions {
+			name, params, err := splitParameterized(symAct.Symbol[1 : len(symAct.Symbol)-1])
+			if err != nil {
+				return nil, nil, lex.NewSyntaxErrorFromToken(err.Error(), symAct.SrcSym)
+			}
+			name = strings.ToUpper(name)
+
+			if params == nil {
+				newActionsBlocks[i].Actions = append(newActionsBlocks[i].Actions, symAct)
+				continue
+			}
+
+			t, ok := e.templates[name]
+			if !ok {
+				return nil, nil, lex.NewSyntaxErrorFromToken(fmt.Sprintf("'%s' is not a parameterized rule in the grammar", name), symAct.SrcSym)
+			}
+			if len(params) != len(t.params) {
+				errMsg := fmt.Sprintf("%s is given with %d parameters here but %d in the grammar", name, len(params), len(t.params))
+				return nil, nil, lex.NewSyntaxErrorFromToken(errMsg, symAct.SrcSym)
+			}
+			for j := range params {
+				params[j] = strings.ToUpper(params[j])
+			}
+
+			t.actions = append(t.actions, symAct)
+			t.actionParams = append(t.actionParams, params)
+		}
+	}
+
+	// now replace every use in the non-parameterized rules, which makes the
+	// rules for each use as it is found.
+	newGrammarBlocks := make([]syntax.GrammarContent, len(grammarBlocks))
+	for i, gBl := range grammarBlocks {
+		newGrammarBlocks[i] = gBl
+		newGrammarBlocks[i].Rules = nil
+		for _, rule := range gBl.Rules {
+			if strings.Contains(rule.Rule.NonTerminal, "(") {
+				continue
+			}
+
+			expanded, err := e.grammarRule(rule, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			newGrammarBlocks[i].Rules = append(newGrammarBlocks[i].Rules, expanded)
+		}
+	}
+
+	for i := range newActionsBlocks {
+		for j := range newActionsBlocks[i].Actions {
+			expanded, err := e.symbolActions(newActionsBlocks[i].Actions[j], nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			newActionsBlocks[i].Actions[j] = expanded
+		}
+	}
+
+	if len(e.rules) > 0 {
+		last := len(newGrammarBlocks) - 1
+		newGrammarBlocks[last].Rules = append(newGrammarBlocks[last].Rules, e.rules...)
+	}
+	if len(e.actions) > 0 {
+		last := len(newActionsBlocks) - 1
+		newActionsBlocks[last].Actions = append(newActionsBlocks[last].Actions, e.actions...)
+	}
+
+	return newGrammarBlocks, newActionsBlocks, nil
+}
+
+// grammarRule returns rule with each parameter in subst replaced with its
+// argument and each use of a parameterized rule replaced with the non-terminal
+// made for it.
+func (e *paramExpander) grammarRule(rule syntax.GrammarRule, subst map[string]syntax.GrammarSymbol) (syntax.GrammarRule, error) {
+	prods := rule.Productions
+	if prods == nil {
+		prods = plainGrammarSymbols(rule.Rule.Productions)
+	}
+
+	expanded := syntax.GrammarRule{Entry: rule.Entry, Src: rule.Src}
+	for _, prod := range prods {
+		newProd := make([]syntax.GrammarSymbol, len(prod))
+		for i := range prod {
+			var err error
+			newProd[i], err = e.symbol(prod[i], subst, rule.Src)
+			if err != nil {
+				return syntax.GrammarRule{}, err
+			}
+		}
+		expanded.Productions = append(expanded.Productions, newProd)
+	}
+
+	expanded.Rule = grammar.Rule{NonTerminal: rule.Rule.NonTerminal}
+	for _, prod := range expanded.Productions {
+		strProd := grammar.Production{}
+		for _, sym := range prod {
+			if sym.Plain() {
+				strProd = append(strProd, sym.Symbol)
+			} else {
+				strProd = append(strProd, sym.String())
+			}
+		}
+		expanded.Rule.Productions = append(expanded.Rule.Productions, strProd)
+	}
+
+	return expanded, nil
+}
+
+// symbol returns sym with each parameter in subst replaced with its argument
+// and each use of a parameterized rule replaced with the non-terminal made for
+// it. src is used for errors if sym does not have its own source token.
+func (e *paramExpander) symbol(sym syntax.GrammarSymbol, subst map[string]syntax.GrammarSymbol, src lex.Token) (syntax.GrammarSymbol, error) {
+	if sym.Src != nil {
+		src = sym.Src
+	}
+
+	if sym.Group != nil {
+		expanded := syntax.GrammarSymbol{Op: sym.Op, Src: sym.Src}
+		for _, alt := range sym.Group {
+			newAlt := make([]syntax.GrammarSymbol, len(alt))
+			for i := range alt {
+				var err error
+				newAlt[i], err = e.symbol(alt[i], subst, src)
+				if err != nil {
+					return syntax.GrammarSymbol{}, err
+				}
+			}
+			expanded.Group = append(expanded.Group, newAlt)
+		}
+		return expanded, nil
+	}
+
+	if !strings.HasPrefix(sym.Symbol, "{") {
+		return sym, nil
+	}
+
+	name, args, err := splitParameterized(sym.Symbol[1 : len(sym.Symbol)-1])
+	if err != nil {
+		return syntax.GrammarSymbol{}, lex.NewSyntaxErrorFromToken(err.Error(), src)
+	}
+	name = strings.ToUpper(name)
+
+	if args == nil {
+		if _, ok := subst[name]; ok {
+			return substituteParams(sym, subst), nil
+		}
+		if _, ok := e.templates[name]; ok {
+			return syntax.GrammarSymbol{}, lex.NewSyntaxErrorFromToken(fmt.Sprintf("%s is a parameterized rule and must be given arguments", name), src)
+		}
+		return sym, nil
+	}
+
+	t, ok := e.templates[name]
+	if !ok {
+		return syntax.GrammarSymbol{}, lex.NewSyntaxErrorFromToken(fmt.Sprintf("%s is not a parameterized rule", name), src)
+	}
+	if len(args) != len(t.params) {
+		errMsg := fmt.Sprintf("%s takes %d arguments but was given %d", name, len(t.params), len(args))
+		return syntax.GrammarSymbol{}, lex.NewSyntaxErrorFromToken(errMsg, src)
+	}
+
+	argSyms := make([]syntax.GrammarSymbol, len(args))
+	for i := range args {
+		argSym := syntax.ParseGrammarSymbol(args[i])
+
+		// within a parameterized rule, its parameters can be given as
+		// arguments without braces.
+		if !strings.HasPrefix(argSym.Symbol, "{") {
+			if _, ok := subst[strings.ToUpper(argSym.Symbol)]; ok {
+				argSym.Symbol = "{" + argSym.Symbol + "}"
+			}
+		}
+
+		argSyms[i], err = e.symbol(argSym, subst, src)
+		if err != nil {
+			return syntax.GrammarSymbol{}, err
+		}
+	}
+
+	inst, err := e.instantiate(t, argSyms, src)
+	if err != nil {
+		return syntax.GrammarSymbol{}, err
+	}
+
+	return syntax.GrammarSymbol{Symbol: "{" + inst + "}", Op: sym.Op, Src: sym.Src}, nil
+}
+
+// instantiate makes the rule and actions of t for args if they have not
+// already been made and returns the name of the non-terminal for them.
+func (e *paramExpander) instantiate(t *paramRule, args []syntax.GrammarSymbol, src lex.Token) (string, error) {
+	argStrs := make([]string, len(args))
+	argNames := make([]string, len(args))
+	depth := 1
+	for i := range args {
+		argStrs[i] = args[i].String()
+		argNames[i] = paramArgName(args[i])
+
+		if strings.HasPrefix(args[i].Symbol, "{") {
+			argDepth := e.depth[strings.ToUpper(args[i].Symbol[1:len(args[i].Symbol)-1])]
+			if argDepth+1 > depth {
+				depth = argDepth + 1
+			}
+		}
+	}
+	key := strings.ToUpper(t.name + "(" + strings.Join(argStrs, ",") + ")")
+
+	if inst, ok := e.instances[key]; ok {
+		return inst, nil
+	}
+	if depth > maxParamNesting {
+		errMsg := fmt.Sprintf("uses of parameterized rule %s are nested more than %d deep; its expansion may never end", t.name, maxParamNesting)
+		return "", lex.NewSyntaxErrorFromToken(errMsg, src)
+	}
+
+	inst := t.name + "_" + strings.Join(argNames, "_")
+	if e.names.Rule(inst).NonTerminal != "" {
+		inst = e.names.GenerateUniqueName(inst)
+	}
+	e.names.AddRule(inst, grammar.Epsilon)
+	e.instances[key] = inst
+	e.depth[inst] = depth
+
+	subst := map[string]syntax.GrammarSymbol{}
+	for i := range t.params {
+		subst[t.params[i]] = args[i]
+	}
+
+	// reserve the spot for the rule now so that rules are in the order that
+	// their non-terminals are first used.
+	idx := len(e.rules)
+	e.rules = append(e.rules, syntax.GrammarRule{})
+
+	rule, err := e.grammarRule(syntax.GrammarRule{
+		Rule:        grammar.Rule{NonTerminal: "{" + inst + "}"},
+		Productions: t.prods,
+		Src:         t.src,
+	}, subst)
+	if err != nil {
+		return "", err
+	}
+	e.rules[idx] = rule
+
+	for i := range t.actions {
+		actSubst := map[string]syntax.GrammarSymbol{}
+		for j := range t.actionParams[i] {
+			actSubst[t.actionParams[i][j]] = args[j]
+		}
+
+		sa, err := e.symbolActions(t.actions[i], actSubst)
+		if err != nil {
+			return "", err
+		}
+		sa.Symbol = "{" + inst + "}"
+		e.actions = append(e.actions, sa)
+	}
+
+	return inst, nil
+}
+
+// symbolActions returns sa with each parameter in subst replaced with its
+// argument and each use of a parameterized rule replaced with the non-terminal
+// made for it in the productions and attribute references of its actions.
+func (e *paramExpander) symbolActions(sa syntax.SymbolActions, subst map[string]syntax.GrammarSymbol) (syntax.SymbolActions, error) {
+	expanded := sa
+	expanded.Actions = make([]syntax.ProductionAction, len(sa.Actions))
+
+	for i, pa := range sa.Actions {
+		newPA := pa
+
+		if len(pa.ProdLiteral) > 0 {
+			src := pa.SrcVal
+			if src == nil {
+				src = pa.Src
+			}
+
+			newPA.ProdLiteral = make([]string, len(pa.ProdLiteral))
+			for j, lit := range pa.ProdLiteral {
+				sym, err := e.symbol(syntax.GrammarSymbol{Symbol: lit}, subst, src)
+				if err != nil {
+					return syntax.SymbolActions{}, err
+				}
+				if !sym.Plain() {
+					errMsg := fmt.Sprintf("%s is %s in this production, which can only be given by index", lit, sym.String())
+					return syntax.SymbolActions{}, lex.NewSyntaxErrorFromToken(errMsg, src)
+				}
+				newPA.ProdLiteral[j] = sym.Symbol
+			}
+		}
+
+		newPA.Actions = make([]syntax.SemanticAction, len(pa.Actions))
+		for j, semAct := range pa.Actions {
+			newSemAct := semAct
+
+			var err error
+			newSemAct.LHS, err = e.attrRef(semAct.LHS, subst)
+			if err != nil {
+				return syntax.SymbolActions{}, err
+			}
+
+			if semAct.With != nil {
+				newSemAct.With = make([]syntax.AttrRef, len(semAct.With))
+				for k := range semAct.With {
+					newSemAct.With[k], err = e.attrRef(semAct.With[k], subst)
+					if err != nil {
+						return syntax.SymbolActions{}, err
+					}
+				}
+			}
+
+			newPA.Actions[j] = newSemAct
+		}
+
+		expanded.Actions[i] = newPA
+	}
+
+	return expanded, nil
+}
+
+// attrRef returns ref with the symbol it refers to by name replaced with its
+// argument if it is a parameter in subst, or with the non-terminal made for it
+// if it is a use of a parameterized rule.
+func (e *paramExpander) attrRef(ref syntax.AttrRef, subst map[string]syntax.GrammarSymbol) (syntax.AttrRef, error) {
+	if ref.Symbol == "" || ref.Terminal {
+		return ref, nil
+	}
+
+	orig := "{" + ref.Symbol + "}"
+	sym, err := e.symbol(syntax.GrammarSymbol{Symbol: orig}, subst, ref.Src)
+	if err != nil {
+		return syntax.AttrRef{}, err
+	}
+	if sym.Symbol == orig && sym.Plain() {
+		return ref, nil
+	}
+
+	if !sym.Plain() {
+		errMsg := fmt.Sprintf("%s is %s in this production, which can only be referred to by index", orig, sym.String())
+		return syntax.AttrRef{}, lex.NewSyntaxErrorFromToken(errMsg, ref.Src)
+	}
+
+	if strings.HasPrefix(sym.Symbol, "{") {
+		ref.Symbol = strings.ToUpper(sym.Symbol[1 : len(sym.Symbol)-1])
+	} else {
+		ref.Symbol = sym.Symbol
+		ref.Terminal = true
+	}
+	return ref, nil
+}
+
+// substituteParams returns sym with its symbol replaced with the argument in
+// subst if it is one of the parameters in it. If both the parameter and the
+// argument have an EBNF operator, the argument is put in a group that has the
+// operator of the parameter.
+func substituteParams(sym syntax.GrammarSymbol, subst map[string]syntax.GrammarSymbol) syntax.GrammarSymbol {
+	if sym.Group != nil {
+		substituted := sym
+		substituted.Group = make([][]syntax.GrammarSymbol, len(sym.Group))
+		for i, alt := range sym.Group {
+			substituted.Group[i] = make([]syntax.GrammarSymbol, len(alt))
+			for j := range alt {
+				substituted.Group[i][j] = substituteParams(alt[j], subst)
+			}
+		}
+		return substituted
+	}
+
+	if !strings.HasPrefix(sym.Symbol, "{") {
+		return sym
+	}
+
+	arg, ok := subst[strings.ToUpper(sym.Symbol[1:len(sym.Symbol)-1])]
+	if !ok {
+		return sym
+	}
+
+	if arg.Src == nil {
+		arg.Src = sym.Src
+	}
+	if sym.Op == "" {
+		return arg
+	}
+	if arg.Plain() {
+		arg.Op = sym.Op
+		return arg
+	}
+	return syntax.GrammarSymbol{Group: [][]syntax.GrammarSymbol{{arg}}, Op: sym.Op, Src: arg.Src}
+}
+
+// paramArgName returns the name of arg as it is used in the name of a
+// non-terminal made for a parameterized rule. Characters that cannot be in the
+// name of a non-terminal are replaced with their Unicode names.
+func paramArgName(arg syntax.GrammarSymbol) string {
+	var parts []string
+	var cur strings.Builder
+	for _, ch := range strings.ToUpper(arg.String()) {
+		if ('A' <= ch && ch <= 'Z') || ch == '-' || ch == '_' {
+			cur.WriteRune(ch)
+			continue
+		} else if ch == '{' || ch == '}' {
+			// braces only mark a non-terminal.
+			continue
+		}
+
+		if cur.Len() > 0 {
+			parts = append(parts, cur.String())
+			cur.Reset()
+		}
+		chName := strings.Join(strings.Fields(runenames.Name(ch)), "-")
+		if chName == "" {
+			chName = "X"
+		}
+		parts = append(parts, unsafeNonTermChars.ReplaceAllString(chName, "-"))
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, cur.String())
+	}
+
+	return strings.Join(parts, "-")
+}
+
+// splitParameterized splits the text of a non-terminal, without its braces,
+// into its name and its list of parameters or arguments. The list is nil if
+// the non-terminal is not parameterized.
+func splitParameterized(nt string) (string, []string, error) {
+	open := strings.IndexRune(nt, '(')
+	if open < 0 {
+		return nt, nil, nil
+	}
+	name := strings.TrimSpace(nt[:open])
+	if !strings.HasSuffix(nt, ")") {
+		return "", nil, fmt.Errorf("argument list of %s is missing closing ')'", name)
+	}
+
+	var list []string
+	inner := nt[open+1 : len(nt)-1]
+	depth := 0
+	start := 0
+	for i, ch := range inner {
+		switch ch {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				list = append(list, strings.TrimSpace(inner[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	list = append(list, strings.TrimSpace(inner[start:]))
+
+	for i := range list {
+		if list[i] == "" {
+			return "", nil, fmt.Errorf("argument list of %s has an empty item", name)
+		}
+	}
+
+	return name, list, nil
+}
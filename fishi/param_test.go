@@ -0,0 +1,96 @@
+package fishi
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/fishi/syntax"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_splitParameterized(t *testing.T) {
+	testCases := []struct {
+		name       string
+		input      string
+		expectName string
+		expectList []string
+		expectErr  bool
+	}{
+		{
+			name:       "not parameterized",
+			input:      "EXPR",
+			expectName: "EXPR",
+		},
+		{
+			name:       "parameters",
+			input:      "SEP-LIST(S, X)",
+			expectName: "SEP-LIST",
+			expectList: []string{"S", "X"},
+		},
+		{
+			name:       "nested arguments",
+			input:      "LIST({PAIR(a, {B})}, comma)",
+			expectName: "LIST",
+			expectList: []string{"{PAIR(a, {B})}", "comma"},
+		},
+		{
+			name:       "parenthesis terminals",
+			input:      "WRAP((, ))",
+			expectName: "WRAP",
+			expectList: []string{"(", ")"},
+		},
+		{
+			name:      "missing closing paren",
+			input:     "LIST(a, b",
+			expectErr: true,
+		},
+		{
+			name:      "empty argument",
+			input:     "LIST(a, )",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actualName, actualList, err := splitParameterized(tc.input)
+
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectName, actualName)
+			assert.Equal(tc.expectList, actualList)
+		})
+	}
+}
+
+func Test_paramArgName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{name: "terminal", input: "comma", expect: "COMMA"},
+		{name: "non-terminal", input: "{EXPR}", expect: "EXPR"},
+		{name: "non-terminal with operator", input: "{EXPR}*", expect: "EXPR-ASTERISK"},
+		{name: "symbol terminal", input: "+", expect: "PLUS-SIGN"},
+		{name: "terminal with digit", input: "int2", expect: "INT-DIGIT-TWO"},
+		{name: "terminal ending in operator chars", input: "c++", expect: "C-PLUS-SIGN-PLUS-SIGN"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := paramArgName(syntax.ParseGrammarSymbol(tc.input))
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package fishi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// DiagnosticsFormat is a format that errors and warnings can be output in.
+type DiagnosticsFormat int
+
+const (
+	// DiagnosticsText is human-readable text, with each error and warning
+	// output as it occurs.
+	DiagnosticsText DiagnosticsFormat = iota
+
+	// DiagnosticsJSON is a single JSON object that has every error and warning
+	// in a Report.
+	DiagnosticsJSON
+
+	// DiagnosticsSARIF is a SARIF 2.1.0 log that has every error and warning
+	// in a Report as a result.
+	DiagnosticsSARIF
+)
+
+// String returns the name of the DiagnosticsFormat as it is given on the
+// command line.
+func (df DiagnosticsFormat) String() string {
+	switch df {
+	case DiagnosticsText:
+		return "text"
+	case DiagnosticsJSON:
+		return "json"
+	case DiagnosticsSARIF:
+		return "sarif"
+	default:
+		return fmt.Sprintf("DiagnosticsFormat(%d)", int(df))
+	}
+}
+
+// ParseDiagnosticsFormat parses the name of a DiagnosticsFormat. It is not
+// case-sensitive.
+func ParseDiagnosticsFormat(s string) (DiagnosticsFormat, error) {
+	switch strings.ToLower(s) {
+	case "text":
+		return DiagnosticsText, nil
+	case "json":
+		return DiagnosticsJSON, nil
+	case "sarif":
+		return DiagnosticsSARIF, nil
+	default:
+		return DiagnosticsText, fmt.Errorf("must be one of text, json, or sarif; not %q", s)
+	}
+}
+
+// Problem is a single error or warning in a Report.
+type Problem struct {
+	syntaxerr.Diagnostic
+
+	// WarnType is the short code of the WarnType of the Warning that the
+	// Problem is for. It is empty if the Problem is not for a Warning.
+	WarnType string `json:"warn_type,omitempty"`
+}
+
+// Report collects errors and warnings so that they can be output all at once
+// in a machine-readable format instead of as text as they occur. The
+// zero-value is ready to use.
+type Report struct {
+	// Tool is the name of the program that the Report is from.
+	Tool string `json:"tool"`
+
+	// Version is the version of the program that the Report is from.
+	Version string `json:"version"`
+
+	// Problems is every error and warning in the Report, in the order they
+	// were added.
+	Problems []Problem `json:"problems"`
+}
+
+// AddDiagnostics adds each Diagnostic in ds to the Report.
+func (r *Report) AddDiagnostics(ds syntaxerr.Diagnostics) {
+	for _, d := range ds {
+		r.Problems = append(r.Problems, Problem{Diagnostic: d})
+	}
+}
+
+// AddError adds err to the Report. If it has Diagnostics, each of those is
+// added; otherwise, it is added as an error for file with no location in it.
+func (r *Report) AddError(file string, err error) {
+	if ds, ok := syntaxerr.AsDiagnostics(err); ok {
+		r.AddDiagnostics(ds)
+		return
+	}
+	r.Problems = append(r.Problems, Problem{Diagnostic: syntaxerr.Diagnostic{
+		Severity: syntaxerr.SeverityError,
+		Message:  err.Error(),
+		Span:     syntaxerr.Span{File: file},
+	}})
+}
+
+// AddWarning adds w to the Report. If fatal is true, it is added with the
+// severity of an error.
+func (r *Report) AddWarning(w Warning, fatal bool) {
+	d := syntaxerr.Diagnostic{Severity: syntaxerr.SeverityWarning, Message: w.Message}
+	if w.Diagnostic != nil {
+		d = *w.Diagnostic
+	}
+	if fatal {
+		d.Severity = syntaxerr.SeverityError
+	}
+	r.Problems = append(r.Problems, Problem{Diagnostic: d, WarnType: w.Type.Short()})
+}
+
+// Merge adds the problems in the JSON of another Report, such as one output by
+// a generated diagnostics binary, to r. Anything in data after the JSON, such
+// as the exit status that go run prints, is ignored.
+func (r *Report) Merge(data []byte) error {
+	var other Report
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&other); err != nil {
+		return err
+	}
+	r.Problems = append(r.Problems, other.Problems...)
+	return nil
+}
+
+// Write writes the Report to w in the given format. DiagnosticsText cannot be
+// written; the problems in it are output as they occur instead.
+func (r *Report) Write(w io.Writer, format DiagnosticsFormat) error {
+	var v interface{}
+
+	switch format {
+	case DiagnosticsJSON:
+		out := *r
+		if out.Problems == nil {
+			out.Problems = []Problem{}
+		}
+		v = out
+	case DiagnosticsSARIF:
+		v = r.sarif()
+	default:
+		return fmt.Errorf("%s is not a format that a report can be written in", format)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// sarif returns the Report as a SARIF log.
+func (r *Report) sarif() sarifLog {
+	driver := sarifDriver{
+		Name:           r.Tool,
+		Version:        r.Version,
+		InformationURI: "https://github.com/dekarrin/ictiobus",
+		Rules:          []sarifRule{},
+	}
+	rules := map[string]bool{}
+
+	results := []sarifResult{}
+	for _, p := range r.Problems {
+		res := sarifResult{
+			RuleID:  p.Code,
+			Level:   p.Severity.String(),
+			Message: sarifMessage{Text: p.Message},
+		}
+		if res.RuleID == "" {
+			res.RuleID = p.WarnType
+		}
+		if res.RuleID != "" && !rules[res.RuleID] {
+			rules[res.RuleID] = true
+			driver.Rules = append(driver.Rules, sarifRule{ID: res.RuleID})
+		}
+		if loc := sarifLocationOf(p.Span, p.SourceLine); loc != nil {
+			res.Locations = []sarifLocation{*loc}
+		}
+		for i, lbl := range p.Labels {
+			if loc := sarifLocationOf(lbl.Span, lbl.SourceLine); loc != nil {
+				loc.ID = i + 1
+				loc.Message = &sarifMessage{Text: lbl.Message}
+				res.RelatedLocations = append(res.RelatedLocations, *loc)
+			}
+		}
+		var help []string
+		for _, sug := range p.Suggestions {
+			if !sug.Span.Known() {
+				// SARIF fixes must say what they change
+				help = append(help, sug.Message)
+				continue
+			}
+			res.Fixes = append(res.Fixes, sarifFix{
+				Description: sarifMessage{Text: sug.Message},
+				ArtifactChanges: []sarifArtifactChange{{
+					ArtifactLocation: sarifArtifactLocation{URI: sarifURI(sug.Span.File)},
+					Replacements: []sarifReplacement{{
+						DeletedRegion:   sarifRegionOf(sug.Span),
+						InsertedContent: &sarifContent{Text: sug.Replacement},
+					}},
+				}},
+			})
+		}
+		if p.WarnType != "" || len(p.Notes) > 0 || len(help) > 0 {
+			res.Properties = &sarifProperties{WarnType: p.WarnType, Notes: p.Notes, Help: help}
+		}
+
+		results = append(results, res)
+	}
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
+
+// sarifLocationOf returns the SARIF location of span, or nil if it has neither
+// a file nor a position.
+func sarifLocationOf(span syntaxerr.Span, sourceLine string) *sarifLocation {
+	if span.File == "" && !span.Known() {
+		return nil
+	}
+	phys := sarifPhysicalLocation{}
+	if span.File != "" {
+		phys.ArtifactLocation = &sarifArtifactLocation{URI: sarifURI(span.File)}
+	}
+	if span.Known() {
+		phys.Region = sarifRegionOf(span)
+		if sourceLine != "" {
+			phys.ContextRegion = &sarifRegion{StartLine: span.Start.Line, Snippet: &sarifContent{Text: sourceLine}}
+		}
+	}
+	return &sarifLocation{PhysicalLocation: phys}
+}
+
+// sarifRegionOf returns the SARIF region that span covers.
+func sarifRegionOf(span syntaxerr.Span) *sarifRegion {
+	reg := &sarifRegion{
+		StartLine:   span.Start.Line,
+		StartColumn: span.Start.Col,
+		EndLine:     span.End.Line,
+		EndColumn:   span.End.Col,
+	}
+	if reg.EndLine < reg.StartLine {
+		reg.EndLine, reg.EndColumn = reg.StartLine, reg.StartColumn
+	}
+	return reg
+}
+
+// sarifURI returns the URI reference for the file with the given name.
+// Names that are not of a file, such as "<STDIN>", are used as-is.
+func sarifURI(file string) string {
+	if strings.HasPrefix(file, "<") {
+		return file
+	}
+	return filepath.ToSlash(file)
+}
+
+// The types below are the parts of the SARIF 2.1.0 format that a Report uses.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID           string           `json:"ruleId,omitempty"`
+	Level            string           `json:"level"`
+	Message          sarifMessage     `json:"message"`
+	Locations        []sarifLocation  `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation  `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix       `json:"fixes,omitempty"`
+	Properties       *sarifProperties `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	ID               int                   `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation *sarifArtifactLocation `json:"artifactLocation,omitempty"`
+	Region           *sarifRegion           `json:"region,omitempty"`
+	ContextRegion    *sarifRegion           `json:"contextRegion,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	EndLine     int           `json:"endLine,omitempty"`
+	EndColumn   int           `json:"endColumn,omitempty"`
+	Snippet     *sarifContent `json:"snippet,omitempty"`
+}
+
+type sarifContent struct {
+	Text string `json:"text"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   *sarifRegion  `json:"deletedRegion"`
+	InsertedContent *sarifContent `json:"insertedContent,omitempty"`
+}
+
+type sarifProperties struct {
+	WarnType string   `json:"warnType,omitempty"`
+	Notes    []string `json:"notes,omitempty"`
+	Help     []string `json:"help,omitempty"`
+}
@@ -0,0 +1,174 @@
+package fishi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseDiagnosticsFormat(t *testing.T) {
+	testCases := []struct {
+		input     string
+		expect    DiagnosticsFormat
+		expectErr bool
+	}{
+		{input: "text", expect: DiagnosticsText},
+		{input: "JSON", expect: DiagnosticsJSON},
+		{input: "sarif", expect: DiagnosticsSARIF},
+		{input: "xml", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := ParseDiagnosticsFormat(tc.input)
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+// testReport returns a Report with a syntax error, a plain error, and two
+// warnings, the second of which is fatal.
+func testReport() *Report {
+	span := func(file string, line, col, endCol int) syntaxerr.Span {
+		return syntaxerr.Span{File: file, Start: syntaxerr.Position{Line: line, Col: col}, End: syntaxerr.Position{Line: line, Col: endCol}}
+	}
+
+	r := &Report{Tool: "ictcc", Version: "1.0.0"}
+	r.AddError("spec.md", syntaxerr.Diagnostics{{
+		Code:        syntaxerr.CodeUnexpectedToken,
+		Message:     "unexpected '+'",
+		Span:        span("spec.md", 3, 5, 6),
+		SourceLine:  "1 + + 2",
+		Labels:      []syntaxerr.Label{{Span: span("spec.md", 3, 3, 4), Message: "after this"}},
+		Suggestions: []syntaxerr.Suggestion{{Message: "remove it", Span: span("spec.md", 3, 5, 6)}},
+	}})
+	r.AddError("other.md", fmt.Errorf("input is empty"))
+	r.AddWarning(Warning{Type: WarnMissingHumanDef, Message: "no human-readable name"}, false)
+	r.AddWarning(Warning{Type: WarnPriorityZero, Message: "priority 0", Diagnostic: &syntaxerr.Diagnostic{
+		Severity: syntaxerr.SeverityWarning,
+		Message:  "setting priority to 0 has no effect",
+		Span:     span("spec.md", 7, 10, 11),
+	}}, true)
+	return r
+}
+
+func Test_Report_Write_json(t *testing.T) {
+	assert := assert.New(t)
+
+	r := testReport()
+
+	// execute
+	var buf bytes.Buffer
+	err := r.Write(&buf, DiagnosticsJSON)
+
+	// assert
+	if !assert.NoError(err) {
+		return
+	}
+
+	var decoded map[string]interface{}
+	if !assert.NoError(json.Unmarshal(buf.Bytes(), &decoded)) {
+		return
+	}
+	assert.Equal("ictcc", decoded["tool"])
+	problems := decoded["problems"].([]interface{})
+	if !assert.Len(problems, 4) {
+		return
+	}
+
+	first := problems[0].(map[string]interface{})
+	assert.Equal("ICT0002", first["code"])
+	assert.Equal("error", first["severity"])
+	assert.Equal("spec.md", first["span"].(map[string]interface{})["file"])
+
+	second := problems[1].(map[string]interface{})
+	assert.Equal("input is empty", second["message"])
+	assert.Equal("other.md", second["span"].(map[string]interface{})["file"])
+
+	third := problems[2].(map[string]interface{})
+	assert.Equal("warning", third["severity"])
+	assert.Equal("missing-human", third["warn_type"])
+
+	fourth := problems[3].(map[string]interface{})
+	assert.Equal("error", fourth["severity"])
+	assert.Equal("priority", fourth["warn_type"])
+	assert.Equal("setting priority to 0 has no effect", fourth["message"])
+
+	// the output can be read back in
+	var merged Report
+	assert.NoError(merged.Merge(buf.Bytes()))
+	assert.Equal(r.Problems[0].Span, merged.Problems[0].Span)
+	assert.Equal(r.Problems[3].Severity, merged.Problems[3].Severity)
+	assert.Equal(r.Problems[3].WarnType, merged.Problems[3].WarnType)
+
+	// output after the report, like what go run adds, is ignored
+	var trailing Report
+	assert.NoError(trailing.Merge(append(buf.Bytes(), "exit status 4\n"...)))
+	assert.Len(trailing.Problems, len(r.Problems))
+}
+
+func Test_Report_Write_sarif(t *testing.T) {
+	assert := assert.New(t)
+
+	r := testReport()
+
+	// execute
+	var buf bytes.Buffer
+	err := r.Write(&buf, DiagnosticsSARIF)
+
+	// assert
+	if !assert.NoError(err) {
+		return
+	}
+
+	var log sarifLog
+	if !assert.NoError(json.Unmarshal(buf.Bytes(), &log)) {
+		return
+	}
+	assert.Equal("2.1.0", log.Version)
+	if !assert.Len(log.Runs, 1) {
+		return
+	}
+	run := log.Runs[0]
+	assert.Equal("ictcc", run.Tool.Driver.Name)
+	assert.Equal([]sarifRule{{ID: "ICT0002"}, {ID: "missing-human"}, {ID: "priority"}}, run.Tool.Driver.Rules)
+	if !assert.Len(run.Results, 4) {
+		return
+	}
+
+	syn := run.Results[0]
+	assert.Equal("ICT0002", syn.RuleID)
+	assert.Equal("error", syn.Level)
+	if assert.Len(syn.Locations, 1) {
+		loc := syn.Locations[0].PhysicalLocation
+		assert.Equal("spec.md", loc.ArtifactLocation.URI)
+		assert.Equal(&sarifRegion{StartLine: 3, StartColumn: 5, EndLine: 3, EndColumn: 6}, loc.Region)
+		assert.Equal("1 + + 2", loc.ContextRegion.Snippet.Text)
+	}
+	if assert.Len(syn.RelatedLocations, 1) {
+		assert.Equal("after this", syn.RelatedLocations[0].Message.Text)
+	}
+	assert.Len(syn.Fixes, 1)
+
+	plain := run.Results[1]
+	assert.Equal("", plain.RuleID)
+	if assert.Len(plain.Locations, 1) {
+		assert.Nil(plain.Locations[0].PhysicalLocation.Region)
+	}
+
+	warn := run.Results[2]
+	assert.Equal("warning", warn.Level)
+	assert.Empty(warn.Locations)
+	assert.Equal("missing-human", warn.Properties.WarnType)
+}
@@ -13,8 +13,8 @@ import (
 	"github.com/dekarrin/ictiobus/internal/textfmt"
 	"github.com/dekarrin/ictiobus/lex"
 	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/syntaxerr"
 	"github.com/dekarrin/ictiobus/trans"
-	"github.com/dekarrin/rosed"
 )
 
 // Spec is a series of statements that together give the specification for a
@@ -207,6 +207,10 @@ func (spec Spec) CreateParser(t parse.Algorithm, allowAmbig bool) (parse.Parser,
 		}
 
 		p, err = ictiobus.NewLLParser(spec.Grammar)
+	case parse.CYK:
+		// CYK parsers accept ambiguous grammars as-is, so allowAmbig does not
+		// matter.
+		p, err = ictiobus.NewCYKParser(spec.Grammar)
 	default:
 		return nil, nil, fmt.Errorf("unsupported parser type: %s", t)
 	}
@@ -225,6 +229,33 @@ func (spec Spec) CreateParser(t parse.Algorithm, allowAmbig bool) (parse.Parser,
 	return p, warns, nil
 }
 
+// CheckAmbiguity searches every sentence of the language that has at most
+// maxLen tokens for one that has more than one parse tree. A
+// WarnAmbiguousSentence Warning is returned for each one found, and it gives
+// the sentence and two of its parse trees. Unlike the warnings given when
+// creating a parser, these show that the grammar itself is ambiguous.
+func (spec Spec) CheckAmbiguity(maxLen int) ([]Warning, error) {
+	if len(spec.Grammar.NonTerminals()) == 0 {
+		return nil, fmt.Errorf("grammar is empty")
+	}
+
+	ambigs, err := parse.FindAmbiguities(spec.Grammar, maxLen)
+	if err != nil {
+		return nil, err
+	}
+
+	var warns []Warning
+	for _, a := range ambigs {
+		msg := fmt.Sprintf("grammar is ambiguous; %q has more than one parse tree:\n\n%s\n\n%s", a.String(), a.Trees[0].String(), a.Trees[1].String())
+		warns = append(warns, Warning{
+			Type:    WarnAmbiguousSentence,
+			Message: msg,
+		})
+	}
+
+	return warns, nil
+}
+
 // CreateSDTS uses the TranslationScheme in the spec to create a new SDTS.
 func (spec Spec) CreateSDTS() (trans.SDTS, error) {
 	if len(spec.TranslationScheme) == 0 {
@@ -250,6 +281,19 @@ func (spec Spec) CreateSDTS() (trans.SDTS, error) {
 	return sdts, nil
 }
 
+// SAttributed returns whether every SDD in the translation scheme sets a
+// synthesized attribute. The SDTS of such a spec can be applied while input is
+// parsed with an LR parser instead of after the full parse tree is built; see
+// trans.EvaluateStream.
+func (spec Spec) SAttributed() bool {
+	for _, sdd := range spec.TranslationScheme {
+		if sdd.Attribute.Rel.Type != trans.RelHead {
+			return false
+		}
+	}
+	return true
+}
+
 // Pattern is a lexer pattern that is used to match a token, along with the
 // action that the lexer should take when it matches.
 type Pattern struct {
@@ -355,8 +399,16 @@ func NewSpec(ast AST) (spec Spec, warnings []Warning, err error) {
 		return ls, warnings, err
 	}
 
+	// parameterized rules are made into plain ones for each of their uses
+	// before anything else looks at the grammar or actions.
+	grammarBlocks, actionsBlocks, err = expandParameterizedRules(grammarBlocks, actionsBlocks)
+	if err != nil {
+		return ls, warnings, err
+	}
+
 	// go over grammarBlocks to get grammar
-	ls.Grammar, subWarns, err = analyzeASTGrammarContentSlice(grammarBlocks, classes)
+	var ebnfRules []ebnfRule
+	ls.Grammar, ebnfRules, subWarns, err = analyzeASTGrammarContentSlice(grammarBlocks, classes)
 	if len(subWarns) > 0 {
 		warnings = append(warnings, subWarns...)
 	}
@@ -373,6 +425,17 @@ func NewSpec(ast AST) (spec Spec, warnings []Warning, err error) {
 		return ls, warnings, err
 	}
 
+	// the non-terminals generated for EBNF operators and groups get their
+	// values from actions generated for them, but only if there are any
+	// actions at all.
+	if len(ls.TranslationScheme) > 0 && len(ebnfRules) > 0 {
+		ebnfSDDs, err := ebnfTranslationScheme(ebnfRules, ls.Grammar, ls.TranslationScheme)
+		if err != nil {
+			return ls, warnings, err
+		}
+		ls.TranslationScheme = append(ls.TranslationScheme, ebnfSDDs...)
+	}
+
 	return ls, warnings, nil
 }
 
@@ -514,7 +577,7 @@ func analyzeASTActionsContentSlice(
 func analyzeASTGrammarContentSlice(
 	grammarBlocks []syntax.GrammarContent,
 	classes map[string]lex.TokenClass,
-) (grammar.CFG, []Warning, error) {
+) (grammar.CFG, []ebnfRule, []Warning, error) {
 	var warnings []Warning
 
 	g := grammar.CFG{}
@@ -522,9 +585,51 @@ func analyzeASTGrammarContentSlice(
 
 	// track terminals in the grammar to make sure they're all used
 	seenTerminals := make(map[string]bool)
+
+	// every non-terminal in the spec must be known before any are generated
+	// for EBNF operators and groups so the generated names don't conflict with
+	// them.
+	ebnf := &ebnfDesugarer{}
+	for _, gBl := range grammarBlocks {
+		for _, rule := range gBl.Rules {
+			head := rule.Rule.NonTerminal
+			ebnf.names.AddRule(strings.ToUpper(head[1:len(head)-1]), grammar.Epsilon)
+		}
+	}
+
+	ebnf.plain = func(sym string, src lex.Token) (string, error) {
+		// epsilons should be left alone
+		if sym == "" {
+			return sym, nil
+		}
+
+		if sym[0] == '{' && sym[len(sym)-1] == '}' {
+			// if it's wrapped in braces, it's a non-terminal; drop braces and make upper-case
+			return strings.ToUpper(sym[1 : len(sym)-1]), nil
+		}
+
+		// else, it's a terminal, make lower-case...
+		sym = strings.ToLower(sym)
+
+		// ...and make sure it's in the lexer's terminals
+		if _, ok := classes[sym]; !ok {
+			synErr := lex.NewSyntaxErrorFromToken(fmt.Sprintf("terminal '%s' is not a defined token class in any tokens block", sym), src)
+			return "", synErr
+		}
+
+		// get token class
+		tc := classes[sym]
+		g.AddTerm(sym, tc)
+
+		// mark it as seen
+		seenTerminals[sym] = true
+
+		return sym, nil
+	}
+
 	for _, gBl := range grammarBlocks {
 		if gBl.State != "" {
-			return g, warnings, fmt.Errorf("grammar blocks in non-default state not supported yet")
+			return g, nil, warnings, fmt.Errorf("grammar blocks in non-default state not supported yet")
 		}
 
 		for _, rule := range gBl.Rules {
@@ -532,34 +637,15 @@ func analyzeASTGrammarContentSlice(
 			// remove braces and make upper-case
 			head = strings.ToUpper(head[1 : len(head)-1])
 
-			for _, prod := range rule.Rule.Productions {
-				newProd := grammar.Production{}
-				for _, sym := range prod {
-					// epsilons should be left alone
-					if sym != "" {
-						if sym[0] == '{' && sym[len(sym)-1] == '}' {
-							// if it's wrapped in braces, it's a non-terminal; drop braces and make upper-case
-							sym = strings.ToUpper(sym[1 : len(sym)-1])
-						} else {
-							// else, it's a terminal, make lower-case...
-							sym = strings.ToLower(sym)
-
-							// ...and make sure it's in the lexer's terminals
-							if _, ok := classes[sym]; !ok {
-								synErr := lex.NewSyntaxErrorFromToken(fmt.Sprintf("terminal '%s' is not a defined token class in any tokens block", sym), rule.Src)
-								return g, warnings, synErr
-							}
-
-							// get token class
-							tc := classes[sym]
-							g.AddTerm(sym, tc)
-
-							// mark it as seen
-							seenTerminals[sym] = true
-						}
-					}
+			prods := rule.Productions
+			if prods == nil {
+				prods = plainGrammarSymbols(rule.Rule.Productions)
+			}
 
-					newProd = append(newProd, sym)
+			for _, prod := range prods {
+				newProd, err := ebnf.production(head, prod, rule.Src)
+				if err != nil {
+					return g, nil, warnings, err
 				}
 				g.AddRule(head, newProd)
 			}
@@ -568,6 +654,18 @@ func analyzeASTGrammarContentSlice(
 				g.Start = head
 				hitFirst = true
 			}
+
+			if rule.Entry && head != g.Start && !slices.In(head, g.EntryPoints) {
+				g.EntryPoints = append(g.EntryPoints, head)
+			}
+		}
+	}
+
+	// rules generated for EBNF operators and groups go after all of the ones
+	// in the spec.
+	for _, r := range ebnf.rules {
+		for _, p := range r.prods {
+			g.AddRule(r.head, p)
 		}
 	}
 
@@ -587,11 +685,24 @@ func analyzeASTGrammarContentSlice(
 	// validate the grammar
 	if len(g.NonTerminals()) != 0 {
 		if err := g.Validate(); err != nil {
-			return g, warnings, fmt.Errorf("invalid grammar: %w", err)
+			return g, nil, warnings, fmt.Errorf("invalid grammar: %w", err)
 		}
 	}
 
-	return g, warnings, nil
+	return g, ebnf.rules, warnings, nil
+}
+
+// plainGrammarSymbols returns prods as GrammarSymbols that are each a plain
+// terminal or non-terminal.
+func plainGrammarSymbols(prods []grammar.Production) [][]syntax.GrammarSymbol {
+	converted := make([][]syntax.GrammarSymbol, len(prods))
+	for i, prod := range prods {
+		converted[i] = make([]syntax.GrammarSymbol, len(prod))
+		for j, sym := range prod {
+			converted[i][j] = syntax.GrammarSymbol{Symbol: sym}
+		}
+	}
+	return converted
 }
 
 func analzyeASTTokensContentSlice(
@@ -661,22 +772,16 @@ func analzyeASTTokensContentSlice(
 				}
 
 				if secondTok != nil {
-					var fullErrMsg string
+					var diag syntaxerr.Diagnostic
 					if firstIsDiscard {
-						errMsg := "human/token/stateshift directive cannot be added to discarded entry:"
-						synErr1 := lex.NewSyntaxErrorFromToken("initial discard defined here", firstTok)
-						synErr2 := lex.NewSyntaxErrorFromToken("directive not allowed", secondTok)
-
-						fullErrMsg = errMsg + "\n" + synErr1.FullMessage() + "\n" + synErr2.FullMessage()
+						diag = lex.NewDiagnosticFromToken("", "human/token/stateshift directive cannot be added to discarded entry", secondTok)
+						diag.Labels = []syntaxerr.Label{lex.NewLabelFromToken("initial discard defined here", firstTok)}
 					} else {
-						errMsg := "can't discard an entry that will be used for stateshift or token lexing:"
-						synErr1 := lex.NewSyntaxErrorFromToken("initial directive defined here", firstTok)
-						synErr2 := lex.NewSyntaxErrorFromToken("discard directive not allowed", secondTok)
-
-						fullErrMsg = errMsg + "\n" + synErr1.FullMessage() + "\n" + synErr2.FullMessage()
+						diag = lex.NewDiagnosticFromToken("", "can't discard an entry that will be used for stateshift or token lexing", secondTok)
+						diag.Labels = []syntaxerr.Label{lex.NewLabelFromToken("initial directive defined here", firstTok)}
 					}
 
-					return nil, warnings, fmt.Errorf(fullErrMsg)
+					return nil, warnings, syntaxerr.Diagnostics{diag}
 				}
 
 				// if here, the only options that could be present are discard and priority. take the discard.
@@ -734,10 +839,12 @@ func analzyeASTTokensContentSlice(
 			// finally, check for priority
 			if len(entry.SrcPriority) > 0 {
 				if entry.Priority == 0 {
-					warn := lex.NewSyntaxErrorFromToken("setting priority to 0 has no effect", entry.SrcPriority[0])
+					warn := lex.NewDiagnosticFromToken("", "setting priority to 0 has no effect", entry.SrcPriority[0])
+					warn.Severity = syntaxerr.SeverityWarning
 					warnings = append(warnings, Warning{
-						Type:    WarnPriorityZero,
-						Message: warn.FullMessage(),
+						Type:       WarnPriorityZero,
+						Message:    warn.FullMessage(),
+						Diagnostic: &warn,
 					})
 				} else if entry.Priority < 0 {
 					synErr := lex.NewSyntaxErrorFromToken("priority cannot be negative", entry.SrcPriority[0])
@@ -936,16 +1043,18 @@ func checkForDuplicateHumanDefs(tcSymTable map[string][]box.Pair[string, lex.Tok
 	// that during reading of tokenBlocks)
 	for tok, humanDefs := range tcSymTable {
 		if len(humanDefs) > 1 {
-			msgStart := fmt.Sprintf("multiple distinct human-readable names given for token %q:", tok)
-			var msg string
-			for _, hd := range humanDefs {
-				synErr := lex.NewSyntaxErrorFromToken("human name last defined here", hd.Second)
-				msg += fmt.Sprintf("%s\n", synErr.FullMessage())
+			msg := fmt.Sprintf("multiple distinct human-readable names given for token %q", tok)
+			last := humanDefs[len(humanDefs)-1].Second
+			diag := lex.NewDiagnosticFromToken("", msg, last)
+			diag.Severity = syntaxerr.SeverityWarning
+			for _, hd := range humanDefs[:len(humanDefs)-1] {
+				diag.Labels = append(diag.Labels, lex.NewLabelFromToken("human name also defined here", hd.Second))
 			}
-			msg = rosed.Edit(msg).IndentOpts(1, rosed.Options{IndentStr: "  "}).String()
+			diag.Notes = []string{fmt.Sprintf("the last one given, %s, is used", humanDefs[len(humanDefs)-1].First)}
 			fullWarn := Warning{
-				Type:    WarnDuplicateHumanDefs,
-				Message: msgStart + "\n" + msg,
+				Type:       WarnDuplicateHumanDefs,
+				Message:    diag.FullMessage(),
+				Diagnostic: &diag,
 			}
 			warnings = append(warnings, fullWarn)
 		}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/dekarrin/ictiobus"
 	"github.com/dekarrin/ictiobus/grammar"
 	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
 	"github.com/dekarrin/ictiobus/trans"
 	"github.com/stretchr/testify/assert"
 )
@@ -168,6 +171,62 @@ func Test_Spec_ValidateSDTS_disconnectedGraph(t *testing.T) {
 	}
 }
 
+func Test_Spec_CheckAmbiguity(t *testing.T) {
+	testCases := []struct {
+		name           string
+		grammar        string
+		maxLen         int
+		expectErr      bool
+		expectMessages []string
+	}{
+		{
+			name:    "unambiguous grammar",
+			grammar: `S -> S plus int | int ;`,
+			maxLen:  5,
+		},
+		{
+			name:    "ambiguous grammar",
+			grammar: `S -> S plus S | int ;`,
+			maxLen:  5,
+			expectMessages: []string{
+				"grammar is ambiguous; \"int plus int plus int\" has more than one parse tree:\n\n( S )",
+			},
+		},
+		{
+			name:      "empty grammar",
+			maxLen:    5,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			spec := Spec{}
+			if tc.grammar != "" {
+				spec.Grammar = grammar.MustParse(tc.grammar)
+			}
+
+			warns, err := spec.CheckAmbiguity(tc.maxLen)
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			if !assert.Len(warns, len(tc.expectMessages)) {
+				return
+			}
+			for i := range warns {
+				assert.Equal(WarnAmbiguousSentence, warns[i].Type)
+				assert.Contains(warns[i].Message, tc.expectMessages[i])
+			}
+		})
+	}
+}
+
 func coerceToString(a interface{}) string {
 	// is it just a string? return if so
 	if str, ok := a.(string); ok {
@@ -187,3 +246,327 @@ func coerceToString(a interface{}) string {
 	// finally, if none of those, get the default formatting and return that
 	return fmt.Sprintf("%v", a)
 }
+
+func Test_NewSpec_EBNF(t *testing.T) {
+	const tokensAndGrammar = `%%tokens
+\d+    %token int    %human integer
+,      %token comma  %human ','
+\[     %token lb     %human '['
+\]     %token rb     %human ']'
+-      %token minus  %human '-'
+\s+    %discard
+
+%%grammar
+{LIST} = lb {( {ITEM} {( comma {ITEM} )}* )}? rb
+{ITEM} = {( minus )}? int
+`
+
+	testCases := []struct {
+		name        string
+		actions     string
+		expectRules []string
+		expectErr   bool
+		input       string
+		expectIR    []int
+	}{
+		{
+			name: "operators and groups are desugared",
+			actions: `
+%%actions
+%symbol {LIST} -> : {^}.value = make_list({1}.value)
+%symbol {ITEM} -> : {^}.value = make_item({0}.value, {1}.$text)
+`,
+			expectRules: []string{
+				"LIST -> lb LIST-PPPPP rb",
+				"ITEM -> ITEM-P int",
+				"LIST-P -> ITEM LIST-PPP",
+				"LIST-PP -> comma ITEM",
+				"LIST-PPP -> LIST-PPPP",
+				"LIST-PPPP -> LIST-PP LIST-PPPP | ε",
+				"LIST-PPPPP -> LIST-P | ε",
+				"ITEM-P -> minus | ε",
+			},
+			input:    "[1, -2, 3]",
+			expectIR: []int{1, -2, 3},
+		},
+		{
+			name: "empty list",
+			actions: `
+%%actions
+%symbol {LIST} -> : {^}.value = make_list({1}.value)
+%symbol {ITEM} -> : {^}.value = make_item({0}.value, {1}.$text)
+`,
+			input:    "[]",
+			expectIR: []int{},
+		},
+		{
+			name: "no translation scheme",
+			expectRules: []string{
+				"LIST -> lb LIST-PPPPP rb",
+				"ITEM -> ITEM-P int",
+				"LIST-P -> ITEM LIST-PPP",
+				"LIST-PP -> comma ITEM",
+				"LIST-PPP -> LIST-PPPP",
+				"LIST-PPPP -> LIST-PP LIST-PPPP | ε",
+				"LIST-PPPPP -> LIST-P | ε",
+				"ITEM-P -> minus | ε",
+			},
+		},
+		{
+			name: "non-terminal in group without attribute",
+			actions: `
+%%actions
+%symbol {LIST} -> : {^}.value = make_list({1}.value)
+`,
+			expectErr: true,
+		},
+	}
+
+	hooks := trans.HookMap{
+		"make_item": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			n, err := strconv.Atoi(args[1].(string))
+			if args[0] != nil {
+				n = -n
+			}
+			return n, err
+		},
+		"make_list": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			list := []int{}
+			if args[0] == nil {
+				return list, nil
+			}
+			group := args[0].([]interface{})
+			list = append(list, group[0].(int))
+			for _, rest := range group[1].([]interface{}) {
+				// each is the (comma {ITEM}) group
+				list = append(list, rest.([]interface{})[1].(int))
+			}
+			return list, nil
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			res, err := Parse(strings.NewReader(tokensAndGrammar+tc.actions), nil)
+			if !assert.NoError(err) {
+				return
+			}
+
+			spec, _, err := NewSpec(*res.AST)
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			if tc.expectRules != nil {
+				var actualRules []string
+				for _, nt := range spec.Grammar.NonTerminalsByPriority() {
+					actualRules = append(actualRules, spec.Grammar.Rule(nt).String())
+				}
+				assert.Equal(tc.expectRules, actualRules)
+			}
+
+			if tc.input == "" {
+				return
+			}
+
+			lx, err := spec.CreateLexer(false)
+			if !assert.NoError(err) {
+				return
+			}
+			p, _, err := spec.CreateParser(parse.LL1, false)
+			if !assert.NoError(err) {
+				return
+			}
+			sdts, err := spec.CreateSDTS()
+			if !assert.NoError(err) {
+				return
+			}
+			sdts.SetHooks(hooks)
+
+			fe := ictiobus.Frontend[[]int]{Lexer: lx, Parser: p, SDTS: sdts, IRAttribute: "value"}
+			actual, _, err := fe.AnalyzeString(tc.input)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectIR, actual)
+		})
+	}
+}
+
+func Test_NewSpec_OperatorCharsInTerminals(t *testing.T) {
+	assert := assert.New(t)
+
+	const input = `%%tokens
+\(        %token (     %human '('
+\)        %token )     %human ')'
+c\+\+     %token c++   %human 'c++'
+\*        %token ptr*  %human '*'
+\s+       %discard
+
+%%grammar
+{S} = ( c++ ptr* ) | ( {S}? ) | ( )
+`
+
+	res, err := Parse(strings.NewReader(input), nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	spec, _, err := NewSpec(*res.AST)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var actualRules []string
+	for _, nt := range spec.Grammar.NonTerminalsByPriority() {
+		actualRules = append(actualRules, spec.Grammar.Rule(nt).String())
+	}
+	assert.Equal([]string{
+		"S -> ( c++ ptr* ) | ( S-P ) | ( )",
+		"S-P -> S | ε",
+	}, actualRules)
+}
+
+func Test_NewSpec_ParameterizedRules(t *testing.T) {
+	const tokens = `%%tokens
+\d+     %token int    %human integer
+[a-z]+  %token id     %human identifier
+,       %token comma  %human ','
+;       %token semi   %human ';'
+\[      %token lb     %human '['
+\]      %token rb     %human ']'
+\s+     %discard
+`
+	const actions = `
+%%actions
+%symbol {PROGRAM} -> : {^}.value = concat({0}.value, {1}.value)
+%symbol {SEP-LIST(S, X)}
+-> : {^}.value = list_start({X}.$text)
+-> : {^}.value = list_prepend({X}.$text, {2}.value)
+%symbol {BRACKETED(Y)} -> : {^}.value = ident({Y}.value)
+`
+
+	testCases := []struct {
+		name        string
+		grammar     string
+		expectRules []string
+		expectErr   bool
+		input       string
+		expectIR    []string
+	}{
+		{
+			name: "nested uses",
+			grammar: `
+%%grammar
+{PROGRAM}         =  {BRACKETED({SEP-LIST(comma, int)})} {BRACKETED({SEP-LIST(semi, id)})}
+{SEP-LIST(S, X)}  =  {X} | {X} {S} {SEP-LIST(S, X)}
+{BRACKETED(X)}    =  lb {X} rb
+`,
+			expectRules: []string{
+				"PROGRAM -> BRACKETED_SEP-LIST_COMMA_INT BRACKETED_SEP-LIST_SEMI_ID",
+				"SEP-LIST_COMMA_INT -> int | int comma SEP-LIST_COMMA_INT",
+				"BRACKETED_SEP-LIST_COMMA_INT -> lb SEP-LIST_COMMA_INT rb",
+				"SEP-LIST_SEMI_ID -> id | id semi SEP-LIST_SEMI_ID",
+				"BRACKETED_SEP-LIST_SEMI_ID -> lb SEP-LIST_SEMI_ID rb",
+			},
+			input:    "[1, 2] [a; b; c]",
+			expectIR: []string{"1", "2", "a", "b", "c"},
+		},
+		{
+			name: "wrong number of arguments",
+			grammar: `
+%%grammar
+{PROGRAM}         =  {BRACKETED({SEP-LIST(comma)})} {BRACKETED({SEP-LIST(semi, id)})}
+{SEP-LIST(S, X)}  =  {X} | {X} {S} {SEP-LIST(S, X)}
+{BRACKETED(X)}    =  lb {X} rb
+`,
+			expectErr: true,
+		},
+		{
+			name: "parameterized rule used without arguments",
+			grammar: `
+%%grammar
+{PROGRAM}         =  {BRACKETED} {BRACKETED({SEP-LIST(semi, id)})}
+{SEP-LIST(S, X)}  =  {X} | {X} {S} {SEP-LIST(S, X)}
+{BRACKETED(X)}    =  lb {X} rb
+`,
+			expectErr: true,
+		},
+		{
+			name: "expansion that never ends",
+			grammar: `
+%%grammar
+{PROGRAM}         =  {BRACKETED({SEP-LIST(comma, int)})} {BRACKETED({SEP-LIST(semi, id)})}
+{SEP-LIST(S, X)}  =  {X} | {X} {S} {SEP-LIST(S, {BRACKETED(X)})}
+{BRACKETED(X)}    =  lb {X} rb
+`,
+			expectErr: true,
+		},
+	}
+
+	hooks := trans.HookMap{
+		"concat": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return append(args[0].([]string), args[1].([]string)...), nil
+		},
+		"list_start": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return []string{args[0].(string)}, nil
+		},
+		"list_prepend": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return append([]string{args[0].(string)}, args[1].([]string)...), nil
+		},
+		"ident": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return args[0], nil
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			res, err := Parse(strings.NewReader(tokens+tc.grammar+actions), nil)
+			if !assert.NoError(err) {
+				return
+			}
+
+			spec, _, err := NewSpec(*res.AST)
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			var actualRules []string
+			for _, nt := range spec.Grammar.NonTerminalsByPriority() {
+				actualRules = append(actualRules, spec.Grammar.Rule(nt).String())
+			}
+			assert.Equal(tc.expectRules, actualRules)
+
+			lx, err := spec.CreateLexer(false)
+			if !assert.NoError(err) {
+				return
+			}
+			p, _, err := spec.CreateParser(parse.LALR1, false)
+			if !assert.NoError(err) {
+				return
+			}
+			sdts, err := spec.CreateSDTS()
+			if !assert.NoError(err) {
+				return
+			}
+			sdts.SetHooks(hooks)
+
+			fe := ictiobus.Frontend[[]string]{Lexer: lx, Parser: p, SDTS: sdts, IRAttribute: "value"}
+			actual, _, err := fe.AnalyzeString(tc.input)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectIR, actual)
+		})
+	}
+}
@@ -77,10 +77,16 @@ var (
 		"token_opt_list_start":                     sdtsFnTokenOptListStart,
 		"token_opt_list_append":                    sdtsFnTokenOptListAppend,
 		"string_list_start":                        sdtsFnStringListStart,
-		"string_list_list_start":                   sdtsFnStringListListStart,
-		"string_list_list_append":                  sdtsFnStringListListAppend,
+		"production_list_start":                    sdtsFnProductionListStart,
+		"production_list_append":                   sdtsFnProductionListAppend,
+		"grammar_symbol_list_start":                sdtsFnGrammarSymbolListStart,
+		"grammar_symbol_list_append":               sdtsFnGrammarSymbolListAppend,
+		"epsilon_grammar_symbol_list":              sdtsFnEpsilonGrammarSymbolList,
+		"make_grammar_symbol":                      sdtsFnMakeGrammarSymbol,
+		"make_grammar_group":                       sdtsFnMakeGrammarGroup,
 		"epsilon_string_list":                      sdtsFnEpsilonStringList,
 		"make_rule":                                sdtsFnMakeRule,
+		"make_entry_rule":                          sdtsFnMakeEntryRule,
 		"make_token_entry":                         sdtsFnMakeTokenEntry,
 	}
 )
@@ -855,30 +861,92 @@ func sdtsFnStringListStart(_ trans.SetterInfo, args []interface{}) (interface{},
 	return []string{toAppend}, nil
 }
 
-func sdtsFnStringListListStart(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
-	toAppend, ok := args[0].([]string)
+func sdtsFnProductionListStart(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
+	toAppend, ok := args[0].([]GrammarSymbol)
 	if !ok {
-		return nil, newArgTypeError(args, 0, "[]string")
+		return nil, newArgTypeError(args, 0, "[]GrammarSymbol")
+	}
+
+	return [][]GrammarSymbol{toAppend}, nil
+}
+
+func sdtsFnProductionListAppend(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
+	list, ok := args[0].([][]GrammarSymbol)
+	if !ok {
+		return nil, newArgTypeError(args, 0, "[][]GrammarSymbol")
+	}
+
+	toAppend, ok := args[1].([]GrammarSymbol)
+	if !ok {
+		return nil, newArgTypeError(args, 1, "[]GrammarSymbol")
+	}
+
+	list = append(list, toAppend)
+	return list, nil
+}
+
+func sdtsFnGrammarSymbolListStart(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
+	toAppend, ok := args[0].(GrammarSymbol)
+	if !ok {
+		return nil, newArgTypeError(args, 0, "GrammarSymbol")
 	}
 
-	return [][]string{toAppend}, nil
+	return []GrammarSymbol{toAppend}, nil
 }
 
-func sdtsFnStringListListAppend(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
-	list, ok := args[0].([][]string)
+func sdtsFnGrammarSymbolListAppend(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
+	list, ok := args[0].([]GrammarSymbol)
 	if !ok {
-		return nil, newArgTypeError(args, 0, "[][]string")
+		return nil, newArgTypeError(args, 0, "[]GrammarSymbol")
 	}
 
-	toAppend, ok := args[1].([]string)
+	toAppend, ok := args[1].(GrammarSymbol)
 	if !ok {
-		return nil, newArgTypeError(args, 1, "[]string")
+		return nil, newArgTypeError(args, 1, "GrammarSymbol")
 	}
 
 	list = append(list, toAppend)
 	return list, nil
 }
 
+func sdtsFnEpsilonGrammarSymbolList(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
+	return []GrammarSymbol{{Symbol: grammar.Epsilon[0]}}, nil
+}
+
+// sdtsFnMakeGrammarSymbol makes a terminal or non-terminal GrammarSymbol from
+// its lexed text, which includes any EBNF operator after it.
+func sdtsFnMakeGrammarSymbol(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
+	str, ok := args[0].(string)
+	if !ok {
+		return nil, newArgTypeError(args, 0, "string")
+	}
+
+	gs := ParseGrammarSymbol(str)
+	gs.Src, _ = args[1].(lex.Token)
+
+	return gs, nil
+}
+
+// sdtsFnMakeGrammarGroup makes a group GrammarSymbol from its alternatives and
+// the lexed text of the closing parenthesis, which includes any EBNF operator
+// after it.
+func sdtsFnMakeGrammarGroup(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
+	alts, ok := args[0].([][]GrammarSymbol)
+	if !ok {
+		return nil, newArgTypeError(args, 0, "[][]GrammarSymbol")
+	}
+
+	closeParen, ok := args[1].(string)
+	if !ok {
+		return nil, newArgTypeError(args, 1, "string")
+	}
+
+	gs := GrammarSymbol{Group: alts, Op: strings.TrimPrefix(strings.TrimSpace(closeParen), ")}")}
+	gs.Src, _ = args[2].(lex.Token)
+
+	return gs, nil
+}
+
 func sdtsFnEpsilonStringList(_ trans.SetterInfo, args []interface{}) (interface{}, error) {
 	strList := grammar.Epsilon.Copy()
 	return []string(strList), nil
@@ -895,25 +963,45 @@ func sdtsFnMakeRule(info trans.SetterInfo, args []interface{}) (interface{}, err
 		return nil, newArgTypeError(args, 0, "string")
 	}
 
-	productions, ok := args[1].([][]string)
+	productions, ok := args[1].([][]GrammarSymbol)
 	if !ok {
-		return nil, newArgTypeError(args, 1, "[][]string")
+		return nil, newArgTypeError(args, 1, "[][]GrammarSymbol")
 	}
 
 	gr := grammar.Rule{NonTerminal: nt, Productions: []grammar.Production{}}
 
 	for _, p := range productions {
-		gr.Productions = append(gr.Productions, p)
+		prod := grammar.Production{}
+		for _, sym := range p {
+			if sym.Plain() {
+				prod = append(prod, sym.Symbol)
+			} else {
+				prod = append(prod, sym.String())
+			}
+		}
+		gr.Productions = append(gr.Productions, prod)
 	}
 
 	r := GrammarRule{
-		Rule: gr,
-		Src:  info.FirstToken,
+		Rule:        gr,
+		Productions: productions,
+		Src:         info.FirstToken,
 	}
 
 	return r, nil
 }
 
+func sdtsFnMakeEntryRule(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+	r, err := sdtsFnMakeRule(info, args)
+	if err != nil {
+		return nil, err
+	}
+
+	entryRule := r.(GrammarRule)
+	entryRule.Entry = true
+	return entryRule, nil
+}
+
 func sdtsFnMakeTokenEntry(info trans.SetterInfo, args []interface{}) (interface{}, error) {
 	pattern, ok := args[0].(string)
 	if !ok {
@@ -86,3 +86,82 @@ func TestMakeFishispec(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeGrammarSymbol(t *testing.T) {
+	testCases := []struct {
+		name   string
+		text   string
+		expect GrammarSymbol
+	}{
+		{
+			name:   "non-terminal",
+			text:   "{ITEM}",
+			expect: GrammarSymbol{Symbol: "{ITEM}"},
+		},
+		{
+			name:   "terminal is lower-cased",
+			text:   "INT",
+			expect: GrammarSymbol{Symbol: "int"},
+		},
+		{
+			name:   "non-terminal with operator",
+			text:   "{ITEM}*",
+			expect: GrammarSymbol{Symbol: "{ITEM}", Op: "*"},
+		},
+		{
+			name:   "terminal ending in operator char is not split",
+			text:   "minus?",
+			expect: GrammarSymbol{Symbol: "minus?"},
+		},
+		{
+			name:   "terminal ending in several operator chars",
+			text:   "c++",
+			expect: GrammarSymbol{Symbol: "c++"},
+		},
+		{
+			name:   "terminal ending in star",
+			text:   "PTR*",
+			expect: GrammarSymbol{Symbol: "ptr*"},
+		},
+		{
+			name:   "operator-only terminal",
+			text:   "+",
+			expect: GrammarSymbol{Symbol: "+"},
+		},
+		{
+			name:   "operator-only terminal with several chars",
+			text:   "++",
+			expect: GrammarSymbol{Symbol: "++"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := sdtsFnMakeGrammarSymbol(trans.SetterInfo{}, []interface{}{tc.text, nil})
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func TestMakeGrammarGroup(t *testing.T) {
+	assert := assert.New(t)
+
+	alts := [][]GrammarSymbol{
+		{{Symbol: "comma"}, {Symbol: "{ITEM}"}},
+		{{Symbol: ""}},
+	}
+
+	actual, err := sdtsFnMakeGrammarGroup(trans.SetterInfo{}, []interface{}{alts, ")}+", nil})
+
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(GrammarSymbol{Group: alts, Op: "+"}, actual)
+	assert.Equal("{( comma {ITEM} | {} )}+", actual.(GrammarSymbol).String())
+}
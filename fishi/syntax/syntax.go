@@ -419,9 +419,20 @@ func (entry TokenEntry) String() string {
 type GrammarRule struct {
 
 	// Rule holds the non-terminal and all productions parsed for this
-	// GrammarRule.
+	// GrammarRule. Each symbol is given as it was written, so a symbol with an
+	// EBNF operator or a group is given in a form such as "{ITEM}*" or
+	// "(comma {ITEM})+"; Productions has them in parsed form.
 	Rule grammar.Rule
 
+	// Productions holds the same productions as Rule with each of their
+	// symbols parsed, including any EBNF operators and groups. It may be nil,
+	// in which case every symbol in Rule is a plain terminal or non-terminal.
+	Productions [][]GrammarSymbol
+
+	// Entry is whether the rule was declared with the %entry directive, which
+	// makes its non-terminal an entry point that input may be parsed as.
+	Entry bool
+
 	// Src is the first token that represents a part of this GrammarRule as
 	// lexed from a FISHI spec.
 	Src lex.Token
@@ -432,6 +443,87 @@ func (agr GrammarRule) String() string {
 	return agr.Rule.String()
 }
 
+// GrammarSymbol is a single symbol in a production of a GrammarRule. It is
+// either a terminal or non-terminal, or a group of alternative productions
+// delimited by "{(" and ")}". A non-terminal or a group may be followed by an
+// EBNF operator.
+type GrammarSymbol struct {
+	// Symbol is the terminal or non-terminal as it was written, with braces
+	// around a non-terminal and without any EBNF operator. It is the empty
+	// string for the epsilon production and for a group.
+	Symbol string
+
+	// Group is the alternative productions of a group. It is nil if the
+	// GrammarSymbol is not a group.
+	Group [][]GrammarSymbol
+
+	// Op is the EBNF operator after the symbol or group. It is one of "?" for
+	// an optional one, "*" for zero or more, "+" for one or more, or the empty
+	// string if there is none.
+	Op string
+
+	// Src is the first token that represents a part of this GrammarSymbol as
+	// lexed from a FISHI spec.
+	Src lex.Token
+}
+
+// String returns the GrammarSymbol as it would be written in FISHI.
+func (gs GrammarSymbol) String() string {
+	if gs.Group == nil {
+		if gs.Symbol == "" {
+			return "{}"
+		}
+		return gs.Symbol + gs.Op
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{( ")
+	for i, alt := range gs.Group {
+		for j := range alt {
+			sb.WriteString(alt[j].String())
+			if j+1 < len(alt) {
+				sb.WriteRune(' ')
+			}
+		}
+		if i+1 < len(gs.Group) {
+			sb.WriteString(" | ")
+		}
+	}
+	sb.WriteString(" )}")
+	sb.WriteString(gs.Op)
+	return sb.String()
+}
+
+// ParseGrammarSymbol parses a GrammarSymbol for a terminal or non-terminal
+// from its text as written in a FISHI grammar, including any EBNF operator
+// after it. An operator is only recognized directly after the closing brace of
+// a non-terminal; a terminal that ends in an operator character, such as "c++",
+// is taken as-is. Terminals are made lower-case. Does not set Src; caller must
+// do so if needed.
+func ParseGrammarSymbol(s string) GrammarSymbol {
+	s = strings.TrimSpace(s)
+
+	var gs GrammarSymbol
+	if len(s) > 3 && strings.HasPrefix(s, "{") && s[len(s)-2] == '}' && strings.ContainsAny(s[len(s)-1:], "?*+") {
+		gs.Op = s[len(s)-1:]
+		s = s[:len(s)-1]
+	}
+
+	if strings.HasPrefix(s, "{") {
+		gs.Symbol = s
+	} else {
+		gs.Symbol = strings.ToLower(s)
+	}
+
+	return gs
+}
+
+// Plain returns whether the GrammarSymbol is a terminal, a non-terminal, or
+// epsilon that has no EBNF operator.
+func (gs GrammarSymbol) Plain() bool {
+	return gs.Group == nil && gs.Op == ""
+}
+
 // TokensContent is a series of token entries grouped with the lexer state they
 // are used in from a %%tokens section of a FISHI spec.
 type TokensContent struct {
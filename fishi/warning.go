@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
 )
 
 // WarnType is a type of warning that can be generated by FISHI functions.
@@ -20,6 +22,7 @@ const (
 	WarnValidationArgs
 	WarnImportInference
 	WarnEFInheritedAttributes
+	WarnAmbiguousSentence
 )
 
 // WarnTypeAll() returns a slice of all the WarnType constants.
@@ -35,6 +38,7 @@ func WarnTypeAll() []WarnType {
 		WarnValidationArgs,
 		WarnImportInference,
 		WarnEFInheritedAttributes,
+		WarnAmbiguousSentence,
 	}
 
 	return wts
@@ -63,6 +67,8 @@ func (wt WarnType) Short() string {
 		return "val-args"
 	case WarnEFInheritedAttributes:
 		return "exp-inherited-attributes"
+	case WarnAmbiguousSentence:
+		return "ambig-sentence"
 	default:
 		return fmt.Sprintf("%d", int(wt))
 	}
@@ -91,6 +97,8 @@ func (wt WarnType) String() string {
 		return "WarnValidationArgs"
 	case WarnEFInheritedAttributes:
 		return "WarnEFInheritedAttributes"
+	case WarnAmbiguousSentence:
+		return "WarnAmbiguousSentence"
 	default:
 		return fmt.Sprintf("WarnType(%d)", int(wt))
 	}
@@ -131,6 +139,11 @@ func ParseShortWarnType(s string) (WarnType, error) {
 type Warning struct {
 	Type    WarnType
 	Message string
+
+	// Diagnostic is the warning with the locations in source text that it
+	// refers to, if it has any. If it is set, WarnHandler shows it instead of
+	// Message.
+	Diagnostic *syntaxerr.Diagnostic
 }
 
 // Error returns the string representation of the warning.
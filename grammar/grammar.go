@@ -8,6 +8,11 @@
 // This package provides [CFG] for representing and manipulating such a grammar.
 // In ictiobus they are used to automatically generate parsers by querying a CFG
 // for information on its rules.
+//
+// A CFG can also be converted to Chomsky or Greibach normal form with
+// [CFG.ChomskyNormalForm] and [CFG.GreibachNormalForm]. The [NormalForm] they
+// return records each step of the conversion so that a parse tree of the new
+// grammar can be turned back into one of the original.
 package grammar
 
 import (
@@ -39,6 +44,12 @@ type CFG struct {
 
 	// Start is the name of the start symbol. If not set, It is assumed to be S.
 	Start string
+
+	// EntryPoints is the non-terminals other than the start symbol that input
+	// may be parsed as. Parsers generated from the grammar will be able to
+	// parse input as any of them in addition to the start symbol. If the start
+	// symbol is included, it is ignored.
+	EntryPoints []string
 }
 
 type marshaledTokenClass struct {
@@ -91,6 +102,7 @@ func (g CFG) MarshalBinary() ([]byte, error) {
 
 	data = append(data, rezi.EncMapStringToBinary(serializedTerminals)...)
 	data = append(data, rezi.EncString(g.Start)...)
+	data = append(data, rezi.EncSliceString(g.EntryPoints)...)
 	return data, nil
 }
 
@@ -136,10 +148,20 @@ func (g *CFG) UnmarshalBinary(data []byte) error {
 		}
 	}
 
-	g.Start, _, err = rezi.DecString(data)
+	g.Start, n, err = rezi.DecString(data)
 	if err != nil {
 		return fmt.Errorf("start: %w", err)
 	}
+	data = data[n:]
+
+	// data from before entry points were added ends after the start symbol.
+	g.EntryPoints = nil
+	if len(data) > 0 {
+		g.EntryPoints, _, err = rezi.DecSliceString(data)
+		if err != nil {
+			return fmt.Errorf("entryPoints: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -202,6 +224,11 @@ func (g CFG) Copy() CFG {
 		Start:       g.Start,
 	}
 
+	if g.EntryPoints != nil {
+		g2.EntryPoints = make([]string, len(g.EntryPoints))
+		copy(g2.EntryPoints, g.EntryPoints)
+	}
+
 	for k := range g.rulesByName {
 		g2.rulesByName[k] = g.rulesByName[k]
 	}
@@ -227,6 +254,28 @@ func (g CFG) StartSymbol() string {
 	}
 }
 
+// AllEntryPoints returns every non-terminal that input may be parsed as. This
+// is the start symbol followed by each of g.EntryPoints that is not the start
+// symbol, without duplicates.
+func (g CFG) AllEntryPoints() []string {
+	entries := []string{g.StartSymbol()}
+	seen := map[string]bool{g.StartSymbol(): true}
+	for _, nt := range g.EntryPoints {
+		if seen[nt] {
+			continue
+		}
+		seen[nt] = true
+		entries = append(entries, nt)
+	}
+	return entries
+}
+
+// IsEntryPoint returns whether nt is the start symbol or one of the entry
+// points of the grammar.
+func (g CFG) IsEntryPoint(nt string) bool {
+	return slices.In(nt, g.AllEntryPoints())
+}
+
 // String returns a string representation of the grammar.
 func (g CFG) String() string {
 	return fmt.Sprintf("(%q, R=%q)", textfmt.OrderedKeys(g.terminals), g.rules)
@@ -481,7 +530,7 @@ func (g CFG) UnitProductions() []Rule {
 // non-terminals.
 func (g CFG) HasUnreachableNonTerminals() bool {
 	for _, nonTerm := range g.NonTerminals() {
-		if nonTerm == g.StartSymbol() {
+		if g.IsEntryPoint(nonTerm) {
 			continue
 		}
 
@@ -508,13 +557,13 @@ func (g CFG) HasUnreachableNonTerminals() bool {
 }
 
 // UnreachableNonTerminals returns all non-terminals (excluding the start
-// symbol) that are currently unreachable due to not being produced by any other
+// symbol and other entry points) that are currently unreachable due to not being produced by any other
 // grammar rule.
 func (g CFG) UnreachableNonTerminals() []string {
 	unreachables := []string{}
 
 	for _, nonTerm := range g.NonTerminals() {
-		if nonTerm == g.StartSymbol() {
+		if g.IsEntryPoint(nonTerm) {
 			continue
 		}
 
@@ -1197,8 +1246,8 @@ func (g CFG) Validate() error {
 
 	// make sure every non-term is used
 	for _, r := range g.rules {
-		// S is used by default, don't check that one
-		if r.NonTerminal == g.StartSymbol() {
+		// S and other entry points are used by default, don't check those
+		if g.IsEntryPoint(r.NonTerminal) {
 			continue
 		}
 
@@ -1207,6 +1256,13 @@ func (g CFG) Validate() error {
 		}
 	}
 
+	// make sure every entry point has rules
+	for _, nt := range g.EntryPoints {
+		if _, ok := g.rulesByName[nt]; !ok {
+			errStr += fmt.Sprintf("ERR: no rules defined for productions of entry point %q\n", nt)
+		}
+	}
+
 	// make sure we HAVE an S
 	if _, ok := g.rulesByName[g.StartSymbol()]; !ok {
 		errStr += fmt.Sprintf("ERR: no rules defined for productions of start symbol '%s'", g.StartSymbol())
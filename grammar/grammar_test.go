@@ -45,6 +45,18 @@ func Test_Grammar_MarshalUnmarshalBinary(t *testing.T) {
 				F -> id | num ;
 			`),
 		},
+		{
+			name: "entry points",
+			input: func() CFG {
+				g := MustParse(`
+					S -> T ;
+					T -> E | T + E ;
+					E -> id | num ;
+				`)
+				g.EntryPoints = []string{"T", "E"}
+				return g
+			}(),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -69,6 +81,7 @@ func Test_Grammar_MarshalUnmarshalBinary(t *testing.T) {
 			assert.Equal(tc.input.rules, actual.rules, "rules mismatch")
 			assert.Equal(tc.input.rulesByName, actual.rulesByName, "rulesByName mismatch")
 			assert.Equal(tc.input.Start, actual.Start, "Start symbol mismatch")
+			assert.Equal(tc.input.EntryPoints, actual.EntryPoints, "EntryPoints mismatch")
 
 			// check terminals
 			assert.Equal(len(tc.input.terminals), len(actual.terminals), "terminal count mismatch")
@@ -82,10 +95,11 @@ func Test_Grammar_MarshalUnmarshalBinary(t *testing.T) {
 
 func Test_Grammar_Validate(t *testing.T) {
 	testCases := []struct {
-		name      string
-		rules     []Rule
-		terminals []lex.TokenClass
-		expectErr bool
+		name        string
+		rules       []Rule
+		terminals   []lex.TokenClass
+		entryPoints []string
+		expectErr   bool
 	}{
 		{
 			name:      "empty grammar",
@@ -122,6 +136,43 @@ func Test_Grammar_Validate(t *testing.T) {
 				testTCNumber,
 			},
 		},
+		{
+			name: "entry point not produced by any rule",
+			rules: []Rule{
+				{
+					NonTerminal: "S",
+					Productions: []Production{
+						{strings.ToLower(testTCNumber.ID())},
+					},
+				},
+				{
+					NonTerminal: "A",
+					Productions: []Production{
+						{strings.ToLower(testTCNumber.ID())},
+					},
+				},
+			},
+			terminals: []lex.TokenClass{
+				testTCNumber,
+			},
+			entryPoints: []string{"A"},
+		},
+		{
+			name: "entry point with no rules",
+			rules: []Rule{
+				{
+					NonTerminal: "S",
+					Productions: []Production{
+						{strings.ToLower(testTCNumber.ID())},
+					},
+				},
+			},
+			terminals: []lex.TokenClass{
+				testTCNumber,
+			},
+			entryPoints: []string{"A"},
+			expectErr:   true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -129,7 +180,7 @@ func Test_Grammar_Validate(t *testing.T) {
 			assert := assert.New(t)
 
 			// set up the grammar
-			g := CFG{}
+			g := CFG{EntryPoints: tc.entryPoints}
 			for _, term := range tc.terminals {
 				g.AddTerm(term.ID(), term)
 			}
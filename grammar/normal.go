@@ -0,0 +1,890 @@
+package grammar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/lex"
+)
+
+// TemplateKind is the kind of a TreeTemplate.
+type TemplateKind int
+
+const (
+	// TemplateNode is a new node for Symbol whose children are given by
+	// Children. If Symbol is Epsilon[0], it is a terminal node for epsilon.
+	TemplateNode TemplateKind = iota
+
+	// TemplateChild is whatever the child at index Child of the node being
+	// replaced is itself replaced with.
+	TemplateChild
+
+	// TemplateInherited is the node that the parent of the node being replaced
+	// gave to it with ProductionMapping.Pass.
+	TemplateInherited
+)
+
+// TreeTemplate is part of a parse tree of one grammar, given in terms of a node
+// of a parse tree of a grammar that was made from it.
+type TreeTemplate struct {
+	Kind TemplateKind
+
+	// Symbol is the symbol of the node. Only used when Kind is TemplateNode.
+	Symbol string
+
+	// Children is the children of the node. Only used when Kind is
+	// TemplateNode.
+	Children []TreeTemplate
+
+	// Child is the index of the child. Only used when Kind is TemplateChild.
+	Child int
+}
+
+// String returns a string representation of the template. Children are given
+// as $0, $1, and so on, and the inherited node is given as $in.
+func (tt TreeTemplate) String() string {
+	switch tt.Kind {
+	case TemplateChild:
+		return fmt.Sprintf("$%d", tt.Child)
+	case TemplateInherited:
+		return "$in"
+	}
+
+	if tt.Symbol == Epsilon[0] {
+		return "ε"
+	}
+	if len(tt.Children) == 0 {
+		return tt.Symbol
+	}
+
+	children := make([]string, len(tt.Children))
+	for i := range tt.Children {
+		children[i] = tt.Children[i].String()
+	}
+	return fmt.Sprintf("%s(%s)", tt.Symbol, strings.Join(children, ", "))
+}
+
+// ProductionMapping gives what a node of a parse tree for a production of a
+// grammar is replaced with to get a parse tree of the grammar it was made from.
+type ProductionMapping struct {
+	// Result is the nodes that the node is replaced with. It is usually a
+	// single node, but the node for a non-terminal that was added to the
+	// grammar is often replaced with the nodes that make it up so that they
+	// become children of its parent instead.
+	Result []TreeTemplate
+
+	// Pass gives the node to give to some of the children of the node, by the
+	// index of the child. What the child is replaced with can then include it
+	// with TemplateInherited. This lets a child wrap nodes that are to its left
+	// in the tree, such as when left recursion was removed from the grammar.
+	Pass map[int]TreeTemplate
+}
+
+// String returns a string representation of the mapping.
+func (pm ProductionMapping) String() string {
+	results := make([]string, len(pm.Result))
+	for i := range pm.Result {
+		results[i] = pm.Result[i].String()
+	}
+	s := strings.Join(results, " ")
+
+	children := make([]int, 0, len(pm.Pass))
+	for idx := range pm.Pass {
+		children = append(children, idx)
+	}
+	sort.Ints(children)
+	for _, idx := range children {
+		s += fmt.Sprintf("; $%d <- %s", idx, pm.Pass[idx])
+	}
+
+	return s
+}
+
+// Transformation is a record of one step of rewriting a grammar. It gives what
+// the node for each production of the rewritten grammar is replaced with in a
+// parse tree of the grammar it was rewritten from.
+type Transformation struct {
+	// Name is a short description of what the step did.
+	Name string
+
+	mappings map[string]ProductionMapping
+	changed  []string
+}
+
+// Mapping returns the ProductionMapping for the given production of head in
+// the rewritten grammar. Productions that the step did not change are mapped
+// to a node for head with the same children.
+func (t Transformation) Mapping(head string, prod Production) ProductionMapping {
+	if m, ok := t.mappings[transformKey(head, prod)]; ok {
+		return m
+	}
+	return ProductionMapping{Result: []TreeTemplate{tmplNode(head, tmplChildren(0, len(prod))...)}}
+}
+
+// String returns the name of the step followed by the mapping of each
+// production that it changed, one per line.
+func (t Transformation) String() string {
+	var sb strings.Builder
+	sb.WriteString(t.Name)
+	for _, key := range t.changed {
+		sb.WriteString(fmt.Sprintf("\n\t%s: %s", key, t.mappings[key]))
+	}
+	return sb.String()
+}
+
+// NormalForm is a grammar that was converted to a normal form, along with the
+// record of each step of the conversion.
+type NormalForm struct {
+	// Original is the grammar that was converted.
+	Original CFG
+
+	// Grammar is the grammar in the normal form.
+	Grammar CFG
+
+	// Steps is each step taken to convert Original into Grammar, in the order
+	// they were taken. Replacing the nodes of a parse tree of Grammar using
+	// each one from last to first gives a parse tree of Original.
+	Steps []Transformation
+}
+
+// ChomskyNormalForm converts the grammar to Chomsky normal form, where every
+// production is either A -> B C for non-terminals B and C or A -> a for a
+// terminal a. An entry point that derives the empty string also has a
+// production A -> ε, and no entry point is produced by any rule.
+//
+// Symbols that cannot be part of any derivation of a string of terminals from
+// an entry point are removed. It is an error if an entry point cannot derive
+// any string of terminals.
+func (g CFG) ChomskyNormalForm() (NormalForm, error) {
+	nf := NormalForm{Original: g.Copy(), Grammar: g.Copy()}
+
+	steps := []func(CFG) (CFG, Transformation, error){
+		normRemoveUseless,
+		normAddStarts,
+		normReplaceTerminals,
+		normSplitProductions,
+		normRemoveEpsilons,
+		normRemoveUnits,
+		normRemoveUseless,
+	}
+	for _, step := range steps {
+		newG, t, err := step(nf.Grammar)
+		if err != nil {
+			return NormalForm{}, err
+		}
+		nf.Grammar = newG
+		nf.Steps = append(nf.Steps, t)
+	}
+
+	return nf, nil
+}
+
+// GreibachNormalForm converts the grammar to Greibach normal form, where every
+// production is A -> a B₁ B₂ ... Bₙ for a terminal a and zero or more
+// non-terminals B. An entry point that derives the empty string also has a
+// production A -> ε, and no entry point is produced by any rule.
+//
+// The grammar is first converted to Chomsky normal form with
+// ChomskyNormalForm, and the steps of that conversion are included in the
+// returned NormalForm.
+func (g CFG) GreibachNormalForm() (NormalForm, error) {
+	nf, err := g.ChomskyNormalForm()
+	if err != nil {
+		return NormalForm{}, err
+	}
+
+	apply := func(newG CFG, t Transformation) {
+		nf.Grammar = newG
+		nf.Steps = append(nf.Steps, t)
+	}
+	isNonTerminal := func(sym string) bool {
+		return nf.Grammar.IsNonTerminal(sym)
+	}
+
+	// order the non-terminals A₁, A₂, ..., Aₙ and make every Aᵢ -> Aⱼγ have
+	// j > i, removing left recursion with a new non-terminal Zᵢ when j = i.
+	var order []string
+	pos := map[string]int{}
+	for _, r := range nf.Grammar.rules {
+		pos[r.NonTerminal] = len(order)
+		order = append(order, r.NonTerminal)
+	}
+
+	var zs []string
+	for i, A := range order {
+		earlier := func(sym string) bool {
+			j, ok := pos[sym]
+			return ok && j < i
+		}
+		for normStartsWith(nf.Grammar, A, earlier) {
+			apply(normSubstitute(nf.Grammar, A, earlier))
+		}
+
+		self := func(sym string) bool {
+			return sym == A
+		}
+		if normStartsWith(nf.Grammar, A, self) {
+			newG, z, t := normRemoveLeftRecursion(nf.Grammar, A)
+			apply(newG, t)
+			zs = append(zs, z)
+		}
+	}
+
+	// Aₙ now only starts with terminals, so going backwards, each Aᵢ only
+	// starts with terminals once the Aⱼ it starts with are replaced.
+	for i := len(order) - 1; i >= 0; i-- {
+		for normStartsWith(nf.Grammar, order[i], isNonTerminal) {
+			apply(normSubstitute(nf.Grammar, order[i], isNonTerminal))
+		}
+	}
+
+	// each Zᵢ starts with an A or a Z made before it, so doing them in the
+	// order they were made gives the same result.
+	for _, z := range zs {
+		for normStartsWith(nf.Grammar, z, isNonTerminal) {
+			apply(normSubstitute(nf.Grammar, z, isNonTerminal))
+		}
+	}
+
+	newG, t, err := normRemoveUseless(nf.Grammar)
+	if err != nil {
+		return NormalForm{}, err
+	}
+	apply(newG, t)
+
+	return nf, nil
+}
+
+// IsChomskyNormalForm returns whether the grammar is in Chomsky normal form.
+// See ChomskyNormalForm.
+func (g CFG) IsChomskyNormalForm() bool {
+	if len(g.rules) < 1 {
+		return false
+	}
+
+	produced := g.producedSymbols()
+	for _, r := range g.rules {
+		for _, p := range r.Productions {
+			switch {
+			case p.Equal(Epsilon):
+				if !g.IsEntryPoint(r.NonTerminal) || produced[r.NonTerminal] {
+					return false
+				}
+			case len(p) == 1:
+				if g.IsNonTerminal(p[0]) {
+					return false
+				}
+			case len(p) == 2:
+				if !g.IsNonTerminal(p[0]) || !g.IsNonTerminal(p[1]) {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsGreibachNormalForm returns whether the grammar is in Greibach normal form.
+// See GreibachNormalForm.
+func (g CFG) IsGreibachNormalForm() bool {
+	if len(g.rules) < 1 {
+		return false
+	}
+
+	produced := g.producedSymbols()
+	for _, r := range g.rules {
+		for _, p := range r.Productions {
+			if p.Equal(Epsilon) {
+				if !g.IsEntryPoint(r.NonTerminal) || produced[r.NonTerminal] {
+					return false
+				}
+				continue
+			}
+			if g.IsNonTerminal(p[0]) {
+				return false
+			}
+			for _, sym := range p[1:] {
+				if !g.IsNonTerminal(sym) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// producedSymbols returns every symbol that is in a production of the grammar.
+func (g CFG) producedSymbols() map[string]bool {
+	produced := map[string]bool{}
+	for _, r := range g.rules {
+		for _, p := range r.Productions {
+			for _, sym := range p {
+				produced[sym] = true
+			}
+		}
+	}
+	return produced
+}
+
+// normBuilder builds the grammar for one step of converting a grammar to a
+// normal form, recording how each production it is given maps to the grammar
+// it is made from.
+type normBuilder struct {
+	from CFG
+	g    CFG
+	t    Transformation
+
+	// names has a rule for every non-terminal in from and every one generated
+	// so far, so that new names can be generated that are not in use.
+	names CFG
+}
+
+func newNormBuilder(name string, from CFG) *normBuilder {
+	b := &normBuilder{
+		from:  from,
+		g:     CFG{Start: from.StartSymbol(), terminals: map[string]lex.TokenClass{}},
+		t:     Transformation{Name: name, mappings: map[string]ProductionMapping{}},
+		names: from.Copy(),
+	}
+	if from.EntryPoints != nil {
+		b.g.EntryPoints = make([]string, len(from.EntryPoints))
+		copy(b.g.EntryPoints, from.EntryPoints)
+	}
+	for k := range from.terminals {
+		b.g.terminals[k] = from.terminals[k]
+	}
+	return b
+}
+
+// reserve returns a new non-terminal name based on base that is not used by
+// any other non-terminal.
+func (b *normBuilder) reserve(base string) string {
+	name := b.names.GenerateUniqueName(base)
+	b.names.AddRule(name, Epsilon)
+	return name
+}
+
+// add adds prod as a production of head if head does not already have it. If
+// m is nil, it is mapped to a node for head with the same children.
+func (b *normBuilder) add(head string, prod Production, m *ProductionMapping) {
+	if b.g.Rule(head).HasProduction(prod) {
+		return
+	}
+	b.g.AddRule(head, prod.Copy())
+
+	if m != nil {
+		key := transformKey(head, prod)
+		b.t.mappings[key] = *m
+		b.t.changed = append(b.t.changed, key)
+	}
+}
+
+// addAll adds every production of the rule for nt in the grammar being built
+// from, mapping each to itself.
+func (b *normBuilder) addAll(nt string) {
+	for _, p := range b.from.Rule(nt).Productions {
+		b.add(nt, p, nil)
+	}
+}
+
+// normRemoveUseless removes every non-terminal that cannot derive a string of
+// terminals as well as every one that cannot be reached from an entry point,
+// along with any terminals that are then no longer used.
+func normRemoveUseless(g CFG) (CFG, Transformation, error) {
+	generating := map[string]bool{}
+	allGenerating := func(p Production) bool {
+		for _, sym := range p {
+			if g.IsNonTerminal(sym) && !generating[sym] {
+				return false
+			}
+		}
+		return true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, r := range g.rules {
+			if generating[r.NonTerminal] {
+				continue
+			}
+			for _, p := range r.Productions {
+				if allGenerating(p) {
+					generating[r.NonTerminal] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	entries := g.AllEntryPoints()
+	for _, nt := range entries {
+		if !generating[nt] {
+			return CFG{}, Transformation{}, fmt.Errorf("%s does not derive any string of terminals", nt)
+		}
+	}
+
+	reachable := map[string]bool{}
+	queue := append([]string{}, entries...)
+	for len(queue) > 0 {
+		nt := queue[0]
+		queue = queue[1:]
+		if reachable[nt] {
+			continue
+		}
+		reachable[nt] = true
+		for _, p := range g.Rule(nt).Productions {
+			if !allGenerating(p) {
+				continue
+			}
+			for _, sym := range p {
+				if g.IsNonTerminal(sym) && !reachable[sym] {
+					queue = append(queue, sym)
+				}
+			}
+		}
+	}
+
+	b := newNormBuilder("remove useless symbols", g)
+	for _, r := range g.rules {
+		if !reachable[r.NonTerminal] {
+			continue
+		}
+		for _, p := range r.Productions {
+			if allGenerating(p) {
+				b.add(r.NonTerminal, p, nil)
+			}
+		}
+	}
+
+	produced := b.g.producedSymbols()
+	for term := range b.g.terminals {
+		if !produced[term] {
+			delete(b.g.terminals, term)
+		}
+	}
+
+	return b.g, b.t, nil
+}
+
+// normAddStarts gives each entry point that is produced by a rule a new
+// non-terminal that produces only it, and makes that the entry point instead.
+func normAddStarts(g CFG) (CFG, Transformation, error) {
+	b := newNormBuilder("add start symbols", g)
+
+	produced := g.producedSymbols()
+	newStarts := map[string]string{}
+	for _, nt := range g.AllEntryPoints() {
+		if produced[nt] {
+			newStarts[nt] = b.reserve(nt)
+			b.add(newStarts[nt], Production{nt}, &ProductionMapping{Result: tmplChildren(0, 1)})
+		}
+	}
+	for _, r := range g.rules {
+		b.addAll(r.NonTerminal)
+	}
+
+	rename := func(nt string) string {
+		if newNT, ok := newStarts[nt]; ok {
+			return newNT
+		}
+		return nt
+	}
+	b.g.Start = rename(g.StartSymbol())
+	for i := range b.g.EntryPoints {
+		b.g.EntryPoints[i] = rename(b.g.EntryPoints[i])
+	}
+
+	return b.g, b.t, nil
+}
+
+// normReplaceTerminals replaces each terminal in a production of more than one
+// symbol with a new non-terminal that produces only that terminal.
+func normReplaceTerminals(g CFG) (CFG, Transformation, error) {
+	b := newNormBuilder("replace terminals", g)
+
+	termRules := map[string]string{}
+	var terms []string
+	for _, r := range g.rules {
+		for _, p := range r.Productions {
+			if len(p) < 2 {
+				b.add(r.NonTerminal, p, nil)
+				continue
+			}
+
+			newProd := make(Production, len(p))
+			for i, sym := range p {
+				if g.IsNonTerminal(sym) {
+					newProd[i] = sym
+					continue
+				}
+				if _, ok := termRules[sym]; !ok {
+					termRules[sym] = b.reserve(normTerminalName(sym))
+					terms = append(terms, sym)
+				}
+				newProd[i] = termRules[sym]
+			}
+			b.add(r.NonTerminal, newProd, nil)
+		}
+	}
+
+	for _, term := range terms {
+		b.add(termRules[term], Production{term}, &ProductionMapping{Result: tmplChildren(0, 1)})
+	}
+
+	return b.g, b.t, nil
+}
+
+// normSplitProductions splits each production of more than two symbols into a
+// chain of productions of two symbols each.
+func normSplitProductions(g CFG) (CFG, Transformation, error) {
+	b := newNormBuilder("split long productions", g)
+
+	for _, r := range g.rules {
+		for _, p := range r.Productions {
+			head := r.NonTerminal
+			var m *ProductionMapping
+
+			// A -> X₁ X₂ ... Xₙ becomes A -> X₁ A', A' -> X₂ A'', and so on.
+			// the new non-terminals are replaced with their children so that
+			// all of the symbols end up as children of A.
+			for len(p) > 2 {
+				next := b.reserve(r.NonTerminal)
+				b.add(head, Production{p[0], next}, m)
+				head, p = next, p[1:]
+				m = &ProductionMapping{Result: tmplChildren(0, 2)}
+			}
+			b.add(head, p, m)
+		}
+	}
+
+	return b.g, b.t, nil
+}
+
+// normRemoveEpsilons removes every epsilon production by adding a copy of each
+// production without each combination of non-terminals that can derive the
+// empty string. Entry points that can derive the empty string are given an
+// epsilon production.
+func normRemoveEpsilons(g CFG) (CFG, Transformation, error) {
+	b := newNormBuilder("remove epsilon productions", g)
+
+	nullable := normNullable(g)
+	for _, r := range g.rules {
+		A := r.NonTerminal
+		for _, p := range r.Productions {
+			if p.Equal(Epsilon) {
+				continue
+			}
+
+			var optional []int
+			for i, sym := range p {
+				if _, ok := nullable[sym]; ok {
+					optional = append(optional, i)
+				}
+			}
+
+			for mask := 0; mask < 1<<len(optional); mask++ {
+				omit := map[int]bool{}
+				for k, i := range optional {
+					if mask&(1<<k) != 0 {
+						omit[i] = true
+					}
+				}
+
+				var newProd Production
+				var children []TreeTemplate
+				for i, sym := range p {
+					if omit[i] {
+						children = append(children, nullable[sym])
+					} else {
+						children = append(children, tmplChild(len(newProd)))
+						newProd = append(newProd, sym)
+					}
+				}
+
+				if len(newProd) == 0 || (len(newProd) == 1 && newProd[0] == A) {
+					continue
+				}
+				if len(omit) == 0 {
+					b.add(A, newProd, nil)
+				} else {
+					b.add(A, newProd, &ProductionMapping{Result: []TreeTemplate{tmplNode(A, children...)}})
+				}
+			}
+		}
+
+		if tmpl, ok := nullable[A]; ok && g.IsEntryPoint(A) {
+			b.add(A, Epsilon, &ProductionMapping{Result: []TreeTemplate{tmpl}})
+		}
+	}
+
+	// a non-terminal that only derives the empty string has no productions
+	// left, so every production that still has it must go.
+	for changed := true; changed; {
+		changed = false
+		for _, r := range b.g.rules {
+			var keep []Production
+			for _, p := range r.Productions {
+				defined := true
+				for _, sym := range p {
+					if g.IsNonTerminal(sym) && !b.g.IsNonTerminal(sym) {
+						defined = false
+						break
+					}
+				}
+				if defined {
+					keep = append(keep, p)
+				}
+			}
+
+			if len(keep) == len(r.Productions) {
+				continue
+			}
+			changed = true
+			if len(keep) == 0 {
+				b.g.RemoveRule(r.NonTerminal)
+			} else {
+				r.Productions = keep
+				b.g.rules[b.g.rulesByName[r.NonTerminal]] = r
+			}
+			break
+		}
+	}
+
+	return b.g, b.t, nil
+}
+
+// normNullable returns every non-terminal of g that can derive the empty
+// string, mapped to a template of a parse tree of it deriving it.
+func normNullable(g CFG) map[string]TreeTemplate {
+	nullable := map[string]TreeTemplate{}
+	for changed := true; changed; {
+		changed = false
+		for _, r := range g.rules {
+			if _, ok := nullable[r.NonTerminal]; ok {
+				continue
+			}
+			for _, p := range r.Productions {
+				if p.Equal(Epsilon) {
+					nullable[r.NonTerminal] = tmplNode(r.NonTerminal, tmplNode(Epsilon[0]))
+					changed = true
+					break
+				}
+
+				// every symbol must already have a tree for this to have one,
+				// so a tree never contains itself.
+				children := make([]TreeTemplate, 0, len(p))
+				for _, sym := range p {
+					tmpl, ok := nullable[sym]
+					if !ok {
+						break
+					}
+					children = append(children, tmpl)
+				}
+				if len(children) == len(p) {
+					nullable[r.NonTerminal] = tmplNode(r.NonTerminal, children...)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return nullable
+}
+
+// normRemoveUnits replaces every production A -> B for a non-terminal B with
+// all of the productions of B that are not themselves of that form, following
+// chains of them.
+func normRemoveUnits(g CFG) (CFG, Transformation, error) {
+	b := newNormBuilder("remove unit productions", g)
+
+	type unitPath struct {
+		nt string
+
+		// chain is every non-terminal that is derived in turn to get to nt.
+		chain []string
+	}
+
+	for _, r := range g.rules {
+		A := r.NonTerminal
+		queue := []unitPath{{nt: A}}
+		seen := map[string]bool{A: true}
+		var hasEpsilon bool
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+
+			for _, p := range g.Rule(cur.nt).Productions {
+				if len(p) == 1 && g.IsNonTerminal(p[0]) {
+					if !seen[p[0]] {
+						seen[p[0]] = true
+						chain := append(append([]string{}, cur.chain...), p[0])
+						queue = append(queue, unitPath{nt: p[0], chain: chain})
+					}
+					continue
+				}
+
+				if len(cur.chain) == 0 {
+					// keep epsilon last so it stays after what was pulled up
+					if p.Equal(Epsilon) {
+						hasEpsilon = true
+					} else {
+						b.add(A, p, nil)
+					}
+					continue
+				}
+
+				tmpl := tmplNode(cur.nt, tmplChildren(0, len(p))...)
+				for i := len(cur.chain) - 2; i >= 0; i-- {
+					tmpl = tmplNode(cur.chain[i], tmpl)
+				}
+				b.add(A, p, &ProductionMapping{Result: []TreeTemplate{tmplNode(A, tmpl)}})
+			}
+		}
+		if hasEpsilon {
+			b.add(A, Epsilon, nil)
+		}
+	}
+
+	return b.g, b.t, nil
+}
+
+// normStartsWith returns whether any production of nt starts with a symbol
+// that matches.
+func normStartsWith(g CFG, nt string, matches func(sym string) bool) bool {
+	for _, p := range g.Rule(nt).Productions {
+		if !p.Equal(Epsilon) && matches(p[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// normSubstitute replaces every production A -> Bγ of head where B matches with
+// A -> δγ for each production B -> δ.
+func normSubstitute(g CFG, head string, matches func(sym string) bool) (CFG, Transformation) {
+	b := newNormBuilder(fmt.Sprintf("substitute into %s", head), g)
+
+	for _, r := range g.rules {
+		if r.NonTerminal != head {
+			b.addAll(r.NonTerminal)
+			continue
+		}
+
+		for _, p := range r.Productions {
+			if p.Equal(Epsilon) || !matches(p[0]) {
+				b.add(head, p, nil)
+				continue
+			}
+
+			B, gamma := p[0], p[1:]
+			for _, delta := range g.Rule(B).Productions {
+				if delta.Equal(Epsilon) {
+					continue
+				}
+				newProd := append(delta.Copy(), gamma...)
+
+				children := []TreeTemplate{tmplNode(B, tmplChildren(0, len(delta))...)}
+				children = append(children, tmplChildren(len(delta), len(gamma))...)
+				b.add(head, newProd, &ProductionMapping{Result: []TreeTemplate{tmplNode(head, children...)}})
+			}
+		}
+	}
+
+	return b.g, b.t
+}
+
+// normRemoveLeftRecursion replaces the productions of A -> Aα₁ | ... | Aαₘ |
+// β₁ | ... | βₙ with A -> β₁ | β₁Z | ... | βₙ | βₙZ and adds Z -> α₁ | α₁Z |
+// ... | αₘ | αₘZ for a new non-terminal Z, which is returned.
+func normRemoveLeftRecursion(g CFG, A string) (CFG, string, Transformation) {
+	b := newNormBuilder(fmt.Sprintf("remove left recursion of %s", A), g)
+	Z := b.reserve(A)
+
+	var alphas, betas []Production
+	for _, p := range g.Rule(A).Productions {
+		if !p.Equal(Epsilon) && p[0] == A {
+			if len(p) > 1 {
+				alphas = append(alphas, p[1:])
+			}
+		} else {
+			betas = append(betas, p)
+		}
+	}
+
+	for _, r := range g.rules {
+		if r.NonTerminal != A {
+			b.addAll(r.NonTerminal)
+			continue
+		}
+
+		// Z derives what followed A in a tree that went down the left side, so
+		// the tree is rebuilt by giving the A to the left of each Z to it,
+		// which wraps it in another A with the symbols of its production.
+		for _, beta := range betas {
+			b.add(A, beta, nil)
+			if beta.Equal(Epsilon) {
+				continue
+			}
+			n := len(beta)
+			b.add(A, append(beta.Copy(), Z), &ProductionMapping{
+				Result: tmplChildren(n, 1),
+				Pass:   map[int]TreeTemplate{n: tmplNode(A, tmplChildren(0, n)...)},
+			})
+		}
+
+		for _, alpha := range alphas {
+			n := len(alpha)
+			wrapped := tmplNode(A, append([]TreeTemplate{{Kind: TemplateInherited}}, tmplChildren(0, n)...)...)
+			b.add(Z, alpha, &ProductionMapping{Result: []TreeTemplate{wrapped}})
+			b.add(Z, append(alpha.Copy(), Z), &ProductionMapping{
+				Result: tmplChildren(n, 1),
+				Pass:   map[int]TreeTemplate{n: wrapped},
+			})
+		}
+	}
+
+	return b.g, Z, b.t
+}
+
+// normTerminalName returns the base of the name for a non-terminal that
+// produces only term.
+func normTerminalName(term string) string {
+	var sb strings.Builder
+	for _, ch := range strings.ToUpper(term) {
+		if ('A' <= ch && ch <= 'Z') || ch == '_' || ch == '-' {
+			sb.WriteRune(ch)
+		}
+	}
+	if sb.Len() == 0 {
+		return "T"
+	}
+	return sb.String()
+}
+
+// transformKey returns the key of the production of head in the mappings of a
+// Transformation.
+func transformKey(head string, prod Production) string {
+	return head + " -> " + prod.String()
+}
+
+// tmplNode returns a template for a new node.
+func tmplNode(sym string, children ...TreeTemplate) TreeTemplate {
+	return TreeTemplate{Kind: TemplateNode, Symbol: sym, Children: children}
+}
+
+// tmplChild returns a template for the child at idx.
+func tmplChild(idx int) TreeTemplate {
+	return TreeTemplate{Kind: TemplateChild, Child: idx}
+}
+
+// tmplChildren returns templates for n children starting at the one at start.
+func tmplChildren(start, n int) []TreeTemplate {
+	children := make([]TreeTemplate, n)
+	for i := range children {
+		children[i] = tmplChild(start + i)
+	}
+	return children
+}
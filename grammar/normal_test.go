@@ -0,0 +1,262 @@
+package grammar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Grammar_ChomskyNormalForm(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		entryPoints []string
+		expectStart string
+		expect      []string
+		expectErr   bool
+	}{
+		{
+			name:        "already in normal form",
+			input:       `S -> A B | a ; A -> a ; B -> b ;`,
+			expectStart: "S",
+			expect: []string{
+				"S -> A B | a",
+				"A -> a",
+				"B -> b",
+			},
+		},
+		{
+			name:        "long productions and terminals are split",
+			input:       `S -> a S b | c ;`,
+			expectStart: "S-P",
+			expect: []string{
+				"S-P -> A-P S-PP | c",
+				"S -> A-P S-PP | c",
+				"S-PP -> S B-P",
+				"A-P -> a",
+				"B-P -> b",
+			},
+		},
+		{
+			name:        "epsilon is kept only for the start symbol",
+			input:       `S -> a S b | ε ;`,
+			expectStart: "S-P",
+			expect: []string{
+				"S-P -> A-P S-PP | ε",
+				"S -> A-P S-PP",
+				"S-PP -> S B-P | b",
+				"A-P -> a",
+				"B-P -> b",
+			},
+		},
+		{
+			name:        "unit productions are removed",
+			input:       `S -> A ; A -> B | a ; B -> b ;`,
+			expectStart: "S",
+			expect: []string{
+				"S -> a | b",
+			},
+		},
+		{
+			name:        "useless symbols are removed",
+			input:       `S -> a | B ; B -> B b ; C -> c ;`,
+			expectStart: "S",
+			expect: []string{
+				"S -> a",
+			},
+		},
+		{
+			name:        "entry points are kept",
+			input:       `S -> T plus T | T ; T -> int ;`,
+			entryPoints: []string{"T"},
+			expectStart: "S",
+			expect: []string{
+				"T-P -> int",
+				"S -> T S-P | int",
+				"S-P -> PLUS-P T",
+				"T -> int",
+				"PLUS-P -> plus",
+			},
+		},
+		{
+			name:      "start symbol derives no string",
+			input:     `S -> S a ;`,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := MustParse(tc.input)
+			g.EntryPoints = tc.entryPoints
+
+			actual, err := g.ChomskyNormalForm()
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			var actualRules []string
+			for _, r := range actual.Grammar.rules {
+				actualRules = append(actualRules, r.String())
+			}
+			assert.Equal(tc.expect, actualRules)
+			assert.Equal(tc.expectStart, actual.Grammar.StartSymbol())
+			assert.True(actual.Grammar.IsChomskyNormalForm())
+			assert.Equal(g, actual.Original)
+		})
+	}
+}
+
+func Test_Grammar_GreibachNormalForm(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expect    []string
+		expectErr bool
+	}{
+		{
+			name:  "balanced strings",
+			input: `S -> a S b | ε ;`,
+			expect: []string{
+				"S-P -> a S-PP | ε",
+				"S-PP -> a S-PP B-P | b",
+				"B-P -> b",
+			},
+		},
+		{
+			name:  "left recursion",
+			input: `S -> S a | b ;`,
+			expect: []string{
+				"S-P -> b A-P | b S-PP A-P | b",
+				"S-PP -> a | a S-PP",
+				"A-P -> a",
+			},
+		},
+		{
+			name:  "expression grammar",
+			input: `E -> E plus T | T ; T -> T times F | F ; F -> lp E rp | int ;`,
+		},
+		{
+			name:      "start symbol derives no string",
+			input:     `S -> S a ;`,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := MustParse(tc.input)
+
+			actual, err := g.GreibachNormalForm()
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			if tc.expect != nil {
+				var actualRules []string
+				for _, r := range actual.Grammar.rules {
+					actualRules = append(actualRules, r.String())
+				}
+				assert.Equal(tc.expect, actualRules)
+			}
+			assert.True(actual.Grammar.IsGreibachNormalForm())
+		})
+	}
+}
+
+func Test_Grammar_IsChomskyNormalForm(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		expect bool
+	}{
+		{
+			name:   "normal form",
+			input:  `S -> A B | a ; A -> a ; B -> b ;`,
+			expect: true,
+		},
+		{
+			name:   "epsilon for start symbol",
+			input:  `S -> A B | ε ; A -> a ; B -> b ;`,
+			expect: true,
+		},
+		{
+			name:   "epsilon for start symbol that is produced",
+			input:  `S -> A S | ε ; A -> a ;`,
+			expect: false,
+		},
+		{
+			name:   "epsilon for other non-terminal",
+			input:  `S -> A B ; A -> a | ε ; B -> b ;`,
+			expect: false,
+		},
+		{
+			name:   "unit production",
+			input:  `S -> A ; A -> a ;`,
+			expect: false,
+		},
+		{
+			name:   "terminal in long production",
+			input:  `S -> a B ; B -> b ;`,
+			expect: false,
+		},
+		{
+			name:   "production too long",
+			input:  `S -> A A A ; A -> a ;`,
+			expect: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := MustParse(tc.input)
+			assert.Equal(t, tc.expect, g.IsChomskyNormalForm())
+		})
+	}
+}
+
+func Test_Grammar_IsGreibachNormalForm(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		expect bool
+	}{
+		{
+			name:   "normal form",
+			input:  `S -> a B | a ; B -> b B | b ;`,
+			expect: true,
+		},
+		{
+			name:   "epsilon for start symbol",
+			input:  `S -> a B | ε ; B -> b ;`,
+			expect: true,
+		},
+		{
+			name:   "starts with non-terminal",
+			input:  `S -> B a ; B -> b ;`,
+			expect: false,
+		},
+		{
+			name:   "terminal after first symbol",
+			input:  `S -> a b ;`,
+			expect: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := MustParse(tc.input)
+			assert.Equal(t, tc.expect, g.IsGreibachNormalForm())
+		})
+	}
+}
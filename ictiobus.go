@@ -57,13 +57,18 @@ package ictiobus
 // validating LALR(1) grammars quickly.
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/dekarrin/ictiobus/grammar"
 	"github.com/dekarrin/ictiobus/lex"
 	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/syntaxerr"
 	"github.com/dekarrin/ictiobus/trans"
 )
 
@@ -159,6 +164,15 @@ func NewCLRParser(g grammar.CFG, allowAmbiguous bool) (parser parse.Parser, ambi
 	return parse.GenerateCLR1Parser(g, allowAmbiguous)
 }
 
+// NewCYKParser returns a CYK parser for the given grammar. Any context-free
+// grammar can be used, including ambiguous ones, but the parser takes time
+// proportional to the cube of the length of its input, so it is never picked
+// by NewParser. Returns an error if the start symbol or an entry point of the
+// grammar cannot derive any string of terminals.
+func NewCYKParser(g grammar.CFG) (parser parse.Parser, err error) {
+	return parse.GenerateCYKParser(g)
+}
+
 // NewSDTS returns a new Syntax-Directed Translation Scheme. The SDTS will be
 // empty and ready to accept bindings, which must be manually added by callers.
 func NewSDTS() trans.SDTS {
@@ -177,6 +191,13 @@ func NewSDTS() trans.SDTS {
 // produce Go source code that provides a pre-configured Frontend for the
 // language. Information on doing this can be found in the README.md in the root
 // of the ictiobus repository.
+//
+// A Frontend whose components are the ones provided by ictiobus, including any
+// generated by ictcc, is safe for concurrent use by multiple goroutines. A
+// single Frontend can be created once and shared, such as by all the handlers
+// of a server, without needing to be created again for each input. Hooks and
+// trace listeners are called from whichever goroutine is analyzing input, so
+// they must be safe for concurrent use too.
 type Frontend[E any] struct {
 	Lexer       lex.Lexer
 	Parser      parse.Parser
@@ -203,12 +224,57 @@ func (fe Frontend[E]) AnalyzeString(s string) (ir E, pt *parse.Tree, err error)
 // fe.IRAttribute in the root node of the annotated tree.
 //
 // If there is a problem with the input, it will be returned in a
-// syntaxerr.Error containing information about the location where it occured in
-// the source text read from r. The returned parse tree may be valid even if
+// syntaxerr.Diagnostics containing information about the location where it
+// occured in the source text read from r; errors.As can be used to get a
+// *syntaxerr.Error for it instead. The returned parse tree may be valid even if
 // there is an error, in which case pt will be non-nil.
 func (fe Frontend[E]) Analyze(r io.Reader) (ir E, pt *parse.Tree, err error) {
+	return fe.AnalyzeContext(context.Background(), r, Limits{})
+}
+
+// Limits are limits on the resources used by AnalyzeContext and the other
+// Context methods of Frontend, which keep input from taking too much time or
+// memory to analyze. A limit of 0 means there is no limit.
+type Limits struct {
+	// MaxInputSize is the maximum number of bytes of input that will be read.
+	MaxInputSize int
+
+	// MaxTokens is the maximum number of tokens that will be lexed from the
+	// input, not counting the end of text token.
+	MaxTokens int
+
+	// MaxTreeDepth is the maximum depth of the parse tree, with the root of
+	// the tree being at depth 1.
+	MaxTreeDepth int
+
+	// MaxEvalSteps is the maximum number of hooks that will be called during
+	// semantic analysis, with each call of a hook for a binding on a node
+	// being one step.
+	MaxEvalSteps int
+
+	// Timeout is the maximum amount of time that analysis will take.
+	Timeout time.Duration
+}
+
+// AnalyzeContext is the same as Analyze but stops analysis when ctx is done or
+// when the input exceeds one of the given limits. This is useful for analyzing
+// input that cannot be trusted, such as in a server.
+//
+// If ctx is done before analysis completes, ctx.Err() is returned. If analysis
+// runs for longer than lim.Timeout, context.DeadlineExceeded is returned. If
+// one of the other limits is exceeded, a *syntaxerr.LimitError is returned;
+// errors.Is can be used with it and the Err variables of package syntaxerr,
+// such as syntaxerr.ErrTooManyTokens, to tell which limit it was.
+func (fe Frontend[E]) AnalyzeContext(ctx context.Context, r io.Reader, lim Limits) (ir E, pt *parse.Tree, err error) {
+	if lim.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lim.Timeout)
+		defer cancel()
+	}
+
 	// lexical analysis
-	tokStream, err := fe.Lexer.Lex(r)
+	lexLim := lex.Limits{MaxInputSize: lim.MaxInputSize, MaxTokens: lim.MaxTokens}
+	tokStream, err := lex.LexContext(ctx, fe.Lexer, r, lexLim)
 	if err != nil {
 		return ir, nil, err
 	}
@@ -219,28 +285,354 @@ func (fe Frontend[E]) Analyze(r io.Reader) (ir E, pt *parse.Tree, err error) {
 	}
 
 	// syntactic analysis
-	parseTree, err := fe.Parser.Parse(tokStream)
+	parseTree, err := parse.ParseContext(ctx, fe.Parser, tokStream, parse.Limits{MaxTreeDepth: lim.MaxTreeDepth})
 	if err != nil {
 		return ir, &parseTree, err
 	}
 
 	// semantic analysis (discard warns at this stage)
-	attrVals, _, err := fe.SDTS.Evaluate(parseTree, fe.IRAttribute)
+	attrVals, _, err := trans.EvaluateContext(ctx, fe.SDTS, parseTree, trans.Limits{MaxSteps: lim.MaxEvalSteps}, fe.IRAttribute)
 	if err != nil {
 		return ir, &parseTree, err
 	}
 
+	ir, err = fe.irFrom(attrVals)
+	return ir, &parseTree, err
+}
+
+// irFrom retrieves the IR from the attribute values produced by fe.SDTS.
+func (fe Frontend[E]) irFrom(attrVals []interface{}) (ir E, err error) {
 	// all analysis complete, now retrieve the result
 	if len(attrVals) != 1 {
-		return ir, &parseTree, fmt.Errorf("requested final IR attribute %q from root node but got %d values back", fe.IRAttribute, len(attrVals))
+		return ir, fmt.Errorf("requested final IR attribute %q from root node but got %d values back", fe.IRAttribute, len(attrVals))
 	}
 	irUncast := attrVals[0]
 	var ok bool
 	ir, ok = irUncast.(E)
 	if !ok {
 		// type mismatch; use reflections to collect type for err reporting
-		return ir, &parseTree, fmt.Errorf("expected final IR attribute %q to be of type %T at the root node, but result was of type %T", fe.IRAttribute, ir, irUncast)
+		return ir, fmt.Errorf("expected final IR attribute %q to be of type %T at the root node, but result was of type %T", fe.IRAttribute, ir, irUncast)
+	}
+
+	return ir, nil
+}
+
+// AnalyzeStream is the same as Analyze but does not build a parse tree. Instead,
+// the SDTS is applied as the input is parsed, with the attributes of each node
+// being computed as soon as the parser creates it and the node being discarded
+// once its parent is done with it. This uses far less memory than Analyze for
+// large inputs. fe.SDTS must be S-attributed, which is the case for any FISHI
+// spec that does not set inherited attributes, and fe.Parser must be an LR
+// parser; otherwise an error is returned.
+func (fe Frontend[E]) AnalyzeStream(r io.Reader) (ir E, err error) {
+	return fe.AnalyzeStreamContext(context.Background(), r, Limits{})
+}
+
+// AnalyzeStreamContext is the same as AnalyzeStream but stops analysis when
+// ctx is done or when the input exceeds one of the given limits, in the same
+// way as AnalyzeContext. lim.MaxTreeDepth applies to the parse tree that the
+// input makes up even though the tree is never built in full.
+func (fe Frontend[E]) AnalyzeStreamContext(ctx context.Context, r io.Reader, lim Limits) (ir E, err error) {
+	if lim.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lim.Timeout)
+		defer cancel()
+	}
+
+	// lexical analysis
+	lexLim := lex.Limits{MaxInputSize: lim.MaxInputSize, MaxTokens: lim.MaxTokens}
+	tokStream, err := lex.LexContext(ctx, fe.Lexer, r, lexLim)
+	if err != nil {
+		return ir, err
+	}
+
+	// sanity check to see if we just got handed an empty reader
+	if tokStream.Peek().Class().ID() == lex.TokenEndOfText.ID() {
+		return ir, fmt.Errorf("input is empty")
+	}
+
+	// syntactic and semantic analysis together
+	parseLim := parse.Limits{MaxTreeDepth: lim.MaxTreeDepth}
+	transLim := trans.Limits{MaxSteps: lim.MaxEvalSteps}
+	attrVals, err := trans.EvaluateStreamContext(ctx, fe.SDTS, fe.Parser, tokStream, parseLim, transLim, fe.IRAttribute)
+	if err != nil {
+		return ir, err
+	}
+
+	return fe.irFrom(attrVals)
+}
+
+// AnalyzeFile is the same as Analyze but reads the input from the file with
+// the given name. Every token lexed from the file has name as its File(), and
+// so does any syntaxerr.Error for a problem in it; any other error returned is
+// prefixed with name. To get the same for input that is not in a file, call
+// Analyze with a reader from lex.NamedReader.
+func (fe Frontend[E]) AnalyzeFile(name string) (ir E, pt *parse.Tree, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return ir, nil, err
+	}
+	defer f.Close()
+
+	ir, pt, err = fe.Analyze(f)
+	return ir, pt, fileErr(name, err)
+}
+
+// fileErr returns err prefixed with the name of the file it occured in, unless
+// it is nil or is a syntaxerr.Error that already has the file.
+func fileErr(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var synErr *syntaxerr.Error
+	if errors.As(err, &synErr) && synErr.File() != "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// Module is the result of analyzing several files together with AnalyzeFiles.
+type Module[E any] struct {
+	// Files is the name of each file, in the order they were given to
+	// AnalyzeFiles.
+	Files []string
+
+	// IRs is the IR produced from each file, in the same order as Files. It is
+	// the zero value of E for a file that could not be analyzed.
+	IRs []E
+
+	// Trees is the parse tree of each file, in the same order as Files. It is
+	// nil for a file that could not be parsed.
+	Trees []*parse.Tree
+}
+
+// AnalyzeFiles analyzes each of the named files with AnalyzeFile and returns
+// the results together in a Module. All of the files are analyzed even if some
+// of them have errors; if any do, a *FilesError with the error for each of
+// them is returned along with the Module.
+func (fe Frontend[E]) AnalyzeFiles(names ...string) (*Module[E], error) {
+	mod := &Module[E]{
+		Files: names,
+		IRs:   make([]E, len(names)),
+		Trees: make([]*parse.Tree, len(names)),
+	}
+
+	filesErr := &FilesError{}
+	for i := range names {
+		var err error
+		mod.IRs[i], mod.Trees[i], err = fe.AnalyzeFile(names[i])
+		if err != nil {
+			filesErr.Files = append(filesErr.Files, names[i])
+			filesErr.Errs = append(filesErr.Errs, err)
+		}
+	}
+
+	if len(filesErr.Errs) > 0 {
+		return mod, filesErr
+	}
+	return mod, nil
+}
+
+// FilesError is returned by AnalyzeFiles when one or more of the files could
+// not be analyzed.
+type FilesError struct {
+	// Files is the name of each file that could not be analyzed, in the order
+	// they were given to AnalyzeFiles.
+	Files []string
+
+	// Errs is the error for each file in Files.
+	Errs []error
+}
+
+// Error returns the message of the error, which has the message of each of the
+// errors in it on its own line.
+func (ferr *FilesError) Error() string {
+	if len(ferr.Errs) == 1 {
+		return ferr.Errs[0].Error()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d files have errors:", len(ferr.Errs)))
+	for _, err := range ferr.Errs {
+		sb.WriteString("\n")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error for each file.
+func (ferr *FilesError) Unwrap() []error {
+	return ferr.Errs
+}
+
+// FullMessage returns the message of each of the errors, separated by blank
+// lines, for reporting to an end-user. Syntax errors are given in the format
+// of syntaxerr.Error.MessageForFile.
+func (ferr *FilesError) FullMessage() string {
+	msgs := make([]string, len(ferr.Errs))
+	for i, err := range ferr.Errs {
+		var synErr *syntaxerr.Error
+		if errors.As(err, &synErr) {
+			msgs[i] = synErr.MessageForFile(ferr.Files[i])
+		} else {
+			msgs[i] = err.Error()
+		}
+	}
+	return strings.Join(msgs, "\n\n")
+}
+
+// Analysis is the result of analyzing input text with AnalyzeIncremental or
+// Reanalyze. Along with the IR and parse tree, it holds the tokens and parse
+// state of the text so that it can be analyzed again after an edit without
+// lexing and parsing all of it from scratch.
+type Analysis[E any] struct {
+	// IR is the value produced from the text. It is the zero value of E if
+	// analysis did not complete.
+	IR E
+
+	// Tree is the parse tree of the text. It is nil if the text could not be
+	// lexed or parsed.
+	Tree *parse.Tree
+
+	lexed  *lex.Snapshot
+	parsed *parse.IncrementalParse
+}
+
+// Text returns the input text that was analyzed.
+func (a *Analysis[E]) Text() string {
+	return a.lexed.Text()
+}
+
+// AnalyzeIncremental is the same as AnalyzeString but returns the result as an
+// Analysis that can be passed to Reanalyze after the text is edited, such as
+// in an editor that needs the analysis kept up to date as the user types.
+//
+// If there is an error, it is returned along with an Analysis that can still
+// be passed to Reanalyze once the error is fixed. The Analysis is nil only if
+// fe.Lexer could not be run at all.
+func (fe Frontend[E]) AnalyzeIncremental(text string) (*Analysis[E], error) {
+	return fe.AnalyzeIncrementalContext(context.Background(), text, Limits{})
+}
+
+// AnalyzeIncrementalContext is the same as AnalyzeIncremental but stops
+// analysis when ctx is done or when the text exceeds one of the given limits,
+// with the same errors as AnalyzeContext. Because all of text is lexed before
+// it is parsed, the input size and token limits are checked after lexing.
+func (fe Frontend[E]) AnalyzeIncrementalContext(ctx context.Context, text string, lim Limits) (*Analysis[E], error) {
+	snap, err := lex.LexSnapshot(fe.Lexer, text)
+	if err != nil {
+		return nil, err
+	}
+	return fe.analyzeSnapshot(ctx, snap, nil, lex.TokenDiff{}, lim)
+}
+
+// Reanalyze makes an edit to the text that prev was analyzed from and analyzes
+// the result. Only the part of the text around the edit is lexed again, and
+// the parts of prev's parse tree that the edit did not affect are reused in the
+// new tree where the parser allows it; this is only possible when fe.Parser is
+// an LR parser. The resulting tree is the same as the one that analyzing all
+// of the edited text would give. prev itself is not modified.
+//
+// Like AnalyzeIncremental, an Analysis is returned even if there is an error.
+func (fe Frontend[E]) Reanalyze(prev *Analysis[E], edit lex.Edit) (*Analysis[E], error) {
+	return fe.ReanalyzeContext(context.Background(), prev, edit, Limits{})
+}
+
+// ReanalyzeContext is the same as Reanalyze but stops analysis when ctx is
+// done or when the edited text exceeds one of the given limits, in the same
+// way as AnalyzeIncrementalContext.
+func (fe Frontend[E]) ReanalyzeContext(ctx context.Context, prev *Analysis[E], edit lex.Edit, lim Limits) (*Analysis[E], error) {
+	snap, diff, err := prev.lexed.Relex(edit)
+	if err != nil {
+		return nil, err
+	}
+	return fe.analyzeSnapshot(ctx, snap, prev.parsed, diff, lim)
+}
+
+// analyzeSnapshot parses and translates the tokens of snap, reusing prevParse
+// if it is non-nil. diff must give how the tokens of snap differ from the ones
+// prevParse was parsed from.
+func (fe Frontend[E]) analyzeSnapshot(ctx context.Context, snap *lex.Snapshot, prevParse *parse.IncrementalParse, diff lex.TokenDiff, lim Limits) (*Analysis[E], error) {
+	if lim.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lim.Timeout)
+		defer cancel()
+	}
+
+	a := &Analysis[E]{lexed: snap}
+
+	if err := snap.Err(); err != nil {
+		// nothing is parsed, so the next edit will need a full parse.
+		return a, err
+	}
+	if lim.MaxInputSize > 0 && len(snap.Text()) > lim.MaxInputSize {
+		return a, &syntaxerr.LimitError{Limit: syntaxerr.LimitInputSize, Max: lim.MaxInputSize}
+	}
+	// the last token is the end of text token, which does not count.
+	if lim.MaxTokens > 0 && len(snap.Tokens())-1 > lim.MaxTokens {
+		return a, &syntaxerr.LimitError{Limit: syntaxerr.LimitTokens, Max: lim.MaxTokens}
+	}
+	if err := ctx.Err(); err != nil {
+		return a, err
+	}
+
+	// sanity check to see if we just got handed empty input
+	if snap.Tokens()[0].Class().ID() == lex.TokenEndOfText.ID() {
+		return a, fmt.Errorf("input is empty")
+	}
+
+	var err error
+	if prevParse == nil {
+		a.parsed, err = parse.ParseIncremental(fe.Parser, snap.Tokens())
+	} else {
+		a.parsed, err = prevParse.Reparse(snap.Tokens(), diff)
+	}
+	if err != nil {
+		return a, err
+	}
+	a.Tree = &a.parsed.Tree
+
+	// the parse is kept even if the tree is too deep so that the next edit can
+	// reuse it.
+	if err := (parse.Limits{MaxTreeDepth: lim.MaxTreeDepth}).CheckTree(a.parsed.Tree); err != nil {
+		return a, err
+	}
+
+	// semantic analysis (discard warns at this stage)
+	attrVals, _, err := trans.EvaluateContext(ctx, fe.SDTS, a.parsed.Tree, trans.Limits{MaxSteps: lim.MaxEvalSteps}, fe.IRAttribute)
+	if err != nil {
+		return a, err
+	}
+
+	a.IR, err = fe.irFrom(attrVals)
+	return a, err
+}
+
+// ExpectedAt returns what may come next in input at the position given by
+// cursor, which is a byte offset into input. This is intended for use in code
+// completion. The input is lexed with fe.Lexer, and the tokens that end at or
+// before the cursor are parsed with fe.Parser; any token that the cursor is in
+// the middle of is taken to be partially typed and is ignored. If there is a
+// syntax error before the cursor, it is returned.
+func (fe Frontend[E]) ExpectedAt(input string, cursor int) (parse.Expectation, error) {
+	tokStream, err := fe.Lexer.Lex(strings.NewReader(input))
+	if err != nil {
+		return parse.Expectation{}, err
+	}
+
+	return parse.ExpectedAt(fe.Parser, tokStream, lex.PositionAt(input, cursor))
+}
+
+// ExpectedAtAs is the same as ExpectedAt but parses the input as the given
+// entry point of the grammar of fe.Parser instead of as its start symbol. This
+// is for completing input that is only part of a full program, such as a
+// single expression. An error is returned if symbol is not an entry point.
+func (fe Frontend[E]) ExpectedAtAs(symbol string, input string, cursor int) (parse.Expectation, error) {
+	tokStream, err := fe.Lexer.Lex(strings.NewReader(input))
+	if err != nil {
+		return parse.Expectation{}, err
 	}
 
-	return ir, &parseTree, nil
+	return parse.ExpectedAtAs(fe.Parser, symbol, tokStream, lex.PositionAt(input, cursor))
 }
@@ -1,12 +1,22 @@
 package ictiobus
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dekarrin/ictiobus/grammar"
 	"github.com/dekarrin/ictiobus/lex"
 	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/syntaxerr"
 	"github.com/dekarrin/ictiobus/trans"
 	"github.com/stretchr/testify/assert"
 )
@@ -70,6 +80,613 @@ func Test_Frontend_AnalyzeString(t *testing.T) {
 	}
 }
 
+func Test_Frontend_Reanalyze(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> S plus int | int ;
+	`)
+
+	lx := NewLexer()
+	lx.RegisterClass(lex.NewTokenClass("int", "integer"), "")
+	lx.RegisterClass(lex.NewTokenClass("plus", "'+'"), "")
+	lx.AddPattern(`[0-9]+`, lex.LexAs("int"), "", 0)
+	lx.AddPattern(`\+`, lex.LexAs("plus"), "", 0)
+	lx.AddPattern(`\s+`, lex.Discard(), "", 0)
+
+	p, _, err := parse.GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	fe := Frontend[string]{
+		Lexer:  lx,
+		Parser: p,
+		SDTS: mockSDTS{fn: func(t parse.Tree, s ...string) ([]interface{}, []error, error) {
+			return []interface{}{t.String()}, nil, nil
+		}},
+	}
+
+	testCases := []struct {
+		name      string
+		input     string
+		edits     []lex.Edit
+		expectErr bool
+	}{
+		{
+			name:  "single edit",
+			input: "1 + 2 + 3",
+			edits: []lex.Edit{{Start: 4, End: 5, Text: "20"}},
+		},
+		{
+			name:      "edit causes lex error",
+			input:     "1 + 2 + 3",
+			edits:     []lex.Edit{{Start: 4, End: 5, Text: "%"}},
+			expectErr: true,
+		},
+		{
+			name:  "edit fixes lex error",
+			input: "1 + 2 + 3",
+			edits: []lex.Edit{
+				{Start: 4, End: 5, Text: "%"},
+				{Start: 4, End: 5, Text: "4"},
+			},
+		},
+		{
+			name:  "edit fixes syntax error",
+			input: "1 + + 3",
+			edits: []lex.Edit{{Start: 3, End: 3, Text: " 8"}},
+		},
+		{
+			name:      "delete everything",
+			input:     "1 + 2",
+			edits:     []lex.Edit{{Start: 0, End: 5}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			a, _ := fe.AnalyzeIncremental(tc.input)
+			if !assert.NotNil(a) {
+				return
+			}
+
+			// execute
+			var err error
+			for _, edit := range tc.edits {
+				a, err = fe.Reanalyze(a, edit)
+				if !assert.NotNil(a) {
+					return
+				}
+			}
+
+			// assert
+			expectIR, expectTree, expectErr := fe.AnalyzeString(a.Text())
+			if tc.expectErr {
+				assert.Error(err)
+				assert.Error(expectErr)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.NoError(expectErr)
+			assert.Equal(expectIR, a.IR)
+			assert.Equal(expectTree, a.Tree)
+		})
+	}
+}
+
+func Test_Frontend_AnalyzeStream(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> S plus int | int ;
+	`)
+
+	lx := NewLazyLexer()
+	lx.RegisterClass(lex.NewTokenClass("int", "integer"), "")
+	lx.RegisterClass(lex.NewTokenClass("plus", "'+'"), "")
+	lx.AddPattern(`[0-9]+`, lex.LexAs("int"), "", 0)
+	lx.AddPattern(`\+`, lex.LexAs("plus"), "", 0)
+	lx.AddPattern(`\s+`, lex.Discard(), "", 0)
+
+	sdts := trans.NewSDTS()
+	sdts.SetHooks(trans.HookMap{
+		"int": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return strconv.Atoi(args[0].(string))
+		},
+		"add": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			n, err := strconv.Atoi(args[1].(string))
+			return args[0].(int) + n, err
+		},
+	})
+	sdts.Bind("S", []string{"int"}, "val", "int", []trans.AttrRef{{Rel: trans.NRTerminal(0), Name: "$text"}})
+	sdts.Bind("S", []string{"S", "plus", "int"}, "val", "add", []trans.AttrRef{{Rel: trans.NRNonTerminal(0), Name: "val"}, {Rel: trans.NRTerminal(1), Name: "$text"}})
+
+	lalr, _, err := parse.GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testCases := []struct {
+		name      string
+		input     string
+		parser    parse.Parser
+		expect    int
+		expectErr bool
+	}{
+		{
+			name:   "sum",
+			input:  "1 + 2 + 30",
+			parser: lalr,
+			expect: 33,
+		},
+		{
+			name:      "empty input",
+			input:     "",
+			parser:    lalr,
+			expectErr: true,
+		},
+		{
+			name:      "syntax error",
+			input:     "1 + + 2",
+			parser:    lalr,
+			expectErr: true,
+		},
+		{
+			name:      "LL(1) parser",
+			input:     "1 + 2",
+			parser:    mockParser{},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			fe := Frontend[int]{
+				Lexer:       lx,
+				Parser:      tc.parser,
+				SDTS:        sdts,
+				IRAttribute: "val",
+			}
+
+			actual, err := fe.AnalyzeStream(strings.NewReader(tc.input))
+
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+
+			fromTree, _, err := fe.AnalyzeString(tc.input)
+			assert.NoError(err)
+			assert.Equal(fromTree, actual, "result does not match Analyze")
+		})
+	}
+}
+
+func Test_Frontend_AnalyzeContext(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> S plus int | int ;
+	`)
+
+	lx := NewLazyLexer()
+	lx.RegisterClass(lex.NewTokenClass("int", "integer"), "")
+	lx.RegisterClass(lex.NewTokenClass("plus", "'+'"), "")
+	lx.AddPattern(`[0-9]+`, lex.LexAs("int"), "", 0)
+	lx.AddPattern(`\+`, lex.LexAs("plus"), "", 0)
+	lx.AddPattern(`\s+`, lex.Discard(), "", 0)
+
+	newSDTS := func(delay time.Duration) trans.SDTS {
+		sdts := trans.NewSDTS()
+		sdts.SetHooks(trans.HookMap{
+			"int": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+				time.Sleep(delay)
+				return strconv.Atoi(args[0].(string))
+			},
+			"add": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+				time.Sleep(delay)
+				n, err := strconv.Atoi(args[1].(string))
+				return args[0].(int) + n, err
+			},
+		})
+		sdts.Bind("S", []string{"int"}, "val", "int", []trans.AttrRef{{Rel: trans.NRTerminal(0), Name: "$text"}})
+		sdts.Bind("S", []string{"S", "plus", "int"}, "val", "add", []trans.AttrRef{{Rel: trans.NRNonTerminal(0), Name: "val"}, {Rel: trans.NRTerminal(1), Name: "$text"}})
+		return sdts
+	}
+
+	lalr, _, err := parse.GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// input is 10 bytes and 5 tokens, and gives a tree 4 deep that takes 3
+	// steps to evaluate.
+	const input = "1 + 2 + 30"
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		lim       Limits
+		hookDelay time.Duration
+		expect    int
+		expectErr error
+	}{
+		{
+			name:   "no limits",
+			ctx:    context.Background(),
+			expect: 33,
+		},
+		{
+			name: "all limits met exactly",
+			ctx:  context.Background(),
+			lim: Limits{
+				MaxInputSize: 10,
+				MaxTokens:    5,
+				MaxTreeDepth: 4,
+				MaxEvalSteps: 3,
+				Timeout:      time.Minute,
+			},
+			expect: 33,
+		},
+		{
+			name:      "input too large",
+			ctx:       context.Background(),
+			lim:       Limits{MaxInputSize: 9},
+			expectErr: syntaxerr.ErrInputTooLarge,
+		},
+		{
+			name:      "too many tokens",
+			ctx:       context.Background(),
+			lim:       Limits{MaxTokens: 4},
+			expectErr: syntaxerr.ErrTooManyTokens,
+		},
+		{
+			name:      "tree too deep",
+			ctx:       context.Background(),
+			lim:       Limits{MaxTreeDepth: 3},
+			expectErr: syntaxerr.ErrTreeTooDeep,
+		},
+		{
+			name:      "too many eval steps",
+			ctx:       context.Background(),
+			lim:       Limits{MaxEvalSteps: 2},
+			expectErr: syntaxerr.ErrTooManyEvalSteps,
+		},
+		{
+			name:      "canceled",
+			ctx:       canceled,
+			expectErr: context.Canceled,
+		},
+		{
+			name:      "timed out",
+			ctx:       context.Background(),
+			lim:       Limits{Timeout: 10 * time.Millisecond},
+			hookDelay: 20 * time.Millisecond,
+			expectErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			fe := Frontend[int]{
+				Lexer:       lx,
+				Parser:      lalr,
+				SDTS:        newSDTS(tc.hookDelay),
+				IRAttribute: "val",
+			}
+
+			actual, _, err := fe.AnalyzeContext(tc.ctx, strings.NewReader(input), tc.lim)
+			streamed, streamErr := fe.AnalyzeStreamContext(tc.ctx, strings.NewReader(input), tc.lim)
+			incr, incrErr := fe.AnalyzeIncrementalContext(tc.ctx, input, tc.lim)
+
+			// reanalysis gets input from an edit to other text analyzed with no
+			// limits.
+			prev, prevErr := fe.AnalyzeIncremental("1 + 2 + 3")
+			if !assert.NoError(prevErr) {
+				return
+			}
+			reanalyzed, reErr := fe.ReanalyzeContext(tc.ctx, prev, lex.Edit{Start: 8, End: 9, Text: "30"}, tc.lim)
+
+			if tc.expectErr != nil {
+				assert.True(errors.Is(err, tc.expectErr), "expected %v, got %v", tc.expectErr, err)
+				assert.True(errors.Is(streamErr, tc.expectErr), "expected %v from AnalyzeStreamContext, got %v", tc.expectErr, streamErr)
+				assert.True(errors.Is(incrErr, tc.expectErr), "expected %v from AnalyzeIncrementalContext, got %v", tc.expectErr, incrErr)
+				assert.True(errors.Is(reErr, tc.expectErr), "expected %v from ReanalyzeContext, got %v", tc.expectErr, reErr)
+				return
+			}
+			if !assert.NoError(err) || !assert.NoError(streamErr) || !assert.NoError(incrErr) || !assert.NoError(reErr) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+			assert.Equal(tc.expect, streamed)
+			assert.Equal(tc.expect, incr.IR)
+			assert.Equal(tc.expect, reanalyzed.IR)
+		})
+	}
+}
+
+func Test_Frontend_AnalyzeFiles(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> S plus int | int ;
+	`)
+
+	lx := NewLexer()
+	lx.RegisterClass(lex.NewTokenClass("int", "integer"), "")
+	lx.RegisterClass(lex.NewTokenClass("plus", "'+'"), "")
+	lx.AddPattern(`[0-9]+`, lex.LexAs("int"), "", 0)
+	lx.AddPattern(`\+`, lex.LexAs("plus"), "", 0)
+	lx.AddPattern(`\s+`, lex.Discard(), "", 0)
+
+	sdts := trans.NewSDTS()
+	sdts.SetHooks(trans.HookMap{
+		"int": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			return strconv.Atoi(args[0].(string))
+		},
+		"add": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+			n, err := strconv.Atoi(args[1].(string))
+			return args[0].(int) + n, err
+		},
+	})
+	sdts.Bind("S", []string{"int"}, "val", "int", []trans.AttrRef{{Rel: trans.NRTerminal(0), Name: "$text"}})
+	sdts.Bind("S", []string{"S", "plus", "int"}, "val", "add", []trans.AttrRef{{Rel: trans.NRNonTerminal(0), Name: "val"}, {Rel: trans.NRTerminal(1), Name: "$text"}})
+
+	lalr, _, err := parse.GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	fe := Frontend[int]{
+		Lexer:       lx,
+		Parser:      lalr,
+		SDTS:        sdts,
+		IRAttribute: "val",
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"good1.txt":  "1 + 2",
+		"good2.txt":  "30",
+		"bad.txt":    "1 +\n+ 2",
+		"lexbad.txt": "1 + x",
+		"empty.txt":  "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	testCases := []struct {
+		name        string
+		files       []string
+		expect      []int
+		expectFiles []string
+		expectErrs  []string
+	}{
+		{
+			name:   "all files good",
+			files:  []string{"good1.txt", "good2.txt"},
+			expect: []int{3, 30},
+		},
+		{
+			name:        "syntax error",
+			files:       []string{"good1.txt", "bad.txt", "good2.txt"},
+			expect:      []int{3, 0, 30},
+			expectFiles: []string{"bad.txt"},
+			expectErrs:  []string{"syntax error: " + path("bad.txt") + ": around line 2, char 1: unexpected '+'; expected an int"},
+		},
+		{
+			name:        "errors in several files",
+			files:       []string{"lexbad.txt", "good2.txt", "empty.txt", "missing.txt"},
+			expect:      []int{0, 30, 0, 0},
+			expectFiles: []string{"lexbad.txt", "empty.txt", "missing.txt"},
+			expectErrs: []string{
+				"syntax error: " + path("lexbad.txt") + ": around line 1, char 5: unexpected end of input; expected an int",
+				path("empty.txt") + ": input is empty",
+				"open " + path("missing.txt") + ": no such file or directory",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			names := make([]string, len(tc.files))
+			for i := range tc.files {
+				names[i] = path(tc.files[i])
+			}
+
+			// execute
+			mod, err := fe.AnalyzeFiles(names...)
+
+			// assert
+			if !assert.NotNil(mod) {
+				return
+			}
+			assert.Equal(names, mod.Files)
+			assert.Equal(tc.expect, mod.IRs)
+
+			// every token must be tagged with the file it came from.
+			var checkTokens func(name string, pt *parse.Tree)
+			checkTokens = func(name string, pt *parse.Tree) {
+				if pt.Source != nil {
+					assert.Equal(name, lex.FileOf(pt.Source))
+				}
+				for _, child := range pt.Children {
+					checkTokens(name, child)
+				}
+			}
+			for i, pt := range mod.Trees {
+				if pt != nil {
+					checkTokens(names[i], pt)
+				}
+			}
+
+			if tc.expectErrs == nil {
+				assert.NoError(err)
+				return
+			}
+
+			filesErr, ok := err.(*FilesError)
+			if !assert.True(ok, "expected a *FilesError, got %T", err) {
+				return
+			}
+
+			expectFiles := make([]string, len(tc.expectFiles))
+			for i := range tc.expectFiles {
+				expectFiles[i] = path(tc.expectFiles[i])
+			}
+			assert.Equal(expectFiles, filesErr.Files)
+
+			actualErrs := make([]string, len(filesErr.Errs))
+			for i := range filesErr.Errs {
+				actualErrs[i] = filesErr.Errs[i].Error()
+			}
+			assert.Equal(tc.expectErrs, actualErrs)
+		})
+	}
+}
+
+// Test_Frontend_Concurrent checks that a single Frontend can be used by many
+// goroutines at once with every kind of parser and lexer. It is most useful
+// when run with the race detector.
+func Test_Frontend_Concurrent(t *testing.T) {
+	const workers = 8
+
+	g := grammar.MustParse(`
+		S -> int X ;
+		X -> plus int X | ε ;
+	`)
+
+	newLexer := func(lazy bool) lex.Lexer {
+		lx := lex.NewLexer(lazy)
+		lx.RegisterClass(lex.NewTokenClass("int", "integer"), "")
+		lx.RegisterClass(lex.NewTokenClass("plus", "'+'"), "")
+		lx.AddPattern(`[0-9]+`, lex.LexAs("int"), "", 0)
+		lx.AddPattern(`\+`, lex.LexAs("plus"), "", 0)
+		lx.AddPattern(`\s+`, lex.Discard(), "", 0)
+		return lx
+	}
+
+	newSDTS := func() trans.SDTS {
+		sdts := trans.NewSDTS()
+		sdts.SetHooks(trans.HookMap{
+			"int": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+				return strconv.Atoi(args[0].(string))
+			},
+			"add": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+				n, err := strconv.Atoi(args[0].(string))
+				return n + args[1].(int), err
+			},
+			"zero": func(info trans.SetterInfo, args []interface{}) (interface{}, error) {
+				return 0, nil
+			},
+		})
+		sdts.Bind("S", []string{"int", "X"}, "val", "add", []trans.AttrRef{{Rel: trans.NRTerminal(0), Name: "$text"}, {Rel: trans.NRNonTerminal(0), Name: "val"}})
+		sdts.Bind("X", []string{"plus", "int", "X"}, "val", "add", []trans.AttrRef{{Rel: trans.NRTerminal(1), Name: "$text"}, {Rel: trans.NRNonTerminal(0), Name: "val"}})
+		sdts.Bind("X", []string{""}, "val", "zero", nil)
+		return sdts
+	}
+
+	ll, err := NewLLParser(g)
+	if !assert.NoError(t, err) {
+		return
+	}
+	lalr, _, err := NewLALRParser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testCases := []struct {
+		name   string
+		lazy   bool
+		parser parse.Parser
+	}{
+		{name: "LL(1), lazy lexer", lazy: true, parser: ll},
+		{name: "LL(1), immediate lexer", lazy: false, parser: ll},
+		{name: "LALR(1), lazy lexer", lazy: true, parser: lalr},
+		{name: "LALR(1), immediate lexer", lazy: false, parser: lalr},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			fe := Frontend[int]{
+				Lexer:       newLexer(tc.lazy),
+				Parser:      tc.parser,
+				SDTS:        newSDTS(),
+				IRAttribute: "val",
+			}
+
+			var wg sync.WaitGroup
+			results := make([][]int, workers)
+			errs := make([]error, workers)
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+
+					// each worker gets its own input so that results getting
+					// mixed up between goroutines would be caught.
+					input := fmt.Sprintf("%d + %d + 1", w, w)
+
+					ir, _, err := fe.AnalyzeString(input)
+					if err != nil {
+						errs[w] = err
+						return
+					}
+					results[w] = append(results[w], ir)
+
+					ir, _, err = fe.AnalyzeContext(context.Background(), strings.NewReader(input), Limits{MaxTreeDepth: 10})
+					if err != nil {
+						errs[w] = err
+						return
+					}
+					results[w] = append(results[w], ir)
+
+					a, err := fe.AnalyzeIncremental(input)
+					if err != nil {
+						errs[w] = err
+						return
+					}
+					results[w] = append(results[w], a.IR)
+				}(w)
+			}
+
+			// changing listeners while analysis runs must not race with it.
+			fe.Lexer.RegisterTraceListener(func(t lex.Token) {})
+			fe.Parser.RegisterTraceListener(func(s string) {})
+			fe.SDTS.RegisterListener(func(e trans.Event) {})
+
+			wg.Wait()
+
+			for w := 0; w < workers; w++ {
+				if !assert.NoError(errs[w], "worker %d", w) {
+					continue
+				}
+				expect := w + w + 1
+				assert.Equal([]int{expect, expect, expect}, results[w], "worker %d", w)
+			}
+		})
+	}
+}
+
 // mock frontend components below here
 
 type mockLexer struct {
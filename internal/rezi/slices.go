@@ -61,6 +61,59 @@ func DecSliceString(data []byte) ([]string, int, error) {
 
 }
 
+func EncSliceInt(sl []int) []byte {
+	if sl == nil {
+		return EncInt(-1)
+	}
+
+	enc := make([]byte, 0)
+
+	for i := range sl {
+		enc = append(enc, EncInt(sl[i])...)
+	}
+
+	enc = append(EncInt(len(enc)), enc...)
+	return enc
+}
+
+func DecSliceInt(data []byte) ([]int, int, error) {
+	var totalConsumed int
+
+	toConsume, n, err := DecInt(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("decode byte count: %w", err)
+	}
+	data = data[n:]
+	totalConsumed += n
+
+	if toConsume == 0 {
+		return []int{}, totalConsumed, nil
+	} else if toConsume == -1 {
+		return nil, totalConsumed, nil
+	}
+
+	if len(data) < toConsume {
+		return nil, 0, fmt.Errorf("unexpected EOF")
+	}
+
+	sl := []int{}
+
+	var i int
+	for i < toConsume {
+		v, n, err := DecInt(data)
+		if err != nil {
+			return nil, totalConsumed, fmt.Errorf("decode item: %w", err)
+		}
+		totalConsumed += n
+		i += n
+		data = data[n:]
+
+		sl = append(sl, v)
+	}
+
+	return sl, totalConsumed, nil
+}
+
 func EncSliceBinary[E encoding.BinaryMarshaler](sl []E) []byte {
 	if sl == nil {
 		return EncInt(-1)
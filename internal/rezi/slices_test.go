@@ -108,6 +108,107 @@ func Test_DecSliceString(t *testing.T) {
 	}
 }
 
+func Test_EncSliceInt(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  []int
+		expect []byte
+	}{
+		{
+			name:   "empty",
+			input:  []int{},
+			expect: []byte{0x00},
+		},
+		{
+			name:   "nil",
+			input:  nil,
+			expect: []byte{0x80},
+		},
+		{
+			name:   "one item",
+			input:  []int{1},
+			expect: []byte{0x01, 0x02, 0x01, 0x01},
+		},
+		{
+			name:   "several items",
+			input:  []int{0, -1, 500},
+			expect: []byte{0x01, 0x05, 0x00, 0x80, 0x02, 0x01, 0xf4},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := EncSliceInt(tc.input)
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_DecSliceInt(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       []byte
+		expectValue []int
+		expectRead  int
+		expectError bool
+	}{
+		{
+			name:        "empty",
+			input:       []byte{0x00},
+			expectValue: []int{},
+			expectRead:  1,
+		},
+		{
+			name:        "nil",
+			input:       []byte{0x80},
+			expectValue: nil,
+			expectRead:  1,
+		},
+		{
+			name:        "one item + some extra bytes",
+			input:       []byte{0x01, 0x02, 0x01, 0x01, 0x00, 0xfe},
+			expectValue: []int{1},
+			expectRead:  4,
+		},
+		{
+			name:        "several items",
+			input:       []byte{0x01, 0x05, 0x00, 0x80, 0x02, 0x01, 0xf4},
+			expectValue: []int{0, -1, 500},
+			expectRead:  7,
+		},
+		{
+			name:        "not enough bytes for list",
+			input:       []byte{0x01, 0x05, 0x01, 0x01},
+			expectError: true,
+		},
+		{
+			name:        "not enough bytes for list item",
+			input:       []byte{0x01, 0x02, 0x02, 0x01},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actualValue, actualRead, err := DecSliceInt(tc.input)
+			if tc.expectError {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectValue, actualValue)
+			assert.Equal(tc.expectRead, actualRead)
+		})
+	}
+}
+
 func Test_EncSliceBinary(t *testing.T) {
 	testCases := []struct {
 		name   string
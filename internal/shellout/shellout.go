@@ -4,6 +4,7 @@
 package shellout
 
 import (
+	"io"
 	"os"
 	"os/exec"
 )
@@ -56,3 +57,14 @@ func ExecFG(wd string, env []string, cmd string, args ...string) error {
 	execCmd.Stderr = os.Stderr
 	return execCmd.Run()
 }
+
+// ExecFGStderrTo is the same as ExecFG but writes the stderr of the command to
+// the given writer instead of to the calling program's stderr.
+func ExecFGStderrTo(wd string, env []string, stderr io.Writer, cmd string, args ...string) error {
+	execCmd := exec.Command(cmd, args...)
+	execCmd.Env = env
+	execCmd.Dir = wd
+	execCmd.Stdout = os.Stdout
+	execCmd.Stderr = stderr
+	return execCmd.Run()
+}
@@ -61,3 +61,54 @@ func leveledStr[N any](getName func(node N) string, getChildren func(node N) []N
 
 	return sb.String()
 }
+
+// DOTTree returns a Graphviz DOT digraph called name that draws the tree
+// rooted at root. The function getChildren is recursively called on nodes of
+// the tree to get all nodes to include in output, getLabel is called on each
+// node to get the text to show in it, and getShape is called on each node to
+// get the Graphviz shape to draw it as. Labels may contain newlines.
+func DOTTree[N any](name string, root N, getLabel func(N) string, getShape func(N) string, getChildren func(N) []N) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("digraph %s {\n", DOTQuote(name)))
+	sb.WriteString("\tordering=out;\n")
+
+	nextID := 0
+	var writeNode func(n N) int
+	writeNode = func(n N) int {
+		id := nextID
+		nextID++
+		sb.WriteString(fmt.Sprintf("\tn%d [label=%s, shape=%s];\n", id, DOTQuote(getLabel(n)), getShape(n)))
+
+		for _, child := range getChildren(n) {
+			childID := writeNode(child)
+			sb.WriteString(fmt.Sprintf("\tn%d -> n%d;\n", id, childID))
+		}
+		return id
+	}
+	writeNode(root)
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// DOTQuote returns s as a double-quoted Graphviz DOT string. Newlines in s
+// become line breaks in the label they are used in.
+func DOTQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteRune('"')
+	for _, ch := range s {
+		switch ch {
+		case '"', '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(ch)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			continue
+		default:
+			sb.WriteRune(ch)
+		}
+	}
+	sb.WriteRune('"')
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package lex
+
+import "io"
+
+// NamedReader returns an io.Reader that reads from r and that is named name.
+// Tokens lexed from it will give name as their file; see FileOf. It is not needed for
+// an *os.File, which already has the name it was opened with.
+func NamedReader(name string, r io.Reader) io.Reader {
+	return &namedReader{r: r, name: name}
+}
+
+// WithFile returns a copy of tok that is a FileToken whose File() is file.
+func WithFile(tok Token, file string) Token {
+	switch t := tok.(type) {
+	case lexerToken:
+		t.file = file
+		return t
+	case stopToken:
+		t.file = file
+		return t
+	case fileToken:
+		t.file = file
+		return t
+	default:
+		return fileToken{Token: tok, file: file}
+	}
+}
+
+// namedReader is an io.Reader with a name.
+type namedReader struct {
+	r    io.Reader
+	name string
+}
+
+// Read reads up to len(p) bytes into p.
+func (nr *namedReader) Read(p []byte) (int, error) {
+	return nr.r.Read(p)
+}
+
+// Name returns the name of the reader.
+func (nr *namedReader) Name() string {
+	return nr.name
+}
+
+// fileToken is a Token from another implementation with its File() replaced.
+type fileToken struct {
+	Token
+	file string
+}
+
+// File returns the name of the file that the token was lexed from.
+func (ft fileToken) File() string {
+	return ft.file
+}
+
+// inputName returns the name of input if it has one, such as when it is an
+// *os.File or was created with NamedReader. Otherwise, it returns the empty
+// string.
+func inputName(input io.Reader) string {
+	if named, ok := input.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return ""
+}
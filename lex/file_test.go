@@ -0,0 +1,96 @@
+package lex
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NamedReader(t *testing.T) {
+	testCases := []struct {
+		name  string
+		lazy  bool
+		input string
+	}{
+		{
+			name:  "lazy lexer",
+			lazy:  true,
+			input: "1 + 2",
+		},
+		{
+			name:  "immediate lexer",
+			input: "1 + 2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			lx := NewLexer(tc.lazy)
+			lx.RegisterClass(testClassInt, "")
+			lx.RegisterClass(testClassPlus, "")
+			lx.AddPattern(`[0-9]+`, LexAs(testClassInt.ID()), "", 0)
+			lx.AddPattern(`\+`, LexAs(testClassPlus.ID()), "", 0)
+			lx.AddPattern(`\s+`, Discard(), "", 0)
+
+			// execute
+			stream, err := lx.Lex(NamedReader("src.txt", strings.NewReader(tc.input)))
+
+			// assert
+			if !assert.NoError(err) {
+				return
+			}
+			for stream.HasNext() {
+				assert.Equal("src.txt", FileOf(stream.Next()))
+			}
+		})
+	}
+}
+
+func Test_WithFile(t *testing.T) {
+	assert := assert.New(t)
+
+	tok := NewToken(testClassInt, "1", 2, 3, "a 1")
+
+	// execute
+	actual := WithFile(tok, "src.txt")
+
+	// assert
+	assert.Equal("src.txt", FileOf(actual))
+	assert.Equal("", FileOf(tok))
+	assert.Equal(tok.Class(), actual.Class())
+	assert.Equal(tok.Lexeme(), actual.Lexeme())
+	assert.Equal(tok.LinePos(), actual.LinePos())
+	assert.Equal(tok.Line(), actual.Line())
+	assert.Equal(tok.FullLine(), actual.FullLine())
+	assert.Equal("src.txt", NewSyntaxErrorFromToken("bad", actual).File())
+}
+
+func Test_WithFile_otherTokenType(t *testing.T) {
+	assert := assert.New(t)
+
+	tok := noFileToken{NewToken(testClassInt, "1", 2, 3, "a 1")}
+
+	// execute
+	actual := WithFile(tok, "src.txt")
+
+	// assert
+	assert.Equal("", FileOf(tok))
+	assert.Equal("src.txt", FileOf(actual))
+	assert.Equal(tok.Lexeme(), actual.Lexeme())
+	assert.Equal("src.txt", NewSyntaxErrorFromToken("bad", actual).File())
+}
+
+// noFileToken is a Token from outside of this package that has no File method.
+type noFileToken struct {
+	tok Token
+}
+
+func (nft noFileToken) Class() TokenClass { return nft.tok.Class() }
+func (nft noFileToken) Lexeme() string    { return nft.tok.Lexeme() }
+func (nft noFileToken) LinePos() int      { return nft.tok.LinePos() }
+func (nft noFileToken) Line() int         { return nft.tok.Line() }
+func (nft noFileToken) FullLine() string  { return nft.tok.FullLine() }
+func (nft noFileToken) String() string    { return nft.tok.String() }
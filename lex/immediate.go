@@ -1,7 +1,10 @@
 package lex
 
 import (
+	"context"
 	"io"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
 )
 
 type immediateTokenStream struct {
@@ -14,9 +17,15 @@ type immediateTokenStream struct {
 // which will return them one at a time as its Next() function is called. If any
 // lexing errors occur, they will be immediately returned as a non-nil error.
 func (lx *lexerTemplate) ImmediatelyLex(input io.Reader) (TokenStream, error) {
+	return lx.immediatelyLex(context.Background(), input, Limits{})
+}
+
+// immediatelyLex is the same as ImmediatelyLex but stops lexing when ctx is
+// done or lim is exceeded.
+func (lx *lexerTemplate) immediatelyLex(ctx context.Context, input io.Reader, lim Limits) (TokenStream, error) {
 	// an immediate lexer is simply a 'lazy' lexer that just, keeps going. so
 	// make one of those.
-	lazyCore, err := lx.LazyLex(input)
+	lazyCore, err := lx.lazyLex(ctx, input, lim)
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +41,11 @@ func (lx *lexerTemplate) ImmediatelyLex(input io.Reader) (TokenStream, error) {
 		if tok.Class().ID() == TokenError.ID() {
 			// stop. do not allow panic mode to continue, lexing has failed.
 
+			// if lexing was stopped, give the reason as-is
+			if stopErr := TokenErr(tok); stopErr != nil {
+				return nil, stopErr
+			}
+
 			// create a new token to hold all values of tok except lexeme so we
 			// don't put the lexeme of "err message" into the actual token
 			// shown when the error is displayed to end user
@@ -40,9 +54,10 @@ func (lx *lexerTemplate) ImmediatelyLex(input io.Reader) (TokenStream, error) {
 				linePos: tok.LinePos(),
 				line:    tok.FullLine(),
 				lineNum: tok.Line(),
+				file:    FileOf(tok),
 			}
 
-			return nil, NewSyntaxErrorFromToken(tok.Lexeme(), tokWrap)
+			return nil, syntaxerr.Diagnostics{NewDiagnosticFromToken(syntaxerr.CodeLexical, tok.Lexeme(), tokWrap)}
 		}
 
 		lexedTokens = append(lexedTokens, tok)
@@ -1,9 +1,11 @@
 package lex
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
+	"github.com/dekarrin/ictiobus/syntaxerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -188,3 +190,31 @@ func Test_ImmediateLex_singleStateLex(t *testing.T) {
 		})
 	}
 }
+
+func Test_ImmediatelyLex_diagnostics(t *testing.T) {
+	assert := assert.New(t)
+
+	lx := NewLexer(false)
+	lx.RegisterClass(testClassInt, "")
+	lx.AddPattern(`[0-9]+`, LexAs(testClassInt.ID()), "", 0)
+	lx.AddPattern(`\s+`, Discard(), "", 0)
+
+	// execute
+	_, err := lx.Lex(NamedReader("src.txt", strings.NewReader("1 % 1")))
+
+	// assert
+	var diags syntaxerr.Diagnostics
+	if !assert.True(errors.As(err, &diags), "expected syntaxerr.Diagnostics, got %T", err) {
+		return
+	}
+	if !assert.Len(diags, 1) {
+		return
+	}
+	d := diags[0]
+	assert.Equal(syntaxerr.CodeLexical, d.Code)
+	assert.Equal(syntaxerr.SeverityError, d.Severity)
+	assert.Equal("src.txt", d.Span.File)
+	assert.Equal(syntaxerr.Position{Line: 1, Col: 3}, d.Span.Start)
+	assert.Equal(d.Span.Start, d.Span.End)
+	assert.Equal("1 % 1", d.SourceLine)
+}
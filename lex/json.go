@@ -0,0 +1,51 @@
+package lex
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonToken is the form a Token takes when encoded as JSON.
+type jsonToken struct {
+	Class    string `json:"class"`
+	Human    string `json:"human,omitempty"`
+	Lexeme   string `json:"lexeme"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	FullLine string `json:"full_line,omitempty"`
+}
+
+// MarshalTokenJSON encodes tok as a JSON object that gives the ID and human
+// name of its class, its lexeme, and its position in source text. The result
+// can be decoded with UnmarshalTokenJSON.
+func MarshalTokenJSON(tok Token) ([]byte, error) {
+	jt := jsonToken{
+		Class:    tok.Class().ID(),
+		Human:    tok.Class().Human(),
+		Lexeme:   tok.Lexeme(),
+		Line:     tok.Line(),
+		Col:      tok.LinePos(),
+		FullLine: tok.FullLine(),
+	}
+	return json.Marshal(jt)
+}
+
+// UnmarshalTokenJSON decodes a Token from JSON created by MarshalTokenJSON. The
+// class of the returned Token is a new TokenClass with the encoded ID and human
+// name.
+func UnmarshalTokenJSON(data []byte) (Token, error) {
+	var jt jsonToken
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return nil, err
+	}
+	if jt.Class == "" {
+		return nil, fmt.Errorf("token has no class")
+	}
+
+	human := jt.Human
+	if human == "" {
+		human = jt.Class
+	}
+
+	return NewToken(NewTokenClass(jt.Class, human), jt.Lexeme, jt.Col, jt.Line, jt.FullLine), nil
+}
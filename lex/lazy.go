@@ -1,6 +1,8 @@
 package lex
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -8,6 +10,8 @@ import (
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
 )
 
 var (
@@ -50,6 +54,21 @@ type lazyTokenStream struct {
 
 	// listener is called whenever a token is produced
 	listener func(Token)
+
+	// text is the full text being lexed. It is only set for streams that are
+	// used to build a Snapshot.
+	text string
+
+	// ctx stops lexing when it is done.
+	ctx context.Context
+
+	// maxTokens is the maximum number of tokens to lex, or 0 for no limit.
+	// count is the number lexed so far.
+	maxTokens int
+	count     int
+
+	// file is the name of the input, given to every token lexed from it.
+	file string
 }
 
 // LazyLex returns a token stream that reads the provided input only as much as
@@ -57,13 +76,31 @@ type lazyTokenStream struct {
 // call to Next(). If any lexing errors occur, they will be returned as an Error
 // token from the stream's Next() method.
 func (lx *lexerTemplate) LazyLex(input io.Reader) (TokenStream, error) {
+	return lx.lazyLex(context.Background(), input, Limits{})
+}
+
+// lazyLex is the same as LazyLex but the returned stream stops lexing when ctx
+// is done or lim is exceeded.
+func (lx *lexerTemplate) lazyLex(ctx context.Context, input io.Reader, lim Limits) (TokenStream, error) {
+	file := inputName(input)
+	if lim.MaxInputSize > 0 {
+		input = &sizeLimitReader{r: input, max: lim.MaxInputSize}
+	}
+
+	// the lexer is only needed until everything has been copied from it, so
+	// its lock is released before any input is read.
+	lx.mu.RLock()
+
 	active := &lazyTokenStream{
-		r:        newRegexReader(input),
-		patterns: make(map[string]*regexp.Regexp),
-		classes:  make(map[string]map[string]TokenClass),
-		actions:  make(map[string][]Action),
-		state:    lx.StartingState(),
-		listener: lx.listener,
+		ctx:       ctx,
+		maxTokens: lim.MaxTokens,
+		r:         newRegexReader(input),
+		patterns:  make(map[string]*regexp.Regexp),
+		classes:   make(map[string]map[string]TokenClass),
+		actions:   make(map[string][]Action),
+		state:     lx.startState,
+		listener:  lx.listener,
+		file:      file,
 	}
 
 	// move all patterns into "super pattern"; one per state. and separate the
@@ -126,6 +163,7 @@ func (lx *lexerTemplate) LazyLex(input io.Reader) (TokenStream, error) {
 		compiled, err := regexp.Compile(superRegex.String())
 		if err != nil {
 			// should never happen
+			lx.mu.RUnlock()
 			return nil, fmt.Errorf("composing token regexes: %w", err)
 		}
 
@@ -150,6 +188,7 @@ func (lx *lexerTemplate) LazyLex(input io.Reader) (TokenStream, error) {
 
 		active.classes[k] = stateClasses
 	}
+	lx.mu.RUnlock()
 
 	// set current line and pos
 	active.curLine = 1
@@ -168,6 +207,10 @@ func (lx *lazyTokenStream) Next() Token {
 	if lx.done {
 		return lx.makeEOTToken()
 	}
+	if err := lx.ctx.Err(); err != nil {
+		lx.done = true
+		return lx.makeStopToken(err)
+	}
 
 	// the rule that you get all default states along with whatever state
 	pat := lx.patterns[lx.state]
@@ -325,6 +368,12 @@ func (lx *lazyTokenStream) Next() Token {
 
 		// return token if we do that now
 		if retToken {
+			lx.count++
+			if lx.maxTokens > 0 && lx.count > lx.maxTokens {
+				lx.done = true
+				return lx.makeStopToken(&syntaxerr.LimitError{Limit: syntaxerr.LimitTokens, Max: lx.maxTokens})
+			}
+
 			if lx.listener != nil {
 				lx.listener(tok)
 			}
@@ -344,6 +393,7 @@ func (lx *lazyTokenStream) Peek() Token {
 	oldPos := lx.curPos
 	oldDone := lx.done
 	oldPanic := lx.panicMode
+	oldCount := lx.count
 
 	// disable the listener quick, and run lexing as normal
 	oldListner := lx.listener
@@ -359,6 +409,7 @@ func (lx *lazyTokenStream) Peek() Token {
 	lx.curPos = oldPos
 	lx.done = oldDone
 	lx.panicMode = oldPanic
+	lx.count = oldCount
 
 	// and finally, return the token
 	return tok
@@ -376,6 +427,7 @@ func (lx *lazyTokenStream) makeToken(class TokenClass, lexeme string) Token {
 		linePos: lx.curPos,
 		lineNum: lx.curLine,
 		lexed:   lexeme,
+		file:    lx.file,
 	}
 }
 
@@ -399,9 +451,22 @@ func (lx *lazyTokenStream) tokenForIOError(err error) Token {
 		lx.panicMode = false
 		return lx.makeEOTToken()
 	}
+
+	var limitErr *syntaxerr.LimitError
+	if errors.As(err, &limitErr) {
+		return lx.makeStopToken(limitErr)
+	}
 	return lx.makeErrorTokenf("I/O error: %s", err.Error())
 }
 
+// makeStopToken returns an error token for lexing being stopped due to err.
+func (lx *lazyTokenStream) makeStopToken(err error) Token {
+	return stopToken{
+		lexerToken: lx.makeToken(TokenError, err.Error()).(lexerToken),
+		err:        err,
+	}
+}
+
 // select match from slice of all regex matches. If there is exactly 1 match,
 // return that. assumes that the first element of candidates is a 'full match'
 // and therefore useless, and that blank entries in subsequent indexes indicates
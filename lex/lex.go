@@ -15,22 +15,28 @@
 package lex
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"regexp"
+	"sync"
 
 	"github.com/dekarrin/ictiobus/internal/unregex"
 )
 
 // A Lexer represents an in-progress or ready-built lexing engine ready for use.
 // It can be stored as a byte representation and retrieved from bytes as well.
+//
+// The Lexers in this package are safe for concurrent use by multiple
+// goroutines. Each TokenStream has all of the state for lexing its own input,
+// but a single TokenStream must not be used by more than one goroutine at once.
 type Lexer interface {
 
 	// Lex returns a token stream. The tokens may be lexed in a lazy fashion or
 	// an immediate fashion; if it is immediate, errors will be returned at that
-	// point. If it is lazy, then error token productions will be returned to
-	// the callers of the returned TokenStream at the point where the error
-	// occured.
+	// point as a syntaxerr.Diagnostics. If it is lazy, then error token
+	// productions will be returned to the callers of the returned TokenStream
+	// at the point where the error occured.
 	Lex(input io.Reader) (TokenStream, error)
 
 	// RegisterClass registers a token class for use in some state of the Lexer.
@@ -70,6 +76,47 @@ type Lexer interface {
 	RegisterTraceListener(func(t Token))
 }
 
+// ContextLexer is a Lexer that can stop lexing when a context is done or when
+// its input exceeds limits. The Lexers in this package implement it. Use
+// the LexContext function to lex with a Lexer that might not.
+type ContextLexer interface {
+	Lexer
+
+	// LexContext is the same as Lex but stops lexing when ctx is done or when
+	// the input exceeds one of the given limits. For an immediate lexer, the
+	// error is returned at that point; this is ctx.Err() if ctx is done, or a
+	// *syntaxerr.LimitError if a limit was exceeded. For a lazy lexer, the
+	// stream gives an error token at the point where lexing stopped, and the
+	// error can be retrieved from that token with TokenErr.
+	LexContext(ctx context.Context, input io.Reader, lim Limits) (TokenStream, error)
+}
+
+// LexContext lexes input with lx, stopping when ctx is done or when the input
+// exceeds one of the given limits. If lx is a ContextLexer, this is the same
+// as calling its LexContext method. Otherwise, the input and the stream that
+// lx gives for it are wrapped so that reading more than lim.MaxInputSize bytes
+// gives a *syntaxerr.LimitError to lx, and the stream gives an error token for
+// TokenErr in the same way as a lazy lexer once ctx is done or once it has
+// given lim.MaxTokens tokens.
+func LexContext(ctx context.Context, lx Lexer, input io.Reader, lim Limits) (TokenStream, error) {
+	if clx, ok := lx.(ContextLexer); ok {
+		return clx.LexContext(ctx, input, lim)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if lim.MaxInputSize > 0 {
+		input = &sizeLimitReader{r: input, max: lim.MaxInputSize}
+	}
+
+	stream, err := lx.Lex(input)
+	if err != nil {
+		return stream, err
+	}
+	return &limitStream{ts: stream, ctx: ctx, max: lim.MaxTokens}, nil
+}
+
 type patAct struct {
 	priority int
 	rx       *regexp.Regexp
@@ -79,6 +126,12 @@ type patAct struct {
 type lexerTemplate struct {
 	lazy bool
 
+	// mu guards the fields below it, so that the lexer can be set up while
+	// it is being used to lex input in other goroutines. Each call to Lex
+	// copies what it needs while holding it, so the stream it returns does
+	// not use the lexer again.
+	mu sync.RWMutex
+
 	patterns   map[string][]patAct
 	startState string
 
@@ -101,28 +154,40 @@ func NewLexer(lazy bool) Lexer {
 
 // Lex returns a stream of tokens lexed from the given input.
 func (lx *lexerTemplate) Lex(input io.Reader) (TokenStream, error) {
+	return lx.LexContext(context.Background(), input, Limits{})
+}
+
+// LexContext returns a stream of tokens lexed from the given input, stopping
+// if ctx is done or if the input exceeds lim.
+func (lx *lexerTemplate) LexContext(ctx context.Context, input io.Reader, lim Limits) (TokenStream, error) {
 	if lx.lazy {
-		return lx.LazyLex(input)
+		return lx.lazyLex(ctx, input, lim)
 	} else {
-		return lx.ImmediatelyLex(input)
+		return lx.immediatelyLex(ctx, input, lim)
 	}
 }
 
 // SetStartingState sets the initial state of the lexer. If not set, the
 // starting state will be the default state.
 func (lx *lexerTemplate) SetStartingState(s string) {
+	lx.mu.Lock()
+	defer lx.mu.Unlock()
 	lx.startState = s
 }
 
 // StartingState returns the initial state of the lexer. If one wasn't set, this
 // will be the default state, "".
 func (lx *lexerTemplate) StartingState() string {
+	lx.mu.RLock()
+	defer lx.mu.RUnlock()
 	return lx.startState
 }
 
 // RegisterTraceListener provides a function to call whenever a new token is
 // lexed. It can be used for debug purposes.
 func (lx *lexerTemplate) RegisterTraceListener(fn func(t Token)) {
+	lx.mu.Lock()
+	defer lx.mu.Unlock()
 	lx.listener = fn
 }
 
@@ -133,6 +198,9 @@ func (lx *lexerTemplate) RegisterTraceListener(fn func(t Token)) {
 // If the given token class's ID() returns a string matching one already added,
 // the provided one will replace the existing one.
 func (lx *lexerTemplate) RegisterClass(cl TokenClass, forState string) {
+	lx.mu.Lock()
+	defer lx.mu.Unlock()
+
 	stateClasses, ok := lx.classes[forState]
 	if !ok {
 		stateClasses = map[string]TokenClass{}
@@ -144,6 +212,9 @@ func (lx *lexerTemplate) RegisterClass(cl TokenClass, forState string) {
 
 // AddPattern adds a new pattern and action to take to the lexer.
 func (lx *lexerTemplate) AddPattern(pat string, action Action, forState string, priority int) error {
+	lx.mu.Lock()
+	defer lx.mu.Unlock()
+
 	statePatterns, ok := lx.patterns[forState]
 	if !ok {
 		statePatterns = make([]patAct, 0)
@@ -200,6 +271,9 @@ func (lx *lexerTemplate) AddPattern(pat string, action Action, forState string,
 // FakeLexemeProducer does not produce a lexable value, then it can be replaced
 // manually by replacing that entry in the map with a custom function.
 func (lx *lexerTemplate) FakeLexemeProducer(combine bool, state string) map[string]func() string {
+	lx.mu.RLock()
+	defer lx.mu.RUnlock()
+
 	mapperFunc := map[string]func() string{}
 	funcsForIDByState := map[string]map[string][]func() string{}
 	unregexers := map[string]*unregex.Unregexer{}
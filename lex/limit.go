@@ -0,0 +1,157 @@
+package lex
+
+import (
+	"context"
+	"io"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// Limits are limits on the input that a Lexer will lex. A limit of 0 means
+// there is no limit.
+type Limits struct {
+	// MaxInputSize is the maximum number of bytes that will be read from the
+	// input.
+	MaxInputSize int
+
+	// MaxTokens is the maximum number of tokens that will be lexed, not
+	// counting the end of text token.
+	MaxTokens int
+}
+
+// TokenErr returns the error that stopped lexing if tok is the error token that
+// a lexer gave when it was stopped due to its context being done or a limit
+// being exceeded. For all other tokens, including other error tokens, it
+// returns nil.
+func TokenErr(tok Token) error {
+	if st, ok := tok.(stopToken); ok {
+		return st.err
+	}
+	return nil
+}
+
+// stopToken is an error token for lexing being stopped.
+type stopToken struct {
+	lexerToken
+	err error
+}
+
+// sizeLimitReader is an io.Reader that reads from r until more than max bytes
+// would be read, at which point it returns a *syntaxerr.LimitError for every
+// read after.
+type sizeLimitReader struct {
+	r        io.Reader
+	max      int
+	n        int
+	exceeded bool
+}
+
+// Name returns the name of the wrapped input, so that wrapping it does not
+// hide it from the lexer.
+func (slr *sizeLimitReader) Name() string {
+	return inputName(slr.r)
+}
+
+// Read reads up to len(p) bytes into p.
+func (slr *sizeLimitReader) Read(p []byte) (int, error) {
+	if slr.exceeded {
+		return 0, &syntaxerr.LimitError{Limit: syntaxerr.LimitInputSize, Max: slr.max}
+	}
+
+	if slr.n >= slr.max {
+		// only an error if there is actually more input.
+		var probe [1]byte
+		n, err := slr.r.Read(probe[:])
+		if n > 0 {
+			slr.exceeded = true
+			return 0, &syntaxerr.LimitError{Limit: syntaxerr.LimitInputSize, Max: slr.max}
+		}
+		return 0, err
+	}
+
+	if len(p) > slr.max-slr.n {
+		p = p[:slr.max-slr.n]
+	}
+	n, err := slr.r.Read(p)
+	slr.n += n
+	return n, err
+}
+
+// limitStream is a TokenStream that gives the tokens of another one until ctx
+// is done or max tokens have been given, at which point it gives a stopToken
+// and then only the end of text. It is used to apply a context and limits to
+// the stream of a Lexer that is not a ContextLexer.
+type limitStream struct {
+	ts    TokenStream
+	ctx   context.Context
+	max   int
+	count int
+	done  bool
+}
+
+// Next returns the next token in the stream and advances the stream by one
+// token.
+func (ls *limitStream) Next() Token {
+	tok := ls.Peek()
+	if ls.done {
+		return tok
+	}
+
+	if _, ok := tok.(stopToken); ok {
+		ls.done = true
+		return tok
+	}
+
+	ls.ts.Next()
+	if tok.Class().ID() != TokenEndOfText.ID() {
+		ls.count++
+	}
+	return tok
+}
+
+// Peek returns the next token in the stream without advancing the stream.
+func (ls *limitStream) Peek() Token {
+	tok := ls.ts.Peek()
+	if ls.done {
+		return ls.endOfText(tok)
+	}
+
+	if err := ls.ctx.Err(); err != nil {
+		return ls.stopAt(tok, err)
+	}
+	if ls.max > 0 && ls.count >= ls.max && tok.Class().ID() != TokenEndOfText.ID() {
+		return ls.stopAt(tok, &syntaxerr.LimitError{Limit: syntaxerr.LimitTokens, Max: ls.max})
+	}
+	return tok
+}
+
+// HasNext returns whether the stream has any additional tokens.
+func (ls *limitStream) HasNext() bool {
+	return !ls.done && ls.ts.HasNext()
+}
+
+// stopAt returns a stopToken for err at the location of tok.
+func (ls *limitStream) stopAt(tok Token, err error) Token {
+	return stopToken{
+		lexerToken: lexerToken{
+			class:   TokenError,
+			lexed:   err.Error(),
+			linePos: tok.LinePos(),
+			lineNum: tok.Line(),
+			line:    tok.FullLine(),
+			file:    FileOf(tok),
+		},
+		err: err,
+	}
+}
+
+// endOfText returns an end of text token at the location of tok.
+func (ls *limitStream) endOfText(tok Token) Token {
+	return lexerToken{
+		class:   TokenEndOfText,
+		linePos: tok.LinePos(),
+		lineNum: tok.Line(),
+		line:    tok.FullLine(),
+		file:    FileOf(tok),
+	}
+}
@@ -0,0 +1,217 @@
+package lex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Lexer_LexContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		input     string
+		lim       Limits
+		expectErr error
+
+		// expectLexed is the number of tokens that the lazy lexer is expected
+		// to give before the error token.
+		expectLexed int
+	}{
+		{
+			name:        "no limits",
+			ctx:         context.Background(),
+			input:       "1 + 2 * 3",
+			expectLexed: 5,
+		},
+		{
+			name:        "within limits",
+			ctx:         context.Background(),
+			input:       "1 + 2 * 3",
+			lim:         Limits{MaxInputSize: 9, MaxTokens: 5},
+			expectLexed: 5,
+		},
+		{
+			name:        "input too large",
+			ctx:         context.Background(),
+			input:       "1 + 2 * 3",
+			lim:         Limits{MaxInputSize: 8},
+			expectErr:   syntaxerr.ErrInputTooLarge,
+			expectLexed: 4,
+		},
+		{
+			name:        "too many tokens",
+			ctx:         context.Background(),
+			input:       "1 + 2 * 3",
+			lim:         Limits{MaxTokens: 3},
+			expectErr:   syntaxerr.ErrTooManyTokens,
+			expectLexed: 3,
+		},
+		{
+			name:        "canceled",
+			ctx:         canceled,
+			input:       "1 + 2 * 3",
+			expectErr:   context.Canceled,
+			expectLexed: 0,
+		},
+	}
+
+	newLexer := func(lazy bool) Lexer {
+		lx := NewLexer(lazy)
+		lx.RegisterClass(testClassInt, "")
+		lx.RegisterClass(testClassPlus, "")
+		lx.RegisterClass(testClassMult, "")
+		lx.AddPattern(`[0-9]+`, LexAs(testClassInt.ID()), "", 0)
+		lx.AddPattern(`\+`, LexAs(testClassPlus.ID()), "", 0)
+		lx.AddPattern(`\*`, LexAs(testClassMult.ID()), "", 0)
+		lx.AddPattern(`\s+`, Discard(), "", 0)
+		return lx
+	}
+
+	for _, tc := range testCases {
+		t.Run("lazy "+tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			stream, err := LexContext(tc.ctx, newLexer(true), strings.NewReader(tc.input), tc.lim)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var lexed int
+			var last Token
+			for {
+				last = stream.Next()
+				if last.Class().ID() == TokenEndOfText.ID() || last.Class().ID() == TokenError.ID() {
+					break
+				}
+				lexed++
+			}
+
+			assert.Equal(tc.expectLexed, lexed)
+			if tc.expectErr != nil {
+				assert.Equal(TokenError.ID(), last.Class().ID())
+				assert.True(errors.Is(TokenErr(last), tc.expectErr), "expected %v, got %v", tc.expectErr, TokenErr(last))
+				assert.Equal(TokenEndOfText.ID(), stream.Next().Class().ID(), "stream does not end after stop")
+			} else {
+				assert.Equal(TokenEndOfText.ID(), last.Class().ID())
+				assert.NoError(TokenErr(last))
+			}
+		})
+		t.Run("immediate "+tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := LexContext(tc.ctx, newLexer(false), strings.NewReader(tc.input), tc.lim)
+
+			if tc.expectErr != nil {
+				assert.True(errors.Is(err, tc.expectErr), "expected %v, got %v", tc.expectErr, err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+// plainLexer is a Lexer that is not a ContextLexer.
+type plainLexer struct {
+	Lexer
+}
+
+func Test_LexContext_notContextLexer(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name        string
+		ctx         context.Context
+		lim         Limits
+		expectErr   error
+		expectLexed int
+	}{
+		{
+			name:        "no limits",
+			ctx:         context.Background(),
+			expectLexed: 5,
+		},
+		{
+			name:        "input too large",
+			lim:         Limits{MaxInputSize: 4},
+			ctx:         context.Background(),
+			expectErr:   syntaxerr.ErrInputTooLarge,
+			expectLexed: 2,
+		},
+		{
+			name:        "too many tokens",
+			ctx:         context.Background(),
+			lim:         Limits{MaxTokens: 3},
+			expectErr:   syntaxerr.ErrTooManyTokens,
+			expectLexed: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			lx := NewLexer(true)
+			lx.RegisterClass(testClassInt, "")
+			lx.RegisterClass(testClassPlus, "")
+			lx.RegisterClass(testClassMult, "")
+			lx.AddPattern(`[0-9]+`, LexAs(testClassInt.ID()), "", 0)
+			lx.AddPattern(`\+`, LexAs(testClassPlus.ID()), "", 0)
+			lx.AddPattern(`\*`, LexAs(testClassMult.ID()), "", 0)
+			lx.AddPattern(`\s+`, Discard(), "", 0)
+
+			stream, err := LexContext(tc.ctx, plainLexer{lx}, strings.NewReader("1 + 2 * 3"), tc.lim)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var lexed int
+			var last Token
+			for {
+				last = stream.Next()
+				if last.Class().ID() == TokenEndOfText.ID() || last.Class().ID() == TokenError.ID() {
+					break
+				}
+				lexed++
+			}
+
+			assert.Equal(tc.expectLexed, lexed)
+			if tc.expectErr != nil {
+				assert.True(errors.Is(TokenErr(last), tc.expectErr), "expected %v, got %v", tc.expectErr, TokenErr(last))
+				assert.Equal(TokenEndOfText.ID(), stream.Next().Class().ID(), "stream does not end after stop")
+			} else {
+				assert.Equal(TokenEndOfText.ID(), last.Class().ID())
+			}
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		_, err := LexContext(canceled, plainLexer{NewLexer(true)}, strings.NewReader("1"), Limits{})
+		assert.True(t, errors.Is(err, context.Canceled), "expected %v, got %v", context.Canceled, err)
+	})
+}
+
+func Test_TokenErr_otherErrorToken(t *testing.T) {
+	lx := NewLexer(true)
+	lx.RegisterClass(testClassInt, "")
+	lx.AddPattern(`[0-9]+`, LexAs(testClassInt.ID()), "", 0)
+	lx.AddPattern(`\s+`, Discard(), "", 0)
+
+	stream, err := lx.Lex(strings.NewReader("1 % 1"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	stream.Next()
+	tok := stream.Next()
+	assert.Equal(t, TokenError.ID(), tok.Class().ID())
+	assert.NoError(t, TokenErr(tok))
+}
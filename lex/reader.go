@@ -20,6 +20,11 @@ type regexReader struct {
 	marks map[string]int
 	atEOF bool
 
+	// extent is the offset just past the furthest byte that has been read
+	// since the last call to ResetExtent. Reaching the end of input counts as
+	// reading one byte past it.
+	extent int
+
 	// lastReadRuneErr only has value set when this is set to a ptr destination
 	lastReadRuneErr *error
 }
@@ -296,9 +301,30 @@ func (rr *regexReader) Read(p []byte) (n int, err error) {
 	// we've now read everyfin we can. copy it to p.
 	n = len(read)
 	copy(p, read)
+
+	if rr.cur > rr.extent {
+		rr.extent = rr.cur
+	}
+	if n < len(p) && rr.cur+1 > rr.extent {
+		// we looked for the end of input
+		rr.extent = rr.cur + 1
+	}
+
 	return n, err
 }
 
+// ResetExtent sets the extent of the reader to its current offset.
+func (rr *regexReader) ResetExtent() {
+	rr.extent = rr.cur
+}
+
+// Extent returns the offset just past the furthest byte that has been read
+// since the last call to ResetExtent, including bytes read only to look ahead
+// and bytes that were later un-read with Restore or Seek.
+func (rr *regexReader) Extent() int {
+	return rr.extent
+}
+
 // Seek moves the internal cursor to the provided offset. As seekableReader
 // itself reads from an underlying Reader whose end is unknown, SeekEnd will be
 // interpreted as relative to the end of the *buffered* bytes, not those in the
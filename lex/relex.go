@@ -0,0 +1,339 @@
+package lex
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// Edit is a change made to source text. It replaces the bytes of the text from
+// offset Start up to but not including offset End with Text. An insertion has
+// a Start equal to its End, and a deletion has an empty Text.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Apply returns the result of making the edit to text. It panics if the edit
+// is not within text.
+func (e Edit) Apply(text string) string {
+	return text[:e.Start] + e.Text + text[e.End:]
+}
+
+// validate returns an error if the edit cannot be made to text.
+func (e Edit) validate(text string) error {
+	if e.Start < 0 || e.End < e.Start || e.End > len(text) {
+		return fmt.Errorf("edit range %d-%d is not within text of length %d", e.Start, e.End, len(text))
+	}
+	return nil
+}
+
+// TokenDiff describes how the tokens of a Snapshot changed when it was relexed
+// after an edit. The tokens before Prefix and the tokens after the old and new
+// suffix indexes were lexed the same way before and after the edit; they have
+// the same class and lexeme, although the positions of the tokens in the
+// suffix may have moved.
+type TokenDiff struct {
+	// Prefix is the number of tokens at the start of the old and new tokens
+	// that are the same.
+	Prefix int
+
+	// OldSuffix is the index of the first of the old tokens at the end of
+	// input that are the same as the new tokens starting at NewSuffix.
+	OldSuffix int
+
+	// NewSuffix is the index of the first of the new tokens at the end of
+	// input that are the same as the old tokens starting at OldSuffix.
+	NewSuffix int
+}
+
+// Map returns the index in the new tokens of the token at index old in the old
+// tokens. If the token was changed by the edit, ok will be false.
+func (td TokenDiff) Map(old int) (idx int, ok bool) {
+	if old < td.Prefix {
+		return old, true
+	}
+	if old >= td.OldSuffix {
+		return old - td.OldSuffix + td.NewSuffix, true
+	}
+	return 0, false
+}
+
+// Snapshot is source text along with all tokens lexed from it, kept so that the
+// text can be lexed again incrementally with Relex after it is edited. Unlike
+// the TokenStream returned by Lex, the tokens of a Snapshot may include error
+// tokens for input that could not be lexed.
+type Snapshot struct {
+	lexer  Lexer
+	text   string
+	tokens []Token
+
+	// marks holds the lexing state that each token in tokens was lexed from.
+	// It is nil if lexer does not support incremental lexing.
+	marks []lexMark
+}
+
+// lexMark is the state that a lexer was in just before it lexed a token, and
+// how much of the input lexing the token depended on.
+type lexMark struct {
+	offset    int
+	state     string
+	line      int
+	pos       int
+	panicMode bool
+
+	// extent is the offset just past the furthest byte of input that lexing
+	// the token examined, including the full line the token is on.
+	extent int
+}
+
+// LexSnapshot lexes all of text with lx and returns a Snapshot of it. If lx was
+// not created by this package it cannot be lexed incrementally, and calling
+// Relex on the returned Snapshot will lex all of the edited text again.
+func LexSnapshot(lx Lexer, text string) (*Snapshot, error) {
+	snap := &Snapshot{lexer: lx, text: text}
+
+	lt, ok := lx.(*lexerTemplate)
+	if !ok {
+		stream, err := lx.Lex(strings.NewReader(text))
+		if err != nil {
+			return nil, err
+		}
+		snap.tokens = drainStream(stream)
+		return snap, nil
+	}
+
+	stream, err := lt.lexFrom(text, 0, lexMark{state: lt.StartingState(), line: 1, pos: 1})
+	if err != nil {
+		return nil, err
+	}
+	snap.tokens, snap.marks = stream.lexRest(0, nil)
+	return snap, nil
+}
+
+// Text returns the source text that the Snapshot was lexed from.
+func (snap *Snapshot) Text() string {
+	return snap.text
+}
+
+// Tokens returns all tokens lexed from the text of the Snapshot. The last one
+// is always of class TokenEndOfText. The returned slice must not be modified.
+func (snap *Snapshot) Tokens() []Token {
+	return snap.tokens
+}
+
+// Stream returns a TokenStream that gives the tokens of the Snapshot.
+func (snap *Snapshot) Stream() TokenStream {
+	return &immediateTokenStream{tokens: snap.tokens}
+}
+
+// Err returns a syntaxerr.Diagnostics for the first error token in the
+// Snapshot, or nil if the text was lexed without errors. The error is the same
+// one that the immediate lexer would have returned for the text.
+func (snap *Snapshot) Err() error {
+	for _, tok := range snap.tokens {
+		if tok.Class().ID() == TokenError.ID() {
+			tokWrap := lexerToken{
+				class:   tok.Class(),
+				linePos: tok.LinePos(),
+				line:    tok.FullLine(),
+				lineNum: tok.Line(),
+				file:    FileOf(tok),
+			}
+			return syntaxerr.Diagnostics{NewDiagnosticFromToken(syntaxerr.CodeLexical, tok.Lexeme(), tokWrap)}
+		}
+	}
+	return nil
+}
+
+// Relex makes an edit to the text of the Snapshot and returns a new Snapshot of
+// the result. Only the tokens whose lexing could have been affected by the edit
+// are lexed again, from just before the first token that examined any of the
+// edited text, up until the lexer reaches a point after the edit where it is in
+// the same state as it was for one of the old tokens. The rest of the old
+// tokens are then reused. The returned TokenDiff gives which of the tokens are
+// the same as the old ones. snap itself is not modified.
+func (snap *Snapshot) Relex(edit Edit) (*Snapshot, TokenDiff, error) {
+	if err := edit.validate(snap.text); err != nil {
+		return nil, TokenDiff{}, err
+	}
+	newText := edit.Apply(snap.text)
+
+	lt, ok := snap.lexer.(*lexerTemplate)
+	if !ok || snap.marks == nil {
+		newSnap, err := LexSnapshot(snap.lexer, newText)
+		if err != nil {
+			return nil, TokenDiff{}, err
+		}
+		diff := TokenDiff{OldSuffix: len(snap.tokens), NewSuffix: len(newSnap.tokens)}
+		return newSnap, diff, nil
+	}
+
+	// restart at the first token that examined any of the edited text. all
+	// tokens before it are unaffected.
+	restart := 0
+	for restart < len(snap.marks)-1 && snap.marks[restart].extent < edit.Start {
+		restart++
+	}
+	from := snap.marks[restart]
+
+	stream, err := lt.lexFrom(newText, from.offset, from)
+	if err != nil {
+		return nil, TokenDiff{}, err
+	}
+
+	offsetDelta := len(edit.Text) - (edit.End - edit.Start)
+	lineDelta := strings.Count(edit.Text, "\n") - strings.Count(snap.text[edit.Start:edit.End], "\n")
+	editEnd := edit.Start + len(edit.Text)
+
+	// the old tokens can be reused once the lexer is in the same state as it
+	// was for one of them, on a line that starts after the edit.
+	var resumeAt int
+	canResume := func(m lexMark) bool {
+		if m.offset < editEnd || strings.LastIndexByte(newText[:m.offset], '\n')+1 < editEnd {
+			return false
+		}
+		oldOffset := m.offset - offsetDelta
+		idx := sort.Search(len(snap.marks), func(i int) bool { return snap.marks[i].offset >= oldOffset })
+		for ; idx < len(snap.marks) && snap.marks[idx].offset == oldOffset; idx++ {
+			old := snap.marks[idx]
+			if old.state == m.state && old.panicMode == m.panicMode {
+				resumeAt = idx
+				return true
+			}
+		}
+		return false
+	}
+
+	relexed, relexedMarks := stream.lexRest(from.offset, canResume)
+
+	newSnap := &Snapshot{lexer: snap.lexer, text: newText}
+	newSnap.tokens = append(newSnap.tokens, snap.tokens[:restart]...)
+	newSnap.tokens = append(newSnap.tokens, relexed...)
+	newSnap.marks = append(newSnap.marks, snap.marks[:restart]...)
+	newSnap.marks = append(newSnap.marks, relexedMarks...)
+
+	diff := TokenDiff{Prefix: restart, OldSuffix: len(snap.tokens), NewSuffix: len(newSnap.tokens)}
+	if len(relexed) == 0 || relexed[len(relexed)-1].Class().ID() != TokenEndOfText.ID() {
+		// lexing stopped early to reuse the rest of the old tokens.
+		diff.OldSuffix = resumeAt
+		diff.NewSuffix = len(newSnap.tokens)
+		for i := resumeAt; i < len(snap.tokens); i++ {
+			newSnap.tokens = append(newSnap.tokens, shiftToken(snap.tokens[i], lineDelta))
+
+			m := snap.marks[i]
+			m.offset += offsetDelta
+			m.extent += offsetDelta
+			m.line += lineDelta
+			newSnap.marks = append(newSnap.marks, m)
+		}
+	}
+
+	// the edit may not have changed every token that was lexed again.
+	for diff.Prefix < diff.NewSuffix && diff.Prefix < diff.OldSuffix && sameLexing(snap.tokens[diff.Prefix], newSnap.tokens[diff.Prefix]) {
+		diff.Prefix++
+	}
+	for diff.NewSuffix > diff.Prefix && diff.OldSuffix > diff.Prefix && sameLexing(snap.tokens[diff.OldSuffix-1], newSnap.tokens[diff.NewSuffix-1]) {
+		diff.OldSuffix--
+		diff.NewSuffix--
+	}
+
+	return newSnap, diff, nil
+}
+
+// sameLexing returns whether two tokens have the same class and lexeme.
+func sameLexing(t1, t2 Token) bool {
+	return t1.Class().ID() == t2.Class().ID() && t1.Lexeme() == t2.Lexeme()
+}
+
+// shiftToken returns a copy of tok that is moved by the given number of lines.
+func shiftToken(tok Token, lines int) Token {
+	return NewToken(tok.Class(), tok.Lexeme(), tok.LinePos(), tok.Line()+lines, tok.FullLine())
+}
+
+// drainStream reads every token from stream up to and including the first end
+// of text token.
+func drainStream(stream TokenStream) []Token {
+	var toks []Token
+	for {
+		tok := stream.Next()
+		toks = append(toks, tok)
+		if tok.Class().ID() == TokenEndOfText.ID() {
+			return toks
+		}
+	}
+}
+
+// lexFrom returns a lazy token stream that lexes text starting at the given
+// byte offset, with the lexer in the state given by from.
+func (lx *lexerTemplate) lexFrom(text string, offset int, from lexMark) (*lazyTokenStream, error) {
+	ts, err := lx.LazyLex(strings.NewReader(text[offset:]))
+	if err != nil {
+		return nil, err
+	}
+	stream := ts.(*lazyTokenStream)
+
+	lineStart := strings.LastIndexByte(text[:offset], '\n') + 1
+	lineEnd := strings.IndexByte(text[offset:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(text)
+	} else {
+		lineEnd += offset
+	}
+
+	stream.state = from.state
+	stream.curLine = from.line
+	stream.curPos = from.pos
+	stream.curFullLine = text[lineStart:lineEnd]
+	stream.panicMode = from.panicMode
+	stream.text = text
+	return stream, nil
+}
+
+// lexRest lexes tokens from the stream until the end of text token, and returns
+// them along with the marks for each. base is the offset in the full text that
+// the stream started at. If stop is non-nil, it is called with the mark for
+// each token before it is lexed, and lexing ends before that token if it
+// returns true.
+func (lx *lazyTokenStream) lexRest(base int, stop func(m lexMark) bool) ([]Token, []lexMark) {
+	var toks []Token
+	var marks []lexMark
+	for {
+		m := lexMark{
+			offset:    base + int(lx.r.Offset()),
+			state:     lx.state,
+			line:      lx.curLine,
+			pos:       lx.curPos,
+			panicMode: lx.panicMode,
+		}
+		if stop != nil && stop(m) {
+			return toks, marks
+		}
+
+		lx.r.ResetExtent()
+		tok := lx.Next()
+
+		m.extent = base + lx.r.Extent()
+		if lineEnd := strings.IndexByte(lx.text[m.offset:], '\n'); lineEnd == -1 {
+			m.extent = maxInt(m.extent, len(lx.text))
+		} else {
+			m.extent = maxInt(m.extent, m.offset+lineEnd)
+		}
+
+		toks = append(toks, tok)
+		marks = append(marks, m)
+		if tok.Class().ID() == TokenEndOfText.ID() {
+			return toks, marks
+		}
+	}
+}
+
+func maxInt(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}
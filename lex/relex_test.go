@@ -0,0 +1,183 @@
+package lex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Snapshot_Relex(t *testing.T) {
+	testClassSlash := NewTokenClass("slash", "'/'")
+	testClassQuote := NewTokenClass("quote", "'\"'")
+	testClassStr := NewTokenClass("str", "string content")
+
+	newTestLexer := func() Lexer {
+		lx := NewLexer(true)
+		for _, cl := range allTestClasses {
+			lx.RegisterClass(cl, "")
+		}
+		lx.RegisterClass(testClassSlash, "")
+		lx.RegisterClass(testClassQuote, "")
+		lx.RegisterClass(testClassQuote, "str")
+		lx.RegisterClass(testClassStr, "str")
+
+		lx.AddPattern(`\+`, LexAs(testClassPlus.ID()), "", 0)
+		lx.AddPattern(`\*`, LexAs(testClassMult.ID()), "", 0)
+		lx.AddPattern(`/`, LexAs(testClassSlash.ID()), "", 0)
+		lx.AddPattern(`/\*(?:[^*]|\*[^/])*\*/`, Discard(), "", 0)
+		lx.AddPattern(`[0-9]+`, LexAs(testClassInt.ID()), "", 0)
+		lx.AddPattern(`[A-Za-z_][A-Za-z_0-9]*`, LexAs(testClassId.ID()), "", 0)
+		lx.AddPattern(`\s+`, Discard(), "", 0)
+		lx.AddPattern(`"`, LexAndSwapState(testClassQuote.ID(), "str"), "", 0)
+		lx.AddPattern(`[^"]+`, LexAs(testClassStr.ID()), "str", 0)
+		lx.AddPattern(`"`, LexAndSwapState(testClassQuote.ID(), ""), "str", 0)
+		return lx
+	}
+
+	input := "a + b\nc * 12\n\"text\" + d\ne + f\ng\n"
+
+	testCases := []struct {
+		name string
+		text string
+		edit Edit
+
+		// expectReuse is whether any of the old tokens are expected to be
+		// kept instead of being lexed again.
+		expectReuse bool
+	}{
+		{
+			name:        "insert into middle of token",
+			text:        input,
+			edit:        Edit{Start: 8, End: 8, Text: "x"},
+			expectReuse: true,
+		},
+		{
+			name:        "extend token at end of line",
+			text:        input,
+			edit:        Edit{Start: 12, End: 12, Text: "3"},
+			expectReuse: true,
+		},
+		{
+			name:        "insert new line",
+			text:        input,
+			edit:        Edit{Start: 6, End: 6, Text: "h + i\n"},
+			expectReuse: true,
+		},
+		{
+			name:        "delete across lines",
+			text:        input,
+			edit:        Edit{Start: 4, End: 10, Text: ""},
+			expectReuse: true,
+		},
+		{
+			name:        "replace at start",
+			text:        input,
+			edit:        Edit{Start: 0, End: 1, Text: "zed"},
+			expectReuse: true,
+		},
+		{
+			name:        "append at end",
+			text:        input,
+			edit:        Edit{Start: len(input), End: len(input), Text: "+ h"},
+			expectReuse: true,
+		},
+		{
+			name: "open string changes lexer state for rest of input",
+			text: input,
+			edit: Edit{Start: 2, End: 2, Text: "\""},
+		},
+		{
+			name:        "close string changes lexer state back",
+			text:        "a \"b + c\n",
+			edit:        Edit{Start: 9, End: 9, Text: "\" + d"},
+			expectReuse: true,
+		},
+		{
+			name:        "closing comment changes earlier tokens",
+			text:        "a + /* b * c d\n",
+			edit:        Edit{Start: 15, End: 15, Text: "*/ e"},
+			expectReuse: true,
+		},
+		{
+			name:        "edit causes lexing error",
+			text:        input,
+			edit:        Edit{Start: 2, End: 3, Text: "%"},
+			expectReuse: true,
+		},
+		{
+			name: "empty text",
+			text: "",
+			edit: Edit{Start: 0, End: 0, Text: "a + b"},
+		},
+		{
+			name: "delete everything",
+			text: input,
+			edit: Edit{Start: 0, End: len(input), Text: ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			lx := newTestLexer()
+
+			old, err := LexSnapshot(lx, tc.text)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var relexed int
+			lx.RegisterTraceListener(func(t Token) { relexed++ })
+
+			// execute
+			actual, diff, err := old.Relex(tc.edit)
+			lx.RegisterTraceListener(nil)
+
+			// assert
+			if !assert.NoError(err) {
+				return
+			}
+
+			expect, err := LexSnapshot(lx, tc.edit.Apply(tc.text))
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(expect.Text(), actual.Text())
+			if !assert.Len(actual.Tokens(), len(expect.Tokens())) {
+				return
+			}
+			for i := range expect.Tokens() {
+				assert.Equal(expect.Tokens()[i], actual.Tokens()[i], "token #%d", i)
+			}
+			assert.Equal(expect.marks, actual.marks, "marks do not match full lex")
+
+			oldToks := old.Tokens()
+			for i := range oldToks {
+				if newIdx, ok := diff.Map(i); ok {
+					assert.True(sameLexing(oldToks[i], actual.Tokens()[newIdx]), "old token #%d is not the same as new token #%d", i, newIdx)
+				}
+			}
+			assert.Equal(len(oldToks)-diff.OldSuffix, len(actual.Tokens())-diff.NewSuffix, "suffix lengths differ")
+
+			if tc.expectReuse {
+				// (the end of text token is not given to trace listeners)
+				assert.Less(relexed, len(actual.Tokens())-1, "all tokens were lexed again")
+			}
+		})
+	}
+}
+
+func Test_Snapshot_Relex_invalidEdit(t *testing.T) {
+	lx := NewLexer(true)
+	lx.RegisterClass(testClassId, "")
+	lx.AddPattern(`[a-z]+`, LexAs(testClassId.ID()), "", 0)
+
+	snap, err := LexSnapshot(lx, "abc")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, _, err = snap.Relex(Edit{Start: 2, End: 4})
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package lex
+
+import (
+	"fmt"
+
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// Position is a location in source text.
+type Position struct {
+	// Line is the 1-indexed line number of the position.
+	Line int `json:"line"`
+
+	// Col is the 1-indexed character-of-line of the position, counted the same
+	// way as a Token's LinePos.
+	Col int `json:"col"`
+}
+
+// String returns the string representation of the Position, in the form
+// LINE:COL.
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// Before returns whether p comes before o in source text.
+func (p Position) Before(o Position) bool {
+	if p.Line != o.Line {
+		return p.Line < o.Line
+	}
+	return p.Col < o.Col
+}
+
+// PositionAt returns the Position of the byte at offset in text, counting lines
+// and characters the same way that a Lexer does. If offset is past the end of
+// text, the Position just after the end of text is returned.
+func PositionAt(text string, offset int) Position {
+	pos := Position{Line: 1, Col: 1}
+	for i, ch := range text {
+		if i >= offset {
+			break
+		}
+		if ch == '\n' {
+			pos.Line++
+			pos.Col = 1
+		} else {
+			pos.Col++
+		}
+	}
+	return pos
+}
+
+// Span is a range of source text. Start is the position of the first character
+// in the range and End is the position just after the last one, so an empty
+// Span, such as for an epsilon production, has an End equal to its Start.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// SpanOf returns the Span of source text that tok was lexed from.
+func SpanOf(tok Token) Span {
+	start := Position{Line: tok.Line(), Col: tok.LinePos()}
+	end := start
+	for _, ch := range tok.Lexeme() {
+		if ch == '\n' {
+			end.Line++
+			end.Col = 1
+		} else {
+			end.Col++
+		}
+	}
+	return Span{Start: start, End: end}
+}
+
+// EmptySpanAt returns an empty Span located at the start of tok.
+func EmptySpanAt(tok Token) Span {
+	start := Position{Line: tok.Line(), Col: tok.LinePos()}
+	return Span{Start: start, End: start}
+}
+
+// InFile returns the syntaxerr.Span for s in the given file.
+func (s Span) InFile(file string) syntaxerr.Span {
+	return syntaxerr.Span{
+		File:  file,
+		Start: syntaxerr.Position{Line: s.Start.Line, Col: s.Start.Col},
+		End:   syntaxerr.Position{Line: s.End.Line, Col: s.End.Col},
+	}
+}
+
+// Empty returns whether the Span covers no source text.
+func (s Span) Empty() bool {
+	return s.Start == s.End
+}
+
+// Covering returns the smallest Span that covers every non-empty Span in spans,
+// which must be in the order they appear in source text. Empty spans do not
+// widen the result; if every span is empty, the first one is returned.
+func Covering(spans ...Span) Span {
+	var covering Span
+	var found bool
+	for _, s := range spans {
+		if s.Empty() {
+			continue
+		}
+		if !found {
+			covering = s
+			found = true
+		} else {
+			covering.End = s.End
+		}
+	}
+
+	if !found && len(spans) > 0 {
+		return spans[0]
+	}
+	return covering
+}
+
+// String returns the string representation of the Span, in the form
+// LINE:COL-LINE:COL.
+func (s Span) String() string {
+	return fmt.Sprintf("%s-%s", s.Start, s.End)
+}
@@ -0,0 +1,126 @@
+package lex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SpanOf(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  Token
+		expect string
+	}{
+		{
+			name:   "single-line token",
+			input:  NewToken(MakeDefaultClass("id"), "glub", 5, 2, "var glub = 3"),
+			expect: "2:5-2:9",
+		},
+		{
+			name:   "multi-line token",
+			input:  NewToken(MakeDefaultClass("str"), "\"glub\nglub\"", 3, 1, "x = \"glub"),
+			expect: "1:3-2:6",
+		},
+		{
+			name:   "empty token",
+			input:  NewToken(TokenEndOfText, "", 10, 4, "some text"),
+			expect: "4:10-4:10",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := SpanOf(tc.input)
+
+			assert.Equal(t, tc.expect, actual.String())
+		})
+	}
+}
+
+func Test_Covering(t *testing.T) {
+	empty := func(line, col int) Span {
+		return Span{Start: Position{line, col}, End: Position{line, col}}
+	}
+	span := func(startLine, startCol, endLine, endCol int) Span {
+		return Span{Start: Position{startLine, startCol}, End: Position{endLine, endCol}}
+	}
+
+	testCases := []struct {
+		name   string
+		input  []Span
+		expect Span
+	}{
+		{
+			name:   "no spans",
+			input:  nil,
+			expect: Span{},
+		},
+		{
+			name:   "all empty",
+			input:  []Span{empty(1, 4), empty(1, 4)},
+			expect: empty(1, 4),
+		},
+		{
+			name:   "empty spans at ends are ignored",
+			input:  []Span{empty(1, 1), span(1, 1, 1, 3), span(1, 4, 2, 2), empty(2, 3)},
+			expect: span(1, 1, 2, 2),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := Covering(tc.input...)
+
+			assert.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func Test_PositionAt(t *testing.T) {
+	testCases := []struct {
+		name   string
+		text   string
+		offset int
+		expect Position
+	}{
+		{
+			name:   "start of text",
+			text:   "abc\ndef",
+			offset: 0,
+			expect: Position{Line: 1, Col: 1},
+		},
+		{
+			name:   "middle of first line",
+			text:   "abc\ndef",
+			offset: 2,
+			expect: Position{Line: 1, Col: 3},
+		},
+		{
+			name:   "after newline",
+			text:   "abc\ndef",
+			offset: 5,
+			expect: Position{Line: 2, Col: 2},
+		},
+		{
+			name:   "multi-byte characters count once",
+			text:   "ĝlub x",
+			offset: 6,
+			expect: Position{Line: 1, Col: 6},
+		},
+		{
+			name:   "past end of text",
+			text:   "abc",
+			offset: 10,
+			expect: Position{Line: 1, Col: 4},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := PositionAt(tc.text, tc.offset)
+
+			assert.Equal(t, tc.expect, actual)
+		})
+	}
+}
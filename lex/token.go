@@ -35,6 +35,28 @@ type Token interface {
 	String() string
 }
 
+// FileToken is a Token that knows the name of the file it was lexed from. It is
+// separate from Token so that implementations of Token outside of this package
+// do not need to have it; use FileOf to get the file of any Token. All Tokens
+// created by the lexers in this package are FileTokens.
+type FileToken interface {
+	Token
+
+	// File returns the name of the file that the token was lexed from, or the
+	// empty string if it was not lexed from a named source.
+	File() string
+}
+
+// FileOf returns the name of the file that tok was lexed from. If tok is not a
+// FileToken, or if it was not lexed from a named source, the empty string is
+// returned.
+func FileOf(tok Token) string {
+	if ft, ok := tok.(FileToken); ok {
+		return ft.File()
+	}
+	return ""
+}
+
 // implementation of Token interface
 type lexerToken struct {
 	class   TokenClass
@@ -42,6 +64,7 @@ type lexerToken struct {
 	linePos int
 	lineNum int
 	line    string
+	file    string
 }
 
 func (lt lexerToken) Class() TokenClass {
@@ -64,6 +87,10 @@ func (lt lexerToken) FullLine() string {
 	return lt.line
 }
 
+func (lt lexerToken) File() string {
+	return lt.file
+}
+
 func (lt lexerToken) String() string {
 	// turn all newline chars into \n because we dont want that in the output
 	fmtStr := "(%s <%d:%d> \"%s\")"
@@ -85,5 +112,39 @@ func NewToken(class TokenClass, lexed string, linePos int, lineNum int, line str
 // to create a SyntaxError with a detailed message on the error and the source
 // code which caused it.
 func NewSyntaxErrorFromToken(msg string, tok Token) *syntaxerr.Error {
-	return syntaxerr.New(msg, tok.FullLine(), tok.Lexeme(), tok.Line(), tok.LinePos())
+	return syntaxerr.NewInFile(FileOf(tok), msg, tok.FullLine(), tok.Lexeme(), tok.Line(), tok.LinePos())
+}
+
+// NewDiagnosticFromToken uses the location information in the provided token
+// to create a Diagnostic with SeverityError and the given code and message. Its
+// primary span covers the lexeme of the token, unless the token is of class
+// TokenError, in which case the span is empty and is at the start of the
+// token.
+func NewDiagnosticFromToken(code string, msg string, tok Token) syntaxerr.Diagnostic {
+	return syntaxerr.Diagnostic{
+		Code:       code,
+		Severity:   syntaxerr.SeverityError,
+		Message:    msg,
+		Span:       diagSpanOf(tok),
+		SourceLine: tok.FullLine(),
+	}
+}
+
+// NewLabelFromToken uses the location information in the provided token to
+// create a Label with the given message whose span covers the lexeme of the
+// token.
+func NewLabelFromToken(msg string, tok Token) syntaxerr.Label {
+	return syntaxerr.Label{
+		Span:       diagSpanOf(tok),
+		SourceLine: tok.FullLine(),
+		Message:    msg,
+	}
+}
+
+// diagSpanOf returns the span of a Diagnostic for tok.
+func diagSpanOf(tok Token) syntaxerr.Span {
+	if tok.Class().ID() == TokenError.ID() {
+		return EmptySpanAt(tok).InFile(FileOf(tok))
+	}
+	return SpanOf(tok).InFile(FileOf(tok))
 }
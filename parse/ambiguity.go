@@ -0,0 +1,189 @@
+package parse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/grammar"
+)
+
+// Ambiguity is a sentence of a grammar that has more than one parse tree.
+type Ambiguity struct {
+	// Sentence is the terminals that make up the sentence, in order. It is
+	// empty if the ambiguous sentence is the empty string.
+	Sentence []string
+
+	// Trees is two of the distinct parse trees of Sentence.
+	Trees [2]Tree
+}
+
+// String returns the sentence of the Ambiguity with each terminal separated
+// by a space.
+func (a Ambiguity) String() string {
+	if len(a.Sentence) == 0 {
+		return "ε"
+	}
+	return strings.Join(a.Sentence, " ")
+}
+
+// derivation is the parse trees found so far for one yield of a non-terminal.
+type derivation struct {
+	yield []string
+	trees []*Tree
+	keys  []string
+}
+
+// FindAmbiguities searches every sentence of g that has at most maxLen
+// terminals for two distinct parse trees, and returns each one found. Unlike
+// the conflicts found when building an LR table, each Ambiguity is proof that
+// the grammar itself is ambiguous and not merely outside of what an LR(1)
+// parser can handle. A grammar with no ambiguities up to maxLen may still be
+// ambiguous for longer sentences.
+//
+// The returned Ambiguities are sorted by the length of their sentences, with
+// the shortest first.
+func FindAmbiguities(g grammar.CFG, maxLen int) ([]Ambiguity, error) {
+	if maxLen < 0 {
+		return nil, fmt.Errorf("max sentence length must be at least 0 but was %d", maxLen)
+	}
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid grammar: %w", err)
+	}
+
+	// trees are built bottom-up one level higher each pass, with a
+	// non-terminal's trees for each yield of at most maxLen terminals being
+	// built out of the trees of its symbols from the prior pass. only two
+	// trees are kept per yield; two are enough to show that the yield, and any
+	// sentence built from it, is ambiguous, and stopping at two keeps cyclic
+	// grammars, which have infinitely many trees for a yield, from running
+	// forever.
+	found := map[string]map[string]*derivation{}
+	for _, nt := range g.NonTerminals() {
+		found[nt] = map[string]*derivation{}
+	}
+
+	// a tree is never stored twice, so trees can be told apart by which
+	// production made them and the stored trees they were made from instead
+	// of by their whole structure.
+	add := func(nt string, prod int, yield []string, pt *Tree) bool {
+		yieldKey := strings.Join(yield, " ")
+		d, ok := found[nt][yieldKey]
+		if !ok {
+			d = &derivation{yield: yield}
+			found[nt][yieldKey] = d
+		}
+		if len(d.trees) >= 2 {
+			return false
+		}
+		treeKey := fmt.Sprintf("%d", prod)
+		for _, child := range pt.Children {
+			if child.Terminal {
+				treeKey += fmt.Sprintf(" %q", child.Value)
+			} else {
+				treeKey += fmt.Sprintf(" %p", child)
+			}
+		}
+		for _, k := range d.keys {
+			if k == treeKey {
+				return false
+			}
+		}
+		d.trees = append(d.trees, pt)
+		d.keys = append(d.keys, treeKey)
+		return true
+	}
+
+	updated := true
+	for updated {
+		updated = false
+
+		// only use what was found before this pass so that each pass is
+		// deterministic.
+		prior := map[string][]derivation{}
+		for nt, byYield := range found {
+			yieldKeys := make([]string, 0, len(byYield))
+			for k := range byYield {
+				yieldKeys = append(yieldKeys, k)
+			}
+			sort.Strings(yieldKeys)
+			for _, k := range yieldKeys {
+				prior[nt] = append(prior[nt], *byYield[k])
+			}
+		}
+
+		for _, nt := range g.NonTerminals() {
+			for i, p := range g.Rule(nt).Productions {
+				prodIdx := i
+				if p.Equal(grammar.Epsilon) {
+					if add(nt, prodIdx, nil, Node(nt, &Tree{Terminal: true})) {
+						updated = true
+					}
+					continue
+				}
+
+				deriveProduction(g, p, maxLen, prior, nil, nil, func(yield []string, children []*Tree) {
+					if add(nt, prodIdx, yield, Node(nt, children...)) {
+						updated = true
+					}
+				})
+			}
+		}
+	}
+
+	var ambigs []Ambiguity
+	for _, d := range found[g.StartSymbol()] {
+		if len(d.trees) < 2 {
+			continue
+		}
+		ambigs = append(ambigs, Ambiguity{
+			Sentence: d.yield,
+			Trees:    [2]Tree{d.trees[0].Copy(), d.trees[1].Copy()},
+		})
+	}
+	sort.Slice(ambigs, func(i, j int) bool {
+		if len(ambigs[i].Sentence) != len(ambigs[j].Sentence) {
+			return len(ambigs[i].Sentence) < len(ambigs[j].Sentence)
+		}
+		return ambigs[i].String() < ambigs[j].String()
+	})
+
+	return ambigs, nil
+}
+
+// deriveProduction calls onDerived with every yield and list of child trees
+// that the symbols of p can be derived into using the trees in prior, without
+// the yield going over budget terminals. yield and children are what was
+// derived for the symbols of p before the current one.
+//
+// DeriveFullTree is not used for this; it only derives enough trees to use
+// every rule at least once, while finding ambiguities needs every tree whose
+// yield is within the budget.
+func deriveProduction(g grammar.CFG, p grammar.Production, budget int, prior map[string][]derivation, yield []string, children []*Tree, onDerived func([]string, []*Tree)) {
+	if len(p) == 0 {
+		onDerived(yield, children)
+		return
+	}
+
+	sym := p[0]
+	if g.IsTerminal(sym) {
+		if budget < 1 {
+			return
+		}
+		nextYield := append(append([]string{}, yield...), sym)
+		nextChildren := append(append([]*Tree{}, children...), Leaf(sym))
+		deriveProduction(g, p[1:], budget-1, prior, nextYield, nextChildren, onDerived)
+		return
+	}
+
+	for _, d := range prior[sym] {
+		if len(d.yield) > budget {
+			continue
+		}
+		nextYield := append(append([]string{}, yield...), d.yield...)
+		for _, pt := range d.trees {
+			nextChildren := append(append([]*Tree{}, children...), pt)
+			deriveProduction(g, p[1:], budget-len(d.yield), prior, nextYield, nextChildren, onDerived)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindAmbiguities(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       grammar.CFG
+		maxLen      int
+		expect      []string
+		expectTrees [][2]*Tree
+		expectErr   bool
+	}{
+		{
+			name: "unambiguous grammar",
+			input: grammar.MustParse(`
+				E -> E plus T | T ;
+				T -> id ;
+			`),
+			maxLen: 7,
+			expect: nil,
+		},
+		{
+			name: "ambiguous binary operator",
+			input: grammar.MustParse(`
+				E -> E plus E | id ;
+			`),
+			maxLen: 5,
+			expect: []string{"id plus id plus id"},
+			expectTrees: [][2]*Tree{{
+				Node("E",
+					Node("E", Leaf("id")),
+					Leaf("plus"),
+					Node("E",
+						Node("E", Leaf("id")),
+						Leaf("plus"),
+						Node("E", Leaf("id")),
+					),
+				),
+				Node("E",
+					Node("E",
+						Node("E", Leaf("id")),
+						Leaf("plus"),
+						Node("E", Leaf("id")),
+					),
+					Leaf("plus"),
+					Node("E", Leaf("id")),
+				),
+			}},
+		},
+		{
+			name: "ambiguous sentence longer than max is not found",
+			input: grammar.MustParse(`
+				E -> E plus E | id ;
+			`),
+			maxLen: 4,
+			expect: nil,
+		},
+		{
+			name: "dangling else",
+			input: grammar.MustParse(`
+				S -> if S | if S else S | x ;
+			`),
+			maxLen: 5,
+			expect: []string{"if if x else x"},
+		},
+		{
+			name: "two derivations of the same terminal",
+			input: grammar.MustParse(`
+				S -> A | B ;
+				A -> a ;
+				B -> a ;
+			`),
+			maxLen: 1,
+			expect: []string{"a"},
+			expectTrees: [][2]*Tree{{
+				Node("S", Node("A", Leaf("a"))),
+				Node("S", Node("B", Leaf("a"))),
+			}},
+		},
+		{
+			name: "ambiguous empty string",
+			input: grammar.MustParse(`
+				S -> A | B ;
+				A -> a | ε ;
+				B -> ε ;
+			`),
+			maxLen: 1,
+			expect: []string{"ε"},
+		},
+		{
+			name: "cycle in grammar",
+			input: grammar.MustParse(`
+				S -> A | b ;
+				A -> S | a ;
+			`),
+			maxLen: 1,
+			expect: []string{"a", "b"},
+		},
+		{
+			name: "negative max length",
+			input: grammar.MustParse(`
+				S -> a ;
+			`),
+			maxLen:    -1,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := FindAmbiguities(tc.input, tc.maxLen)
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			var actualSentences []string
+			for _, a := range actual {
+				actualSentences = append(actualSentences, a.String())
+				assert.False(a.Trees[0].Equal(a.Trees[1]), "trees for %q are the same", a.String())
+			}
+			assert.Equal(tc.expect, actualSentences)
+
+			for i := range tc.expectTrees {
+				if i >= len(actual) {
+					break
+				}
+				assert.Equal(tc.expectTrees[i][0].String(), actual[i].Trees[0].String())
+				assert.Equal(tc.expectTrees[i][1].String(), actual[i].Trees[1].String())
+			}
+		})
+	}
+}
@@ -30,12 +30,13 @@ func EmptyCLR1Parser() Parser {
 // ambiguous, the 2nd arg 'ambiguity warnings' will be filled with each
 // ambiguous case detected.
 func GenerateCLR1Parser(g grammar.CFG, allowAmbig bool) (Parser, []string, error) {
-	table, ambigWarns, err := constructCLR1ParseTable(g, allowAmbig)
+	tg := entryGrammar(g)
+	table, ambigWarns, err := constructCLR1ParseTable(tg, allowAmbig)
 	if err != nil {
 		return &lrParser{}, ambigWarns, err
 	}
 
-	return &lrParser{table: table, parseType: CLR1, gram: g}, ambigWarns, nil
+	return &lrParser{table: compressedFrom(table, tg), parseType: CLR1, gram: g}, ambigWarns, nil
 }
 
 // constructCLR1ParseTable constructs the canonical LR(1) table for G.
@@ -85,7 +86,7 @@ func constructCLR1ParseTable(g grammar.CFG, allowAmbig bool) (lrParseTable, []st
 	var ambigWarns []string
 	for _, stateName := range lr1Automaton.States() {
 		fromState := fmt.Sprintf(" (from DFA state %q)", textfmt.TruncateWith(stateName, 4, "..."))
-		for _, a := range table.gPrime.Terminals() {
+		for _, a := range append(table.gPrime.Terminals(), "$") {
 			itemSet := table.lr1.GetValue(stateName)
 			var matchFound bool
 			var act lrAction
@@ -392,6 +393,12 @@ func (clr1 *canonicalLR1Table) String() string {
 		String()
 }
 
+// States returns the names of all states in the table, with the initial state
+// first.
+func (clr1 *canonicalLR1Table) States() []string {
+	return orderStates(clr1.lr1.States(), clr1.lr1.Start)
+}
+
 // Initial returns the starting state of the parser DFA.
 func (clr1 *canonicalLR1Table) Initial() string {
 	return clr1.lr1.Start
@@ -519,3 +526,13 @@ func (clr1 *canonicalLR1Table) Action(i, a string) lrAction {
 
 	return act
 }
+
+// kernelItems returns the cores of the kernel items of the given state of the
+// table's DFA.
+func (clr1 *canonicalLR1Table) kernelItems(state string) []grammar.LR0Item {
+	var items []grammar.LR0Item
+	for _, item := range clr1.lr1.GetValue(state) {
+		items = append(items, item.LR0Item)
+	}
+	return kernelOf(items, clr1.gPrime.StartSymbol())
+}
@@ -0,0 +1,438 @@
+package parse
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// File complete.go contains functions for finding what input may come next at
+// some point in the input, such as for code completion in an editor.
+
+// Expectation is what may come next in input at some point in it, as found by
+// ExpectedAt.
+type Expectation struct {
+	// Expected is the token classes that may come next, in the same order as
+	// their terminals are in the grammar.
+	Expected []lex.TokenClass
+
+	// EndAllowed is whether the input may end at this point.
+	EndAllowed bool
+
+	// Completing is the non-terminals that are partway through being parsed at
+	// this point; that is, those that the symbols just before the point may be
+	// the start of. For an LR parser, these are the heads of the kernel items of
+	// the state the parser is in, and there may be several as the parser has
+	// not yet decided between them. For an LL(1) parser, there is at most one;
+	// it is the innermost non-terminal that has had at least one of its
+	// symbols parsed. It is empty if nothing before the point has been parsed.
+	Completing []string
+}
+
+// completer is implemented by parsers that can find the Expectation at the end
+// of a stream.
+type completer interface {
+	// expectedAtEnd parses all tokens from stream as the given entry point
+	// until the end of text token and returns the Expectation at that point.
+	// If a syntax error occurs before then, it is returned.
+	expectedAtEnd(symbol string, stream lex.TokenStream) (Expectation, error)
+}
+
+// ExpectedAt parses tokens from stream up to the position of a cursor in the
+// source text and returns what may come next at the cursor.
+//
+// Only tokens that end at or before the cursor are parsed. The first token
+// that does not is taken to be one that is in the middle of being typed, so it
+// and every token after it are ignored, and the returned Expectation is for
+// the point just before it. If a syntax error occurs in the parsed tokens, it
+// is returned.
+//
+// A generated recursive-descent parser is handled by using a table-driven
+// LL(1) parser built from its grammar.
+func ExpectedAt(p Parser, stream lex.TokenStream, cursor lex.Position) (Expectation, error) {
+	return ExpectedAtAs(p, p.Grammar().StartSymbol(), stream, cursor)
+}
+
+// ExpectedAtAs is the same as ExpectedAt but parses the tokens as the given
+// entry point of the parser's grammar instead of as its start symbol, in the
+// same way as EntryParser.ParseAs. An error is returned if symbol is not an entry
+// point of the grammar.
+func ExpectedAtAs(p Parser, symbol string, stream lex.TokenStream, cursor lex.Position) (Expectation, error) {
+	if err := checkEntryPoint(p.Grammar(), symbol); err != nil {
+		return Expectation{}, err
+	}
+
+	c, ok := p.(completer)
+	if !ok {
+		if p.Type() != LL1 {
+			return Expectation{}, fmt.Errorf("%s parser does not support finding expected input", p.Type())
+		}
+
+		ll, err := GenerateLL1Parser(p.Grammar())
+		if err != nil {
+			return Expectation{}, err
+		}
+		c = ll.(completer)
+	}
+
+	return c.expectedAtEnd(symbol, cursorStream{stream: stream, cursor: cursor})
+}
+
+// cursorStream is a TokenStream that ends at a cursor in the stream it wraps.
+// Any token that does not end at or before the cursor is replaced by an end of
+// text token.
+type cursorStream struct {
+	stream lex.TokenStream
+	cursor lex.Position
+}
+
+// Next returns the next token of the stream, or an end of text token if the
+// cursor has been reached.
+func (cs cursorStream) Next() lex.Token {
+	tok := cs.stream.Peek()
+	if cs.pastCursor(tok) {
+		return cs.endToken(tok)
+	}
+	return cs.stream.Next()
+}
+
+// Peek returns the next token of the stream without advancing it, or an end of
+// text token if the cursor has been reached.
+func (cs cursorStream) Peek() lex.Token {
+	tok := cs.stream.Peek()
+	if cs.pastCursor(tok) {
+		return cs.endToken(tok)
+	}
+	return tok
+}
+
+// HasNext returns whether the stream has any additional tokens before the
+// cursor.
+func (cs cursorStream) HasNext() bool {
+	return cs.stream.HasNext() && !cs.pastCursor(cs.stream.Peek())
+}
+
+func (cs cursorStream) pastCursor(tok lex.Token) bool {
+	if tok.Class().ID() == lex.TokenEndOfText.ID() {
+		return false
+	}
+	return cs.cursor.Before(lex.SpanOf(tok).End)
+}
+
+func (cs cursorStream) endToken(next lex.Token) lex.Token {
+	return lex.NewToken(lex.TokenEndOfText, "", cs.cursor.Col, cs.cursor.Line, next.FullLine())
+}
+
+// expectedAtEnd parses all tokens from stream as the given entry point until
+// the end of text token and returns the Expectation at that point.
+func (lr *lrParser) expectedAtEnd(symbol string, stream lex.TokenStream) (Expectation, error) {
+	if len(lr.gram.EntryPoints) > 0 {
+		_, markers := entryNames(lr.gram)
+		stream = markEntry(stream, markers[symbol])
+	}
+
+	stateStack := []string{lr.table.Initial()}
+
+	a := stream.Next()
+	for a.Class().ID() != lex.TokenEndOfText.ID() {
+		s := stateStack[len(stateStack)-1]
+
+		act := lr.table.Action(s, a.Class().ID())
+		switch act.Type {
+		case lrShift:
+			stateStack = append(stateStack, act.State)
+			a = stream.Next()
+		case lrReduce:
+			var ok bool
+			stateStack, ok = lr.reduceStates(stateStack, act)
+			if !ok {
+				return Expectation{}, diagnose(syntaxerr.CodeParserState, fmt.Sprintf("LR parsing error; DFA has no valid transition from here on %q", act.Symbol), a)
+			}
+		default:
+			return Expectation{}, lr.syntaxError(s, a)
+		}
+	}
+
+	var exp Expectation
+	for _, term := range lr.gram.Terminals() {
+		if lr.canShift(stateStack, term) {
+			exp.Expected = append(exp.Expected, lr.gram.Term(term))
+		}
+	}
+	exp.EndAllowed = lr.canShift(stateStack, "$")
+
+	items, err := lr.kernelItems(stateStack[len(stateStack)-1])
+	if err != nil {
+		return Expectation{}, fmt.Errorf("finding non-terminals being completed: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, item := range items {
+		// skip the start symbols added for the augmented and entry grammars.
+		if !lr.gram.IsNonTerminal(item.NonTerminal) || seen[item.NonTerminal] {
+			continue
+		}
+		seen[item.NonTerminal] = true
+		exp.Completing = append(exp.Completing, item.NonTerminal)
+	}
+
+	return exp, nil
+}
+
+// reduceStates pops the states for the production of reduce action act off of
+// stateStack and pushes the state that GOTO gives for its head. Returns false
+// if this cannot be done.
+func (lr *lrParser) reduceStates(stateStack []string, act lrAction) ([]string, bool) {
+	// epsilon productions pop nothing
+	popCount := len(act.Production)
+	if act.Production.Equal(grammar.Epsilon) {
+		popCount = 0
+	}
+	if popCount >= len(stateStack) {
+		return stateStack, false
+	}
+	stateStack = stateStack[:len(stateStack)-popCount]
+
+	t, err := lr.table.Goto(stateStack[len(stateStack)-1], act.Symbol)
+	if err != nil {
+		return stateStack, false
+	}
+	return append(stateStack, t), true
+}
+
+// canShift returns whether terminal a would be shifted (or accepted, if it is
+// "$") by the parser if stateStack were its stack of states, after any
+// reductions that it would cause.
+func (lr *lrParser) canShift(stateStack []string, a string) bool {
+	st := make([]string, len(stateStack))
+	copy(st, stateStack)
+
+	for {
+		act := lr.table.Action(st[len(st)-1], a)
+		switch act.Type {
+		case lrShift, lrAccept:
+			return true
+		case lrReduce:
+			var ok bool
+			st, ok = lr.reduceStates(st, act)
+			if !ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+}
+
+// lrKernels holds the kernel items of each state of a compressedLRTable once
+// they have been found.
+type lrKernels struct {
+	once  sync.Once
+	items [][]grammar.LR0Item
+	err   error
+}
+
+// lrItemTable is an lrParseTable that has the items of each of its states.
+type lrItemTable interface {
+	lrParseTable
+
+	// kernelItems returns the LR(0) cores of the kernel items of the given
+	// state.
+	kernelItems(state string) []grammar.LR0Item
+}
+
+// kernelItems returns the LR(0) cores of the kernel items of the given state
+// of lr's table. The table lr uses does not have them, so the first call finds
+// them in the table that lr's was compressed from, rebuilding it from the grammar if
+// needed.
+func (lr *lrParser) kernelItems(state string) ([]grammar.LR0Item, error) {
+	kernels, err := lr.allKernelItems()
+	if err != nil {
+		return nil, err
+	}
+
+	var idx int
+	if _, err := fmt.Sscanf(state, "%d", &idx); err != nil || idx < 0 || idx >= len(kernels) {
+		return nil, fmt.Errorf("no state %q in table", state)
+	}
+	return kernels[idx], nil
+}
+
+// allKernelItems returns the LR(0) cores of the kernel items of every state of
+// lr's table in the same way as kernelItems, indexed by the number of the
+// state.
+func (lr *lrParser) allKernelItems() ([][]grammar.LR0Item, error) {
+	ct, ok := lr.table.(*compressedLRTable)
+	if !ok {
+		ct = compressedFrom(lr.table, lr.tableGrammar())
+	}
+	kernels := ct.kernels
+	if kernels == nil {
+		kernels = &lrKernels{}
+	}
+
+	kernels.once.Do(func() {
+		kernels.items, kernels.err = lr.findKernels(ct)
+	})
+	return kernels.items, kernels.err
+}
+
+// findKernels gets the kernel items of every state in ct.
+func (lr *lrParser) findKernels(ct *compressedLRTable) ([][]grammar.LR0Item, error) {
+	full := ct.src
+	if full == nil {
+		var err error
+		full, err = lr.rebuildTable()
+		if err != nil {
+			return nil, fmt.Errorf("rebuilding table: %w", err)
+		}
+
+		// states are matched up by position, so make sure the rebuilt one
+		// is actually the same table.
+		if compressedFrom(full, lr.tableGrammar()).String() != ct.String() {
+			return nil, fmt.Errorf("parse table is not the %s table for its grammar", lr.parseType)
+		}
+	}
+
+	itemTable, ok := full.(lrItemTable)
+	if !ok {
+		return nil, fmt.Errorf("parse table does not have items")
+	}
+
+	states := itemTable.States()
+	items := make([][]grammar.LR0Item, len(states))
+	for i := range states {
+		items[i] = itemTable.kernelItems(states[i])
+	}
+	return items, nil
+}
+
+// kernelOf returns the kernel items in items, which are those that have at
+// least one symbol before the dot as well as the initial item of the augmented
+// grammar whose start symbol is augStart. Items with the same core, such as
+// LR(1) items that differ only by lookahead, are included once. They are sorted
+// by their string representation.
+func kernelOf(items []grammar.LR0Item, augStart string) []grammar.LR0Item {
+	var kernel []grammar.LR0Item
+	seen := map[string]bool{}
+	for _, item := range items {
+		if len(item.Left) > 0 || item.NonTerminal == augStart {
+			if seen[item.String()] {
+				continue
+			}
+			seen[item.String()] = true
+			kernel = append(kernel, item)
+		}
+	}
+	sort.Slice(kernel, func(i, j int) bool {
+		return kernel[i].String() < kernel[j].String()
+	})
+	return kernel
+}
+
+// llStackEntry is a symbol on the stack of an LL(1) parser being used to find
+// an Expectation.
+type llStackEntry struct {
+	symbol string
+
+	// parent is the entry for the non-terminal whose production this symbol
+	// came from, or nil if it is the start symbol.
+	parent *llStackEntry
+
+	// index is the index of the symbol in the production of its parent.
+	index int
+}
+
+// expectedAtEnd parses all tokens from stream as the given entry point until
+// the end of text token and returns the Expectation at that point.
+func (ll1 *ll1Parser) expectedAtEnd(symbol string, stream lex.TokenStream) (Expectation, error) {
+	stack := []*llStackEntry{{symbol: "$"}, {symbol: symbol}}
+
+	next := stream.Peek()
+	for next.Class().ID() != lex.TokenEndOfText.ID() {
+		X := stack[len(stack)-1]
+
+		if X.symbol == "$" {
+			return Expectation{}, ll1.noPredictionError(next)
+		}
+
+		if ll1.g.IsTerminal(X.symbol) {
+			t := ll1.g.Term(X.symbol)
+			if next.Class().ID() != t.ID() {
+				return Expectation{}, ll1.mismatchError(t, next)
+			}
+			stack = stack[:len(stack)-1]
+			stream.Next()
+			next = stream.Peek()
+			continue
+		}
+
+		prod := ll1.table.Get(X.symbol, ll1.g.TermFor(next.Class()))
+		if prod.Equal(grammar.Error) {
+			return Expectation{}, ll1.noPredictionError(next)
+		}
+		stack = ll1.expand(stack, prod)
+	}
+
+	var exp Expectation
+	for _, term := range ll1.g.Terminals() {
+		if ll1.canMatch(stack, term) {
+			exp.Expected = append(exp.Expected, ll1.g.Term(term))
+		}
+	}
+	exp.EndAllowed = ll1.canMatch(stack, "$")
+
+	// the innermost non-terminal with at least one symbol parsed is the
+	// parent of the first entry up the tree that is not the first symbol of
+	// its production.
+	for e := stack[len(stack)-1]; e.parent != nil; e = e.parent {
+		if e.index > 0 {
+			exp.Completing = []string{e.parent.symbol}
+			break
+		}
+	}
+
+	return exp, nil
+}
+
+// expand replaces the non-terminal on top of stack with the symbols of prod.
+func (ll1 *ll1Parser) expand(stack []*llStackEntry, prod grammar.Production) []*llStackEntry {
+	X := stack[len(stack)-1]
+	stack = stack[:len(stack)-1]
+
+	for i := len(prod) - 1; i >= 0; i-- {
+		if prod[i] == grammar.Epsilon[0] {
+			continue
+		}
+		stack = append(stack, &llStackEntry{symbol: prod[i], parent: X, index: i})
+	}
+
+	return stack
+}
+
+// canMatch returns whether terminal a would be matched (or accepted, if it is
+// "$") by the parser if stack were its stack, after any predictions that it
+// would cause.
+func (ll1 *ll1Parser) canMatch(stack []*llStackEntry, a string) bool {
+	st := make([]*llStackEntry, len(stack))
+	copy(st, stack)
+
+	for {
+		X := st[len(st)-1].symbol
+		if X == "$" {
+			return a == "$"
+		}
+		if ll1.g.IsTerminal(X) {
+			return X == a
+		}
+
+		prod := ll1.table.Get(X, a)
+		if prod.Equal(grammar.Error) {
+			return false
+		}
+		st = ll1.expand(st, prod)
+	}
+}
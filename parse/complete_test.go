@@ -0,0 +1,242 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExpectedAt(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> id eq E ;
+		E -> T X ;
+		X -> plus T X | ε ;
+		T -> id | int | lp E rp ;
+	`)
+
+	// most cases use "id eq id plus", which mockTokens places at:
+	// id 1:1-1:3, eq 1:4-1:6, id 1:7-1:9, plus 1:10-1:14
+	input := []string{"id", "eq", "id", "plus", "$"}
+
+	testCases := []struct {
+		name      string
+		input     []string
+		cursor    lex.Position
+		expect    []string
+		expectEnd bool
+		expectLR  []string
+		expectLL  []string
+		expectErr bool
+	}{
+		{
+			name:   "at start of input",
+			input:  input,
+			cursor: lex.Position{Line: 1, Col: 1},
+			expect: []string{"id"},
+		},
+		{
+			name:     "in middle of token",
+			input:    input,
+			cursor:   lex.Position{Line: 1, Col: 8},
+			expect:   []string{"id", "int", "lp"},
+			expectLR: []string{"S"},
+			expectLL: []string{"S"},
+		},
+		{
+			name:      "at end of token",
+			input:     input,
+			cursor:    lex.Position{Line: 1, Col: 9},
+			expect:    []string{"plus"},
+			expectEnd: true,
+			expectLR:  []string{"T"},
+			expectLL:  []string{"E"},
+		},
+		{
+			name:     "after all tokens",
+			input:    input,
+			cursor:   lex.Position{Line: 1, Col: 15},
+			expect:   []string{"id", "int", "lp"},
+			expectLR: []string{"X"},
+			expectLL: []string{"X"},
+		},
+		{
+			name:      "syntax error before cursor",
+			input:     []string{"id", "id", "$"},
+			cursor:    lex.Position{Line: 1, Col: 6},
+			expectErr: true,
+		},
+	}
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"SLR(1)": func() (Parser, error) {
+			p, _, err := GenerateSLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+		"CLR(1)": func() (Parser, error) {
+			p, _, err := GenerateCLR1Parser(g, false)
+			return p, err
+		},
+		"decoded LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			if err != nil {
+				return nil, err
+			}
+			data, err := p.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			decoded := EmptyLALR1Parser()
+			err = decoded.UnmarshalBinary(data)
+			return decoded, err
+		},
+	}
+
+	for pName, ctor := range parsers {
+		for _, tc := range testCases {
+			t.Run(pName+" "+tc.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				p, err := ctor()
+				if !assert.NoError(err, "generating parser failed") {
+					return
+				}
+
+				// execute
+				actual, err := ExpectedAt(p, mockTokens(tc.input...), tc.cursor)
+
+				// assert
+				if tc.expectErr {
+					assert.Error(err)
+					return
+				} else if !assert.NoError(err) {
+					return
+				}
+
+				var actualIDs []string
+				for _, tc := range actual.Expected {
+					actualIDs = append(actualIDs, tc.ID())
+				}
+				assert.ElementsMatch(tc.expect, actualIDs, "expected tokens do not match")
+				assert.Equal(tc.expectEnd, actual.EndAllowed, "end allowed does not match")
+
+				expectCompleting := tc.expectLR
+				if p.Type() == LL1 {
+					expectCompleting = tc.expectLL
+				}
+				assert.Equal(expectCompleting, actual.Completing, "completing non-terminals do not match")
+			})
+		}
+	}
+}
+
+func Test_ExpectedAtAs(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> id eq E ;
+		E -> T X ;
+		X -> plus T X | ε ;
+		T -> id | int | lp E rp ;
+	`)
+	g.EntryPoints = []string{"E"}
+
+	// "id plus" is placed by mockTokens at id 1:1-1:3, plus 1:4-1:8.
+	input := []string{"id", "plus", "$"}
+
+	testCases := []struct {
+		name      string
+		symbol    string
+		cursor    lex.Position
+		expect    []string
+		expectEnd bool
+		expectLR  []string
+		expectLL  []string
+		expectErr bool
+	}{
+		{
+			name:   "entry point at start of input",
+			symbol: "E",
+			cursor: lex.Position{Line: 1, Col: 1},
+			expect: []string{"id", "int", "lp"},
+		},
+		{
+			name:      "entry point at end of token",
+			symbol:    "E",
+			cursor:    lex.Position{Line: 1, Col: 3},
+			expect:    []string{"plus"},
+			expectEnd: true,
+			expectLR:  []string{"T"},
+			expectLL:  []string{"E"},
+		},
+		{
+			name:     "entry point after all tokens",
+			symbol:   "E",
+			cursor:   lex.Position{Line: 1, Col: 9},
+			expect:   []string{"id", "int", "lp"},
+			expectLR: []string{"X"},
+			expectLL: []string{"X"},
+		},
+		{
+			name:      "start symbol",
+			symbol:    "S",
+			cursor:    lex.Position{Line: 1, Col: 9},
+			expectErr: true,
+		},
+		{
+			name:      "not an entry point",
+			symbol:    "T",
+			cursor:    lex.Position{Line: 1, Col: 1},
+			expectErr: true,
+		},
+	}
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+	}
+
+	for pName, ctor := range parsers {
+		for _, tc := range testCases {
+			t.Run(pName+" "+tc.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				p, err := ctor()
+				if !assert.NoError(err, "generating parser failed") {
+					return
+				}
+
+				// execute
+				actual, err := ExpectedAtAs(p, tc.symbol, mockTokens(input...), tc.cursor)
+
+				// assert
+				if tc.expectErr {
+					assert.Error(err)
+					return
+				} else if !assert.NoError(err) {
+					return
+				}
+
+				var actualIDs []string
+				for _, tc := range actual.Expected {
+					actualIDs = append(actualIDs, tc.ID())
+				}
+				assert.ElementsMatch(tc.expect, actualIDs, "expected tokens do not match")
+				assert.Equal(tc.expectEnd, actual.EndAllowed, "end allowed does not match")
+
+				expectCompleting := tc.expectLR
+				if p.Type() == LL1 {
+					expectCompleting = tc.expectLL
+				}
+				assert.Equal(expectCompleting, actual.Completing, "completing non-terminals do not match")
+			})
+		}
+	}
+}
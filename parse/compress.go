@@ -0,0 +1,647 @@
+package parse
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/rezi"
+	"github.com/dekarrin/rosed"
+)
+
+// File compress.go contains the compressed form of LR parsing tables that all
+// LR parsers use at parse time and that is written when they are encoded.
+
+// lrCompressedTableMarker is appended to the name of the algorithm of an
+// lrParser when it is encoded with a compressedLRTable so that decoding knows
+// to read that type of table instead of the one built by the algorithm. Data
+// without it was encoded before compression was added.
+const lrCompressedTableMarker = "/compressed"
+
+// action codes used in a compressedLRTable. A shift to state t is given as
+// 2+2t, and a reduce by reductions[r] is given as 3+2r.
+const (
+	lrCodeError  = 0
+	lrCodeAccept = 1
+)
+
+// compressedLRTable is an lrParseTable whose entries are stored compactly. It
+// uses three techniques to do so:
+//
+//   - Default reductions: the most common reduction in each state's ACTION row
+//     is taken out of the row and used for any terminal that has no entry.
+//     Each state has a bitset of the terminals that are valid in it, so that
+//     errors are still detected in exactly the same state they would be with
+//     the full table.
+//   - Equivalent-row sharing: states with identical rows store them only once.
+//   - Row displacement: the remaining rows are overlaid into a single comb
+//     vector.
+//
+// States are named by their index.
+type compressedLRTable struct {
+	initial   int
+	numStates int
+	gTerms    []string
+	gNonTerms []string
+
+	// reductions holds each distinct reduce action in the table.
+	reductions []lrAction
+
+	// defaults is the action code used for a state when its row in actions
+	// has no entry for a valid terminal.
+	defaults []int
+
+	// valid is the index into validSets of the bitset of terminals that have a
+	// non-error action in each state, or -1 if the state's default is an error
+	// and so no set is needed.
+	valid     []int
+	validSets [][]int
+
+	actions combVector
+	gotos   combVector
+
+	// termCols and ntCols map symbols to their column in actions and gotos.
+	// They are built from gTerms and gNonTerms and are not encoded.
+	termCols map[string]int
+	ntCols   map[string]int
+
+	// src is the table that this one was compressed from, if any. It is not
+	// encoded and is kept only so its DFA can be shown.
+	src lrParseTable
+
+	// kernels caches the kernel items of each state once they are needed for
+	// finding expected input. It is not encoded.
+	kernels *lrKernels
+}
+
+// compressedFrom creates a compressedLRTable with the same entries as table,
+// which must be for grammar g.
+func compressedFrom(table lrParseTable, g grammar.CFG) *compressedLRTable {
+	ct := compressLRTable(lrTableOf(table, g), g.Terminals(), g.NonTerminals())
+	ct.src = table
+	return ct
+}
+
+// compressLRTable creates a compressedLRTable from a full one. terms and
+// nonTerms are the terminals and non-terminals of the grammar the table is
+// for; terms must not include the end of input marker "$".
+func compressLRTable(table LRTable, terms, nonTerms []string) *compressedLRTable {
+	ct := &compressedLRTable{
+		initial:   table.Initial,
+		numStates: len(table.Action),
+		gTerms:    make([]string, len(terms)),
+		gNonTerms: make([]string, len(nonTerms)),
+		defaults:  make([]int, len(table.Action)),
+		valid:     make([]int, len(table.Action)),
+		kernels:   &lrKernels{},
+	}
+	copy(ct.gTerms, terms)
+	copy(ct.gNonTerms, nonTerms)
+	ct.buildColumns()
+
+	allTerms := append(ct.gTerms[:len(ct.gTerms):len(ct.gTerms)], "$")
+
+	reduceCodes := map[string]int{}
+	validSetIDs := map[string]int{}
+	actionRows := make([]map[int]int, ct.numStates)
+	for s := range table.Action {
+		row := map[int]int{}
+		bits := make([]int, (len(allTerms)+31)/32)
+		reduceCounts := map[int]int{}
+
+		// go by column rather than by map so that reductions are numbered the
+		// same way every time.
+		for col, a := range allTerms {
+			entry, ok := table.Action[s][a]
+			if !ok || entry.act.Type == lrError {
+				continue
+			}
+
+			code := ct.encodeAction(entry.act, reduceCodes)
+			row[col] = code
+			bits[col/32] |= 1 << (col % 32)
+			if entry.act.Type == lrReduce {
+				reduceCounts[code]++
+			}
+		}
+
+		def, defCount := lrCodeError, 0
+		for code, count := range reduceCounts {
+			if count > defCount || (count == defCount && code < def) {
+				def, defCount = code, count
+			}
+		}
+		ct.defaults[s] = def
+
+		if def == lrCodeError {
+			ct.valid[s] = -1
+		} else {
+			for col := range row {
+				if row[col] == def {
+					delete(row, col)
+				}
+			}
+
+			key := fmt.Sprintf("%v", bits)
+			id, ok := validSetIDs[key]
+			if !ok {
+				id = len(ct.validSets)
+				validSetIDs[key] = id
+				ct.validSets = append(ct.validSets, bits)
+			}
+			ct.valid[s] = id
+		}
+
+		actionRows[s] = row
+	}
+	ct.actions = packRows(actionRows)
+
+	gotoRows := make([]map[int]int, ct.numStates)
+	for s := range table.Goto {
+		gotoRows[s] = map[int]int{}
+		for A, t := range table.Goto[s] {
+			if col, ok := ct.ntCols[A]; ok {
+				gotoRows[s][col] = t
+			}
+		}
+	}
+	ct.gotos = packRows(gotoRows)
+
+	return ct
+}
+
+// buildColumns sets termCols and ntCols from gTerms and gNonTerms.
+func (ct *compressedLRTable) buildColumns() {
+	ct.termCols = map[string]int{}
+	for i := range ct.gTerms {
+		ct.termCols[ct.gTerms[i]] = i
+	}
+	ct.termCols["$"] = len(ct.gTerms)
+
+	ct.ntCols = map[string]int{}
+	for i := range ct.gNonTerms {
+		ct.ntCols[ct.gNonTerms[i]] = i
+	}
+}
+
+// encodeAction gives the action code for act, adding it to ct.reductions if it
+// is a reduction not yet seen. reduceCodes tracks the codes of reductions
+// already added, keyed by their string representation.
+func (ct *compressedLRTable) encodeAction(act lrAction, reduceCodes map[string]int) int {
+	switch act.Type {
+	case lrAccept:
+		return lrCodeAccept
+	case lrShift:
+		t, _ := strconv.Atoi(act.State)
+		return 2 + 2*t
+	case lrReduce:
+		key := act.String()
+		code, ok := reduceCodes[key]
+		if !ok {
+			code = 3 + 2*len(ct.reductions)
+			reduceCodes[key] = code
+			ct.reductions = append(ct.reductions, act)
+		}
+		return code
+	default:
+		return lrCodeError
+	}
+}
+
+// decodeAction gives the action that the action code refers to.
+func (ct *compressedLRTable) decodeAction(code int) lrAction {
+	switch {
+	case code == lrCodeAccept:
+		return lrAction{Type: lrAccept}
+	case code >= 2 && code%2 == 0:
+		return lrAction{Type: lrShift, State: strconv.Itoa((code - 2) / 2)}
+	case code >= 3 && (code-3)/2 < len(ct.reductions):
+		return ct.reductions[(code-3)/2]
+	default:
+		return lrAction{Type: lrError}
+	}
+}
+
+// size returns the number of values stored in ct.
+func (ct *compressedLRTable) size() int {
+	total := len(ct.reductions) + len(ct.defaults) + len(ct.valid)
+	for i := range ct.validSets {
+		total += len(ct.validSets[i])
+	}
+	return total + ct.actions.size() + ct.gotos.size()
+}
+
+// fullSize returns the number of entries that the table would have if it
+// were not compressed.
+func (ct *compressedLRTable) fullSize() int {
+	return ct.numStates * (len(ct.gTerms) + 1 + len(ct.gNonTerms))
+}
+
+// MarshalBinary converts ct into a slice of bytes that can be decoded with
+// UnmarshalBinary.
+func (ct *compressedLRTable) MarshalBinary() ([]byte, error) {
+	data := rezi.EncInt(ct.initial)
+	data = append(data, rezi.EncInt(ct.numStates)...)
+	data = append(data, rezi.EncSliceString(ct.gTerms)...)
+	data = append(data, rezi.EncSliceString(ct.gNonTerms)...)
+	data = append(data, rezi.EncSliceBinary(ct.reductions)...)
+	data = append(data, rezi.EncSliceInt(ct.defaults)...)
+	data = append(data, rezi.EncSliceInt(ct.valid)...)
+	data = append(data, rezi.EncInt(len(ct.validSets))...)
+	for i := range ct.validSets {
+		data = append(data, rezi.EncSliceInt(ct.validSets[i])...)
+	}
+	data = append(data, rezi.EncBinary(ct.actions)...)
+	data = append(data, rezi.EncBinary(ct.gotos)...)
+	return data, nil
+}
+
+// UnmarshalBinary decodes a slice of bytes created by MarshalBinary into ct.
+// All of ct's fields will be replaced by the fields decoded from data.
+func (ct *compressedLRTable) UnmarshalBinary(data []byte) error {
+	var err error
+	var n int
+
+	ct.initial, n, err = rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf(".initial: %w", err)
+	}
+	data = data[n:]
+
+	ct.numStates, n, err = rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf(".numStates: %w", err)
+	}
+	data = data[n:]
+
+	ct.gTerms, n, err = rezi.DecSliceString(data)
+	if err != nil {
+		return fmt.Errorf(".gTerms: %w", err)
+	}
+	data = data[n:]
+
+	ct.gNonTerms, n, err = rezi.DecSliceString(data)
+	if err != nil {
+		return fmt.Errorf(".gNonTerms: %w", err)
+	}
+	data = data[n:]
+
+	var reductions []*lrAction
+	reductions, n, err = rezi.DecSliceBinary[*lrAction](data)
+	if err != nil {
+		return fmt.Errorf(".reductions: %w", err)
+	}
+	data = data[n:]
+	ct.reductions = make([]lrAction, len(reductions))
+	for i := range reductions {
+		ct.reductions[i] = *reductions[i]
+	}
+
+	ct.defaults, n, err = rezi.DecSliceInt(data)
+	if err != nil {
+		return fmt.Errorf(".defaults: %w", err)
+	}
+	data = data[n:]
+
+	ct.valid, n, err = rezi.DecSliceInt(data)
+	if err != nil {
+		return fmt.Errorf(".valid: %w", err)
+	}
+	data = data[n:]
+
+	var numSets int
+	numSets, n, err = rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf(".validSets: %w", err)
+	}
+	data = data[n:]
+	ct.validSets = make([][]int, numSets)
+	for i := 0; i < numSets; i++ {
+		ct.validSets[i], n, err = rezi.DecSliceInt(data)
+		if err != nil {
+			return fmt.Errorf(".validSets[%d]: %w", i, err)
+		}
+		data = data[n:]
+	}
+
+	n, err = rezi.DecBinary(data, &ct.actions)
+	if err != nil {
+		return fmt.Errorf(".actions: %w", err)
+	}
+	data = data[n:]
+
+	_, err = rezi.DecBinary(data, &ct.gotos)
+	if err != nil {
+		return fmt.Errorf(".gotos: %w", err)
+	}
+
+	ct.buildColumns()
+	ct.src = nil
+	ct.kernels = &lrKernels{}
+	return nil
+}
+
+// Initial returns the initial state of the table.
+func (ct *compressedLRTable) Initial() string {
+	return strconv.Itoa(ct.initial)
+}
+
+// States returns the names of all states in the table, with the initial state
+// first.
+func (ct *compressedLRTable) States() []string {
+	states := make([]string, ct.numStates)
+	for i := range states {
+		states[i] = strconv.Itoa(i)
+	}
+	return orderStates(states, ct.Initial())
+}
+
+// Action returns the LR-parser action to perform given that the current state
+// is i and the next terminal input symbol seen is a.
+func (ct *compressedLRTable) Action(i, a string) lrAction {
+	s, err := strconv.Atoi(i)
+	if err != nil || s < 0 || s >= ct.numStates {
+		return lrAction{Type: lrError}
+	}
+	col, ok := ct.termCols[a]
+	if !ok {
+		return lrAction{Type: lrError}
+	}
+
+	if code, ok := ct.actions.get(s, col); ok {
+		return ct.decodeAction(code)
+	}
+
+	setID := ct.valid[s]
+	if setID < 0 || ct.validSets[setID][col/32]&(1<<(col%32)) == 0 {
+		return lrAction{Type: lrError}
+	}
+	return ct.decodeAction(ct.defaults[s])
+}
+
+// Goto returns the state to transition to after reducing a non-terminal symbol.
+func (ct *compressedLRTable) Goto(state, symbol string) (string, error) {
+	s, err := strconv.Atoi(state)
+	if err != nil || s < 0 || s >= ct.numStates {
+		return "", fmt.Errorf("GOTO[%q, %q] is an error entry", state, symbol)
+	}
+	col, ok := ct.ntCols[symbol]
+	if !ok {
+		return "", fmt.Errorf("GOTO[%q, %q] is an error entry", state, symbol)
+	}
+
+	t, ok := ct.gotos.get(s, col)
+	if !ok {
+		return "", fmt.Errorf("GOTO[%q, %q] is an error entry", state, symbol)
+	}
+	return strconv.Itoa(t), nil
+}
+
+// DFAString returns the DFA of the table that ct was compressed from. If ct
+// was not created from one, as is the case when it was decoded from bytes or
+// made from an LRTable, an empty string is returned.
+func (ct *compressedLRTable) DFAString() string {
+	if ct.src == nil {
+		return ""
+	}
+	return ct.src.DFAString()
+}
+
+// String returns the string representation of the table.
+func (ct *compressedLRTable) String() string {
+	stateNames := ct.States()
+
+	// states are named by index, but not necessarily in the same order that
+	// they are shown in.
+	stateRefs := map[string]string{}
+	for i := range stateNames {
+		stateRefs[stateNames[i]] = fmt.Sprintf("%d", i)
+	}
+
+	allTerms := make([]string, len(ct.gTerms))
+	copy(allTerms, ct.gTerms)
+	allTerms = append(allTerms, "$")
+
+	data := [][]string{}
+
+	headers := []string{"S", "|"}
+	for _, t := range allTerms {
+		headers = append(headers, fmt.Sprintf("A:%s", t))
+	}
+	headers = append(headers, "|")
+	for _, nt := range ct.gNonTerms {
+		headers = append(headers, fmt.Sprintf("G:%s", nt))
+	}
+	data = append(data, headers)
+
+	for stateIdx := range stateNames {
+		i := stateNames[stateIdx]
+		row := []string{stateRefs[i], "|"}
+
+		for _, t := range allTerms {
+			act := ct.Action(i, t)
+
+			cell := ""
+			switch act.Type {
+			case lrAccept:
+				cell = "acc"
+			case lrReduce:
+				var prodStr string
+				if len(act.Production) > 0 {
+					prodStr = act.Production.String()
+				} else {
+					prodStr = grammar.Epsilon.String()
+				}
+				cell = fmt.Sprintf("r%s -> %s", act.Symbol, prodStr)
+			case lrShift:
+				cell = fmt.Sprintf("s%s", stateRefs[act.State])
+			case lrError:
+				// do nothing, err is blank
+			}
+
+			row = append(row, cell)
+		}
+
+		row = append(row, "|")
+
+		for _, nt := range ct.gNonTerms {
+			var cell = ""
+
+			gotoState, err := ct.Goto(i, nt)
+			if err == nil {
+				cell = stateRefs[gotoState]
+			}
+
+			row = append(row, cell)
+		}
+
+		data = append(data, row)
+	}
+
+	return rosed.
+		Edit("").
+		InsertTableOpts(0, data, 10, rosed.Options{
+			TableHeaders:             true,
+			NoTrailingLineSeparators: true,
+		}).
+		String()
+}
+
+// combVector is a set of sparse rows of ints overlaid into a single pair of
+// arrays by row displacement. Rows are stored at an offset given by base such
+// that no two rows' entries fall in the same slot; check records which row
+// owns each slot so that a lookup can tell a missing entry from another row's.
+// Rows with the same entries are stored only once.
+type combVector struct {
+	// rowOf is the stored row that each logical row uses.
+	rowOf []int
+
+	// base is the offset into next and check of each stored row.
+	base []int
+
+	next  []int
+	check []int
+}
+
+// packRows creates a combVector containing rows, each of which maps a column
+// to a value.
+func packRows(rows []map[int]int) combVector {
+	cv := combVector{rowOf: make([]int, len(rows))}
+
+	var distinct []map[int]int
+	rowIDs := map[string]int{}
+	for i := range rows {
+		cols := sortedCols(rows[i])
+		var sb strings.Builder
+		for _, c := range cols {
+			sb.WriteString(fmt.Sprintf("%d:%d,", c, rows[i][c]))
+		}
+		key := sb.String()
+
+		id, ok := rowIDs[key]
+		if !ok {
+			id = len(distinct)
+			rowIDs[key] = id
+			distinct = append(distinct, rows[i])
+		}
+		cv.rowOf[i] = id
+	}
+
+	// place the rows with the most entries first, since they are the hardest
+	// to fit.
+	order := make([]int, len(distinct))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(distinct[order[i]]) > len(distinct[order[j]])
+	})
+
+	cv.base = make([]int, len(distinct))
+	for _, id := range order {
+		cols := sortedCols(distinct[id])
+		if len(cols) == 0 {
+			continue
+		}
+
+		b := 0
+		for ; ; b++ {
+			fits := true
+			for _, c := range cols {
+				if b+c < len(cv.check) && cv.check[b+c] != -1 {
+					fits = false
+					break
+				}
+			}
+			if fits {
+				break
+			}
+		}
+
+		for len(cv.check) < b+cols[len(cols)-1]+1 {
+			cv.check = append(cv.check, -1)
+			cv.next = append(cv.next, 0)
+		}
+		for _, c := range cols {
+			cv.check[b+c] = id
+			cv.next[b+c] = distinct[id][c]
+		}
+		cv.base[id] = b
+	}
+
+	return cv
+}
+
+// sortedCols returns the columns of row in ascending order.
+func sortedCols(row map[int]int) []int {
+	cols := make([]int, 0, len(row))
+	for c := range row {
+		cols = append(cols, c)
+	}
+	sort.Ints(cols)
+	return cols
+}
+
+// get returns the value at the given row and column. If there is no entry
+// there, the returned bool will be false.
+func (cv combVector) get(row, col int) (int, bool) {
+	if row < 0 || row >= len(cv.rowOf) {
+		return 0, false
+	}
+
+	id := cv.rowOf[row]
+	i := cv.base[id] + col
+	if i < 0 || i >= len(cv.check) || cv.check[i] != id {
+		return 0, false
+	}
+	return cv.next[i], true
+}
+
+// size returns the number of values stored in cv.
+func (cv combVector) size() int {
+	return len(cv.rowOf) + len(cv.base) + len(cv.next) + len(cv.check)
+}
+
+// MarshalBinary converts cv into a slice of bytes that can be decoded with
+// UnmarshalBinary.
+func (cv combVector) MarshalBinary() ([]byte, error) {
+	data := rezi.EncSliceInt(cv.rowOf)
+	data = append(data, rezi.EncSliceInt(cv.base)...)
+	data = append(data, rezi.EncSliceInt(cv.next)...)
+	data = append(data, rezi.EncSliceInt(cv.check)...)
+	return data, nil
+}
+
+// UnmarshalBinary decodes a slice of bytes created by MarshalBinary into cv.
+// All of cv's fields will be replaced by the fields decoded from data.
+func (cv *combVector) UnmarshalBinary(data []byte) error {
+	var err error
+	var n int
+
+	cv.rowOf, n, err = rezi.DecSliceInt(data)
+	if err != nil {
+		return fmt.Errorf(".rowOf: %w", err)
+	}
+	data = data[n:]
+
+	cv.base, n, err = rezi.DecSliceInt(data)
+	if err != nil {
+		return fmt.Errorf(".base: %w", err)
+	}
+	data = data[n:]
+
+	cv.next, n, err = rezi.DecSliceInt(data)
+	if err != nil {
+		return fmt.Errorf(".next: %w", err)
+	}
+	data = data[n:]
+
+	cv.check, _, err = rezi.DecSliceInt(data)
+	if err != nil {
+		return fmt.Errorf(".check: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/rezi"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compressedFrom(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ctor    func(grammar.CFG, bool) (lrParseTable, []string, error)
+		g       string
+		ambig   bool
+		smaller bool
+	}{
+		{
+			name:    "SLR table",
+			ctor:    constructSLR1ParseTable,
+			smaller: true,
+			g: `
+				E -> E + T | T ;
+				T -> T * F | F ;
+				F -> ( E ) | id ;
+			`,
+		},
+		{
+			name: "LALR table with epsilon productions",
+			ctor: constructLALR1ParseTable,
+			g: `
+				S -> a A | b B ;
+				A -> a A | ε   ;
+				B -> b B | ε   ;
+			`,
+		},
+		{
+			name: "CLR table",
+			ctor: constructCLR1ParseTable,
+			g: `
+				S -> C C ;
+				C -> c C | d ;
+			`,
+		},
+		{
+			name: "ambiguous LALR table",
+			ctor: constructLALR1ParseTable,
+			g: `
+				S -> S S + | S S * | a ;
+			`,
+			ambig: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			g := grammar.MustParse(tc.g)
+
+			full, _, err := tc.ctor(g, tc.ambig)
+			if !assert.NoError(err, "constructing table failed") {
+				return
+			}
+
+			// execute
+			actual := compressedFrom(full, g)
+
+			// assert
+			assert.Equal(full.String(), actual.String())
+
+			// every entry must match, including errors. states are renamed to
+			// their index, so map them first.
+			fullStates := full.States()
+			stateIdx := map[string]string{}
+			for i, s := range actual.States() {
+				stateIdx[fullStates[i]] = s
+			}
+			terms := append(g.Terminals(), "$", "not-a-terminal")
+			for _, s := range fullStates {
+				for _, a := range terms {
+					expect := full.Action(s, a)
+					if expect.Type == lrShift {
+						expect.State = stateIdx[expect.State]
+					}
+					assert.Equal(expect.String(), actual.Action(stateIdx[s], a).String(), "ACTION[%s, %s]", s, a)
+				}
+				for _, A := range g.NonTerminals() {
+					expect, expectErr := full.Goto(s, A)
+					actualState, err := actual.Goto(stateIdx[s], A)
+					if expectErr != nil {
+						assert.Error(err, "GOTO[%s, %s]", s, A)
+					} else {
+						assert.Equal(stateIdx[expect], actualState, "GOTO[%s, %s]", s, A)
+					}
+				}
+			}
+
+			// tiny tables can take more space compressed, so only check the
+			// ones that shouldn't.
+			if tc.smaller {
+				assert.Less(actual.size(), actual.fullSize())
+			}
+		})
+	}
+}
+
+func Test_packRows(t *testing.T) {
+	assert := assert.New(t)
+	rows := []map[int]int{
+		{0: 10, 2: 12},
+		{1: 21},
+		{0: 10, 2: 12},
+		{},
+		{0: 40, 1: 41, 2: 42, 3: 43},
+	}
+
+	// execute
+	cv := packRows(rows)
+
+	// assert
+	assert.Equal(cv.rowOf[0], cv.rowOf[2], "equivalent rows are not shared")
+	assert.Len(cv.base, 4)
+	for r := range rows {
+		for c := 0; c < 5; c++ {
+			v, ok := cv.get(r, c)
+			expect, expectOk := rows[r][c]
+			assert.Equal(expectOk, ok, "row %d col %d", r, c)
+			assert.Equal(expect, v, "row %d col %d", r, c)
+		}
+	}
+}
+
+func Test_lrParser_UnmarshalBinary_uncompressed(t *testing.T) {
+	assert := assert.New(t)
+	g := grammar.MustParse(`
+		S -> C C ;
+		C -> c C | d ;
+	`)
+	table, _, err := constructLALR1ParseTable(g, false)
+	if !assert.NoError(err, "constructing table failed") {
+		return
+	}
+
+	// encoded the way parsers were before table compression
+	data := rezi.EncString(LALR1.String())
+	data = append(data, rezi.EncBinary(table)...)
+	data = append(data, rezi.EncBinary(g)...)
+
+	// execute
+	actual := &lrParser{}
+	err = actual.UnmarshalBinary(data)
+
+	// assert
+	if !assert.NoError(err) {
+		return
+	}
+	assert.IsType(&compressedLRTable{}, actual.table)
+	assert.Equal(table.String(), actual.TableString())
+	assert.Equal(table.DFAString(), actual.DFAString())
+}
@@ -0,0 +1,510 @@
+package parse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/rosed"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/rezi"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+type cykParser struct {
+	g  grammar.CFG
+	nf grammar.NormalForm
+
+	// entries maps each entry point of g to the symbol it was renamed to in
+	// the CNF grammar.
+	entries map[string]string
+
+	// pairs is every production A -> B C of the CNF grammar, in the order they
+	// are in it.
+	pairs []cykPair
+
+	// terms maps each terminal to the non-terminals A with a production A -> a
+	// in the CNF grammar, in the order they are in it.
+	terms map[string][]string
+
+	trace tracer
+}
+
+// cykPair is a production Head -> Left Right of a grammar in Chomsky normal
+// form.
+type cykPair struct {
+	Head, Left, Right string
+}
+
+// cykEntry is how a non-terminal in a cell of a CYKChart derives the tokens of
+// the cell.
+type cykEntry struct {
+	prod grammar.Production
+
+	// split is the number of tokens derived by the first symbol of prod. It is
+	// 0 when prod is a single terminal.
+	split int
+}
+
+// Grammar returns the grammar that was used to generate the parser.
+func (cyk *cykParser) Grammar() grammar.CFG {
+	return cyk.g
+}
+
+// DFAString would normally return a string representation of the DFA that
+// drives the parser, but CYK parsers do not construct a DFA, and so this
+// returns a string indicating such.
+func (cyk *cykParser) DFAString() string {
+	return "(CYK parser does not use a DFA)"
+}
+
+// RegisterTraceListener sets a function to be called with messages that
+// indicate what action the parser is taking. It is useful for debug purposes.
+func (cyk *cykParser) RegisterTraceListener(listener func(s string)) {
+	cyk.trace.setMsgs(listener)
+}
+
+// RegisterTraceEventListener sets a function to be called with each step that
+// the parser takes.
+func (cyk *cykParser) RegisterTraceEventListener(listener func(ev TraceEvent)) {
+	cyk.trace.setEvents(listener)
+}
+
+// TableString returns the rules of the grammar in Chomsky normal form that the
+// parser fills its chart with as a string. The chart itself depends on the
+// input; use CYKChartOf to get it for a particular one.
+func (cyk *cykParser) TableString() string {
+	data := [][]string{{"", "Production"}}
+	for _, nt := range cyk.nf.Grammar.NonTerminalsByPriority() {
+		for _, p := range cyk.nf.Grammar.Rule(nt).Productions {
+			data = append(data, []string{nt, p.String()})
+		}
+	}
+
+	return rosed.Edit("").
+		InsertTableOpts(0, data, 80, rosed.Options{
+			TableBorders: true,
+			TableHeaders: true,
+		}).
+		String()
+}
+
+// MarshalBinary converts cyk into a slice of bytes that can be decoded with
+// UnmarshalBinary. Only the grammar is encoded, as everything else is made
+// from it again when it is decoded.
+func (cyk *cykParser) MarshalBinary() ([]byte, error) {
+	return rezi.EncBinary(cyk.g), nil
+}
+
+// UnmarshalBinary decodes a slice of bytes created by MarshalBinary into cyk.
+// All of cyk's fields will be replaced by the fields decoded from data.
+func (cyk *cykParser) UnmarshalBinary(data []byte) error {
+	var g grammar.CFG
+	_, err := rezi.DecBinary(data, &g)
+	if err != nil {
+		return fmt.Errorf("g: %w", err)
+	}
+
+	decoded, err := newCYKParser(g)
+	if err != nil {
+		return fmt.Errorf("g: %w", err)
+	}
+
+	cyk.g = decoded.g
+	cyk.nf = decoded.nf
+	cyk.entries = decoded.entries
+	cyk.pairs = decoded.pairs
+	cyk.terms = decoded.terms
+	return nil
+}
+
+// EmptyCYKParser returns a completely empty CYK parser, unsuitable for use.
+// Generally this should not be used directly except for internal purposes; use
+// GenerateCYKParser to generate one ready for use.
+func EmptyCYKParser() Parser {
+	return &cykParser{}
+}
+
+// GenerateCYKParser generates a CYK parser for grammar g. Unlike the other
+// parsers, any context-free grammar can be used, including ambiguous ones; g is
+// converted to Chomsky normal form internally, and the parse trees returned are
+// converted back to be in terms of g. If g is ambiguous, one of the possible
+// parse trees is returned, and it will be the same one each time for the same
+// input; where the first symbols of a production could derive more or fewer of
+// the tokens, the tree where they derive more is preferred, which makes a
+// left-recursive rule such as E -> E plus E associate to the left.
+//
+// Parsing takes time proportional to the cube of the number of tokens, so this
+// is best suited to short inputs, checking whether a string is in the language
+// of a grammar, and comparing against the results of the other parsers.
+func GenerateCYKParser(g grammar.CFG) (Parser, error) {
+	cyk, err := newCYKParser(g)
+	if err != nil {
+		return &cykParser{}, err
+	}
+	return cyk, nil
+}
+
+// newCYKParser converts g to Chomsky normal form and returns a cykParser that
+// uses it.
+func newCYKParser(g grammar.CFG) (*cykParser, error) {
+	nf, err := g.ChomskyNormalForm()
+	if err != nil {
+		return nil, fmt.Errorf("convert to Chomsky normal form: %w", err)
+	}
+
+	cyk := &cykParser{
+		g:       nf.Original,
+		nf:      nf,
+		entries: map[string]string{g.StartSymbol(): nf.Grammar.StartSymbol()},
+		terms:   map[string][]string{},
+	}
+
+	// the conversion renames entry points in place, so each is at the same
+	// index it was in g.
+	for i := range g.EntryPoints {
+		cyk.entries[g.EntryPoints[i]] = nf.Grammar.EntryPoints[i]
+	}
+
+	for _, nt := range nf.Grammar.NonTerminalsByPriority() {
+		for _, p := range nf.Grammar.Rule(nt).Productions {
+			if len(p) == 2 {
+				cyk.pairs = append(cyk.pairs, cykPair{Head: nt, Left: p[0], Right: p[1]})
+			} else if !p.Equal(grammar.Epsilon) {
+				cyk.terms[p[0]] = append(cyk.terms[p[0]], nt)
+			}
+		}
+	}
+
+	return cyk, nil
+}
+
+// Type returns the type of the parser. This will be CYK for a CYK parser.
+func (cyk *cykParser) Type() Algorithm {
+	return CYK
+}
+
+// Parse takes a stream of tokens and parses it into a parse tree. If any syntax
+// errors are encountered, an empty parse tree and a syntaxerr.Diagnostics is
+// returned.
+func (cyk *cykParser) Parse(stream lex.TokenStream) (Tree, error) {
+	return cyk.ParseAs(cyk.g.StartSymbol(), stream)
+}
+
+// ParseAs takes a stream of tokens and parses it into a parse tree for the
+// given entry point of the grammar. If any syntax errors are encountered, an
+// empty parse tree and a syntaxerr.Diagnostics is returned.
+func (cyk *cykParser) ParseAs(symbol string, stream lex.TokenStream) (Tree, error) {
+	return cyk.parseAs(context.Background(), symbol, stream, Limits{})
+}
+
+// ParseContext is the same as Parse but stops parsing when ctx is done or when
+// the parse tree would exceed one of the given limits, returning ctx.Err() or a
+// *syntaxerr.LimitError respectively.
+func (cyk *cykParser) ParseContext(ctx context.Context, stream lex.TokenStream, lim Limits) (Tree, error) {
+	return cyk.parseAs(ctx, cyk.g.StartSymbol(), stream, lim)
+}
+
+// parseAs parses stream as the given entry point of the grammar. It stops
+// when ctx is done or lim is exceeded.
+func (cyk *cykParser) parseAs(ctx context.Context, symbol string, stream lex.TokenStream, lim Limits) (Tree, error) {
+	if err := checkEntryPoint(cyk.g, symbol); err != nil {
+		return Tree{}, err
+	}
+
+	chart, err := cyk.fill(ctx, symbol, stream)
+	if err != nil {
+		return Tree{}, err
+	}
+
+	if !chart.Accepts() {
+		err := cyk.syntaxError(chart)
+		cyk.notify(TraceError, chart.end, func(ev *TraceEvent) { ev.Err = err })
+		return Tree{}, err
+	}
+
+	var cnfTree *Tree
+	if len(chart.tokens) == 0 {
+		cnfTree = &Tree{Value: chart.start, Children: []*Tree{{Terminal: true, Span: lex.EmptySpanAt(chart.end)}}}
+	} else {
+		cnfTree = chart.tree(chart.start, 0, len(chart.tokens))
+	}
+
+	pt, err := cnfTree.Denormalize(cyk.nf)
+	if err != nil {
+		return Tree{}, fmt.Errorf("convert parse tree from Chomsky normal form: %w", err)
+	}
+	if err := lim.CheckTree(pt); err != nil {
+		return Tree{}, err
+	}
+
+	cyk.notify(TraceAccept, chart.end, nil)
+	return pt, nil
+}
+
+// notify sends an event of the given type to the trace listeners of cyk. The
+// event is filled out with the given values.
+func (cyk *cykParser) notify(evType TraceEventType, next lex.Token, fill func(ev *TraceEvent)) {
+	cyk.trace.notify(func() TraceEvent {
+		ev := TraceEvent{Type: evType, Lookahead: next}
+		if fill != nil {
+			fill(&ev)
+		}
+		return ev
+	})
+}
+
+// fill reads every token from stream and returns the chart of them for the
+// given entry point. It stops when ctx is done.
+func (cyk *cykParser) fill(ctx context.Context, symbol string, stream lex.TokenStream) (CYKChart, error) {
+	chart := CYKChart{g: cyk.nf.Grammar, start: cyk.entries[symbol]}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return chart, err
+		}
+
+		tok := stream.Next()
+		cyk.notify(TraceLookahead, tok, nil)
+		if err := lex.TokenErr(tok); err != nil {
+			return chart, err
+		}
+		if tok.Class().ID() == lex.TokenError.ID() {
+			err := diagnose(syntaxerr.CodeLexical, tok.Lexeme(), tok)
+			cyk.notify(TraceError, tok, func(ev *TraceEvent) { ev.Err = err })
+			return chart, err
+		}
+		if tok.Class().ID() == lex.TokenEndOfText.ID() {
+			chart.end = tok
+			break
+		}
+
+		// a token that is not in the grammar gets an empty cell, which leaves
+		// every cell with it empty as well.
+		term := cyk.g.TermFor(tok.Class())
+		cell := map[string]cykEntry{}
+		for _, A := range cyk.terms[term] {
+			cell[A] = cykEntry{prod: grammar.Production{term}}
+		}
+		chart.tokens = append(chart.tokens, tok)
+		chart.cells = append(chart.cells, []map[string]cykEntry{cell})
+	}
+
+	// cells[i][l-1] holds the non-terminals that derive the l tokens starting
+	// at token i, so each cell only needs the ones for shorter spans.
+	n := len(chart.tokens)
+	for l := 2; l <= n; l++ {
+		if err := ctx.Err(); err != nil {
+			return chart, err
+		}
+
+		for i := 0; i+l <= n; i++ {
+			// splits are tried longest first so that of several derivations,
+			// the one with the most tokens under the first child is kept.
+			cell := map[string]cykEntry{}
+			for split := l - 1; split >= 1; split-- {
+				left := chart.cells[i][split-1]
+				right := chart.cells[i+split][l-split-1]
+				if len(left) == 0 || len(right) == 0 {
+					continue
+				}
+				for _, p := range cyk.pairs {
+					if _, ok := cell[p.Head]; ok {
+						continue
+					}
+					_, leftOK := left[p.Left]
+					_, rightOK := right[p.Right]
+					if leftOK && rightOK {
+						cell[p.Head] = cykEntry{prod: grammar.Production{p.Left, p.Right}, split: split}
+					}
+				}
+			}
+			chart.cells[i] = append(chart.cells[i], cell)
+		}
+	}
+
+	return chart, nil
+}
+
+// syntaxError returns the error for chart not deriving its input from its
+// start symbol. The error is given for the first token that cannot follow the
+// ones before it in any input, or for the end of input if every token can.
+func (cyk *cykParser) syntaxError(chart CYKChart) error {
+	n := len(chart.tokens)
+	pre := cyk.prefixes(chart)
+
+	// the empty prefix is always fine, so find the longest one after it.
+	next := chart.end
+	for j := 1; j <= n; j++ {
+		if !pre[0][j-1][chart.start] {
+			next = chart.tokens[j-1]
+			break
+		}
+	}
+
+	return diagnose(syntaxerr.CodeUnexpectedToken, fmt.Sprintf("unexpected %s", next.Class().Human()), next)
+}
+
+// prefixes returns which non-terminals derive a string that starts with each
+// span of the tokens in chart. The returned slice is indexed the same way as
+// the cells of chart.
+func (cyk *cykParser) prefixes(chart CYKChart) [][]map[string]bool {
+	n := len(chart.tokens)
+	pre := make([][]map[string]bool, n)
+	for i := range pre {
+		pre[i] = make([]map[string]bool, n-i)
+	}
+
+	for l := 1; l <= n; l++ {
+		for i := 0; i+l <= n; i++ {
+			cell := map[string]bool{}
+			for A := range chart.cells[i][l-1] {
+				cell[A] = true
+			}
+
+			// A -> B C derives a string starting with the span if C does and B
+			// derives the tokens before C's part exactly.
+			for split := 1; split < l; split++ {
+				for _, p := range cyk.pairs {
+					if _, ok := chart.cells[i][split-1][p.Left]; ok && pre[i+split][l-split-1][p.Right] {
+						cell[p.Head] = true
+					}
+				}
+			}
+
+			// A -> B C also does if B does, which can go through any number
+			// of productions.
+			for changed := true; changed; {
+				changed = false
+				for _, p := range cyk.pairs {
+					if cell[p.Left] && !cell[p.Head] {
+						cell[p.Head] = true
+						changed = true
+					}
+				}
+			}
+
+			pre[i][l-1] = cell
+		}
+	}
+
+	return pre
+}
+
+// CYKChart is the table that a CYK parser fills in for a particular input.
+// Each cell is for a span of tokens of the input and holds the non-terminals of
+// the parser's grammar in Chomsky normal form that derive exactly that span.
+// The input is accepted if the cell for all of it has the start symbol.
+type CYKChart struct {
+	g      grammar.CFG
+	start  string
+	tokens []lex.Token
+	end    lex.Token
+
+	// cells[i][l-1] is the cell for the l tokens starting at token i.
+	cells [][]map[string]cykEntry
+}
+
+// CYKChartOf fills in the chart of p, which must be a CYK parser, for the tokens
+// of stream. This only recognizes the input; a syntax error in it is not an
+// error for this function, and Accepts can be called on the returned chart to
+// check whether there was one. An error is only returned if stream gives a
+// lexical error.
+func CYKChartOf(p Parser, stream lex.TokenStream) (CYKChart, error) {
+	cyk, ok := p.(*cykParser)
+	if !ok {
+		return CYKChart{}, fmt.Errorf("not a CYK parser")
+	}
+
+	return cyk.fill(context.Background(), cyk.g.StartSymbol(), stream)
+}
+
+// Len returns the number of tokens in the input of the chart.
+func (c CYKChart) Len() int {
+	return len(c.tokens)
+}
+
+// Tokens returns the tokens of the input of the chart, not including the end
+// of input.
+func (c CYKChart) Tokens() []lex.Token {
+	toks := make([]lex.Token, len(c.tokens))
+	copy(toks, c.tokens)
+	return toks
+}
+
+// Cell returns the non-terminals that derive the length tokens starting at
+// token start, in the order they are in the grammar. An empty slice is
+// returned if the span is not within the input.
+func (c CYKChart) Cell(start, length int) []string {
+	if start < 0 || length < 1 || start+length > len(c.tokens) {
+		return []string{}
+	}
+
+	cell := c.cells[start][length-1]
+	nts := []string{}
+	for _, nt := range c.g.NonTerminalsByPriority() {
+		if _, ok := cell[nt]; ok {
+			nts = append(nts, nt)
+		}
+	}
+	return nts
+}
+
+// Accepts returns whether the input of the chart is derived from the start
+// symbol, which is to say whether it is in the language of the grammar.
+func (c CYKChart) Accepts() bool {
+	if len(c.tokens) == 0 {
+		return c.g.Rule(c.start).HasProduction(grammar.Epsilon)
+	}
+	_, ok := c.cells[0][len(c.tokens)-1][c.start]
+	return ok
+}
+
+// String returns the chart as a table with a column for each token of the input
+// and a row for each length of span. The cell in the row for length l and the
+// column for token i lists the non-terminals that derive the l tokens starting
+// at token i.
+func (c CYKChart) String() string {
+	topRow := []string{""}
+	for _, tok := range c.tokens {
+		topRow = append(topRow, tok.Class().ID())
+	}
+	data := [][]string{topRow}
+
+	for l := 1; l <= len(c.tokens); l++ {
+		row := []string{fmt.Sprintf("%d", l)}
+		for i := range c.tokens {
+			row = append(row, strings.Join(c.Cell(i, l), ", "))
+		}
+		data = append(data, row)
+	}
+
+	return rosed.Edit("").
+		InsertTableOpts(0, data, 80, rosed.Options{
+			TableBorders: true,
+			TableHeaders: true,
+		}).
+		String()
+}
+
+// tree returns the parse tree of the grammar in Chomsky normal form for nt
+// deriving the length tokens starting at token start. nt must be in the cell
+// for them.
+func (c CYKChart) tree(nt string, start, length int) *Tree {
+	entry := c.cells[start][length-1][nt]
+	node := &Tree{Value: nt}
+
+	if entry.split == 0 {
+		tok := c.tokens[start]
+		node.Children = []*Tree{{Terminal: true, Value: entry.prod[0], Source: tok, Span: lex.SpanOf(tok)}}
+		return node
+	}
+
+	node.Children = []*Tree{
+		c.tree(entry.prod[0], start, entry.split),
+		c.tree(entry.prod[1], start+entry.split, length-entry.split),
+	}
+	return node
+}
@@ -0,0 +1,265 @@
+package parse
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CYKParse(t *testing.T) {
+	testCases := []struct {
+		name      string
+		grammar   string
+		entry     string
+		input     []string
+		expect    string
+		expectErr string
+	}{
+		{
+			name:    "ambiguous grammar",
+			grammar: `E -> E plus E | int ;`,
+			input:   []string{"int", "plus", "int", "plus", "int", "$"},
+			expect:  `[E [E [E (int)] (plus) [E (int)]] (plus) [E (int)]]`,
+		},
+		{
+			name:    "left-recursive grammar",
+			grammar: `E -> E plus T | T ; T -> T times F | F ; F -> lp E rp | int ;`,
+			input:   []string{"int", "times", "lp", "int", "plus", "int", "rp", "$"},
+			expect:  `[E [T [T [F (int)]] (times) [F (lp) [E [E [T [F (int)]]] (plus) [T [F (int)]]] (rp)]]]`,
+		},
+		{
+			name:    "epsilon productions",
+			grammar: `S -> a S b | ε ;`,
+			input:   []string{"a", "a", "b", "b", "$"},
+			expect:  `[S (a) [S (a) [S ()] (b)] (b)]`,
+		},
+		{
+			name:    "empty input",
+			grammar: `S -> a S b | ε ;`,
+			input:   []string{"$"},
+			expect:  `[S ()]`,
+		},
+		{
+			name:    "entry point",
+			grammar: `S -> T plus T ; T -> int | lp S rp ;`,
+			entry:   "T",
+			input:   []string{"lp", "int", "plus", "int", "rp", "$"},
+			expect:  `[T (lp) [S [T (int)] (plus) [T (int)]] (rp)]`,
+		},
+		{
+			name:      "unexpected token",
+			grammar:   `E -> E plus T | T ; T -> T times F | F ; F -> lp E rp | int ;`,
+			input:     []string{"int", "plus", "times", "int", "$"},
+			expectErr: "unexpected times",
+		},
+		{
+			name:      "unexpected end of input",
+			grammar:   `E -> E plus T | T ; T -> T times F | F ; F -> lp E rp | int ;`,
+			input:     []string{"lp", "int", "plus", "int", "$"},
+			expectErr: "unexpected $",
+		},
+		{
+			name:      "empty input not in language",
+			grammar:   `S -> a S b | a b ;`,
+			input:     []string{"$"},
+			expectErr: "unexpected $",
+		},
+		{
+			name:      "token not in grammar",
+			grammar:   `S -> a S b | a b ;`,
+			input:     []string{"a", "c", "b", "$"},
+			expectErr: "unexpected c",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := grammar.MustParse(tc.grammar)
+			if tc.entry != "" {
+				g.EntryPoints = []string{tc.entry}
+			}
+			p, err := GenerateCYKParser(g)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var actual Tree
+			if tc.entry != "" {
+				actual, err = ParseAs(p, tc.entry, mockTokens(tc.input...))
+			} else {
+				actual, err = p.Parse(mockTokens(tc.input...))
+			}
+
+			if tc.expectErr != "" {
+				if assert.Error(err) {
+					assert.Contains(err.Error(), tc.expectErr)
+				}
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			expect := MustParseTreeFromDiagram(tc.expect)
+			assert.Equal(expect.String(), actual.String())
+		})
+	}
+}
+
+func Test_CYKParse_GeneratedSentences(t *testing.T) {
+	grammars := []string{
+		`S -> a S b | ε ;`,
+		`E -> E plus E | E times E | lp E rp | int ;`,
+		`S -> A a | b ; A -> A c | S d | ε ;`,
+		`S -> L eq R | R ; L -> star R | id ; R -> L ;`,
+	}
+
+	for _, src := range grammars {
+		t.Run(src, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := grammar.MustParse(src)
+			p, err := GenerateCYKParser(g)
+			if !assert.NoError(err) {
+				return
+			}
+			gen, err := NewGenerator(g, GeneratorOptions{Seed: 1, MaxTokens: 15})
+			if !assert.NoError(err) {
+				return
+			}
+
+			for i := 0; i < 30; i++ {
+				sent := gen.Generate()
+				terms := treeTerminals(sent.Tree)
+
+				actual, err := p.Parse(mockTokens(append(terms, "$")...))
+				if !assert.NoError(err, sent.Text) {
+					return
+				}
+
+				assert.Equal(terms, treeTerminals(actual))
+				if err := checkDerivation(g, &actual); err != nil {
+					assert.NoError(err, actual.String())
+					return
+				}
+			}
+		})
+	}
+}
+
+func Test_CYKChartOf(t *testing.T) {
+	testCases := []struct {
+		name         string
+		grammar      string
+		input        []string
+		expectAccept bool
+		expectCells  map[[2]int][]string
+	}{
+		{
+			name:         "accepted",
+			grammar:      `S -> A B | B A ; A -> a ; B -> b ;`,
+			input:        []string{"a", "b", "$"},
+			expectAccept: true,
+			expectCells: map[[2]int][]string{
+				{0, 1}: {"A"},
+				{1, 1}: {"B"},
+				{0, 2}: {"S"},
+			},
+		},
+		{
+			name:         "not accepted",
+			grammar:      `S -> A B ; A -> a ; B -> b ;`,
+			input:        []string{"b", "a", "$"},
+			expectAccept: false,
+			expectCells: map[[2]int][]string{
+				{0, 1}: {"B"},
+				{1, 1}: {"A"},
+				{0, 2}: {},
+			},
+		},
+		{
+			name:         "empty input",
+			grammar:      `S -> a S | ε ;`,
+			input:        []string{"$"},
+			expectAccept: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			p, err := GenerateCYKParser(grammar.MustParse(tc.grammar))
+			if !assert.NoError(err) {
+				return
+			}
+
+			chart, err := CYKChartOf(p, mockTokens(tc.input...))
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectAccept, chart.Accepts())
+			assert.Equal(len(tc.input)-1, chart.Len())
+			for span, expect := range tc.expectCells {
+				assert.Equal(expect, chart.Cell(span[0], span[1]), fmt.Sprintf("cell %v", span))
+			}
+			assert.NotEmpty(chart.String())
+		})
+	}
+}
+
+func Test_CYKChartOf_NotCYKParser(t *testing.T) {
+	p, err := GenerateLL1Parser(grammar.MustParse(`S -> a ;`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = CYKChartOf(p, mockTokens("a", "$"))
+	assert.Error(t, err)
+}
+
+func Test_CYKParse_Spans(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := GenerateCYKParser(grammar.MustParse(`S -> a S b | ε ;`))
+	if !assert.NoError(err) {
+		return
+	}
+
+	stream := mockTokens("a", "b", "$")
+	actual, err := p.Parse(stream)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(lex.Position{Line: 1, Col: 1}, actual.Span.Start)
+	assert.Equal(actual.Children[0].Span.Start, actual.Span.Start)
+	assert.Equal(actual.Children[2].Span.End, actual.Span.End)
+
+	// the epsilon node is between the two tokens.
+	eps := actual.Children[1].Children[0]
+	assert.Equal(actual.Children[2].Span.Start, eps.Span.Start)
+	assert.Equal(eps.Span.Start, eps.Span.End)
+}
+
+// treeTerminals returns the value of every non-epsilon terminal node in pt in
+// order.
+func treeTerminals(pt Tree) []string {
+	var terms []string
+	if pt.Terminal {
+		if pt.Value != grammar.Epsilon[0] {
+			terms = append(terms, pt.Value)
+		}
+		return terms
+	}
+	for _, c := range pt.Children {
+		terms = append(terms, treeTerminals(*c)...)
+	}
+	return terms
+}
@@ -0,0 +1,132 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/box"
+	"github.com/dekarrin/ictiobus/lex"
+)
+
+// File entry.go contains functions for parsing input as one of the entry
+// points of a grammar instead of only as its start symbol.
+//
+// A grammar with entry points is parsed by building tables for its "entry
+// grammar", which has a new start symbol with one production for each entry
+// point that consists of a marker terminal for it followed by the entry point.
+// To parse input as an entry point, the marker terminal for it is given to the
+// parser before the input, and the node for the new start symbol is removed
+// from the resulting parse tree.
+
+// entryNames returns the name of the new start symbol in the entry grammar of
+// g, and the marker terminal used for each entry point of g.
+func entryNames(g grammar.CFG) (start string, markers map[string]string) {
+	start = g.GenerateUniqueName("ENTRY")
+	markers = map[string]string{}
+
+	used := box.NewStringSet()
+	for _, nt := range g.AllEntryPoints() {
+		m := "entry-" + strings.ToLower(nt)
+		for g.IsTerminal(m) || used.Has(m) {
+			m += "-p"
+		}
+		used.Add(m)
+		markers[nt] = m
+	}
+
+	return start, markers
+}
+
+// entryGrammar returns the grammar that parse tables for g are built from. If
+// g has no entry points, this is g itself; otherwise, it is the entry grammar
+// of g.
+func entryGrammar(g grammar.CFG) grammar.CFG {
+	if len(g.EntryPoints) == 0 {
+		return g
+	}
+
+	start, markers := entryNames(g)
+
+	eg := g.Copy()
+	eg.EntryPoints = nil
+	for _, nt := range g.AllEntryPoints() {
+		eg.AddTerm(markers[nt], lex.NewTokenClass(markers[nt], "start of "+nt))
+		eg.AddRule(start, []string{markers[nt], nt})
+	}
+	eg.Start = start
+
+	return eg
+}
+
+// checkEntryPoint returns an error if nt is not an entry point of g.
+func checkEntryPoint(g grammar.CFG, nt string) error {
+	if !g.IsEntryPoint(nt) {
+		return fmt.Errorf("%q is not an entry point of the grammar", nt)
+	}
+	return nil
+}
+
+// parseEntry parses stream as entry point nt of g by giving the marker for it
+// to parse before the tokens of stream. g must have entry points.
+func parseEntry(g grammar.CFG, nt string, stream lex.TokenStream, parse func(lex.TokenStream) (Tree, error)) (Tree, error) {
+	start, markers := entryNames(g)
+
+	pt, err := parse(markEntry(stream, markers[nt]))
+
+	// remove the node for the entry grammar's start symbol and the marker
+	// terminal, if the parser got far enough to create them.
+	if pt.Value == start && len(pt.Children) == 2 && pt.Children[1] != nil {
+		pt = *pt.Children[1]
+	}
+	return pt, err
+}
+
+// markedStream is a TokenStream that gives a marker token before the tokens
+// of the stream it wraps.
+type markedStream struct {
+	marker lex.Token
+	marked bool
+	stream lex.TokenStream
+}
+
+// markEntry returns a stream that gives a token for the given marker terminal
+// of an entry grammar before the tokens of stream. The marker token is empty
+// and is placed at the start of the first token of stream.
+func markEntry(stream lex.TokenStream, marker string) *markedStream {
+	return &markedStream{
+		marker: entryMarker(marker, stream.Peek()),
+		stream: stream,
+	}
+}
+
+// entryMarker returns a token for the given marker terminal of an entry
+// grammar. The token is empty and is placed at the start of first, in the same
+// file.
+func entryMarker(marker string, first lex.Token) lex.Token {
+	class := lex.NewTokenClass(marker, "entry point marker")
+	tok := lex.NewToken(class, "", first.LinePos(), first.Line(), first.FullLine())
+	return lex.WithFile(tok, lex.FileOf(first))
+}
+
+// Next returns the next token of the stream.
+func (ms *markedStream) Next() lex.Token {
+	if !ms.marked {
+		ms.marked = true
+		return ms.marker
+	}
+	return ms.stream.Next()
+}
+
+// Peek returns the next token of the stream without advancing it.
+func (ms *markedStream) Peek() lex.Token {
+	if !ms.marked {
+		return ms.marker
+	}
+	return ms.stream.Peek()
+}
+
+// HasNext returns whether the stream has any additional tokens.
+func (ms *markedStream) HasNext() bool {
+	return !ms.marked || ms.stream.HasNext()
+}
@@ -0,0 +1,224 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseAs(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> id eq E ;
+		E -> T X ;
+		X -> plus T X | ε ;
+		T -> id | int | lp E rp ;
+	`)
+	g.EntryPoints = []string{"E", "T"}
+
+	testCases := []struct {
+		name      string
+		symbol    string
+		input     []string
+		expect    *Tree
+		expectErr bool
+	}{
+		{
+			name:   "start symbol",
+			symbol: "S",
+			input:  []string{"id", "eq", "int", "$"},
+			expect: Node("S",
+				Leaf("id"),
+				Leaf("eq"),
+				Node("E",
+					Node("T", Leaf("int")),
+					Node("X", Leaf("")),
+				),
+			),
+		},
+		{
+			name:   "entry point",
+			symbol: "E",
+			input:  []string{"id", "plus", "int", "$"},
+			expect: Node("E",
+				Node("T", Leaf("id")),
+				Node("X",
+					Leaf("plus"),
+					Node("T", Leaf("int")),
+					Node("X", Leaf("")),
+				),
+			),
+		},
+		{
+			name:   "entry point also used by other entry point",
+			symbol: "T",
+			input:  []string{"lp", "int", "rp", "$"},
+			expect: Node("T",
+				Leaf("lp"),
+				Node("E",
+					Node("T", Leaf("int")),
+					Node("X", Leaf("")),
+				),
+				Leaf("rp"),
+			),
+		},
+		{
+			name:      "syntax error in entry point",
+			symbol:    "E",
+			input:     []string{"id", "eq", "$"},
+			expectErr: true,
+		},
+		{
+			name:      "not an entry point",
+			symbol:    "X",
+			input:     []string{"plus", "int", "$"},
+			expectErr: true,
+		},
+	}
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"SLR(1)": func() (Parser, error) {
+			p, _, err := GenerateSLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+		"CLR(1)": func() (Parser, error) {
+			p, _, err := GenerateCLR1Parser(g, false)
+			return p, err
+		},
+		"decoded LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			if err != nil {
+				return nil, err
+			}
+			return DecodeBytes(EncodeBytes(p))
+		},
+		"LALR(1) from table": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			if err != nil {
+				return nil, err
+			}
+			table, err := LRTableOf(p)
+			if err != nil {
+				return nil, err
+			}
+			return LRParserFromTable(LALR1, g, table)
+		},
+		"LL(1) from table": func() (Parser, error) {
+			p, err := GenerateLL1Parser(g)
+			if err != nil {
+				return nil, err
+			}
+			preds, err := LL1Predictions(p)
+			if err != nil {
+				return nil, err
+			}
+			return LL1ParserFromTable(g, preds)
+		},
+	}
+
+	for pName, ctor := range parsers {
+		for _, tc := range testCases {
+			t.Run(pName+" "+tc.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				p, err := ctor()
+				if !assert.NoError(err, "generating parser failed") {
+					return
+				}
+
+				// execute
+				actual, err := ParseAs(p, tc.symbol, mockTokens(tc.input...))
+
+				// assert
+				if tc.expectErr {
+					assert.Error(err)
+					return
+				} else if !assert.NoError(err) {
+					return
+				}
+
+				assert.True(tc.expect.Equal(actual), "expected:\n%s\nactual:\n%s", tc.expect.String(), actual.String())
+				assert.Equal(lex.Position{Line: 1, Col: 1}, actual.Span.Start, "span does not start at first token")
+			})
+		}
+	}
+}
+
+func Test_ParseAs_notEntryParser(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> id eq E ;
+		E -> T X ;
+		X -> plus T X | ε ;
+		T -> id | int | lp E rp ;
+	`)
+	g.EntryPoints = []string{"E"}
+
+	testCases := []struct {
+		name      string
+		symbol    string
+		input     []string
+		expectErr bool
+	}{
+		{
+			name:   "start symbol",
+			symbol: "S",
+			input:  []string{"id", "eq", "int", "$"},
+		},
+		{
+			name:      "entry point",
+			symbol:    "E",
+			input:     []string{"int", "$"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ll, err := GenerateLL1Parser(g)
+			if !assert.NoError(err, "generating parser failed") {
+				return
+			}
+
+			// execute
+			actual, err := ParseAs(plainParser{ll}, tc.symbol, mockTokens(tc.input...))
+
+			// assert
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+			assert.Equal("S", actual.Value)
+		})
+	}
+}
+
+func Test_EntryPoints_conflicts(t *testing.T) {
+	// B may be followed by the end of input only when it is an entry point,
+	// which makes reducing to it conflict with reducing to A for an SLR(1)
+	// parser. LALR(1) keeps the lookaheads for the two apart.
+	g := grammar.MustParse(`
+		S -> A | B c ;
+		A -> a ;
+		B -> a ;
+	`)
+
+	_, _, err := GenerateSLR1Parser(g, false)
+	assert.NoError(t, err, "SLR(1) without entry points")
+
+	g.EntryPoints = []string{"B"}
+	_, _, err = GenerateSLR1Parser(g, false)
+	assert.Error(t, err, "SLR(1) with entry points")
+
+	_, _, err = GenerateLALR1Parser(g, false)
+	assert.NoError(t, err, "LALR(1) with entry points")
+}
@@ -0,0 +1,339 @@
+package parse
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+)
+
+// GeneratorOptions are options for a Generator. The zero-value uses defaults
+// for every option.
+type GeneratorOptions struct {
+	// Seed is the seed of the random numbers used to pick productions. Two
+	// Generators for the same grammar with the same options and Seed will
+	// generate the same sentences in the same order.
+	Seed int64
+
+	// MaxDepth is the maximum depth of a generated parse tree, with the root of
+	// the tree being at depth 1. If 0, a default of 20 is used. It is an error
+	// for MaxDepth to be less than the depth of the shallowest parse tree of
+	// the grammar.
+	MaxDepth int
+
+	// MaxTokens is the number of tokens that a generated sentence is kept to
+	// when possible. Once a sentence would go over it, the rest of the
+	// sentence is derived with as few tokens as MaxDepth allows. If 0, a
+	// default of 100 is used.
+	MaxTokens int
+
+	// Weights gives how likely each production of a non-terminal is to be
+	// picked. The key is the non-terminal, and the value gives a weight for
+	// each production of its rule, in the same order as they are in the rule.
+	// A production is picked with a chance of its weight over the total
+	// weight of all productions that can be picked. A weight of 0 means the
+	// production is only picked when no other production can be. Non-terminals
+	// not in Weights have a weight of 1 for every production.
+	Weights map[string][]float64
+
+	// Lexemes gives a function that returns the text of a token for each
+	// token class ID, such as the map returned by
+	// lex.Lexer.FakeLexemeProducer. A token whose class is not in Lexemes
+	// uses placeholder text that will likely not lex.
+	Lexemes map[string]func() string
+
+	// Separator is the text placed between each token in generated source
+	// text. If empty, the lexemes of the tokens are put right after each
+	// other, which for most languages will only lex correctly if every token
+	// is ended by a symbol; setting it to " " for languages that discard
+	// whitespace avoids this.
+	Separator string
+}
+
+// Sentence is a random sentence made by a Generator.
+type Sentence struct {
+	// Tree is the parse tree that the sentence was derived with. The Source of
+	// each terminal node is set to the token in Text that it is for.
+	Tree Tree
+
+	// Text is the source text of the sentence, made by joining the lexeme of
+	// each token with the Separator of the Generator.
+	Text string
+}
+
+// Generator makes random sentences of a grammar along with their parse trees.
+// It can be used to make valid-by-construction inputs for testing or fuzzing
+// a frontend. A Generator is not safe for concurrent use.
+type Generator struct {
+	g    grammar.CFG
+	opts GeneratorOptions
+	rng  *rand.Rand
+
+	// minHeight and minTokens are the height and number of tokens of the
+	// smallest parse trees of each non-terminal.
+	minHeight map[string]int
+	minTokens map[string]int
+}
+
+// NewGenerator returns a Generator that makes sentences of g with the given
+// options.
+func NewGenerator(g grammar.CFG, opts GeneratorOptions) (*Generator, error) {
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid grammar: %w", err)
+	}
+
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = 20
+	}
+	if opts.MaxTokens == 0 {
+		opts.MaxTokens = 100
+	}
+	if opts.MaxDepth < 0 {
+		return nil, fmt.Errorf("max depth must be at least 0 but was %d", opts.MaxDepth)
+	}
+	if opts.MaxTokens < 0 {
+		return nil, fmt.Errorf("max tokens must be at least 0 but was %d", opts.MaxTokens)
+	}
+
+	for nt, weights := range opts.Weights {
+		if !g.IsNonTerminal(nt) {
+			return nil, fmt.Errorf("weights given for %q, which is not a non-terminal in the grammar", nt)
+		}
+		prodCount := len(g.Rule(nt).Productions)
+		if len(weights) != prodCount {
+			return nil, fmt.Errorf("%d weight(s) given for %s but it has %d production(s)", len(weights), nt, prodCount)
+		}
+		for i := range weights {
+			if weights[i] < 0 {
+				return nil, fmt.Errorf("weight %d of %s is negative", i, nt)
+			}
+		}
+	}
+
+	gen := &Generator{
+		g:         g,
+		opts:      opts,
+		rng:       rand.New(rand.NewSource(opts.Seed)),
+		minHeight: map[string]int{},
+		minTokens: map[string]int{},
+	}
+
+	// find the smallest trees by repeatedly improving on the smallest found
+	// so far until nothing changes.
+	updated := true
+	for updated {
+		updated = false
+		for _, nt := range g.NonTerminals() {
+			for _, p := range g.Rule(nt).Productions {
+				if h, ok := gen.prodHeight(p); ok {
+					if cur, ok := gen.minHeight[nt]; !ok || h < cur {
+						gen.minHeight[nt] = h
+						updated = true
+					}
+				}
+				if n, ok := gen.prodTokens(p); ok {
+					if cur, ok := gen.minTokens[nt]; !ok || n < cur {
+						gen.minTokens[nt] = n
+						updated = true
+					}
+				}
+			}
+		}
+	}
+
+	for _, nt := range g.NonTerminals() {
+		if _, ok := gen.minHeight[nt]; !ok {
+			return nil, fmt.Errorf("%s never derives a string of only terminals", nt)
+		}
+	}
+	if gen.minHeight[g.StartSymbol()] > opts.MaxDepth {
+		return nil, fmt.Errorf("max depth of %d is less than the shallowest parse tree depth of %d", opts.MaxDepth, gen.minHeight[g.StartSymbol()])
+	}
+
+	return gen, nil
+}
+
+// Generate returns a new random Sentence.
+func (gen *Generator) Generate() Sentence {
+	var leaves []*Tree
+	tokens := 0
+
+	root := gen.derive(gen.g.StartSymbol(), gen.opts.MaxDepth, 0, &tokens, &leaves)
+
+	// now that every token is known, lay them out in text and give each its
+	// position in it.
+	lexemes := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		class := gen.g.Term(leaf.Value)
+		lexemes[i] = fmt.Sprintf("<SIMULATED %s>", class.ID())
+		if fn, ok := gen.opts.Lexemes[class.ID()]; ok {
+			lexemes[i] = fn()
+		}
+	}
+	text := strings.Join(lexemes, gen.opts.Separator)
+	lines := strings.Split(text, "\n")
+
+	pos := lex.Position{Line: 1, Col: 1}
+	advance := func(s string) {
+		for _, ch := range s {
+			if ch == '\n' {
+				pos.Line++
+				pos.Col = 1
+			} else {
+				pos.Col++
+			}
+		}
+	}
+	for i, leaf := range leaves {
+		if i > 0 {
+			advance(gen.opts.Separator)
+		}
+		class := gen.g.Term(leaf.Value)
+		leaf.Source = lex.NewToken(class, lexemes[i], pos.Col, pos.Line, lines[pos.Line-1])
+		leaf.Span = lex.SpanOf(leaf.Source)
+		advance(lexemes[i])
+	}
+	root.spanChildren()
+
+	return Sentence{Tree: *root, Text: text}
+}
+
+// derive returns a random parse tree of sym that is at most depth levels deep
+// and that leaves room for reserve more tokens after it. tokens is the number
+// of tokens derived so far, and each terminal node is added to leaves in
+// order.
+func (gen *Generator) derive(sym string, depth int, reserve int, tokens *int, leaves *[]*Tree) *Tree {
+	if gen.g.IsTerminal(sym) {
+		leaf := Leaf(sym)
+		*leaves = append(*leaves, leaf)
+		*tokens++
+		return leaf
+	}
+
+	p := gen.pick(sym, depth, gen.opts.MaxTokens-*tokens-reserve)
+
+	pt := &Tree{Value: sym}
+	if p.Equal(grammar.Epsilon) {
+		pt.Children = []*Tree{{Terminal: true}}
+		return pt
+	}
+
+	pt.Children = make([]*Tree, len(p))
+	for i := range p {
+		after := reserve
+		for _, next := range p[i+1:] {
+			n, _ := gen.symTokens(next)
+			after += n
+		}
+		pt.Children[i] = gen.derive(p[i], depth-1, after, tokens, leaves)
+	}
+	return pt
+}
+
+// pick randomly selects a production of nt whose smallest parse tree fits in
+// depth levels and budget tokens. If none fit in budget, the one with the
+// fewest tokens that fits in depth is selected.
+func (gen *Generator) pick(nt string, depth int, budget int) grammar.Production {
+	prods := gen.g.Rule(nt).Productions
+	weights := gen.opts.Weights[nt]
+
+	var fits []int
+	var total float64
+	fewest := -1
+	for i, p := range prods {
+		h, _ := gen.prodHeight(p)
+		if h > depth {
+			continue
+		}
+		n, _ := gen.prodTokens(p)
+		if fewest == -1 || n < gen.mustProdTokens(prods[fewest]) {
+			fewest = i
+		}
+		if n > budget {
+			continue
+		}
+		fits = append(fits, i)
+		if weights != nil {
+			total += weights[i]
+		} else {
+			total++
+		}
+	}
+
+	if len(fits) == 0 {
+		return prods[fewest]
+	}
+	if total == 0 {
+		// only 0-weighted productions fit; any of them will do
+		return prods[fits[gen.rng.Intn(len(fits))]]
+	}
+
+	r := gen.rng.Float64() * total
+	for _, i := range fits {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		if r < w {
+			return prods[i]
+		}
+		r -= w
+	}
+	return prods[fits[len(fits)-1]]
+}
+
+// prodHeight returns the height of the shallowest parse tree that can be made
+// with p, and whether one is known yet.
+func (gen *Generator) prodHeight(p grammar.Production) (int, bool) {
+	h := 1
+	for _, sym := range p {
+		symH := 1
+		if gen.g.IsNonTerminal(sym) {
+			var ok bool
+			if symH, ok = gen.minHeight[sym]; !ok {
+				return 0, false
+			}
+		}
+		if symH > h {
+			h = symH
+		}
+	}
+	return h + 1, true
+}
+
+// prodTokens returns the fewest tokens that can be derived from p, and
+// whether that is known yet.
+func (gen *Generator) prodTokens(p grammar.Production) (int, bool) {
+	n := 0
+	for _, sym := range p {
+		symN, ok := gen.symTokens(sym)
+		if !ok {
+			return 0, false
+		}
+		n += symN
+	}
+	return n, true
+}
+
+// mustProdTokens is prodTokens for a production that is known to derive a
+// string of only terminals.
+func (gen *Generator) mustProdTokens(p grammar.Production) int {
+	n, _ := gen.prodTokens(p)
+	return n
+}
+
+// symTokens returns the fewest tokens that can be derived from sym, and
+// whether that is known yet.
+func (gen *Generator) symTokens(sym string) (int, bool) {
+	if sym == "" {
+		// epsilon
+		return 0, true
+	}
+	if gen.g.IsTerminal(sym) {
+		return 1, true
+	}
+	n, ok := gen.minTokens[sym]
+	return n, ok
+}
@@ -0,0 +1,226 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewGenerator(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     grammar.CFG
+		opts      GeneratorOptions
+		expectErr bool
+	}{
+		{
+			name: "defaults",
+			input: grammar.MustParse(`
+				E -> E plus T | T ;
+				T -> int ;
+			`),
+		},
+		{
+			name: "max depth too small for any tree",
+			input: grammar.MustParse(`
+				E -> E plus T | T ;
+				T -> int ;
+			`),
+			opts:      GeneratorOptions{MaxDepth: 2},
+			expectErr: true,
+		},
+		{
+			name: "weights for a terminal",
+			input: grammar.MustParse(`
+				E -> E plus T | T ;
+				T -> int ;
+			`),
+			opts:      GeneratorOptions{Weights: map[string][]float64{"int": {1}}},
+			expectErr: true,
+		},
+		{
+			name: "wrong number of weights",
+			input: grammar.MustParse(`
+				E -> E plus T | T ;
+				T -> int ;
+			`),
+			opts:      GeneratorOptions{Weights: map[string][]float64{"E": {1}}},
+			expectErr: true,
+		},
+		{
+			name: "negative weight",
+			input: grammar.MustParse(`
+				E -> E plus T | T ;
+				T -> int ;
+			`),
+			opts:      GeneratorOptions{Weights: map[string][]float64{"E": {1, -1}}},
+			expectErr: true,
+		},
+		{
+			name: "non-terminal that never ends",
+			input: grammar.MustParse(`
+				S -> a | B ;
+				B -> b B ;
+			`),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := NewGenerator(tc.input, tc.opts)
+
+			if tc.expectErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func Test_Generator_Generate(t *testing.T) {
+	g := grammar.MustParse(`
+		E -> E plus T | T ;
+		T -> T times F | F ;
+		F -> lp E rp | int ;
+	`)
+	lexemes := map[string]func() string{
+		"plus":  func() string { return "+" },
+		"times": func() string { return "*" },
+		"lp":    func() string { return "(" },
+		"rp":    func() string { return ")" },
+		"int":   func() string { return "8" },
+	}
+
+	testCases := []struct {
+		name   string
+		opts   GeneratorOptions
+		expect string
+	}{
+		{
+			name: "only the non-recursive productions",
+			opts: GeneratorOptions{
+				Lexemes: lexemes,
+				Weights: map[string][]float64{
+					"E": {0, 1},
+					"T": {0, 1},
+					"F": {0, 1},
+				},
+			},
+			expect: "8",
+		},
+		{
+			name: "weights that force a shape",
+			opts: GeneratorOptions{
+				Lexemes:   lexemes,
+				MaxTokens: 3,
+				Weights: map[string][]float64{
+					"E": {1, 0},
+					"T": {0, 1},
+					"F": {0, 1},
+				},
+			},
+			expect: "8+8",
+		},
+		{
+			name: "missing lexemes use placeholders",
+			opts: GeneratorOptions{
+				MaxDepth: 4,
+			},
+			expect: "<SIMULATED int>",
+		},
+		{
+			name: "newline separator",
+			opts: GeneratorOptions{
+				Lexemes:   lexemes,
+				Separator: "\n",
+				MaxTokens: 3,
+				Weights: map[string][]float64{
+					"E": {0, 1},
+					"T": {1, 0},
+					"F": {0, 1},
+				},
+			},
+			expect: "8\n*\n8",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gen, err := NewGenerator(g, tc.opts)
+			if !assert.NoError(err) {
+				return
+			}
+
+			actual := gen.Generate()
+
+			assert.Equal(tc.expect, actual.Text)
+		})
+	}
+}
+
+func Test_Generator_Generate_bounds(t *testing.T) {
+	assert := assert.New(t)
+
+	g := grammar.MustParse(`
+		E -> E plus T | T ;
+		T -> T times F | F ;
+		F -> lp E rp | int ;
+	`)
+	opts := GeneratorOptions{
+		Seed:      42,
+		MaxDepth:  12,
+		MaxTokens: 15,
+		Weights: map[string][]float64{
+			// favor recursion so that the bounds are hit
+			"E": {4, 1},
+			"T": {4, 1},
+			"F": {4, 1},
+		},
+	}
+
+	gen, err := NewGenerator(g, opts)
+	if !assert.NoError(err) {
+		return
+	}
+	sameSeed, err := NewGenerator(g, opts)
+	if !assert.NoError(err) {
+		return
+	}
+
+	for i := 0; i < 50; i++ {
+		s := gen.Generate()
+
+		assert.LessOrEqual(treeDepth(&s.Tree), opts.MaxDepth)
+		assert.Equal(s.Text, sameSeed.Generate().Text, "same seed gave different sentence")
+
+		// every token must be where the text says it is
+		leaves := treeLeaves(s.Tree)
+		assert.LessOrEqual(len(leaves), opts.MaxTokens)
+		for _, leaf := range leaves {
+			assert.Equal(1, leaf.Source.Line())
+			start := leaf.Source.LinePos() - 1
+			assert.Equal(leaf.Source.Lexeme(), s.Text[start:start+len(leaf.Source.Lexeme())])
+		}
+	}
+}
+
+func treeLeaves(pt Tree) []*Tree {
+	var leaves []*Tree
+	for _, child := range pt.Children {
+		if child.Terminal {
+			if child.Value != "" {
+				leaves = append(leaves, child)
+			}
+		} else {
+			leaves = append(leaves, treeLeaves(*child)...)
+		}
+	}
+	return leaves
+}
@@ -0,0 +1,313 @@
+package parse
+
+import (
+	"fmt"
+
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// File incremental.go contains functions for parsing tokens again after the
+// text they were lexed from is edited, reusing the parts of the previous parse
+// tree that the edit did not affect.
+//
+// This is done in the style of Wagner and Graham's incremental LR parsing.
+// While an LR parser builds a tree, it records for each non-terminal node the
+// state that it went to the GOTO of for that node and the range of tokens that
+// the node covers. When parsing again, if the parser is in the same state that
+// one of the old nodes was started in, and the tokens that the node covers as
+// well as the token after them are unchanged, then the parser is guaranteed
+// to build the exact same node from those tokens, because the actions of an LR
+// parser depend only on its current state and the lookahead token. So the old
+// node is copied into the new tree and the parser skips to the token after it,
+// as though it had just reduced to it.
+
+// IncrementalParse is the result of parsing tokens with ParseIncremental or
+// Reparse. Along with the parse tree, it holds what is needed to parse the
+// tokens again after they are edited while reusing the parts of the tree that
+// the edit did not change.
+type IncrementalParse struct {
+	// Tree is the parse tree built from the tokens. It is the same tree that
+	// the parser would produce if it parsed all of the tokens from scratch. It
+	// must not be modified if the IncrementalParse will be passed to Reparse.
+	Tree Tree
+
+	// Reused is the number of subtrees of the previous parse tree that were
+	// copied into Tree instead of being parsed again. It is always 0 for the
+	// result of ParseIncremental.
+	Reused int
+
+	parser Parser
+	tokens []lex.Token
+
+	// nodes holds how each non-terminal node that can be reused was built. It
+	// is nil if parser is not an LR parser.
+	nodes map[*Tree]parsedNode
+
+	// starts holds the nodes in nodes by the index of the first token they
+	// cover, in the order that they were built.
+	starts map[int][]*Tree
+}
+
+// parsedNode is how an LR parser built a non-terminal node of a parse tree.
+type parsedNode struct {
+	// state is the state on top of the stack just before the first action
+	// for the node was taken. It is the state the parser went to the GOTO of
+	// after reducing to the node.
+	state string
+
+	// first is the index of the first token covered by the node. For a node
+	// that covers no tokens, it is the index of the token after it.
+	first int
+
+	// next is the index of the token after the last one covered by the node.
+	// This was the lookahead token when the parser reduced to the node.
+	next int
+}
+
+// ParseIncremental parses tokens with p and returns the result in a form that
+// can be passed to Reparse after the tokens are changed. tokens must end with
+// a token of class lex.TokenEndOfText, such as the tokens of a lex.Snapshot.
+//
+// Only LR parsers can reuse parts of the previous parse tree; for other
+// parsers, Reparse parses all of the tokens again.
+//
+// If there is a syntax error, it is returned along with an IncrementalParse
+// whose Tree is empty. The IncrementalParse can still be passed to Reparse, and
+// the nodes that were completed before the error may be reused.
+func ParseIncremental(p Parser, tokens []lex.Token) (*IncrementalParse, error) {
+	return reparse(p, nil, tokens, lex.TokenDiff{})
+}
+
+// Reparse parses tokens with the same parser that was used for ip. The tokens
+// are the result of changing the ones that ip was parsed from as described by
+// diff, such as by calling Relex on a lex.Snapshot. The subtrees of ip.Tree
+// that the change did not affect are reused in the new tree where the parser
+// state permits. ip itself is not modified.
+func (ip *IncrementalParse) Reparse(tokens []lex.Token, diff lex.TokenDiff) (*IncrementalParse, error) {
+	return reparse(ip.parser, ip, tokens, diff)
+}
+
+func reparse(p Parser, prev *IncrementalParse, tokens []lex.Token, diff lex.TokenDiff) (*IncrementalParse, error) {
+	if len(tokens) == 0 || tokens[len(tokens)-1].Class().ID() != lex.TokenEndOfText.ID() {
+		return nil, fmt.Errorf("tokens do not end with end of text")
+	}
+
+	lr, ok := p.(*lrParser)
+	if !ok {
+		pt, err := p.Parse(&tokenSlice{tokens: tokens})
+		return &IncrementalParse{Tree: pt, parser: p, tokens: tokens}, err
+	}
+
+	ip := &IncrementalParse{
+		parser: p,
+		tokens: tokens,
+		nodes:  map[*Tree]parsedNode{},
+		starts: map[int][]*Tree{},
+	}
+	if prev != nil && prev.nodes == nil {
+		prev = nil
+	}
+
+	pt, err := lr.parseIncremental(ip, prev, diff)
+	ip.Tree = pt
+	return ip, err
+}
+
+// lrFrame is an entry on the stack of an incremental LR parse.
+type lrFrame struct {
+	state string
+	node  *Tree
+	first int
+}
+
+// parseIncremental parses the tokens of ip, recording how each non-terminal
+// node was built in ip and reusing nodes from prev where possible. prev may be
+// nil to parse without reusing anything.
+//
+// Other than the reuse, this is the same algorithm as lr.parse.
+func (lr *lrParser) parseIncremental(ip *IncrementalParse, prev *IncrementalParse, diff lex.TokenDiff) (Tree, error) {
+	stack := []lrFrame{{state: lr.table.Initial()}}
+	i := 0
+
+	hasEntries := len(lr.gram.EntryPoints) > 0
+	if hasEntries {
+		// give the marker for the start symbol before any of the tokens.
+		_, markers := entryNames(lr.gram)
+		marker := entryMarker(markers[lr.gram.StartSymbol()], ip.tokens[0])
+
+		act := lr.table.Action(stack[0].state, marker.Class().ID())
+		if act.Type != lrShift {
+			return Tree{}, fmt.Errorf("parse table does not accept start of entry point")
+		}
+		leaf := &Tree{Terminal: true, Value: marker.Class().ID(), Source: marker, Span: lex.SpanOf(marker)}
+		stack = append(stack, lrFrame{state: act.State, node: leaf, first: -1})
+	}
+
+	for {
+		s := stack[len(stack)-1].state
+
+		if prev != nil {
+			if old, ok := prev.reusable(s, i, diff); ok {
+				to, err := lr.table.Goto(s, old.Value)
+				if err == nil {
+					node, next := ip.copyNode(prev, old, i)
+					stack = append(stack, lrFrame{state: to, node: node, first: i})
+					i = next
+					ip.Reused++
+					continue
+				}
+			}
+		}
+
+		a := ip.token(i)
+		act := lr.table.Action(s, a.Class().ID())
+
+		switch act.Type {
+		case lrShift:
+			leaf := &Tree{Terminal: true, Value: a.Class().ID(), Source: a, Span: lex.SpanOf(a)}
+			stack = append(stack, lrFrame{state: act.State, node: leaf, first: i})
+			i++
+		case lrReduce:
+			A := act.Symbol
+			beta := act.Production
+			node := &Tree{Value: A}
+			first := i
+
+			if len(beta) == 0 {
+				node.Children = []*Tree{{Terminal: true, Span: lex.EmptySpanAt(a)}}
+			} else {
+				popped := stack[len(stack)-len(beta):]
+				first = popped[0].first
+				for _, f := range popped {
+					node.Children = append(node.Children, f.node)
+				}
+				stack = stack[:len(stack)-len(beta)]
+			}
+			node.Span = childrenSpan(node.Children)
+
+			t := stack[len(stack)-1].state
+			ip.record(node, parsedNode{state: t, first: first, next: i})
+
+			to, err := lr.table.Goto(t, A)
+			if err != nil {
+				return Tree{}, diagnose(syntaxerr.CodeParserState, fmt.Sprintf("LR parsing error; DFA has no valid transition from here on %q", A), a)
+			}
+			stack = append(stack, lrFrame{state: to, node: node, first: first})
+		case lrAccept:
+			root := stack[len(stack)-1].node
+			if hasEntries {
+				// remove the node for the entry grammar's start symbol.
+				root = root.Children[1]
+			}
+			return *root, nil
+		case lrError:
+			return Tree{}, lr.syntaxError(s, a)
+		}
+	}
+}
+
+// token returns the token at index i. If i is past the end of the tokens, the
+// end of text token is returned.
+func (ip *IncrementalParse) token(i int) lex.Token {
+	if i >= len(ip.tokens) {
+		return ip.tokens[len(ip.tokens)-1]
+	}
+	return ip.tokens[i]
+}
+
+// record adds the info on how node was built to ip.
+func (ip *IncrementalParse) record(node *Tree, pn parsedNode) {
+	ip.nodes[node] = pn
+	ip.starts[pn.first] = append(ip.starts[pn.first], node)
+}
+
+// reusable returns the largest node of ip that an LR parser in the given state
+// would build again starting at index i of the new tokens described by diff.
+func (ip *IncrementalParse) reusable(state string, i int, diff lex.TokenDiff) (*Tree, bool) {
+	// find the old index of the token at i, if it is unchanged.
+	var first int
+	if i < diff.Prefix {
+		first = i
+	} else if i >= diff.NewSuffix {
+		first = i - diff.NewSuffix + diff.OldSuffix
+	} else {
+		return nil, false
+	}
+
+	// nodes that were built later are the larger ones.
+	candidates := ip.starts[first]
+	for j := len(candidates) - 1; j >= 0; j-- {
+		pn := ip.nodes[candidates[j]]
+		if pn.state != state {
+			continue
+		}
+
+		// the tokens covered by the node and the lookahead after it must all
+		// be unchanged.
+		if first < diff.Prefix && pn.next >= diff.Prefix {
+			continue
+		}
+		return candidates[j], true
+	}
+
+	return nil, false
+}
+
+// copyNode copies old, a non-terminal node of prev, into ip, with the copy
+// covering the tokens of ip starting at index first. The copy is recorded in
+// ip, and the index of the token after it is returned along with it.
+func (ip *IncrementalParse) copyNode(prev *IncrementalParse, old *Tree, first int) (*Tree, int) {
+	cur := first
+
+	var copyRec func(old *Tree) *Tree
+	copyRec = func(old *Tree) *Tree {
+		if old.Terminal {
+			if old.Source == nil {
+				// epsilon
+				return &Tree{Terminal: true, Span: lex.EmptySpanAt(ip.token(cur))}
+			}
+			tok := ip.token(cur)
+			cur++
+			return &Tree{Terminal: true, Value: tok.Class().ID(), Source: tok, Span: lex.SpanOf(tok)}
+		}
+
+		start := cur
+		node := &Tree{Value: old.Value}
+		for _, child := range old.Children {
+			node.Children = append(node.Children, copyRec(child))
+		}
+		node.Span = childrenSpan(node.Children)
+		ip.record(node, parsedNode{state: prev.nodes[old].state, first: start, next: cur})
+		return node
+	}
+
+	node := copyRec(old)
+	return node, cur
+}
+
+// tokenSlice is a TokenStream that gives the tokens of a slice. The last token
+// in the slice must be the end of text.
+type tokenSlice struct {
+	tokens []lex.Token
+	cur    int
+}
+
+// Next returns the next token of the stream.
+func (ts *tokenSlice) Next() lex.Token {
+	tok := ts.Peek()
+	if ts.cur < len(ts.tokens)-1 {
+		ts.cur++
+	}
+	return tok
+}
+
+// Peek returns the next token of the stream without advancing it.
+func (ts *tokenSlice) Peek() lex.Token {
+	return ts.tokens[ts.cur]
+}
+
+// HasNext returns whether the stream has any additional tokens.
+func (ts *tokenSlice) HasNext() bool {
+	return ts.cur < len(ts.tokens)-1
+}
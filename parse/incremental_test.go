@@ -0,0 +1,220 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Reparse(t *testing.T) {
+	// right-recursive so that it can be used with LL(1) as well as LR parsers.
+	g := grammar.MustParse(`
+		P -> S P | ε ;
+		S -> id eq E semi ;
+		E -> T X ;
+		X -> plus T X | ε ;
+		T -> id | int | lp E rp ;
+	`)
+
+	leftRecursive := grammar.MustParse(`
+		P -> P S | ε ;
+		S -> id eq E semi ;
+		E -> E plus T | T ;
+		T -> id | int | lp E rp ;
+	`)
+
+	withEntries := g.Copy()
+	withEntries.EntryPoints = []string{"E"}
+
+	lx := lex.NewLexer(true)
+	classes := map[string]string{
+		"id":   `[a-z]+`,
+		"int":  `[0-9]+`,
+		"eq":   `=`,
+		"plus": `\+`,
+		"semi": `;`,
+		"lp":   `\(`,
+		"rp":   `\)`,
+	}
+	for id, pat := range classes {
+		lx.RegisterClass(lex.NewTokenClass(id, id), "")
+		lx.AddPattern(pat, lex.LexAs(id), "", 0)
+	}
+	lx.AddPattern(`\s+`, lex.Discard(), "", 0)
+
+	input := "a = 1;\nb = a + 2;\nc = (a + b) + 3;\nd = c;\n"
+
+	testCases := []struct {
+		name  string
+		input string
+		edits []lex.Edit
+
+		// expectReuse is whether the LR parsers are expected to reuse
+		// subtrees of the previous tree on the last edit.
+		expectReuse bool
+		expectErr   bool
+	}{
+		{
+			name:        "change token in middle",
+			input:       input,
+			edits:       []lex.Edit{{Start: 11, End: 12, Text: "x"}},
+			expectReuse: true,
+		},
+		{
+			name:        "add statement",
+			input:       input,
+			edits:       []lex.Edit{{Start: 7, End: 7, Text: "e = 5 + 6;\n"}},
+			expectReuse: true,
+		},
+		{
+			name:        "remove statement",
+			input:       input,
+			edits:       []lex.Edit{{Start: 7, End: 17}},
+			expectReuse: true,
+		},
+		{
+			name:        "extend expression at end",
+			input:       input,
+			edits:       []lex.Edit{{Start: 39, End: 41, Text: "c + 4;"}},
+			expectReuse: true,
+		},
+		{
+			name:        "change first token",
+			input:       input,
+			edits:       []lex.Edit{{Start: 0, End: 1, Text: "z"}},
+			expectReuse: true,
+		},
+		{
+			name:      "edit causes syntax error",
+			input:     input,
+			edits:     []lex.Edit{{Start: 27, End: 28}},
+			expectErr: true,
+		},
+		{
+			name:  "edit fixes syntax error",
+			input: input,
+			edits: []lex.Edit{
+				{Start: 27, End: 28},
+				{Start: 27, End: 27, Text: "b"},
+			},
+			expectReuse: true,
+		},
+		{
+			name:  "several edits",
+			input: input,
+			edits: []lex.Edit{
+				{Start: 4, End: 5, Text: "10"},
+				{Start: 0, End: 0, Text: "q = 8;\n"},
+				{Start: 19, End: 20, Text: "(1 + 2)"},
+			},
+			expectReuse: true,
+		},
+		{
+			name:        "edit that changes nothing",
+			input:       input,
+			edits:       []lex.Edit{{Start: 1, End: 2, Text: "  "}},
+			expectReuse: true,
+		},
+	}
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"SLR(1)": func() (Parser, error) {
+			p, _, err := GenerateSLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+		"CLR(1)": func() (Parser, error) {
+			p, _, err := GenerateCLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1) left-recursive": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(leftRecursive, false)
+			return p, err
+		},
+		"LALR(1) with entry points": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(withEntries, false)
+			return p, err
+		},
+		"decoded LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			if err != nil {
+				return nil, err
+			}
+			return DecodeBytes(EncodeBytes(p))
+		},
+	}
+
+	for pName, ctor := range parsers {
+		for _, tc := range testCases {
+			t.Run(pName+" "+tc.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				p, err := ctor()
+				if !assert.NoError(err, "generating parser failed") {
+					return
+				}
+
+				snap, err := lex.LexSnapshot(lx, tc.input)
+				if !assert.NoError(err) {
+					return
+				}
+				ip, err := ParseIncremental(p, snap.Tokens())
+				if !assert.NoError(err) {
+					return
+				}
+
+				// execute
+				var parseErr error
+				for _, edit := range tc.edits {
+					var diff lex.TokenDiff
+					snap, diff, err = snap.Relex(edit)
+					if !assert.NoError(err) {
+						return
+					}
+					ip, parseErr = ip.Reparse(snap.Tokens(), diff)
+				}
+
+				// assert
+				full, err := lex.LexSnapshot(lx, snap.Text())
+				if !assert.NoError(err) {
+					return
+				}
+				expect, expectErr := p.Parse(full.Stream())
+
+				if tc.expectErr {
+					assert.Error(parseErr)
+					assert.Error(expectErr, "full parse did not give error")
+					return
+				} else if !assert.NoError(parseErr) {
+					return
+				}
+				if !assert.NoError(expectErr, "full parse failed") {
+					return
+				}
+
+				assert.Equal(expect, ip.Tree, "reparsed tree does not match full parse")
+
+				if tc.expectReuse && p.Type() != LL1 {
+					assert.Greater(ip.Reused, 0, "no subtrees were reused")
+				}
+			})
+		}
+	}
+}
+
+func Test_ParseIncremental_notEndOfText(t *testing.T) {
+	g := grammar.MustParse(`S -> a ;`)
+	p, _, err := GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = ParseIncremental(p, []lex.Token{mockTokens("a").Next()})
+	assert.Error(t, err)
+}
@@ -29,12 +29,13 @@ func EmptyLALR1Parser() Parser {
 // ambiguous, the 2nd arg 'ambiguity warnings' will be filled with each
 // ambiguous case detected.
 func GenerateLALR1Parser(g grammar.CFG, allowAmbig bool) (Parser, []string, error) {
-	table, ambigWarns, err := constructLALR1ParseTable(g, allowAmbig)
+	tg := entryGrammar(g)
+	table, ambigWarns, err := constructLALR1ParseTable(tg, allowAmbig)
 	if err != nil {
 		return &lrParser{}, nil, err
 	}
 
-	return &lrParser{table: table, parseType: LALR1, gram: g}, ambigWarns, nil
+	return &lrParser{table: compressedFrom(table, tg), parseType: LALR1, gram: g}, ambigWarns, nil
 }
 
 // constructLALR1ParseTable constructs the LALR(1) table for G.
@@ -80,7 +81,7 @@ func constructLALR1ParseTable(g grammar.CFG, allowAmbig bool) (lrParseTable, []s
 	var ambigWarns []string
 	for _, stateName := range dfa.States() {
 		fromState := fmt.Sprintf(" (from DFA state %q)", textfmt.TruncateWith(stateName, 4, "..."))
-		for _, a := range table.gPrime.Terminals() {
+		for _, a := range append(table.gPrime.Terminals(), "$") {
 			itemSet := table.dfa.GetValue(stateName)
 			var matchFound bool
 			var act lrAction
@@ -414,6 +415,12 @@ func (lalr1 *lalr1Table) Goto(state, symbol string) (string, error) {
 	return newState, nil
 }
 
+// States returns the names of all states in the table, with the initial state
+// first.
+func (lalr1 *lalr1Table) States() []string {
+	return orderStates(lalr1.dfa.States(), lalr1.dfa.Start)
+}
+
 // Initial returns the starting state of the parser DFA.
 func (lalr1 *lalr1Table) Initial() string {
 	return lalr1.dfa.Start
@@ -515,3 +522,13 @@ func (lalr1 *lalr1Table) String() string {
 		}).
 		String()
 }
+
+// kernelItems returns the cores of the kernel items of the given state of the
+// table's DFA.
+func (lalr1 *lalr1Table) kernelItems(state string) []grammar.LR0Item {
+	var items []grammar.LR0Item
+	for _, item := range lalr1.dfa.GetValue(state) {
+		items = append(items, item.LR0Item)
+	}
+	return kernelOf(items, lalr1.gPrime.StartSymbol())
+}
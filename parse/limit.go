@@ -0,0 +1,64 @@
+package parse
+
+import (
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// Limits are limits on the parse tree that a Parser will build. A limit of 0
+// means there is no limit.
+type Limits struct {
+	// MaxTreeDepth is the maximum depth of the parse tree, with the root of
+	// the tree being at depth 1.
+	MaxTreeDepth int
+}
+
+// CheckTree returns a *syntaxerr.LimitError if pt is deeper than the
+// MaxTreeDepth of lim, and nil otherwise. This is for checking a tree that was
+// not built under lim, such as one from an IncrementalParse.
+func (lim Limits) CheckTree(pt Tree) error {
+	if lim.depthExceeded(treeDepth(&pt)) {
+		return lim.depthError()
+	}
+	return nil
+}
+
+// depthExceeded returns whether depth is more than the MaxTreeDepth of lim.
+func (lim Limits) depthExceeded(depth int) bool {
+	return lim.MaxTreeDepth > 0 && depth > lim.MaxTreeDepth
+}
+
+// depthError returns the error for a parse tree being deeper than the
+// MaxTreeDepth of lim.
+func (lim Limits) depthError() error {
+	return &syntaxerr.LimitError{Limit: syntaxerr.LimitTreeDepth, Max: lim.MaxTreeDepth}
+}
+
+// treeDepth returns the depth of pt, with pt being at depth 1.
+func treeDepth(pt *Tree) int {
+	depth := 0
+	for _, c := range pt.Children {
+		if d := treeDepth(c); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+// stopWatchStream is a TokenStream that records the error of the first token
+// taken from it that lex.TokenErr returns one for. It is used to get that error
+// from a Parser that is not a ContextParser.
+type stopWatchStream struct {
+	lex.TokenStream
+	err error
+}
+
+// Next returns the next token in the stream and advances the stream by one
+// token.
+func (sws *stopWatchStream) Next() lex.Token {
+	tok := sws.TokenStream.Next()
+	if sws.err == nil {
+		sws.err = lex.TokenErr(tok)
+	}
+	return tok
+}
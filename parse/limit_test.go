@@ -0,0 +1,202 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+	"github.com/stretchr/testify/assert"
+)
+
+// plainParser is a Parser that has only the methods of the Parser interface,
+// so it is not a ContextParser or any of the other optional interfaces.
+type plainParser struct {
+	Parser
+}
+
+func Test_ParseContext(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> id eq E ;
+		E -> T X ;
+		X -> plus T X | ε ;
+		T -> id | int | lp E rp ;
+	`)
+
+	// the entry grammar's start symbol must not count towards the depth.
+	g.EntryPoints = []string{"E"}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		input     []string
+		lim       Limits
+		expectErr error
+	}{
+		{
+			name:  "no limits",
+			ctx:   context.Background(),
+			input: []string{"id", "eq", "lp", "int", "rp", "$"},
+		},
+		{
+			name:  "tree as deep as limit",
+			ctx:   context.Background(),
+			input: []string{"id", "eq", "int", "$"},
+			lim:   Limits{MaxTreeDepth: 4},
+		},
+		{
+			name:      "tree deeper than limit",
+			ctx:       context.Background(),
+			input:     []string{"id", "eq", "int", "$"},
+			lim:       Limits{MaxTreeDepth: 3},
+			expectErr: syntaxerr.ErrTreeTooDeep,
+		},
+		{
+			name:      "nested tree deeper than limit",
+			ctx:       context.Background(),
+			input:     []string{"id", "eq", "lp", "int", "rp", "$"},
+			lim:       Limits{MaxTreeDepth: 5},
+			expectErr: syntaxerr.ErrTreeTooDeep,
+		},
+		{
+			name:      "canceled",
+			ctx:       canceled,
+			input:     []string{"id", "eq", "int", "$"},
+			expectErr: context.Canceled,
+		},
+	}
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"SLR(1)": func() (Parser, error) {
+			p, _, err := GenerateSLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+		"CLR(1)": func() (Parser, error) {
+			p, _, err := GenerateCLR1Parser(g, false)
+			return p, err
+		},
+		"not a ContextParser": func() (Parser, error) {
+			p, err := GenerateLL1Parser(g)
+			return plainParser{p}, err
+		},
+	}
+
+	for pName, ctor := range parsers {
+		for _, tc := range testCases {
+			t.Run(pName+" "+tc.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				p, err := ctor()
+				if !assert.NoError(err, "generating parser failed") {
+					return
+				}
+
+				// execute
+				_, err = ParseContext(tc.ctx, p, mockTokens(tc.input...), tc.lim)
+
+				// assert
+				if tc.expectErr != nil {
+					assert.True(errors.Is(err, tc.expectErr), "expected %v, got %v", tc.expectErr, err)
+				} else {
+					assert.NoError(err)
+				}
+			})
+		}
+	}
+}
+
+func Test_ParseContext_lexerStopped(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> int X ;
+		X -> plus int X | ε ;
+	`)
+
+	lx := lex.NewLexer(true)
+	lx.RegisterClass(lex.NewTokenClass("int", "integer"), "")
+	lx.RegisterClass(lex.NewTokenClass("plus", "'+'"), "")
+	lx.AddPattern(`[0-9]+`, lex.LexAs("int"), "", 0)
+	lx.AddPattern(`\+`, lex.LexAs("plus"), "", 0)
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+		"not a ContextParser": func() (Parser, error) {
+			p, err := GenerateLL1Parser(g)
+			return plainParser{p}, err
+		},
+	}
+
+	for pName, ctor := range parsers {
+		t.Run(pName, func(t *testing.T) {
+			assert := assert.New(t)
+
+			p, err := ctor()
+			if !assert.NoError(err, "generating parser failed") {
+				return
+			}
+
+			stream, err := lex.LexContext(context.Background(), lx, strings.NewReader("1+2+3+4"), lex.Limits{MaxTokens: 4})
+			if !assert.NoError(err) {
+				return
+			}
+
+			// execute
+			_, err = ParseContext(context.Background(), p, stream, Limits{})
+
+			// assert
+			assert.True(errors.Is(err, syntaxerr.ErrTooManyTokens), "expected %v, got %v", syntaxerr.ErrTooManyTokens, err)
+		})
+	}
+}
+
+func Test_Limits_CheckTree(t *testing.T) {
+	// the tree is 4 deep.
+	tree := MustParseTreeFromDiagram(`[S [S [S (int)] (plus) (int)] (plus) (int)]`)
+
+	testCases := []struct {
+		name      string
+		lim       Limits
+		expectErr bool
+	}{
+		{
+			name: "no limit",
+		},
+		{
+			name: "depth equal to limit",
+			lim:  Limits{MaxTreeDepth: 4},
+		},
+		{
+			name:      "depth more than limit",
+			lim:       Limits{MaxTreeDepth: 3},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// execute
+			err := tc.lim.CheckTree(*tree)
+
+			// assert
+			if tc.expectErr {
+				assert.True(t, errors.Is(err, syntaxerr.ErrTreeTooDeep), "expected %v, got %v", syntaxerr.ErrTreeTooDeep, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package parse
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
@@ -12,12 +13,13 @@ import (
 	"github.com/dekarrin/ictiobus/internal/rezi"
 	"github.com/dekarrin/ictiobus/internal/textfmt"
 	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
 )
 
 type ll1Parser struct {
 	table ll1Table
 	g     grammar.CFG
-	trace func(s string)
+	trace tracer
 }
 
 // Grammar returns the grammar that was used to generate the parser.
@@ -35,7 +37,13 @@ func (ll *ll1Parser) DFAString() string {
 // RegisterTraceListener sets a function to be called with messages that
 // indicate what action the parser is taking. It is useful for debug purposes.
 func (ll *ll1Parser) RegisterTraceListener(listener func(s string)) {
-	ll.trace = listener
+	ll.trace.setMsgs(listener)
+}
+
+// RegisterTraceEventListener sets a function to be called with each step that
+// the parser takes.
+func (ll *ll1Parser) RegisterTraceEventListener(listener func(ev TraceEvent)) {
+	ll.trace.setEvents(listener)
 }
 
 // TableString returns the parser table as a string.
@@ -82,44 +90,115 @@ func EmptyLL1Parser() Parser {
 // context-free Grammar g (k=1). The grammar must already be LL(1); it will not
 // be forced to it.
 func GenerateLL1Parser(g grammar.CFG) (Parser, error) {
-	M, err := generateLL1ParseTable(g)
+	tg := entryGrammar(g)
+	M, err := generateLL1ParseTable(tg)
 	if err != nil {
 		return &ll1Parser{}, err
 	}
+
+	// an LL(1) parser can start from any non-terminal, so the entry grammar is
+	// only needed to make the end of input follow each entry point; its start
+	// symbol is never used.
+	if len(g.EntryPoints) > 0 {
+		for _, a := range M.Terminals() {
+			M.d.Delete(tg.StartSymbol(), a)
+		}
+	}
+
 	return &ll1Parser{table: M, g: g.Copy()}, nil
 }
 
+// LL1Predictions returns the prediction table of p, which must be an LL(1)
+// parser. The returned map is keyed first by non-terminal and then by the
+// terminal that is next in the input ("$" for the end of input), and gives the
+// production that the parser predicts for that combination. Combinations that
+// would result in a syntax error are not included.
+func LL1Predictions(p Parser) (map[string]map[string]grammar.Production, error) {
+	ll, ok := p.(*ll1Parser)
+	if !ok {
+		return nil, fmt.Errorf("not an LL(1) parser")
+	}
+
+	preds := map[string]map[string]grammar.Production{}
+	for _, A := range ll.table.NonTerminals() {
+		for _, a := range ll.table.Terminals() {
+			prod := ll.table.Get(A, a)
+			if prod.Equal(grammar.Error) {
+				continue
+			}
+
+			if _, ok := preds[A]; !ok {
+				preds[A] = map[string]grammar.Production{}
+			}
+			preds[A][a] = prod
+		}
+	}
+
+	return preds, nil
+}
+
 // Type returns the type of the parser. This will be ParserLL1 for an
 // LL(1)-parser.
 func (ll1 *ll1Parser) Type() Algorithm {
 	return LL1
 }
 
-func (ll1 ll1Parser) notifyPopped(s string) {
-	if ll1.trace != nil {
-		ll1.trace(fmt.Sprintf("popped %q", s))
-	}
-}
-
-func (ll1 ll1Parser) notifyPushed(s string) {
-	if ll1.trace != nil {
-		ll1.trace(fmt.Sprintf("pushed %q", s))
-	}
+// notify sends an event of the given type to the trace listeners of ll1. The
+// event is filled out with the given values and a snapshot of the stack.
+func (ll1 *ll1Parser) notify(evType TraceEventType, next lex.Token, stack *box.Stack[string], fill func(ev *TraceEvent)) {
+	ll1.trace.notify(func() TraceEvent {
+		ev := TraceEvent{Type: evType, Lookahead: next, Stack: stackSnapshot(stack)}
+		if fill != nil {
+			fill(&ev)
+		}
+		return ev
+	})
 }
 
 // Parse takes a stream of tokens and parses it into a parse tree. If any syntax
 // errors are encountered, an empty parse tree and a *types.SyntaxError is
 // returned.
 func (ll1 *ll1Parser) Parse(stream lex.TokenStream) (Tree, error) {
-	symStack := box.NewStack([]string{ll1.g.StartSymbol(), "$"})
+	return ll1.ParseAs(ll1.g.StartSymbol(), stream)
+}
+
+// ParseAs takes a stream of tokens and parses it into a parse tree for the
+// given entry point of the grammar. If any syntax errors are encountered, an
+// empty parse tree and a syntaxerr.Diagnostics is returned.
+func (ll1 *ll1Parser) ParseAs(symbol string, stream lex.TokenStream) (Tree, error) {
+	return ll1.parseAs(context.Background(), symbol, stream, Limits{})
+}
+
+// ParseContext is the same as Parse but stops parsing when ctx is done or when
+// the parse tree would exceed one of the given limits, returning ctx.Err() or a
+// *syntaxerr.LimitError respectively.
+func (ll1 *ll1Parser) ParseContext(ctx context.Context, stream lex.TokenStream, lim Limits) (Tree, error) {
+	return ll1.parseAs(ctx, ll1.g.StartSymbol(), stream, lim)
+}
+
+// parseAs parses stream as the given entry point of the grammar. It stops
+// when ctx is done or lim is exceeded.
+func (ll1 *ll1Parser) parseAs(ctx context.Context, symbol string, stream lex.TokenStream, lim Limits) (Tree, error) {
+	if err := checkEntryPoint(ll1.g, symbol); err != nil {
+		return Tree{}, err
+	}
+
+	symStack := box.NewStack([]string{symbol, "$"})
 	next := stream.Peek()
+	ll1.notify(TraceLookahead, next, symStack, nil)
 	X := symStack.Peek()
-	ll1.notifyPopped(X)
-	pt := Tree{Value: ll1.g.StartSymbol()}
+	pt := Tree{Value: symbol}
 	ptStack := box.NewStack([]*Tree{&pt})
 
+	// depth of each node in ptStack, with the root at 1.
+	depthStack := box.NewStack([]int{1})
+
 	node := ptStack.Peek()
 	for X != "$" { /* stack is not empty */
+		if err := ctx.Err(); err != nil {
+			return pt, err
+		}
+
 		if strings.ToLower(X) == X {
 			stream.Next()
 
@@ -128,54 +207,65 @@ func (ll1 *ll1Parser) Parse(stream lex.TokenStream) (Tree, error) {
 			if next.Class().ID() == t.ID() {
 				node.Terminal = true
 				node.Source = next
+				node.Span = lex.SpanOf(next)
 				symStack.Pop()
+				ll1.notify(TraceMatch, next, symStack, func(ev *TraceEvent) { ev.Symbol = X })
 				X = symStack.Peek()
-				ll1.notifyPopped(X)
 				ptStack.Pop()
-				node = ptStack.Peek()
-			} else {
-				expMessage := "expected " + textfmt.ArticleFor(t.Human(), false) + " " + t.Human()
+				depthStack.Pop()
 
-				if next.Class().ID() == lex.TokenError.ID() {
-					return pt, lex.NewSyntaxErrorFromToken(fmt.Sprintf("%s; %s", next.Lexeme(), expMessage), next)
+				// the last symbol of the input may be a terminal, in which case
+				// there is no node left to go to.
+				if ptStack.Len() > 0 {
+					node = ptStack.Peek()
 				}
-
-				return pt, lex.NewSyntaxErrorFromToken(fmt.Sprintf("unexpected %s; %s", next.Class().Human(), expMessage), next)
+			} else {
+				err := ll1.mismatchError(t, next)
+				ll1.notify(TraceError, next, symStack, func(ev *TraceEvent) { ev.Err = err })
+				return pt, err
 			}
 
 			next = stream.Peek()
+			ll1.notify(TraceLookahead, next, symStack, nil)
 		} else {
 			nextProd := ll1.table.Get(X, ll1.g.TermFor(next.Class()))
 			if nextProd.Equal(grammar.Error) {
+				err := ll1.noPredictionError(next)
+				ll1.notify(TraceError, next, symStack, func(ev *TraceEvent) { ev.Err = err })
+				return pt, err
+			}
 
-				if next.Class().ID() == lex.TokenError.ID() {
-					return pt, lex.NewSyntaxErrorFromToken(next.Lexeme(), next)
-				}
-
-				return pt, lex.NewSyntaxErrorFromToken(fmt.Sprintf("unexpected %s", next.Class().Human()), next)
+			depth := depthStack.Peek() + 1
+			if lim.depthExceeded(depth) {
+				return pt, lim.depthError()
 			}
 
 			symStack.Pop()
 			ptStack.Pop()
+			depthStack.Pop()
 			for i := len(nextProd) - 1; i >= 0; i-- {
 				if nextProd[i] != grammar.Epsilon[0] {
 					symStack.Push(nextProd[i])
-					ll1.notifyPushed(nextProd[i])
 				}
 
 				child := &Tree{Value: nextProd[i]}
 				if nextProd[i] == grammar.Epsilon[0] {
 					child.Terminal = true
+					child.Span = lex.EmptySpanAt(next)
 				}
 				node.Children = append([]*Tree{child}, node.Children...)
 
 				if nextProd[i] != grammar.Epsilon[0] {
 					ptStack.Push(child)
+					depthStack.Push(depth)
 				}
 			}
 
+			ll1.notify(TracePredict, next, symStack, func(ev *TraceEvent) {
+				ev.Symbol = X
+				ev.Production = nextProd
+			})
 			X = symStack.Peek()
-			ll1.notifyPopped(X)
 
 			// node stack will always be one smaller than symbol stack bc
 			// glub, we dont put a node onto the stack for "$".
@@ -185,6 +275,11 @@ func (ll1 *ll1Parser) Parse(stream lex.TokenStream) (Tree, error) {
 		}
 	}
 
+	// spans of non-terminals can only be known once all of their children
+	// have been parsed.
+	pt.spanChildren()
+
+	ll1.notify(TraceAccept, next, symStack, nil)
 	return pt, nil
 }
 
@@ -194,6 +289,38 @@ type ll1Table struct {
 	d *box.Matrix2[string, grammar.Production]
 }
 
+// mismatchError returns the error for getting token next when terminal t was
+// expected. If next is the token a lexer gives when it is stopped, the error
+// that stopped it is returned instead.
+func (ll1 *ll1Parser) mismatchError(t lex.TokenClass, next lex.Token) error {
+	if err := lex.TokenErr(next); err != nil {
+		return err
+	}
+
+	expMessage := "expected " + textfmt.ArticleFor(t.Human(), false) + " " + t.Human()
+
+	if next.Class().ID() == lex.TokenError.ID() {
+		return diagnose(syntaxerr.CodeLexical, fmt.Sprintf("%s; %s", next.Lexeme(), expMessage), next)
+	}
+
+	return diagnose(syntaxerr.CodeUnexpectedToken, fmt.Sprintf("unexpected %s; %s", next.Class().Human(), expMessage), next)
+}
+
+// noPredictionError returns the error for getting token next when no
+// production can be predicted for it. If next is the token a lexer gives when
+// it is stopped, the error that stopped it is returned instead.
+func (ll1 *ll1Parser) noPredictionError(next lex.Token) error {
+	if err := lex.TokenErr(next); err != nil {
+		return err
+	}
+
+	if next.Class().ID() == lex.TokenError.ID() {
+		return diagnose(syntaxerr.CodeLexical, next.Lexeme(), next)
+	}
+
+	return diagnose(syntaxerr.CodeUnexpectedToken, fmt.Sprintf("unexpected %s", next.Class().Human()), next)
+}
+
 func newLL1Table() ll1Table {
 	return ll1Table{
 		d: box.NewMatrix2[string, grammar.Production](),
@@ -219,7 +346,7 @@ func generateLL1ParseTable(g grammar.CFG) (M ll1Table, err error) {
 	for _, A := range nts {
 		ARule := g.Rule(A)
 		for _, alpha := range ARule.Productions {
-			FIRSTalpha := box.StringSetOf(findFIRSTSet(g, alpha[0]).Elements())
+			FIRSTalpha := box.StringSetOf(findFIRSTSetString(g, alpha...).Elements())
 
 			// 1. For each terminal a in FIRST(A), add A -> α to M[A, a].
 			// -purple dragon book
@@ -214,3 +214,71 @@ func Test_LL1Table_MarshalUnmarshalBinary(t *testing.T) {
 		})
 	}
 }
+
+func Test_LL1Predictions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		g         string
+		algo      Algorithm
+		expect    map[string]map[string]grammar.Production
+		expectErr bool
+	}{
+		{
+			name: "aiken example",
+			g: `
+				S -> T X                        ;
+				T -> lparen S rparen | int Y    ;
+				X -> p S | ε                    ;
+				Y -> m T | ε                    ;
+			`,
+			algo: LL1,
+			expect: map[string]map[string]grammar.Production{
+				"S": {"int": grammar.Production{"T", "X"}, "lparen": grammar.Production{"T", "X"}},
+				"X": {"p": grammar.Production{"p", "S"}, "rparen": grammar.Epsilon, "$": grammar.Epsilon},
+				"T": {"int": grammar.Production{"int", "Y"}, "lparen": grammar.Production{"lparen", "S", "rparen"}},
+				"Y": {"m": grammar.Production{"m", "T"}, "p": grammar.Epsilon, "rparen": grammar.Epsilon, "$": grammar.Epsilon},
+			},
+		},
+		{
+			name: "non-LL parser",
+			g: `
+				S -> T X                        ;
+				T -> lparen S rparen | int Y    ;
+				X -> p S | ε                    ;
+				Y -> m T | ε                    ;
+			`,
+			algo:      SLR1,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// setup
+			assert := assert.New(t)
+			g := grammar.MustParse(tc.g)
+
+			var p Parser
+			var err error
+			if tc.algo == LL1 {
+				p, err = GenerateLL1Parser(g)
+			} else {
+				p, _, err = GenerateSLR1Parser(g, false)
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			// execute
+			actual, err := LL1Predictions(p)
+
+			// assert
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
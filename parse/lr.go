@@ -1,6 +1,7 @@
 package parse
 
 import (
+	"context"
 	"encoding"
 	"fmt"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/dekarrin/ictiobus/internal/rezi"
 	"github.com/dekarrin/ictiobus/internal/textfmt"
 	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
 )
 
 // lrParseTable is a table of information passed to an LR parser. These will be
@@ -37,13 +39,32 @@ type lrParseTable interface {
 	// DFAString returns the DFA simulated by the table. Some tables may in fact
 	// be the DFA itself along with supplementary info.
 	DFAString() string
+
+	// States returns the names of all states in the table. The initial state
+	// is always first, and the rest are in the same order they are shown in
+	// by String.
+	States() []string
+}
+
+// orderStates puts the start state at the front of the given states. The order
+// of the other states is changed only as needed to accomplish this.
+func orderStates(states []string, start string) []string {
+	for i := range states {
+		if states[i] == start {
+			old := states[0]
+			states[0] = states[i]
+			states[i] = old
+			break
+		}
+	}
+	return states
 }
 
 type lrParser struct {
 	table     lrParseTable
 	parseType Algorithm
 	gram      grammar.CFG
-	trace     func(s string)
+	trace     tracer
 }
 
 // Grammar returns the grammar that was used to generate the parser.
@@ -52,15 +73,56 @@ func (lr *lrParser) Grammar() grammar.CFG {
 }
 
 // DFAString returns a string representation. of the DFA that drives the LR
-// parser.
+// parser. The DFA is not kept when a parser is encoded or created from an
+// LRTable, so in that case it is rebuilt from the grammar.
 func (lr *lrParser) DFAString() string {
-	return lr.table.DFAString()
+	dfa := lr.table.DFAString()
+	if dfa != "" {
+		return dfa
+	}
+
+	full, err := lr.rebuildTable()
+	if err != nil {
+		return fmt.Sprintf("(DFA could not be rebuilt: %s)", err.Error())
+	}
+
+	return full.DFAString()
+}
+
+// tableGrammar returns the grammar that lr's table is for. This is lr's grammar
+// with any entry points added to it.
+func (lr *lrParser) tableGrammar() grammar.CFG {
+	return entryGrammar(lr.gram)
+}
+
+// rebuildTable builds the full parse table for lr's grammar with lr's
+// algorithm, DFA and all.
+func (lr *lrParser) rebuildTable() (lrParseTable, error) {
+	var full lrParseTable
+	var err error
+	switch lr.parseType {
+	case SLR1:
+		full, _, err = constructSLR1ParseTable(lr.tableGrammar(), true)
+	case LALR1:
+		full, _, err = constructLALR1ParseTable(lr.tableGrammar(), true)
+	case CLR1:
+		full, _, err = constructCLR1ParseTable(lr.tableGrammar(), true)
+	default:
+		err = fmt.Errorf("unknown parse type: %s", lr.parseType.String())
+	}
+	return full, err
 }
 
 // RegisterTraceListener sets a function to be called with messages that
 // indicate what action the parser is taking. It is useful for debug purposes.
 func (lr *lrParser) RegisterTraceListener(listener func(s string)) {
-	lr.trace = listener
+	lr.trace.setMsgs(listener)
+}
+
+// RegisterTraceEventListener sets a function to be called with each step that
+// the parser takes.
+func (lr *lrParser) RegisterTraceEventListener(listener func(ev TraceEvent)) {
+	lr.trace.setEvents(listener)
 }
 
 // Type returns the type of the parser.
@@ -76,8 +138,14 @@ func (lr *lrParser) TableString() string {
 // MarshalBinary converts lr into a slice of bytes that can be decoded with
 // UnmarshalBinary.
 func (lr *lrParser) MarshalBinary() ([]byte, error) {
-	data := rezi.EncString(lr.parseType.String())
-	data = append(data, rezi.EncBinary(lr.table)...)
+	// always write the compressed table, no matter what was used to build lr.
+	ct, ok := lr.table.(*compressedLRTable)
+	if !ok {
+		ct = compressedFrom(lr.table, lr.tableGrammar())
+	}
+
+	data := rezi.EncString(lr.parseType.String() + lrCompressedTableMarker)
+	data = append(data, rezi.EncBinary(ct)...)
 	data = append(data, rezi.EncBinary(lr.gram)...)
 	return data, nil
 }
@@ -94,18 +162,26 @@ func (lr *lrParser) UnmarshalBinary(data []byte) error {
 		return fmt.Errorf("parseType: %w", err)
 	}
 	data = data[n:]
+
+	// data from before table compression was added has the full table and
+	// no marker.
+	compressed := strings.HasSuffix(parseTypeName, lrCompressedTableMarker)
+	parseTypeName = strings.TrimSuffix(parseTypeName, lrCompressedTableMarker)
+
 	lr.parseType, err = ParseAlgorithm(parseTypeName)
 	if err != nil {
 		return fmt.Errorf("parsing parseType: %w", err)
 	}
 
 	var tableVal lrParseTable
-	switch lr.parseType {
-	case CLR1:
+	switch {
+	case compressed:
+		tableVal = &compressedLRTable{}
+	case lr.parseType == CLR1:
 		tableVal = &canonicalLR1Table{}
-	case LALR1:
+	case lr.parseType == LALR1:
 		tableVal = &lalr1Table{}
-	case SLR1:
+	case lr.parseType == SLR1:
 		tableVal = &slrTable{}
 	default:
 		return fmt.Errorf("unknown parse type: %s", lr.parseType.String())
@@ -116,103 +192,102 @@ func (lr *lrParser) UnmarshalBinary(data []byte) error {
 		return fmt.Errorf("table: %w", err)
 	}
 	data = data[n:]
-	lr.table = tableVal
 
 	_, err = rezi.DecBinary(data, &lr.gram)
 	if err != nil {
 		return fmt.Errorf("gram: %w", err)
 	}
 
-	return nil
-}
-
-func (lr lrParser) notifyTraceFn(fn func() string) {
-	if lr.trace != nil {
-		lr.trace(fn())
+	if compressed {
+		lr.table = tableVal
+	} else {
+		lr.table = compressedFrom(tableVal, lr.tableGrammar())
 	}
-}
 
-func (lr lrParser) notifyTrace(fmtStr string, args ...interface{}) {
-	lr.notifyTraceFn(func() string { return fmt.Sprintf(fmtStr, args...) })
+	return nil
 }
 
-func (lr lrParser) notifyStatePush(s string) {
-	lr.notifyTrace("states.push(): %s", s)
+// notify sends an event of the given type to the trace listeners of lr. The
+// event is filled out with the given values and a snapshot of the stack.
+func (lr *lrParser) notify(evType TraceEventType, a lex.Token, stack *box.Stack[string], fill func(ev *TraceEvent)) {
+	lr.trace.notify(func() TraceEvent {
+		ev := TraceEvent{Type: evType, Lookahead: a, Stack: stackSnapshot(stack)}
+		if stack.Len() > 0 {
+			ev.State = stack.Peek()
+		}
+		if fill != nil {
+			fill(&ev)
+		}
+		return ev
+	})
 }
 
-func (lr lrParser) notifyStatePop(s string) {
-	if s == "" {
-		lr.notifyTrace("states.pop()")
-	} else {
-		lr.notifyTrace("states.pop(): %s", s)
-	}
+// Parse parses the input stream with the internal LR parse table. If any syntax
+// errors are encountered, an empty parse tree and a *types.SyntaxError is
+// returned.
+func (lr *lrParser) Parse(stream lex.TokenStream) (Tree, error) {
+	return lr.ParseAs(lr.gram.StartSymbol(), stream)
 }
 
-func (lr lrParser) notifyAction(act lrAction) {
-	lr.notifyTrace("Action: %s", act.Type.String())
+// ParseAs parses the input stream as the given entry point of the grammar. If
+// any syntax errors are encountered, an empty parse tree and a
+// syntaxerr.Diagnostics is returned.
+func (lr *lrParser) ParseAs(symbol string, stream lex.TokenStream) (Tree, error) {
+	return lr.parseAs(context.Background(), symbol, stream, Limits{})
 }
 
-func (lr lrParser) notifyNextToken(tok lex.Token) {
-	lr.notifyTrace("Got next token: %s", tok.String())
+// ParseContext is the same as Parse but stops parsing when ctx is done or when
+// the parse tree would exceed one of the given limits, returning ctx.Err() or a
+// *syntaxerr.LimitError respectively.
+func (lr *lrParser) ParseContext(ctx context.Context, stream lex.TokenStream, lim Limits) (Tree, error) {
+	return lr.parseAs(ctx, lr.gram.StartSymbol(), stream, lim)
 }
 
-func (lr lrParser) notifyTokenStack(st *box.Stack[lex.Token]) {
-	stackElems := st.Elements()
-	lr.notifyTraceFn(func() string {
-		var lexStr strings.Builder
-		var tokStr strings.Builder
-		for i := range stackElems {
-			tok := stackElems[(len(stackElems)-1)-i]
-			lexStr.WriteRune('"')
-			lexStr.WriteString(strings.ReplaceAll(tok.Lexeme(), "\n", "\\n"))
-			lexStr.WriteRune('"')
-
-			tokStr.WriteString(strings.ToUpper(tok.Class().ID()))
-
-			if i+1 < len(stackElems) {
-				lexStr.WriteString(", ")
-				tokStr.WriteString(", ")
-			}
-		}
-		if len(stackElems) < 1 {
-			lexStr.WriteString("(empty)")
-			tokStr.WriteString("(empty)")
-		}
-
-		str := fmt.Sprintf("Token stack (lexed): %s", lexStr.String())
-		str += "\n"
-		str += fmt.Sprintf("Token stack (ttype): %s", tokStr.String())
-
-		return str
-	})
+// parseAs parses stream as the given entry point of the grammar. It stops
+// when ctx is done or lim is exceeded.
+func (lr *lrParser) parseAs(ctx context.Context, symbol string, stream lex.TokenStream, lim Limits) (Tree, error) {
+	if err := checkEntryPoint(lr.gram, symbol); err != nil {
+		return Tree{}, err
+	}
+	parse := func(s lex.TokenStream) (Tree, error) {
+		return lr.parse(ctx, s, lim)
+	}
+	if len(lr.gram.EntryPoints) == 0 {
+		return parse(stream)
+	}
+	return parseEntry(lr.gram, symbol, stream, parse)
 }
 
-// Parse parses the input stream with the internal LR parse table. If any syntax
-// errors are encountered, an empty parse tree and a *types.SyntaxError is
-// returned.
+// parse parses the input stream with the internal LR parse table, as the
+// start symbol of the grammar the table was built from. It stops when ctx is
+// done or lim is exceeded.
 //
 // This is an implementation of Algorithm 4.44, "LR-parsing algorithm", from
 // the purple dragon book.
-func (lr *lrParser) Parse(stream lex.TokenStream) (Tree, error) {
+func (lr *lrParser) parse(ctx context.Context, stream lex.TokenStream, lim Limits) (Tree, error) {
 	stateStack := box.NewStack([]string{lr.table.Initial()})
 
 	// we will use these to build our parse tree
 	tokenBuffer := &box.Stack[lex.Token]{}
 	subTreeRoots := &box.Stack[*Tree]{}
 
+	// height of each tree in subTreeRoots, counting terminals as 1. The
+	// height of the final tree is its depth.
+	heights := &box.Stack[int]{}
+
 	// let a be the first symbol of w$;
 	a := stream.Next()
-	lr.notifyNextToken(a)
+	lr.notify(TraceLookahead, a, stateStack, nil)
 
 	for { /* repeat forever */
+		if err := ctx.Err(); err != nil {
+			return Tree{}, err
+		}
+
 		// let s be the state on top of the stack;
 		s := stateStack.Peek()
 
-		lr.notifyTrace("NEXT PASS: state=%s, tok=%s", s, a.String())
-		lr.notifyTokenStack(tokenBuffer)
-
 		ACTION := lr.table.Action(s, a.Class().ID())
-		lr.notifyAction(ACTION)
 
 		switch ACTION.Type {
 		case lrShift: // if ( ACTION[s, a] = shift t )
@@ -223,28 +298,28 @@ func (lr *lrParser) Parse(stream lex.TokenStream) (Tree, error) {
 
 			// push t onto the stack
 			stateStack.Push(t)
-			lr.notifyStatePush(t)
+			lr.notify(TraceShift, a, stateStack, func(ev *TraceEvent) {
+				ev.State = s
+				ev.Target = t
+			})
 
 			// let a be the next input symbol
 			a = stream.Next()
-			lr.notifyNextToken(a)
+			lr.notify(TraceLookahead, a, stateStack, nil)
 		case lrReduce: // else if ( ACTION[s, a] = reduce A -> β )
 			A := ACTION.Symbol
 			beta := ACTION.Production
-			prodStr := strings.ToLower(beta.String())
-			if len(prodStr) == 0 {
-				prodStr = grammar.Epsilon.String()
-			}
-			lr.notifyTrace("%s -> %s", strings.ToUpper(A), prodStr)
 
 			// use the reduce to create a node in the parse tree
 			node := &Tree{Value: A, Children: make([]*Tree, 0)}
+			childHeight := 1
 
 			// SPECIAL CASE: if we just reduced an epsilon production, immediately
 			// add the epsilon node to the new one
 			if len(beta) == 0 {
 				node.Children = append(node.Children, &Tree{
 					Terminal: true,
+					Span:     lex.EmptySpanAt(a),
 				})
 			}
 
@@ -255,41 +330,65 @@ func (lr *lrParser) Parse(stream lex.TokenStream) (Tree, error) {
 				if strings.ToLower(sym) == sym {
 					// it is a terminal. read the source from the token buffer
 					tok := tokenBuffer.Pop()
-					subNode := &Tree{Terminal: true, Value: tok.Class().ID(), Source: tok}
+					subNode := &Tree{Terminal: true, Value: tok.Class().ID(), Source: tok, Span: lex.SpanOf(tok)}
 					node.Children = append([]*Tree{subNode}, node.Children...)
 				} else {
 					// it is a non-terminal. it should be in our stack of
 					// current tree roots.
 					subNode := subTreeRoots.Pop()
 					node.Children = append([]*Tree{subNode}, node.Children...)
+					if h := heights.Pop(); h > childHeight {
+						childHeight = h
+					}
 				}
 			}
+			node.Span = childrenSpan(node.Children)
+
+			// the node for the start symbol of an entry grammar is removed
+			// from the final tree, so it does not count.
+			if lr.gram.IsNonTerminal(A) && lim.depthExceeded(childHeight+1) {
+				return Tree{}, lim.depthError()
+			}
+
 			// remember it for next time
 			subTreeRoots.Push(node)
+			heights.Push(childHeight + 1)
 
 			// pop |β| symbols off the stack;
 			for i := 0; i < len(beta); i++ {
 				stateStack.Pop()
-				lr.notifyStatePop("")
 			}
+			lr.notify(TraceReduce, a, stateStack, func(ev *TraceEvent) {
+				ev.State = s
+				ev.Symbol = A
+				ev.Production = beta
+				if len(beta) == 0 {
+					ev.Production = grammar.Epsilon
+				}
+			})
 
 			// let state t now be on top of the stack
 			t := stateStack.Peek()
-			lr.notifyTrace("back to old state %s", t)
 
 			// push GOTO[t, A] onto the stack
 			toPush, err := lr.table.Goto(t, A)
 			if err != nil {
-				return Tree{}, lex.NewSyntaxErrorFromToken(fmt.Sprintf("LR parsing error; DFA has no valid transition from here on %q", A), a)
+				err = diagnose(syntaxerr.CodeParserState, fmt.Sprintf("LR parsing error; DFA has no valid transition from here on %q", A), a)
+				lr.notify(TraceError, a, stateStack, func(ev *TraceEvent) { ev.Err = err })
+				return Tree{}, err
 			}
 			stateStack.Push(toPush)
-			lr.notifyTrace("Transition %s =(%q)=> %s", t, strings.ToLower(A), toPush)
-			lr.notifyStatePush(toPush)
+			lr.notify(TraceGoto, a, stateStack, func(ev *TraceEvent) {
+				ev.State = t
+				ev.Symbol = A
+				ev.Target = toPush
+			})
 
 			// output the production A -> β
 			// (TODO: put it on the parse tree)
 		case lrAccept: // else if ( ACTION[s, a] = accept )
 			// parsing is done. there should be at least one item on the stack
+			lr.notify(TraceAccept, a, stateStack, nil)
 			pt := subTreeRoots.Pop()
 			return *pt, nil
 		case lrError:
@@ -297,19 +396,31 @@ func (lr *lrParser) Parse(stream lex.TokenStream) (Tree, error) {
 			// call error-recovery routine here when/if we add it in future
 			// - So never, huh? -V
 			// - nonononono glub, it's a feature req for later -D
-			expMessage := lr.getExpectedString(s)
-
-			// if it's an error token, then display that as a message
-			if a.Class().ID() == lex.TokenError.ID() {
-				return Tree{}, lex.NewSyntaxErrorFromToken(fmt.Sprintf("%s; %s", a.Lexeme(), expMessage), a)
-			}
-			return Tree{}, lex.NewSyntaxErrorFromToken(fmt.Sprintf("unexpected %s; %s", a.Class().Human(), expMessage), a)
+			err := lr.syntaxError(s, a)
+			lr.notify(TraceError, a, stateStack, func(ev *TraceEvent) { ev.Err = err })
+			return Tree{}, err
 		}
-		lr.notifyTrace("-----------------")
 	}
 }
 
-func (lr lrParser) getExpectedString(stateName string) string {
+// syntaxError returns the error for getting token a in the given state when
+// there is no action for it. If a is the token a lexer gives when it is
+// stopped, the error that stopped it is returned instead.
+func (lr *lrParser) syntaxError(stateName string, a lex.Token) error {
+	if err := lex.TokenErr(a); err != nil {
+		return err
+	}
+
+	expMessage := lr.getExpectedString(stateName)
+
+	// if it's an error token, then display that as a message
+	if a.Class().ID() == lex.TokenError.ID() {
+		return diagnose(syntaxerr.CodeLexical, fmt.Sprintf("%s; %s", a.Lexeme(), expMessage), a)
+	}
+	return diagnose(syntaxerr.CodeUnexpectedToken, fmt.Sprintf("unexpected %s; %s", a.Class().Human(), expMessage), a)
+}
+
+func (lr *lrParser) getExpectedString(stateName string) string {
 	expected := lr.findExpectedTokens(stateName)
 
 	var sb strings.Builder
@@ -356,7 +467,7 @@ func (lr lrParser) getExpectedString(stateName string) string {
 
 // findExpectedAt returns all token classes that are allowed/expected for
 // the given state, that is, those symbols that result in a non-error entry.
-func (lr lrParser) findExpectedTokens(stateName string) []lex.TokenClass {
+func (lr *lrParser) findExpectedTokens(stateName string) []lex.TokenClass {
 	terms := lr.gram.Terminals()
 
 	classes := make([]lex.TokenClass, 0)
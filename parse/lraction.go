@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/rezi"
 )
 
 func isShiftReduceConlict(act1, act2 lrAction) (isSR bool, shiftAct lrAction) {
@@ -100,6 +101,50 @@ type lrAction struct {
 	State string
 }
 
+// MarshalBinary converts act into a slice of bytes that can be decoded with
+// UnmarshalBinary.
+func (act lrAction) MarshalBinary() ([]byte, error) {
+	data := rezi.EncInt(int(act.Type))
+	data = append(data, rezi.EncBinary(act.Production)...)
+	data = append(data, rezi.EncString(act.Symbol)...)
+	data = append(data, rezi.EncString(act.State)...)
+	return data, nil
+}
+
+// UnmarshalBinary decodes a slice of bytes created by MarshalBinary into act.
+// All of act's fields will be replaced by the fields decoded from data.
+func (act *lrAction) UnmarshalBinary(data []byte) error {
+	var err error
+	var n int
+
+	var actType int
+	actType, n, err = rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf(".Type: %w", err)
+	}
+	act.Type = lrActionType(actType)
+	data = data[n:]
+
+	n, err = rezi.DecBinary(data, &act.Production)
+	if err != nil {
+		return fmt.Errorf(".Production: %w", err)
+	}
+	data = data[n:]
+
+	act.Symbol, n, err = rezi.DecString(data)
+	if err != nil {
+		return fmt.Errorf(".Symbol: %w", err)
+	}
+	data = data[n:]
+
+	act.State, _, err = rezi.DecString(data)
+	if err != nil {
+		return fmt.Errorf(".State: %w", err)
+	}
+
+	return nil
+}
+
 // String returns a string representation of the LRAction.
 func (act lrAction) String() string {
 	switch act.Type {
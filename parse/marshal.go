@@ -40,6 +40,8 @@ func DecodeBytes(data []byte) (p Parser, err error) {
 		p = EmptyLALR1Parser()
 	case CLR1:
 		p = EmptyCLR1Parser()
+	case CYK:
+		p = EmptyCYKParser()
 	default:
 		panic("should never happen: parsed parserType is not valid")
 	}
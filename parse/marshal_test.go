@@ -49,6 +49,17 @@ func Test_EncodeDecodeParserBytes(t *testing.T) {
 				C -> c C | d ;
 			`,
 		},
+		{
+			name: "CYK parser",
+			ctor: func(g grammar.CFG, b bool) (Parser, []string, error) {
+				p, err := GenerateCYKParser(g)
+				return p, nil, err
+			},
+			g: `
+				S -> C C ;
+				C -> c C | d ;
+			`,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,152 @@
+package parse
+
+import (
+	"fmt"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+)
+
+// Denormalize takes a parse tree of the grammar of nf and returns the parse
+// tree of the grammar that nf was converted from that derives the same tokens,
+// undoing each step of the conversion in turn. The returned tree shares no
+// nodes with pt.
+//
+// Terminal nodes for epsilon that were not in pt are given an empty Span at the
+// start of the next token, or at the end of the last token if there is none
+// after them. All other terminal nodes keep their Source and Span, and the Span
+// of each non-terminal node is set to cover its children.
+func (pt Tree) Denormalize(nf grammar.NormalForm) (Tree, error) {
+	cur := pt.Copy()
+	for i := len(nf.Steps) - 1; i >= 0; i-- {
+		nodes, err := denormalizeNode(&cur, nf.Steps[i], nil)
+		if err != nil {
+			return Tree{}, fmt.Errorf("undo %s: %w", nf.Steps[i].Name, err)
+		}
+		if len(nodes) != 1 {
+			return Tree{}, fmt.Errorf("undo %s: root was replaced with %d nodes instead of 1", nf.Steps[i].Name, len(nodes))
+		}
+		cur = *nodes[0]
+	}
+
+	// now that the tree is complete, give new epsilon nodes a location.
+	var leaves []*Tree
+	var collect func(t *Tree)
+	collect = func(t *Tree) {
+		if t.Terminal {
+			leaves = append(leaves, t)
+		}
+		for _, c := range t.Children {
+			collect(c)
+		}
+	}
+	collect(&cur)
+
+	var zero lex.Span
+	var last lex.Position
+	var pending []*Tree
+	for _, leaf := range leaves {
+		if leaf.Value == grammar.Epsilon[0] && leaf.Span == zero {
+			pending = append(pending, leaf)
+			continue
+		}
+		for _, p := range pending {
+			p.Span = lex.Span{Start: leaf.Span.Start, End: leaf.Span.Start}
+		}
+		pending = nil
+		last = leaf.Span.End
+	}
+	for _, p := range pending {
+		p.Span = lex.Span{Start: last, End: last}
+	}
+
+	cur.spanChildren()
+	return cur, nil
+}
+
+// denormalizeNode returns the nodes that pt is replaced with to undo step t,
+// along with the replacements of all of its descendants. inherited is the node
+// given to pt by its parent, if any.
+func denormalizeNode(pt *Tree, t grammar.Transformation, inherited *Tree) ([]*Tree, error) {
+	if pt.Terminal {
+		return []*Tree{pt}, nil
+	}
+
+	prod := make(grammar.Production, len(pt.Children))
+	for i := range pt.Children {
+		prod[i] = pt.Children[i].Value
+	}
+	m := t.Mapping(pt.Value, prod)
+
+	// a child can be given a node that is made from its siblings, so children
+	// are replaced as they are needed rather than in order.
+	replaced := make([][]*Tree, len(pt.Children))
+	inProgress := make([]bool, len(pt.Children))
+
+	var build func(tmpl grammar.TreeTemplate) ([]*Tree, error)
+	child := func(idx int) ([]*Tree, error) {
+		if idx < 0 || idx >= len(pt.Children) {
+			return nil, fmt.Errorf("%s -> %s has no child %d", pt.Value, prod, idx)
+		}
+		if replaced[idx] != nil {
+			return replaced[idx], nil
+		}
+		if inProgress[idx] {
+			return nil, fmt.Errorf("%s -> %s: child %d is given a node made from itself", pt.Value, prod, idx)
+		}
+		inProgress[idx] = true
+
+		var given *Tree
+		if tmpl, ok := m.Pass[idx]; ok {
+			nodes, err := build(tmpl)
+			if err != nil {
+				return nil, err
+			}
+			if len(nodes) != 1 {
+				return nil, fmt.Errorf("%s -> %s: child %d is given %d nodes instead of 1", pt.Value, prod, idx, len(nodes))
+			}
+			given = nodes[0]
+		}
+
+		nodes, err := denormalizeNode(pt.Children[idx], t, given)
+		if err != nil {
+			return nil, err
+		}
+		replaced[idx] = nodes
+		return nodes, nil
+	}
+	build = func(tmpl grammar.TreeTemplate) ([]*Tree, error) {
+		switch tmpl.Kind {
+		case grammar.TemplateChild:
+			return child(tmpl.Child)
+		case grammar.TemplateInherited:
+			if inherited == nil {
+				return nil, fmt.Errorf("%s -> %s uses a node from its parent but was not given one", pt.Value, prod)
+			}
+			return []*Tree{inherited}, nil
+		}
+
+		if tmpl.Symbol == grammar.Epsilon[0] {
+			return []*Tree{{Terminal: true}}, nil
+		}
+		node := &Tree{Value: tmpl.Symbol}
+		for _, c := range tmpl.Children {
+			nodes, err := build(c)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, nodes...)
+		}
+		return []*Tree{node}, nil
+	}
+
+	var result []*Tree
+	for _, tmpl := range m.Result {
+		nodes, err := build(tmpl)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, nodes...)
+	}
+	return result, nil
+}
@@ -0,0 +1,170 @@
+package parse
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Tree_Denormalize(t *testing.T) {
+	testCases := []struct {
+		name   string
+		g      string
+		gnf    bool
+		input  string
+		expect string
+	}{
+		{
+			name:   "CNF with epsilon",
+			g:      `S -> a S b | ε ;`,
+			input:  `[S-P [A-P (a)] [S-PP (b)]]`,
+			expect: `[S (a) [S ()] (b)]`,
+		},
+		{
+			name:   "CNF of empty string",
+			g:      `S -> a S b | ε ;`,
+			input:  `[S-P ()]`,
+			expect: `[S ()]`,
+		},
+		{
+			name:   "CNF with unit productions",
+			g:      `S -> A ; A -> B | a ; B -> b ;`,
+			input:  `[S (b)]`,
+			expect: `[S [A [B (b)]]]`,
+		},
+		{
+			name:   "GNF with epsilon",
+			g:      `S -> a S b | ε ;`,
+			gnf:    true,
+			input:  `[S-P (a) [S-PP (a) [S-PP (b)] [B-P (b)]]]`,
+			expect: `[S (a) [S (a) [S ()] (b)] (b)]`,
+		},
+		{
+			name:   "GNF with left recursion",
+			g:      `S -> S a | b ;`,
+			gnf:    true,
+			input:  `[S-P (b) [S-PP (a)] [A-P (a)]]`,
+			expect: `[S [S [S (b)] (a)] (a)]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := grammar.MustParse(tc.g)
+			var nf grammar.NormalForm
+			var err error
+			if tc.gnf {
+				nf, err = g.GreibachNormalForm()
+			} else {
+				nf, err = g.ChomskyNormalForm()
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			input := MustParseTreeFromDiagram(tc.input)
+			expect := MustParseTreeFromDiagram(tc.expect)
+
+			actual, err := input.Denormalize(nf)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(expect.String(), actual.String())
+		})
+	}
+}
+
+func Test_Tree_Denormalize_GeneratedTrees(t *testing.T) {
+	grammars := []string{
+		`S -> a S b | ε ;`,
+		`S -> A S B | a ; A -> a A | ε ; B -> b | ε ;`,
+		`E -> E plus T | T ; T -> T times F | F ; F -> lp E rp | int ;`,
+		`S -> A a | b ; A -> A c | S d | ε ;`,
+		`S -> L eq R | R ; L -> star R | id ; R -> L ;`,
+	}
+
+	for _, src := range grammars {
+		for _, form := range []string{"CNF", "GNF"} {
+			t.Run(fmt.Sprintf("%s of %s", form, src), func(t *testing.T) {
+				assert := assert.New(t)
+
+				g := grammar.MustParse(src)
+				var nf grammar.NormalForm
+				var err error
+				if form == "GNF" {
+					nf, err = g.GreibachNormalForm()
+				} else {
+					nf, err = g.ChomskyNormalForm()
+				}
+				if !assert.NoError(err) {
+					return
+				}
+
+				gen, err := NewGenerator(nf.Grammar, GeneratorOptions{Seed: 1, MaxTokens: 20})
+				if !assert.NoError(err) {
+					return
+				}
+
+				for i := 0; i < 50; i++ {
+					sent := gen.Generate()
+
+					actual, err := sent.Tree.Denormalize(nf)
+					if !assert.NoError(err, sent.Tree.String()) {
+						return
+					}
+
+					assert.Equal(g.StartSymbol(), actual.Value)
+					assert.Equal(treeTokens(sent.Tree), treeTokens(actual))
+					assert.Equal(sent.Tree.Span, actual.Span)
+					if err := checkDerivation(g, &actual); err != nil {
+						assert.NoError(err, actual.String())
+						return
+					}
+				}
+			})
+		}
+	}
+}
+
+// treeTokens returns the lexeme of every non-epsilon terminal node in pt in
+// order.
+func treeTokens(pt Tree) []string {
+	var lexemes []string
+	if pt.Terminal {
+		if pt.Value != grammar.Epsilon[0] {
+			lexemes = append(lexemes, pt.Source.Lexeme())
+		}
+		return lexemes
+	}
+	for _, c := range pt.Children {
+		lexemes = append(lexemes, treeTokens(*c)...)
+	}
+	return lexemes
+}
+
+// checkDerivation returns an error if any non-terminal node in pt does not
+// have children that are a production of it in g.
+func checkDerivation(g grammar.CFG, pt *Tree) error {
+	if pt.Terminal {
+		return nil
+	}
+
+	prod := make(grammar.Production, len(pt.Children))
+	for i := range pt.Children {
+		prod[i] = pt.Children[i].Value
+	}
+	if !g.Rule(pt.Value).HasProduction(prod) {
+		return fmt.Errorf("%s -> %s is not a production of the grammar", pt.Value, prod)
+	}
+
+	for _, c := range pt.Children {
+		if err := checkDerivation(g, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
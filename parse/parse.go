@@ -7,11 +7,14 @@
 // This package currently provides an LL(1) parser, a Simple LR(1) parser, a
 // Canonical LR(1) parser, and an LALR(1) parser, as well as the means to
 // generate each from a context-free grammar describing the accepted language.
-// The exact type of parser needed depends on the grammar.
+// The exact type of parser needed depends on the grammar. A CYK parser is also
+// provided, which accepts any context-free grammar at the cost of taking time
+// proportional to the cube of the length of the input.
 package parse
 
 import (
 	"bufio"
+	"context"
 	"encoding"
 	"fmt"
 	"io"
@@ -24,31 +27,38 @@ import (
 	"github.com/dekarrin/ictiobus/internal/box"
 	"github.com/dekarrin/ictiobus/internal/slices"
 	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
 	"github.com/dekarrin/rosed"
 )
 
 // A Parser represents an in-progress or ready-built parsing engine ready for
 // use. It can be stored as a byte representation and retrieved from bytes as
 // well.
+//
+// The Parsers in this package are safe for concurrent use by multiple
+// goroutines, except for UnmarshalBinary, which must not be called while the
+// Parser is in use. Trace listeners are called from whichever goroutine is
+// parsing, so they must be safe for concurrent use themselves if the Parser is
+// shared.
 type Parser interface {
 	encoding.BinaryMarshaler
 	encoding.BinaryUnmarshaler
 
 	// Parse parses input text and returns the parse tree built from it, or a
-	// SyntaxError with the description of the problem.
+	// syntaxerr.Diagnostics with the description of the problem. errors.As can
+	// be used to get a *syntaxerr.Error from it.
 	Parse(stream lex.TokenStream) (Tree, error)
 
 	// Type returns a string indicating what kind of parser was generated. This
-	// will be "LL(1)", "SLR(1)", "CLR(1)", or "LALR(1)"
+	// will be "LL(1)", "SLR(1)", "CLR(1)", "LALR(1)", or "CYK".
 	Type() Algorithm
 
 	// TableString returns the parsing table as a string.
 	TableString() string
 
 	// RegisterTraceListener sets up a function to call when an event occurs.
-	// The events are determined by the individual parsers but involve
-	// examination of the parser stack or other critical moments that may aid in
-	// debugging.
+	// For a TraceEventSource, it is given the String() of each TraceEvent that
+	// would be given to a listener set with RegisterTraceEventListener.
 	RegisterTraceListener(func(s string))
 
 	// DFAString returns a string representation of the DFA for this parser, if one
@@ -60,6 +70,89 @@ type Parser interface {
 	Grammar() grammar.CFG
 }
 
+// EntryParser is a Parser that can parse input as any of the entry points of
+// its grammar. The Parsers in this package implement it. Use the ParseAs
+// function to parse as an entry point with a Parser that might not.
+type EntryParser interface {
+	Parser
+
+	// ParseAs is the same as Parse but parses the input text as the given
+	// non-terminal instead of the start symbol. The non-terminal must be the
+	// start symbol or one of the entry points of the parser's grammar.
+	ParseAs(symbol string, stream lex.TokenStream) (Tree, error)
+}
+
+// ParseAs parses stream with p as the given entry point of p's grammar. If p
+// is an EntryParser, this is the same as calling its ParseAs method.
+// Otherwise, stream is parsed with p.Parse if symbol is the start symbol of
+// the grammar, and an error is returned for any other symbol.
+func ParseAs(p Parser, symbol string, stream lex.TokenStream) (Tree, error) {
+	if ep, ok := p.(EntryParser); ok {
+		return ep.ParseAs(symbol, stream)
+	}
+
+	if symbol != p.Grammar().StartSymbol() {
+		return Tree{}, fmt.Errorf("%s parser does not support parsing as entry point %q", p.Type(), symbol)
+	}
+	return p.Parse(stream)
+}
+
+// TraceEventSource is a Parser that gives a TraceEvent for each step it takes.
+// The Parsers in this package implement it.
+type TraceEventSource interface {
+	Parser
+
+	// RegisterTraceEventListener sets up a function to call with a TraceEvent
+	// each time the parser takes a step, such as shifting a token, reducing or
+	// predicting with a rule, or hitting an error. It is useful for debugging
+	// and for building tools that visualize or check the steps of a parse.
+	RegisterTraceEventListener(func(ev TraceEvent))
+}
+
+// ContextParser is a Parser that can stop parsing when a context is done or
+// when the parse tree exceeds limits. The Parsers in this package implement
+// it. Use the ParseContext function to parse with a Parser that might not.
+type ContextParser interface {
+	Parser
+
+	// ParseContext is the same as Parse but stops parsing when ctx is done or
+	// when the parse tree would be larger than the given limits allow. In the
+	// first case, ctx.Err() is returned, and in the second, a
+	// *syntaxerr.LimitError. If the stream gives a token that lex.TokenErr
+	// returns an error for, parsing stops with that error.
+	ParseContext(ctx context.Context, stream lex.TokenStream, lim Limits) (Tree, error)
+}
+
+// ParseContext parses stream with p, stopping when ctx is done or when the
+// parse tree would be larger than the given limits allow. If p is a
+// ContextParser, this is the same as calling its ParseContext method.
+// Otherwise, p.Parse is called unless ctx is already done, and the tree it
+// returns is checked against lim once it is built. In that case, an error from
+// a token that lex.TokenErr returns one for is returned in place of the error
+// p.Parse gives for that token.
+func ParseContext(ctx context.Context, p Parser, stream lex.TokenStream, lim Limits) (Tree, error) {
+	if cp, ok := p.(ContextParser); ok {
+		return cp.ParseContext(ctx, stream, lim)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Tree{}, err
+	}
+
+	watched := &stopWatchStream{TokenStream: stream}
+	pt, err := p.Parse(watched)
+	if watched.err != nil {
+		return Tree{}, watched.err
+	}
+	if err != nil {
+		return pt, err
+	}
+	if err := lim.CheckTree(pt); err != nil {
+		return Tree{}, err
+	}
+	return pt, nil
+}
+
 // Algorithm is a classification of parsers in ictiobus.
 type Algorithm string
 
@@ -68,6 +161,7 @@ const (
 	SLR1  Algorithm = "SLR(1)"
 	CLR1  Algorithm = "CLR(1)"
 	LALR1 Algorithm = "LALR(1)"
+	CYK   Algorithm = "CYK"
 )
 
 // String returns the string representation of a ParserType.
@@ -75,6 +169,12 @@ func (pt Algorithm) String() string {
 	return string(pt)
 }
 
+// IsLR returns whether pt is one of the LR parsing algorithms. Only parsers
+// that use one can be given to ParseReductions.
+func (pt Algorithm) IsLR() bool {
+	return pt == SLR1 || pt == CLR1 || pt == LALR1
+}
+
 // ParseAlgorithm parses a string containing the name of an Algorithm.
 func ParseAlgorithm(s string) (Algorithm, error) {
 	switch s {
@@ -86,6 +186,8 @@ func ParseAlgorithm(s string) (Algorithm, error) {
 		return CLR1, nil
 	case LALR1.String():
 		return LALR1, nil
+	case CYK.String():
+		return CYK, nil
 	default:
 		return LL1, fmt.Errorf("not a valid ParserType: %q", s)
 	}
@@ -104,8 +206,8 @@ func IsLL1(g grammar.CFG) bool {
 		// -purple dragon book
 		for i := range AiRule.Productions {
 			for j := i + 1; j < len(AiRule.Productions); j++ {
-				alphaFIRST := findFIRSTSet(g, AiRule.Productions[i][0])
-				betaFIRST := findFIRSTSet(g, AiRule.Productions[j][0])
+				alphaFIRST := findFIRSTSetString(g, AiRule.Productions[i]...)
+				betaFIRST := findFIRSTSetString(g, AiRule.Productions[j]...)
 
 				aFSet := box.StringSetOf(alphaFIRST.Elements())
 				bFSet := box.StringSetOf(betaFIRST.Elements())
@@ -497,3 +599,9 @@ func outputSetValuedDFA[E fmt.Stringer](w io.Writer, dfa automaton.DFA[box.SVSet
 
 	bw.Flush()
 }
+
+// diagnose returns a syntaxerr.Diagnostics with a single error for tok with
+// the given code and message.
+func diagnose(code string, msg string, tok lex.Token) error {
+	return syntaxerr.Diagnostics{lex.NewDiagnosticFromToken(code, msg, tok)}
+}
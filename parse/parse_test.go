@@ -1,11 +1,13 @@
 package parse
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/dekarrin/ictiobus/grammar"
 	"github.com/dekarrin/ictiobus/internal/box"
 	"github.com/dekarrin/ictiobus/internal/textfmt"
+	"github.com/dekarrin/ictiobus/syntaxerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,6 +39,22 @@ func Test_IsLL1(t *testing.T) {
 			`,
 			expect: false,
 		},
+		{
+			name: "nullable first symbol shares FIRST with other prod",
+			g: `
+				S -> A b | b ;
+				A -> a | ε ;
+			`,
+			expect: false,
+		},
+		{
+			name: "nullable first symbol followed by distinct terminal",
+			g: `
+				S -> A b | c ;
+				A -> a | ε ;
+			`,
+			expect: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -334,3 +352,123 @@ func Test_findFIRSTSet(t *testing.T) {
 		})
 	}
 }
+
+func Test_ParserSpans(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> A b C ;
+		A -> a | ε ;
+		C -> c | ε ;
+	`)
+
+	// paths are into the tree for the input.
+	testCases := []struct {
+		name   string
+		input  []string
+		expect map[string][]int
+	}{
+		{
+			name:  "no epsilons",
+			input: []string{"a", "b", "c", "$"},
+			expect: map[string][]int{
+				"1:1-1:6": {},
+				"1:1-1:2": {0},
+				"1:3-1:4": {1},
+				"1:5-1:6": {2},
+			},
+		},
+		{
+			name:  "epsilons at start and end",
+			input: []string{"b", "$"},
+			expect: map[string][]int{
+				"1:1-1:2": {},
+				"1:1-1:1": {0, 0},
+				"1:3-1:3": {2, 0},
+			},
+		},
+	}
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"SLR(1)": func() (Parser, error) {
+			p, _, err := GenerateSLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+		"CLR(1)": func() (Parser, error) {
+			p, _, err := GenerateCLR1Parser(g, false)
+			return p, err
+		},
+	}
+
+	for pName, ctor := range parsers {
+		for _, tc := range testCases {
+			t.Run(pName+" "+tc.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				p, err := ctor()
+				if !assert.NoError(err, "generating parser failed") {
+					return
+				}
+
+				// execute
+				tree, err := p.Parse(mockTokens(tc.input...))
+
+				// assert
+				if !assert.NoError(err) {
+					return
+				}
+				for expect, path := range tc.expect {
+					node := tree.Follow(path)
+					assert.Equal(expect, node.Span.String(), "span of %v (%s)", path, node.Value)
+				}
+			})
+		}
+	}
+}
+
+func Test_Parse_diagnostics(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> id eq E ;
+		E -> int | id ;
+	`)
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+	}
+
+	for pName, ctor := range parsers {
+		t.Run(pName, func(t *testing.T) {
+			assert := assert.New(t)
+
+			p, err := ctor()
+			if !assert.NoError(err, "generating parser failed") {
+				return
+			}
+
+			// execute
+			_, err = p.Parse(mockTokens("id", "eq", "eq", "$"))
+
+			// assert
+			var diags syntaxerr.Diagnostics
+			if !assert.True(errors.As(err, &diags), "expected syntaxerr.Diagnostics, got %T", err) {
+				return
+			}
+			if !assert.Len(diags, 1) {
+				return
+			}
+			d := diags[0]
+			assert.Equal(syntaxerr.CodeUnexpectedToken, d.Code)
+			assert.Equal(syntaxerr.SeverityError, d.Severity)
+			assert.Equal(syntaxerr.Position{Line: 1, Col: 7}, d.Span.Start)
+			assert.Equal(syntaxerr.Position{Line: 1, Col: 9}, d.Span.End)
+			assert.Equal("eq", d.Source())
+		})
+	}
+}
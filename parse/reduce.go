@@ -0,0 +1,207 @@
+package parse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/box"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// File reduce.go contains functions for parsing input without building a parse
+// tree, by calling a function on each reduction of an LR parser instead.
+
+// Reduction is a single reduction made by an LR parser, in which the symbols of
+// a production were replaced with the non-terminal at its head.
+type Reduction struct {
+	// Symbol is the non-terminal at the head of the rule that was reduced.
+	Symbol string
+
+	// Production is the production of the rule that was reduced. It is
+	// grammar.Epsilon for an epsilon production.
+	Production grammar.Production
+
+	// First is the first token of the input that the symbol covers. It is nil
+	// if the symbol derived only epsilon.
+	First lex.Token
+
+	// Span is the range of source text that the symbol covers. This is an
+	// empty span at the point where the symbol is if it derived only epsilon.
+	Span lex.Span
+}
+
+// ReduceFunc is called by ParseReductions each time the parser reduces by a rule. values
+// holds the value for each symbol in the production, in order: for a terminal,
+// this is the lex.Token that was shifted for it, and for a non-terminal, it is
+// whatever the ReduceFunc returned when that non-terminal was reduced. For an
+// epsilon production, values is empty.
+//
+// The returned value becomes the value of the head symbol. If a non-nil error
+// is returned, parsing stops and ParseReductions returns that error.
+type ReduceFunc func(red Reduction, values []interface{}) (interface{}, error)
+
+// ParseReductions parses the tokens from stream with p without building a parse tree.
+// Instead, fn is called each time a rule is reduced, with the values of the
+// symbols in the rule, in the manner of the semantic actions of a bison
+// parser. Only the values of symbols that have not yet been reduced are kept,
+// so memory use is bounded by the depth of the parse rather than the size of
+// the input. The value returned by fn for the start symbol of the grammar is
+// returned.
+//
+// p must be an LR parser; an error is returned if it is not. If there is a
+// syntax error, a syntaxerr.Diagnostics is returned. p's trace listeners are
+// notified as they would be for a call to Parse.
+func ParseReductions(p Parser, stream lex.TokenStream, fn ReduceFunc) (interface{}, error) {
+	return ParseReductionsContext(context.Background(), p, stream, Limits{}, fn)
+}
+
+// ParseReductionsContext is the same as ParseReductions but stops parsing when
+// ctx is done or when the parse tree that the reductions make up would exceed
+// one of the given limits, returning ctx.Err() or a *syntaxerr.LimitError
+// respectively.
+func ParseReductionsContext(ctx context.Context, p Parser, stream lex.TokenStream, lim Limits, fn ReduceFunc) (interface{}, error) {
+	lr, ok := p.(*lrParser)
+	if !ok {
+		return nil, fmt.Errorf("%s parser cannot parse without building a tree", p.Type())
+	}
+
+	if len(lr.gram.EntryPoints) == 0 {
+		return lr.reduce(ctx, stream, lim, fn, "")
+	}
+
+	start, markers := entryNames(lr.gram)
+	return lr.reduce(ctx, markEntry(stream, markers[lr.gram.StartSymbol()]), lim, fn, start)
+}
+
+// reduceEntry is a value on the value stack of a tree-less LR parse.
+type reduceEntry struct {
+	value interface{}
+	first lex.Token
+	span  lex.Span
+
+	// height is the height of the tree that the reductions for the value
+	// would make, counting a terminal as 1.
+	height int
+}
+
+// reduce parses the input stream with the internal LR parse table, calling fn
+// instead of building a tree. It is the same algorithm as lr.parse. If
+// entryStart is not empty, it is the start symbol of the entry grammar; fn is
+// not called for its rule, and the value of the symbol after the marker is
+// returned instead. It stops when ctx is done or lim is exceeded.
+func (lr *lrParser) reduce(ctx context.Context, stream lex.TokenStream, lim Limits, fn ReduceFunc, entryStart string) (interface{}, error) {
+	stateStack := box.NewStack([]string{lr.table.Initial()})
+	var values []reduceEntry
+
+	a := stream.Next()
+	lr.notify(TraceLookahead, a, stateStack, nil)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		s := stateStack.Peek()
+
+		ACTION := lr.table.Action(s, a.Class().ID())
+
+		switch ACTION.Type {
+		case lrShift:
+			values = append(values, reduceEntry{value: a, first: a, span: lex.SpanOf(a), height: 1})
+
+			t := ACTION.State
+			stateStack.Push(t)
+			lr.notify(TraceShift, a, stateStack, func(ev *TraceEvent) {
+				ev.State = s
+				ev.Target = t
+			})
+
+			a = stream.Next()
+			lr.notify(TraceLookahead, a, stateStack, nil)
+		case lrReduce:
+			A := ACTION.Symbol
+			beta := ACTION.Production
+
+			popped := values[len(values)-len(beta):]
+			childHeight := 1
+			for i := range popped {
+				if popped[i].height > childHeight {
+					childHeight = popped[i].height
+				}
+			}
+
+			// the rule for the start symbol of an entry grammar is not part of
+			// the user's grammar, so it does not count.
+			if lr.gram.IsNonTerminal(A) && lim.depthExceeded(childHeight+1) {
+				return nil, lim.depthError()
+			}
+
+			red := Reduction{Symbol: A, Production: beta}
+			if len(beta) == 0 {
+				red.Production = grammar.Epsilon
+				red.Span = lex.EmptySpanAt(a)
+			} else {
+				spans := make([]lex.Span, len(popped))
+				for i := range popped {
+					if red.First == nil {
+						red.First = popped[i].first
+					}
+					spans[i] = popped[i].span
+				}
+				red.Span = lex.Covering(spans...)
+			}
+
+			var val interface{}
+			if A == entryStart && entryStart != "" {
+				// the rule for the entry grammar's start symbol is not part of
+				// the user's grammar; pass through the value of the entry point.
+				val = popped[1].value
+			} else {
+				args := make([]interface{}, len(popped))
+				for i := range popped {
+					args[i] = popped[i].value
+				}
+
+				var err error
+				val, err = fn(red, args)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			values = append(values[:len(values)-len(beta)], reduceEntry{value: val, first: red.First, span: red.Span, height: childHeight + 1})
+
+			for i := 0; i < len(beta); i++ {
+				stateStack.Pop()
+			}
+			lr.notify(TraceReduce, a, stateStack, func(ev *TraceEvent) {
+				ev.State = s
+				ev.Symbol = A
+				ev.Production = red.Production
+			})
+
+			t := stateStack.Peek()
+			toPush, err := lr.table.Goto(t, A)
+			if err != nil {
+				err = diagnose(syntaxerr.CodeParserState, fmt.Sprintf("LR parsing error; DFA has no valid transition from here on %q", A), a)
+				lr.notify(TraceError, a, stateStack, func(ev *TraceEvent) { ev.Err = err })
+				return nil, err
+			}
+			stateStack.Push(toPush)
+			lr.notify(TraceGoto, a, stateStack, func(ev *TraceEvent) {
+				ev.State = t
+				ev.Symbol = A
+				ev.Target = toPush
+			})
+		case lrAccept:
+			lr.notify(TraceAccept, a, stateStack, nil)
+			return values[len(values)-1].value, nil
+		case lrError:
+			err := lr.syntaxError(s, a)
+			lr.notify(TraceError, a, stateStack, func(ev *TraceEvent) { ev.Err = err })
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseReductions(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> S plus T | T ;
+		T -> lp S rp | A int ;
+		A -> a | ε ;
+	`)
+
+	withEntries := g.Copy()
+	withEntries.EntryPoints = []string{"T"}
+
+	// buildTree builds the same tree that Parse would from the reductions.
+	buildTree := func(red Reduction, values []interface{}) (interface{}, error) {
+		node := &Tree{Value: red.Symbol, Span: red.Span}
+		if len(values) == 0 {
+			node.Children = []*Tree{{Terminal: true, Span: red.Span}}
+		}
+		for _, v := range values {
+			switch v := v.(type) {
+			case lex.Token:
+				node.Children = append(node.Children, &Tree{Terminal: true, Value: v.Class().ID(), Source: v, Span: lex.SpanOf(v)})
+			case *Tree:
+				node.Children = append(node.Children, v)
+			default:
+				return nil, fmt.Errorf("unexpected value of type %T", v)
+			}
+		}
+		return node, nil
+	}
+
+	testCases := []struct {
+		name      string
+		input     []string
+		expectErr bool
+	}{
+		{
+			name:  "single terminal",
+			input: []string{"int", "$"},
+		},
+		{
+			name:  "nested",
+			input: []string{"int", "plus", "lp", "a", "int", "plus", "int", "rp", "plus", "int", "$"},
+		},
+		{
+			name:      "syntax error",
+			input:     []string{"int", "plus", "plus", "$"},
+			expectErr: true,
+		},
+	}
+
+	parsers := map[string]func() (Parser, error){
+		"SLR(1)": func() (Parser, error) {
+			p, _, err := GenerateSLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+		"CLR(1)": func() (Parser, error) {
+			p, _, err := GenerateCLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1) with entry points": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(withEntries, false)
+			return p, err
+		},
+	}
+
+	for pName, ctor := range parsers {
+		for _, tc := range testCases {
+			t.Run(pName+" "+tc.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				p, err := ctor()
+				if !assert.NoError(err, "generating parser failed") {
+					return
+				}
+
+				// execute
+				actual, err := ParseReductions(p, mockTokens(tc.input...), buildTree)
+
+				// assert
+				expect, expectErr := p.Parse(mockTokens(tc.input...))
+				if tc.expectErr {
+					assert.Error(err)
+					assert.Equal(expectErr, err)
+					return
+				}
+				if !assert.NoError(err) {
+					return
+				}
+				if !assert.IsType(&Tree{}, actual) {
+					return
+				}
+				assert.Equal(expect, *actual.(*Tree))
+			})
+		}
+	}
+}
+
+func Test_ParseReductions_values(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> S plus int | int ;
+	`)
+	p, _, err := GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var reds []string
+	sum := func(red Reduction, values []interface{}) (interface{}, error) {
+		reds = append(reds, red.Symbol+" -> "+red.Production.String())
+		if len(values) == 1 {
+			return 1, nil
+		}
+		return values[0].(int) + 1, nil
+	}
+
+	actual, err := ParseReductions(p, mockTokens("int", "plus", "int", "plus", "int", "$"), sum)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 3, actual)
+	assert.Equal(t, []string{"S -> int", "S -> S plus int", "S -> S plus int"}, reds)
+}
+
+func Test_ParseReductions_errors(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> S plus int | int ;
+	`)
+
+	t.Run("callback error stops parsing", func(t *testing.T) {
+		p, _, err := GenerateLALR1Parser(g, false)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var calls int
+		fail := func(red Reduction, values []interface{}) (interface{}, error) {
+			calls++
+			return nil, fmt.Errorf("bad value")
+		}
+
+		_, err = ParseReductions(p, mockTokens("int", "plus", "int", "$"), fail)
+		assert.EqualError(t, err, "bad value")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("LL(1) parser", func(t *testing.T) {
+		p, err := GenerateLL1Parser(grammar.MustParse(`S -> int ;`))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = ParseReductions(p, mockTokens("int", "$"), func(red Reduction, values []interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		assert.Error(t, err)
+	})
+}
+
+func Test_ParseReductionsContext(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> id eq E ;
+		E -> T X ;
+		X -> plus T X | ε ;
+		T -> id | int | lp E rp ;
+	`)
+
+	// the entry grammar's start symbol must not count towards the depth.
+	g.EntryPoints = []string{"E"}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		input     []string
+		lim       Limits
+		expectErr error
+	}{
+		{
+			name:  "no limits",
+			ctx:   context.Background(),
+			input: []string{"id", "eq", "lp", "int", "rp", "$"},
+		},
+		{
+			name:  "tree as deep as limit",
+			ctx:   context.Background(),
+			input: []string{"id", "eq", "int", "$"},
+			lim:   Limits{MaxTreeDepth: 4},
+		},
+		{
+			name:      "tree deeper than limit",
+			ctx:       context.Background(),
+			input:     []string{"id", "eq", "int", "$"},
+			lim:       Limits{MaxTreeDepth: 3},
+			expectErr: syntaxerr.ErrTreeTooDeep,
+		},
+		{
+			name:      "nested tree deeper than limit",
+			ctx:       context.Background(),
+			input:     []string{"id", "eq", "lp", "int", "rp", "$"},
+			lim:       Limits{MaxTreeDepth: 5},
+			expectErr: syntaxerr.ErrTreeTooDeep,
+		},
+		{
+			name:      "canceled",
+			ctx:       canceled,
+			input:     []string{"id", "eq", "int", "$"},
+			expectErr: context.Canceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			p, _, err := GenerateLALR1Parser(g, false)
+			if !assert.NoError(err, "generating parser failed") {
+				return
+			}
+
+			// execute
+			_, err = ParseReductionsContext(tc.ctx, p, mockTokens(tc.input...), tc.lim, func(red Reduction, values []interface{}) (interface{}, error) {
+				return nil, nil
+			})
+
+			// assert
+			if tc.expectErr != nil {
+				assert.True(errors.Is(err, tc.expectErr), "expected %v, got %v", tc.expectErr, err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
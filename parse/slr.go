@@ -28,12 +28,13 @@ func EmptySLR1Parser() Parser {
 // ambiguous, the 2nd arg 'ambiguity warnings' will be filled with each
 // ambiguous case detected.
 func GenerateSLR1Parser(g grammar.CFG, allowAmbig bool) (Parser, []string, error) {
-	table, ambigWarns, err := constructSLR1ParseTable(g, allowAmbig)
+	tg := entryGrammar(g)
+	table, ambigWarns, err := constructSLR1ParseTable(tg, allowAmbig)
 	if err != nil {
 		return &lrParser{}, ambigWarns, err
 	}
 
-	return &lrParser{table: table, parseType: SLR1, gram: g}, ambigWarns, nil
+	return &lrParser{table: compressedFrom(table, tg), parseType: SLR1, gram: g}, ambigWarns, nil
 }
 
 // constructSLR1ParseTable constructs the SLR(1) table for G. It augments
@@ -77,7 +78,7 @@ func constructSLR1ParseTable(g grammar.CFG, allowAmbig bool) (lrParseTable, []st
 	var ambigWarns []string
 	for _, stateName := range lr0Automaton.States() {
 		fromState := fmt.Sprintf(" (from DFA state %q)", textfmt.TruncateWith(stateName, 4, "..."))
-		for _, a := range table.gPrime.Terminals() {
+		for _, a := range append(table.gPrime.Terminals(), "$") {
 			itemSet := table.lr0.GetValue(stateName)
 			var matchFound bool
 			var act lrAction
@@ -391,6 +392,12 @@ func (slr *slrTable) String() string {
 		String()
 }
 
+// States returns the names of all states in the table, with the initial state
+// first.
+func (slr *slrTable) States() []string {
+	return orderStates(slr.lr0.States(), slr.lr0.Start)
+}
+
 // Initial returns the starting state of the parser DFA.
 func (slr *slrTable) Initial() string {
 	return slr.lr0.Start
@@ -527,3 +534,12 @@ func (slr *slrTable) Action(i, a string) lrAction {
 
 	return act
 }
+
+// kernelItems returns the kernel items of the given state of the table's DFA.
+func (slr *slrTable) kernelItems(state string) []grammar.LR0Item {
+	var items []grammar.LR0Item
+	for _, item := range slr.lr0.GetValue(state) {
+		items = append(items, item)
+	}
+	return kernelOf(items, slr.gPrime.StartSymbol())
+}
@@ -0,0 +1,255 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/textfmt"
+)
+
+// File table.go contains parsing tables given as plain data. These are used
+// for giving the parser of a generated frontend as Go source code instead of as
+// an encoded binary.
+
+// LRTable is the ACTION and GOTO table of an LR parser given as plain data,
+// with states identified by their index. It can be retrieved from an LR parser
+// with LRTableOf and made back into one with LRParserFromTable.
+type LRTable struct {
+	// Initial is the index of the state the parser starts in.
+	Initial int
+
+	// Action is the ACTION table. Action[s][a] is the action to take in state
+	// s when the next terminal in the input is a ("$" for the end of input).
+	// Missing entries are errors.
+	Action []map[string]LRTableEntry
+
+	// Goto is the GOTO table. Goto[s][A] is the state to go to from state s
+	// after reducing to non-terminal A. Missing entries are errors.
+	Goto []map[string]int
+}
+
+// LRTableEntry is a single entry in the ACTION table of an LRTable. Create one
+// with Shift, Reduce, or Accept.
+type LRTableEntry struct {
+	act lrAction
+}
+
+// Shift returns an LRTableEntry that shifts the next token and goes to the
+// given state.
+func Shift(state int) LRTableEntry {
+	return LRTableEntry{act: lrAction{Type: lrShift, State: strconv.Itoa(state)}}
+}
+
+// Reduce returns an LRTableEntry that reduces the given production to
+// nonTerminal. An epsilon production is given by not giving any symbols for
+// production.
+func Reduce(nonTerminal string, production ...string) LRTableEntry {
+	return LRTableEntry{act: lrAction{Type: lrReduce, Symbol: nonTerminal, Production: grammar.Production(production)}}
+}
+
+// Accept returns an LRTableEntry that accepts the input.
+func Accept() LRTableEntry {
+	return LRTableEntry{act: lrAction{Type: lrAccept}}
+}
+
+// String returns the string representation of e.
+func (e LRTableEntry) String() string {
+	return e.act.String()
+}
+
+// GoString returns Go source code that gives e when evaluated in a package that
+// imports ictiobus/parse.
+func (e LRTableEntry) GoString() string {
+	switch e.act.Type {
+	case lrShift:
+		return fmt.Sprintf("parse.Shift(%s)", e.act.State)
+	case lrReduce:
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("parse.Reduce(%q", e.act.Symbol))
+		if !e.act.Production.Equal(grammar.Epsilon) {
+			for _, sym := range e.act.Production {
+				sb.WriteString(fmt.Sprintf(", %q", sym))
+			}
+		}
+		sb.WriteRune(')')
+		return sb.String()
+	case lrAccept:
+		return "parse.Accept()"
+	default:
+		return "parse.LRTableEntry{}"
+	}
+}
+
+// LRTableOf returns the ACTION and GOTO table of p, which must be an LR parser.
+// States are numbered in the same order they are shown in by p.TableString().
+func LRTableOf(p Parser) (LRTable, error) {
+	lr, ok := p.(*lrParser)
+	if !ok {
+		return LRTable{}, fmt.Errorf("not an LR parser")
+	}
+
+	return lrTableOf(lr.table, lr.tableGrammar()), nil
+}
+
+// LRTableSize returns the number of entries that the ACTION and GOTO table of
+// p, which must be an LR parser, has in full, as well as the number of values
+// actually stored for it by the compressed table that p uses.
+func LRTableSize(p Parser) (full int, compressed int, err error) {
+	lr, ok := p.(*lrParser)
+	if !ok {
+		return 0, 0, fmt.Errorf("not an LR parser")
+	}
+
+	ct, ok := lr.table.(*compressedLRTable)
+	if !ok {
+		ct = compressedFrom(lr.table, lr.tableGrammar())
+	}
+
+	return ct.fullSize(), ct.size(), nil
+}
+
+// LRStateKernels returns the kernel items of each state of p, which must be an
+// LR parser, given as their LR(0) cores. States are numbered the same as in the
+// LRTable that LRTableOf returns for p. If p does not have its DFA, such as when
+// it was created from an LRTable, the DFA is rebuilt from its grammar to find
+// them.
+func LRStateKernels(p Parser) ([][]grammar.LR0Item, error) {
+	lr, ok := p.(*lrParser)
+	if !ok {
+		return nil, fmt.Errorf("not an LR parser")
+	}
+
+	kernels, err := lr.allKernelItems()
+	if err != nil {
+		return nil, err
+	}
+
+	// the cached items must not be changed by the caller.
+	kernelsCopy := make([][]grammar.LR0Item, len(kernels))
+	for i := range kernels {
+		kernelsCopy[i] = make([]grammar.LR0Item, len(kernels[i]))
+		copy(kernelsCopy[i], kernels[i])
+	}
+	return kernelsCopy, nil
+}
+
+// lrTableOf gets all entries of table, which is for grammar g, as an LRTable.
+func lrTableOf(table lrParseTable, g grammar.CFG) LRTable {
+	states := table.States()
+	stateIdx := map[string]int{}
+	for i := range states {
+		stateIdx[states[i]] = i
+	}
+
+	terms := append(g.Terminals(), "$")
+	nonTerms := g.NonTerminals()
+
+	lrTable := LRTable{
+		Initial: stateIdx[table.Initial()],
+		Action:  make([]map[string]LRTableEntry, len(states)),
+		Goto:    make([]map[string]int, len(states)),
+	}
+
+	for i, s := range states {
+		lrTable.Action[i] = map[string]LRTableEntry{}
+		for _, a := range terms {
+			act := table.Action(s, a)
+			switch act.Type {
+			case lrError:
+				continue
+			case lrShift:
+				act.State = strconv.Itoa(stateIdx[act.State])
+			}
+			lrTable.Action[i][a] = LRTableEntry{act: act}
+		}
+
+		lrTable.Goto[i] = map[string]int{}
+		for _, A := range nonTerms {
+			t, err := table.Goto(s, A)
+			if err != nil {
+				continue
+			}
+			lrTable.Goto[i][A] = stateIdx[t]
+		}
+	}
+
+	return lrTable
+}
+
+// LRParserFromTable creates an LR parser of the given algorithm that parses
+// the language of g by using the given ACTION and GOTO table. No checks are
+// made that the table is actually the one the algorithm would produce for g,
+// but every state referred to in the table must exist in it and every symbol
+// must be in g. If g has entry points, the table must include the marker
+// terminals for them that are added by the parser generators, as one returned by
+// LRTableOf will.
+//
+// A parser created this way does not have its DFA available, so it will be
+// rebuilt from g if DFAString() is called.
+func LRParserFromTable(algo Algorithm, g grammar.CFG, table LRTable) (Parser, error) {
+	if algo != SLR1 && algo != LALR1 && algo != CLR1 {
+		return nil, fmt.Errorf("not an LR algorithm: %s", algo)
+	}
+	if len(table.Action) != len(table.Goto) {
+		return nil, fmt.Errorf("ACTION table has %d states but GOTO table has %d", len(table.Action), len(table.Goto))
+	}
+
+	// the table is for the grammar with entry points added, if it has any.
+	tg := entryGrammar(g)
+
+	numStates := len(table.Action)
+	if table.Initial < 0 || table.Initial >= numStates {
+		return nil, fmt.Errorf("initial state %d does not exist", table.Initial)
+	}
+
+	for s := 0; s < numStates; s++ {
+		for a, entry := range table.Action[s] {
+			if a != "$" && !tg.IsTerminal(a) {
+				return nil, fmt.Errorf("ACTION[%d, %q]: %q is not a terminal in the grammar", s, a, a)
+			}
+			if entry.act.Type == lrShift {
+				t, err := strconv.Atoi(entry.act.State)
+				if err != nil || t < 0 || t >= numStates {
+					return nil, fmt.Errorf("ACTION[%d, %q]: shift to state %s that does not exist", s, a, entry.act.State)
+				}
+			}
+		}
+
+		for A, t := range table.Goto[s] {
+			if !tg.IsNonTerminal(A) {
+				return nil, fmt.Errorf("GOTO[%d, %q]: %q is not a non-terminal in the grammar", s, A, A)
+			}
+			if t < 0 || t >= numStates {
+				return nil, fmt.Errorf("GOTO[%d, %q]: state %d does not exist", s, A, t)
+			}
+		}
+	}
+
+	ct := compressLRTable(table, tg.Terminals(), tg.NonTerminals())
+	return &lrParser{table: ct, parseType: algo, gram: g.Copy()}, nil
+}
+
+// LL1ParserFromTable creates an LL(1) parser that parses the language of g by
+// using the given prediction table, which is in the same form as is returned
+// by LL1Predictions. No checks are made that the table is actually the one
+// that would be produced for g, but every entry in it must be for a
+// non-terminal and terminal in g.
+func LL1ParserFromTable(g grammar.CFG, preds map[string]map[string]grammar.Production) (Parser, error) {
+	M := newLL1Table()
+
+	for _, A := range textfmt.OrderedKeys(preds) {
+		if !g.IsNonTerminal(A) {
+			return nil, fmt.Errorf("%q is not a non-terminal in the grammar", A)
+		}
+		for _, a := range textfmt.OrderedKeys(preds[A]) {
+			if a != "$" && !g.IsTerminal(a) {
+				return nil, fmt.Errorf("M[%q, %q]: %q is not a terminal in the grammar", A, a, a)
+			}
+			M.Set(A, a, preds[A][a])
+		}
+	}
+
+	return &ll1Parser{table: M, g: g.Copy()}, nil
+}
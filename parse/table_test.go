@@ -0,0 +1,294 @@
+package parse
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LRParserFromTable(t *testing.T) {
+	testCases := []struct {
+		name  string
+		ctor  func(grammar.CFG, bool) (Parser, []string, error)
+		g     string
+		input []string
+		ambig bool
+	}{
+		{
+			name: "SLR parser",
+			ctor: GenerateSLR1Parser,
+			g: `
+				E -> E + T | T ;
+				T -> T * F | F ;
+				F -> ( E ) | id ;
+			`,
+			input: []string{"id", "*", "id", "+", "id", "$"},
+		},
+		{
+			name: "LALR parser",
+			ctor: GenerateLALR1Parser,
+			g: `
+				S -> C C ;
+				C -> c C | d ;
+			`,
+			input: []string{"c", "d", "c", "c", "d", "$"},
+		},
+		{
+			name: "CLR parser with epsilon production",
+			ctor: GenerateCLR1Parser,
+			g: `
+				S -> a A | b B ;
+				A -> a A | ε   ;
+				B -> b B | ε   ;
+			`,
+			input: []string{"a", "a", "a", "$"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			g := grammar.MustParse(tc.g)
+
+			orig, _, err := tc.ctor(g, tc.ambig)
+			if !assert.NoError(err, "generating parser failed") {
+				return
+			}
+
+			// execute
+			table, err := LRTableOf(orig)
+			if !assert.NoError(err, "getting table failed") {
+				return
+			}
+			actual, err := LRParserFromTable(orig.Type(), g, table)
+
+			// assert
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(orig.Type(), actual.Type())
+			assert.Equal(orig.TableString(), actual.TableString())
+
+			expectTree, err := orig.Parse(mockTokens(tc.input...))
+			if !assert.NoError(err, "parsing with original parser failed") {
+				return
+			}
+			actualTree, err := actual.Parse(mockTokens(tc.input...))
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(expectTree.String(), actualTree.String())
+
+			// make sure it survives encoding as well
+			decoded, err := DecodeBytes(EncodeBytes(actual))
+			if !assert.NoError(err, "decoding failed") {
+				return
+			}
+			assert.Equal(orig.TableString(), decoded.TableString())
+		})
+	}
+}
+
+func Test_LRParserFromTable_badTable(t *testing.T) {
+	testCases := []struct {
+		name  string
+		algo  Algorithm
+		table LRTable
+	}{
+		{
+			name:  "not an LR algorithm",
+			algo:  LL1,
+			table: LRTable{Action: []map[string]LRTableEntry{{}}, Goto: []map[string]int{{}}},
+		},
+		{
+			name:  "missing initial state",
+			algo:  SLR1,
+			table: LRTable{Initial: 1, Action: []map[string]LRTableEntry{{}}, Goto: []map[string]int{{}}},
+		},
+		{
+			name:  "shift to missing state",
+			algo:  LALR1,
+			table: LRTable{Action: []map[string]LRTableEntry{{"a": Shift(2)}}, Goto: []map[string]int{{}}},
+		},
+		{
+			name:  "goto missing state",
+			algo:  CLR1,
+			table: LRTable{Action: []map[string]LRTableEntry{{}}, Goto: []map[string]int{{"S": 8}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := grammar.MustParse(`S -> a ;`)
+
+			_, err := LRParserFromTable(tc.algo, g, tc.table)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_LRStateKernels(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> C C ;
+		C -> c C | d ;
+	`)
+
+	testCases := []struct {
+		name      string
+		parser    func() (Parser, error)
+		expectErr bool
+	}{
+		{
+			name: "generated parser",
+			parser: func() (Parser, error) {
+				p, _, err := GenerateLALR1Parser(g, false)
+				return p, err
+			},
+		},
+		{
+			name: "parser from table",
+			parser: func() (Parser, error) {
+				p, _, err := GenerateLALR1Parser(g, false)
+				if err != nil {
+					return nil, err
+				}
+				table, err := LRTableOf(p)
+				if err != nil {
+					return nil, err
+				}
+				return LRParserFromTable(LALR1, g, table)
+			},
+		},
+		{
+			name:      "not an LR parser",
+			parser:    func() (Parser, error) { return GenerateLL1Parser(g) },
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			p, err := tc.parser()
+			if !assert.NoError(err, "generating parser failed") {
+				return
+			}
+
+			// execute
+			kernels, err := LRStateKernels(p)
+
+			// assert
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			table, err := LRTableOf(p)
+			if !assert.NoError(err) {
+				return
+			}
+			if !assert.Len(kernels, len(table.Action)) {
+				return
+			}
+
+			// an LR(0) core is listed only once even if the LR(1) items of the
+			// state have it with several lookaheads.
+			for s := range kernels {
+				seen := map[string]bool{}
+				for _, item := range kernels[s] {
+					assert.False(seen[item.String()], "state %d has kernel item %s more than once", s, item)
+					seen[item.String()] = true
+				}
+			}
+
+			// the initial state's kernel is the item for the augmented start
+			// rule, and every other state's kernel items have the dot right
+			// after the symbol that leads to the state.
+			if assert.Len(kernels[table.Initial], 1) {
+				assert.Empty(kernels[table.Initial][0].Left)
+				assert.Equal([]string{"S"}, kernels[table.Initial][0].Right)
+			}
+			for s := range table.Action {
+				for a, entry := range table.Action[s] {
+					if entry.act.Type != lrShift {
+						continue
+					}
+					to, _ := strconv.Atoi(entry.act.State)
+					for _, item := range kernels[to] {
+						assert.Equal(a, item.Left[len(item.Left)-1], "kernel item %s of state shifted to on %q", item, a)
+					}
+				}
+				for A, to := range table.Goto[s] {
+					for _, item := range kernels[to] {
+						assert.Equal(A, item.Left[len(item.Left)-1], "kernel item %s of state gone to on %q", item, A)
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_LL1ParserFromTable(t *testing.T) {
+	assert := assert.New(t)
+	g := grammar.MustParse(`
+		E  -> T E- ;
+		E- -> + T E- | ε ;
+		T  -> F T- ;
+		T- -> * F T- | ε ;
+		F  -> ( E ) | id ;
+	`)
+	input := []string{"id", "+", "id", "*", "id", "$"}
+
+	orig, err := GenerateLL1Parser(g)
+	if !assert.NoError(err, "generating parser failed") {
+		return
+	}
+	preds, err := LL1Predictions(orig)
+	if !assert.NoError(err, "getting predictions failed") {
+		return
+	}
+
+	// execute
+	actual, err := LL1ParserFromTable(g, preds)
+
+	// assert
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(orig.TableString(), actual.TableString())
+
+	expectTree, err := orig.Parse(mockTokens(input...))
+	if !assert.NoError(err, "parsing with original parser failed") {
+		return
+	}
+	actualTree, err := actual.Parse(mockTokens(input...))
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(expectTree.String(), actualTree.String())
+}
+
+func Test_LRTableEntry_GoString(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  LRTableEntry
+		expect string
+	}{
+		{name: "shift", input: Shift(3), expect: `parse.Shift(3)`},
+		{name: "reduce", input: Reduce("E", "E", "+", "T"), expect: `parse.Reduce("E", "E", "+", "T")`},
+		{name: "reduce epsilon", input: Reduce("A"), expect: `parse.Reduce("A")`},
+		{name: "accept", input: Accept(), expect: `parse.Accept()`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.input.GoString())
+		})
+	}
+}
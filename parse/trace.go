@@ -0,0 +1,227 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/internal/box"
+	"github.com/dekarrin/ictiobus/lex"
+)
+
+// TraceEventType is the kind of step that a parser took which is described by
+// a TraceEvent.
+type TraceEventType int
+
+const (
+	// TraceLookahead is emitted when the parser reads the next token of input
+	// to decide what to do next. The token is in the Lookahead field of the
+	// event.
+	TraceLookahead TraceEventType = iota
+
+	// TraceShift is emitted when an LR parser shifts the lookahead token onto
+	// its stack. The state that was pushed is in the Target field of the
+	// event.
+	TraceShift
+
+	// TraceReduce is emitted when an LR parser reduces the top of its stack to
+	// a non-terminal. The rule used is in the Symbol and Production fields of
+	// the event.
+	TraceReduce
+
+	// TraceGoto is emitted when an LR parser goes to a new state after a
+	// reduction. The state it went from is in the State field, the
+	// non-terminal that was reduced to is in the Symbol field, and the state
+	// it went to is in the Target field of the event.
+	TraceGoto
+
+	// TracePredict is emitted when an LL parser picks the production to use
+	// for the non-terminal it is parsing. The rule used is in the Symbol and
+	// Production fields of the event.
+	TracePredict
+
+	// TraceMatch is emitted when an LL parser matches the lookahead token to
+	// the terminal it expected. The terminal is in the Symbol field of the
+	// event.
+	TraceMatch
+
+	// TraceAccept is emitted when the parser accepts its input. It is the last
+	// event emitted by a successful parse.
+	TraceAccept
+
+	// TraceError is emitted when the parser encounters a syntax error. It is
+	// the last event emitted by a failed parse, and the error is in the Err
+	// field of the event.
+	TraceError
+)
+
+// String returns the name of the TraceEventType.
+func (tet TraceEventType) String() string {
+	switch tet {
+	case TraceLookahead:
+		return "lookahead"
+	case TraceShift:
+		return "shift"
+	case TraceReduce:
+		return "reduce"
+	case TraceGoto:
+		return "goto"
+	case TracePredict:
+		return "predict"
+	case TraceMatch:
+		return "match"
+	case TraceAccept:
+		return "accept"
+	case TraceError:
+		return "error"
+	default:
+		return fmt.Sprintf("TraceEventType(%d)", int(tet))
+	}
+}
+
+// TraceEvent is a single step taken by a parser. Trace events are given to
+// the listener set with RegisterTraceEventListener of a TraceEventSource in the
+// order that the parser takes the steps they describe.
+type TraceEvent struct {
+	// Type is the kind of step that the event describes. It determines which
+	// of the other fields are set.
+	Type TraceEventType
+
+	// Lookahead is the next token of input at the time of the event.
+	Lookahead lex.Token
+
+	// State is the state that an LR parser was in at the time of the event. It
+	// is empty for LL parsers.
+	State string
+
+	// Target is the state that an LR parser moved to for a TraceShift or a
+	// TraceGoto event.
+	Target string
+
+	// Symbol is the non-terminal for TraceReduce, TraceGoto, and TracePredict
+	// events, and the terminal for TraceMatch events.
+	Symbol string
+
+	// Production is the production of Symbol that was used for a TraceReduce
+	// or TracePredict event. An epsilon production is given as
+	// grammar.Epsilon.
+	Production grammar.Production
+
+	// Stack is a snapshot of the parser's stack after the step was taken,
+	// listed from bottom to top. For LR parsers, this is the stack of states.
+	// For table-driven LL parsers, this is the stack of symbols yet to be
+	// parsed. For recursive-descent parsers, this is the non-terminals whose
+	// functions have been entered but not yet returned from.
+	Stack []string
+
+	// Err is the error that the parser encountered for a TraceError event.
+	Err error
+}
+
+// String returns a human-readable one-line description of the event. This is
+// what is given to listeners set with RegisterTraceListener.
+func (ev TraceEvent) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(ev.Type.String())
+	switch ev.Type {
+	case TraceLookahead:
+		sb.WriteRune(' ')
+		sb.WriteString(traceTokenString(ev.Lookahead))
+	case TraceShift:
+		sb.WriteRune(' ')
+		sb.WriteString(traceTokenString(ev.Lookahead))
+		sb.WriteString(", go to state ")
+		sb.WriteString(ev.Target)
+	case TraceReduce, TracePredict:
+		sb.WriteRune(' ')
+		sb.WriteString(ev.Symbol)
+		sb.WriteString(" -> ")
+		sb.WriteString(ev.Production.String())
+	case TraceGoto:
+		sb.WriteString(fmt.Sprintf(" state %s on %s", ev.Target, ev.Symbol))
+	case TraceMatch:
+		sb.WriteRune(' ')
+		sb.WriteString(traceTokenString(ev.Lookahead))
+	case TraceError:
+		if ev.Err != nil {
+			sb.WriteString(": ")
+			sb.WriteString(ev.Err.Error())
+		}
+	}
+
+	if ev.State != "" {
+		sb.WriteString("; state ")
+		sb.WriteString(ev.State)
+	}
+	if ev.Stack != nil {
+		sb.WriteString("; stack [")
+		sb.WriteString(strings.Join(ev.Stack, " "))
+		sb.WriteRune(']')
+	}
+
+	return sb.String()
+}
+
+// traceTokenString returns the token as it is shown in trace event strings.
+func traceTokenString(tok lex.Token) string {
+	if tok == nil {
+		return "(none)"
+	}
+	return fmt.Sprintf("%s %q", tok.Class().ID(), tok.Lexeme())
+}
+
+// tracer holds the trace listeners registered with a parser and sends events
+// to them. It is safe for concurrent use; listeners may be set while parses
+// that use it are running in other goroutines.
+type tracer struct {
+	mu     sync.RWMutex
+	events func(TraceEvent)
+	msgs   func(string)
+}
+
+// setEvents sets the listener that is given each TraceEvent.
+func (tr *tracer) setEvents(listener func(TraceEvent)) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.events = listener
+}
+
+// setMsgs sets the listener that is given the String() of each TraceEvent.
+func (tr *tracer) setMsgs(listener func(string)) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.msgs = listener
+}
+
+// notify sends the event returned by fn to the listeners of tr. fn is only
+// called if there is at least one listener, so events that are expensive to
+// build cost nothing when tracing is not in use.
+func (tr *tracer) notify(fn func() TraceEvent) {
+	tr.mu.RLock()
+	events, msgs := tr.events, tr.msgs
+	tr.mu.RUnlock()
+
+	if events == nil && msgs == nil {
+		return
+	}
+
+	ev := fn()
+	if events != nil {
+		events(ev)
+	}
+	if msgs != nil {
+		msgs(ev.String())
+	}
+}
+
+// stackSnapshot returns the elements of st from bottom to top.
+func stackSnapshot(st *box.Stack[string]) []string {
+	elems := st.Elements()
+	snap := make([]string, len(elems))
+	for i := range elems {
+		snap[len(elems)-1-i] = elems[i]
+	}
+	return snap
+}
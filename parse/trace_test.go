@@ -0,0 +1,240 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TraceEvents(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> A b ;
+		A -> a | ε ;
+	`)
+
+	type step struct {
+		Type       TraceEventType
+		Symbol     string
+		Production grammar.Production
+		Lookahead  string
+	}
+
+	lrSteps := []step{
+		{Type: TraceLookahead, Lookahead: "a"},
+		{Type: TraceShift, Lookahead: "a"},
+		{Type: TraceLookahead, Lookahead: "b"},
+		{Type: TraceReduce, Symbol: "A", Production: grammar.Production{"a"}, Lookahead: "b"},
+		{Type: TraceGoto, Symbol: "A", Lookahead: "b"},
+		{Type: TraceShift, Lookahead: "b"},
+		{Type: TraceLookahead, Lookahead: "$"},
+		{Type: TraceReduce, Symbol: "S", Production: grammar.Production{"A", "b"}, Lookahead: "$"},
+		{Type: TraceGoto, Symbol: "S", Lookahead: "$"},
+		{Type: TraceAccept, Lookahead: "$"},
+	}
+
+	testCases := []struct {
+		name        string
+		input       []string
+		expectLR    []step
+		expectLL    []step
+		expectStack [][]string
+	}{
+		{
+			name:     "accepted input",
+			input:    []string{"a", "b", "$"},
+			expectLR: lrSteps,
+			expectLL: []step{
+				{Type: TraceLookahead, Lookahead: "a"},
+				{Type: TracePredict, Symbol: "S", Production: grammar.Production{"A", "b"}, Lookahead: "a"},
+				{Type: TracePredict, Symbol: "A", Production: grammar.Production{"a"}, Lookahead: "a"},
+				{Type: TraceMatch, Symbol: "a", Lookahead: "a"},
+				{Type: TraceLookahead, Lookahead: "b"},
+				{Type: TraceMatch, Symbol: "b", Lookahead: "b"},
+				{Type: TraceLookahead, Lookahead: "$"},
+				{Type: TraceAccept, Lookahead: "$"},
+			},
+			expectStack: [][]string{
+				{"$", "S"},
+				{"$", "b", "A"},
+				{"$", "b", "a"},
+				{"$", "b"},
+				{"$", "b"},
+				{"$"},
+				{"$"},
+				{"$"},
+			},
+		},
+		{
+			name:  "epsilon production",
+			input: []string{"b", "$"},
+			expectLR: []step{
+				{Type: TraceLookahead, Lookahead: "b"},
+				{Type: TraceReduce, Symbol: "A", Production: grammar.Epsilon, Lookahead: "b"},
+				{Type: TraceGoto, Symbol: "A", Lookahead: "b"},
+				{Type: TraceShift, Lookahead: "b"},
+				{Type: TraceLookahead, Lookahead: "$"},
+				{Type: TraceReduce, Symbol: "S", Production: grammar.Production{"A", "b"}, Lookahead: "$"},
+				{Type: TraceGoto, Symbol: "S", Lookahead: "$"},
+				{Type: TraceAccept, Lookahead: "$"},
+			},
+			expectLL: []step{
+				{Type: TraceLookahead, Lookahead: "b"},
+				{Type: TracePredict, Symbol: "S", Production: grammar.Production{"A", "b"}, Lookahead: "b"},
+				{Type: TracePredict, Symbol: "A", Production: grammar.Epsilon, Lookahead: "b"},
+				{Type: TraceMatch, Symbol: "b", Lookahead: "b"},
+				{Type: TraceLookahead, Lookahead: "$"},
+				{Type: TraceAccept, Lookahead: "$"},
+			},
+		},
+		{
+			name:  "syntax error",
+			input: []string{"a", "a", "$"},
+			expectLR: []step{
+				{Type: TraceLookahead, Lookahead: "a"},
+				{Type: TraceShift, Lookahead: "a"},
+				{Type: TraceLookahead, Lookahead: "a"},
+				{Type: TraceError, Lookahead: "a"},
+			},
+			expectLL: []step{
+				{Type: TraceLookahead, Lookahead: "a"},
+				{Type: TracePredict, Symbol: "S", Production: grammar.Production{"A", "b"}, Lookahead: "a"},
+				{Type: TracePredict, Symbol: "A", Production: grammar.Production{"a"}, Lookahead: "a"},
+				{Type: TraceMatch, Symbol: "a", Lookahead: "a"},
+				{Type: TraceLookahead, Lookahead: "a"},
+				{Type: TraceError, Lookahead: "a"},
+			},
+		},
+	}
+
+	parsers := map[string]func() (Parser, error){
+		"LL(1)": func() (Parser, error) { return GenerateLL1Parser(g) },
+		"SLR(1)": func() (Parser, error) {
+			p, _, err := GenerateSLR1Parser(g, false)
+			return p, err
+		},
+		"LALR(1)": func() (Parser, error) {
+			p, _, err := GenerateLALR1Parser(g, false)
+			return p, err
+		},
+		"CLR(1)": func() (Parser, error) {
+			p, _, err := GenerateCLR1Parser(g, false)
+			return p, err
+		},
+	}
+
+	for pName, ctor := range parsers {
+		for _, tc := range testCases {
+			t.Run(pName+" "+tc.name, func(t *testing.T) {
+				assert := assert.New(t)
+
+				p, err := ctor()
+				if !assert.NoError(err, "generating parser failed") {
+					return
+				}
+
+				src, ok := p.(TraceEventSource)
+				if !assert.True(ok, "parser is not a TraceEventSource") {
+					return
+				}
+
+				var events []TraceEvent
+				var msgs []string
+				src.RegisterTraceEventListener(func(ev TraceEvent) {
+					events = append(events, ev)
+				})
+				p.RegisterTraceListener(func(s string) {
+					msgs = append(msgs, s)
+				})
+
+				// execute
+				_, parseErr := p.Parse(mockTokens(tc.input...))
+
+				// assert
+				var actual []step
+				for _, ev := range events {
+					actual = append(actual, step{
+						Type:       ev.Type,
+						Symbol:     ev.Symbol,
+						Production: ev.Production,
+						Lookahead:  ev.Lookahead.Class().ID(),
+					})
+				}
+
+				expect := tc.expectLR
+				if p.Type() == LL1 {
+					expect = tc.expectLL
+				}
+				assert.Equal(expect, actual)
+
+				if assert.Len(msgs, len(events), "string listener did not get one message per event") {
+					for i := range events {
+						assert.Equal(events[i].String(), msgs[i])
+					}
+				}
+
+				if parseErr != nil {
+					last := events[len(events)-1]
+					assert.Equal(parseErr, last.Err, "error event does not have parse error")
+				}
+
+				if p.Type() == LL1 && tc.expectStack != nil {
+					var actualStacks [][]string
+					for _, ev := range events {
+						actualStacks = append(actualStacks, ev.Stack)
+					}
+					assert.Equal(tc.expectStack, actualStacks)
+				} else if p.Type() != LL1 {
+					for _, ev := range events {
+						if !assert.NotEmpty(ev.Stack, "LR event has empty stack") {
+							break
+						}
+						if ev.Type == TraceShift || ev.Type == TraceGoto {
+							assert.Equal(ev.Target, ev.Stack[len(ev.Stack)-1], "%s target is not on top of stack", ev.Type)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func Test_TraceEvent_String(t *testing.T) {
+	testCases := []struct {
+		name   string
+		ev     TraceEvent
+		expect string
+	}{
+		{
+			name:   "shift",
+			ev:     TraceEvent{Type: TraceShift, Lookahead: mockTokens("int", "$").Next(), State: "0", Target: "3", Stack: []string{"0", "3"}},
+			expect: `shift int "int", go to state 3; state 0; stack [0 3]`,
+		},
+		{
+			name:   "reduce epsilon",
+			ev:     TraceEvent{Type: TraceReduce, Symbol: "A", Production: grammar.Epsilon, State: "2", Stack: []string{"0"}},
+			expect: "reduce A -> ε; state 2; stack [0]",
+		},
+		{
+			name:   "goto",
+			ev:     TraceEvent{Type: TraceGoto, Symbol: "A", State: "0", Target: "1", Stack: []string{"0", "1"}},
+			expect: "goto state 1 on A; state 0; stack [0 1]",
+		},
+		{
+			name:   "predict",
+			ev:     TraceEvent{Type: TracePredict, Symbol: "S", Production: grammar.Production{"A", "b"}, Stack: []string{"$", "b", "A"}},
+			expect: "predict S -> A b; stack [$ b A]",
+		},
+		{
+			name:   "accept",
+			ev:     TraceEvent{Type: TraceAccept},
+			expect: "accept",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.ev.String())
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package traverse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dekarrin/ictiobus/parse"
+)
+
+// Match is a node found by a Query.
+type Match struct {
+	// Node is the node that matched.
+	Node *parse.Tree
+
+	// Path is the path to Node from the root of the tree that was searched.
+	Path []int
+}
+
+// Query is a compiled pattern for selecting nodes of a tree. Create one with
+// Compile.
+type Query struct {
+	steps []queryStep
+}
+
+// queryStep is one node pattern in a Query along with how the node it matches
+// must be related to the node matched by the step before it.
+type queryStep struct {
+	// child is whether the node must be a child of the node matched by the
+	// previous step; if false, it only needs to be a descendant of it.
+	child bool
+
+	// symbol is the symbol to match.
+	symbol string
+
+	// wildcard is whether any symbol is matched, ignoring symbol.
+	wildcard bool
+
+	// kind is the kind of node to match; one of kindAny, kindTerm, or
+	// kindNonTerm.
+	kind int
+}
+
+const (
+	kindAny = iota
+	kindTerm
+	kindNonTerm
+)
+
+func (step queryStep) matches(node *parse.Tree) bool {
+	if step.kind == kindTerm && !node.Terminal {
+		return false
+	}
+	if step.kind == kindNonTerm && node.Terminal {
+		return false
+	}
+	return step.wildcard || step.symbol == node.Value
+}
+
+func (step queryStep) String() string {
+	sym := step.symbol
+	if step.wildcard {
+		sym = "*"
+	} else if needsQuote(sym) {
+		sym = strconv.Quote(sym)
+	}
+
+	switch step.kind {
+	case kindTerm:
+		return "(" + sym + ")"
+	case kindNonTerm:
+		return "[" + sym + "]"
+	default:
+		return sym
+	}
+}
+
+// MustCompile is the same as Compile but panics if any error occurs.
+func MustCompile(query string) *Query {
+	q, err := Compile(query)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// Compile parses a query that selects nodes of a tree.
+//
+// A query is a series of node patterns. A node pattern is a symbol, which
+// matches any node with that symbol. The symbol may be enclosed in parentheses
+// to only match terminal nodes with it, or in square brackets to only match
+// non-terminal nodes with it, the same as in the diagrams read by
+// [parse.ParseTreeFromDiagram]. The symbol "*" matches any symbol. A symbol
+// that contains whitespace or any of the characters ()[]>" must be given as a
+// double-quoted string.
+//
+// Node patterns separated by ">" must match a node and its child, and node
+// patterns separated by only whitespace must match a node and any of its
+// descendants. The nodes selected by the query are the ones matched by the
+// last node pattern. For example, "expr > term > (int)" selects every int
+// terminal that is a child of a term node that is itself a child of an expr
+// node, and "stmt *" selects every node under a stmt node.
+func Compile(query string) (*Query, error) {
+	toks, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{}
+	pendingChild := false
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+
+		if tok.val == ">" && !tok.quoted {
+			if len(q.steps) == 0 {
+				return nil, fmt.Errorf("query cannot start with '>'")
+			}
+			if pendingChild {
+				return nil, fmt.Errorf("query has '>' twice in a row")
+			}
+			pendingChild = true
+			continue
+		}
+
+		step := queryStep{child: pendingChild, kind: kindAny}
+		pendingChild = false
+
+		if !tok.quoted && (tok.val == "(" || tok.val == "[") {
+			closer := ")"
+			step.kind = kindTerm
+			if tok.val == "[" {
+				closer = "]"
+				step.kind = kindNonTerm
+			}
+
+			if i+2 >= len(toks) || toks[i+2].quoted || toks[i+2].val != closer {
+				return nil, fmt.Errorf("unclosed %q in query", tok.val)
+			}
+			tok = toks[i+1]
+			i += 2
+		}
+
+		if !tok.quoted && strings.ContainsAny(tok.val, "()[]>") {
+			return nil, fmt.Errorf("unexpected %q in query", tok.val)
+		}
+		step.symbol = tok.val
+		step.wildcard = !tok.quoted && tok.val == "*"
+		q.steps = append(q.steps, step)
+	}
+
+	if pendingChild {
+		return nil, fmt.Errorf("query cannot end with '>'")
+	}
+	if len(q.steps) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	return q, nil
+}
+
+// Find compiles query and returns all nodes in the tree rooted at root that it
+// selects. See Compile for the syntax of query.
+func Find(root *parse.Tree, query string) ([]Match, error) {
+	q, err := Compile(query)
+	if err != nil {
+		return nil, err
+	}
+	return q.Find(root), nil
+}
+
+// Find returns all nodes in the tree rooted at root that q selects, in
+// pre-order.
+func (q *Query) Find(root *parse.Tree) []Match {
+	var matches []Match
+	var ancestors []*parse.Tree
+
+	Walk(root, func(node *parse.Tree, path []int) Action {
+		if q.matchesAt(len(q.steps)-1, node, ancestors) {
+			matches = append(matches, Match{Node: node, Path: path})
+		}
+		ancestors = append(ancestors, node)
+		return Continue
+	}, func(node *parse.Tree, path []int) Action {
+		ancestors = ancestors[:len(ancestors)-1]
+		return Continue
+	})
+
+	return matches
+}
+
+// matchesAt returns whether node matches step i of q, and the steps before it
+// are matched by the given ancestors of node, which go from the root to
+// node's parent.
+func (q *Query) matchesAt(i int, node *parse.Tree, ancestors []*parse.Tree) bool {
+	step := q.steps[i]
+	if !step.matches(node) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if len(ancestors) == 0 {
+		return false
+	}
+
+	parent := ancestors[len(ancestors)-1]
+	if step.child {
+		return q.matchesAt(i-1, parent, ancestors[:len(ancestors)-1])
+	}
+
+	for j := len(ancestors) - 1; j >= 0; j-- {
+		if q.matchesAt(i-1, ancestors[j], ancestors[:j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the query in normalized form.
+func (q *Query) String() string {
+	var sb strings.Builder
+	for i, step := range q.steps {
+		if i > 0 {
+			if step.child {
+				sb.WriteString(" > ")
+			} else {
+				sb.WriteRune(' ')
+			}
+		}
+		sb.WriteString(step.String())
+	}
+	return sb.String()
+}
+
+// queryToken is a single token of a query.
+type queryToken struct {
+	val    string
+	quoted bool
+}
+
+// lexQuery splits query into its tokens.
+func lexQuery(query string) ([]queryToken, error) {
+	var toks []queryToken
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case unicode.IsSpace(ch):
+			continue
+		case strings.ContainsRune("()[]>", ch):
+			toks = append(toks, queryToken{val: string(ch)})
+		case ch == '"':
+			start := i
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in query")
+			}
+			val, err := strconv.Unquote(string(runes[start : i+1]))
+			if err != nil {
+				return nil, fmt.Errorf("bad string %s in query: %w", string(runes[start:i+1]), err)
+			}
+			toks = append(toks, queryToken{val: val, quoted: true})
+		default:
+			start := i
+			for i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && !strings.ContainsRune("()[]>\"", runes[i+1]) {
+				i++
+			}
+			toks = append(toks, queryToken{val: string(runes[start : i+1])})
+		}
+	}
+
+	return toks, nil
+}
+
+func needsQuote(sym string) bool {
+	if sym == "" || sym == "*" {
+		return true
+	}
+	for _, ch := range sym {
+		if unicode.IsSpace(ch) || strings.ContainsRune("()[]>\"", ch) {
+			return true
+		}
+	}
+	return false
+}
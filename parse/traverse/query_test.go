@@ -0,0 +1,123 @@
+package traverse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Find(t *testing.T) {
+	// tree for "1 + 2 * 3"
+	tree := `[expr
+		[expr [term (int)]]
+		(+)
+		[term
+			[term (int)]
+			(*)
+			(int)
+		]
+	]`
+
+	testCases := []struct {
+		name   string
+		query  string
+		expect [][]int
+	}{
+		{
+			name:   "single symbol",
+			query:  "term",
+			expect: [][]int{{0, 0}, {2}, {2, 0}},
+		},
+		{
+			name:   "terminal only",
+			query:  "(int)",
+			expect: [][]int{{0, 0, 0}, {2, 0, 0}, {2, 2}},
+		},
+		{
+			name:   "non-terminal only does not match terminal",
+			query:  "[int]",
+			expect: nil,
+		},
+		{
+			name:   "child chain",
+			query:  "expr > term > (int)",
+			expect: [][]int{{0, 0, 0}, {2, 2}},
+		},
+		{
+			name:   "descendant",
+			query:  "expr > term (int)",
+			expect: [][]int{{0, 0, 0}, {2, 0, 0}, {2, 2}},
+		},
+		{
+			name:   "wildcard child",
+			query:  "[term] > *",
+			expect: [][]int{{0, 0, 0}, {2, 0}, {2, 0, 0}, {2, 1}, {2, 2}},
+		},
+		{
+			name:   "quoted symbol",
+			query:  `expr > ("+")`,
+			expect: [][]int{{1}},
+		},
+		{
+			name:   "root only matched by first step",
+			query:  "expr > expr",
+			expect: [][]int{{0}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			pt := parse.MustParseTreeFromDiagram(tree)
+
+			actual, err := Find(pt, tc.query)
+			if !assert.NoError(err) {
+				return
+			}
+
+			var actualPaths [][]int
+			for _, m := range actual {
+				actualPaths = append(actualPaths, m.Path)
+				assert.Same(follow(pt, m.Path), m.Node)
+			}
+			assert.Equal(tc.expect, actualPaths)
+		})
+	}
+}
+
+func Test_Compile(t *testing.T) {
+	testCases := []struct {
+		name      string
+		query     string
+		expect    string
+		expectErr bool
+	}{
+		{name: "symbols", query: "expr   term>  (int)", expect: "expr term > (int)"},
+		{name: "quoted", query: `[ "a b" ] > (">")`, expect: `["a b"] > (">")`},
+		{name: "quoted star is not wildcard", query: `("*") *`, expect: `("*") *`},
+		{name: "empty", query: "  ", expectErr: true},
+		{name: "leading >", query: "> a", expectErr: true},
+		{name: "trailing >", query: "a >", expectErr: true},
+		{name: "double >", query: "a > > b", expectErr: true},
+		{name: "unclosed paren", query: "(a", expectErr: true},
+		{name: "mismatched brackets", query: "(a]", expectErr: true},
+		{name: "unterminated string", query: `("a)`, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := Compile(tc.query)
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual.String())
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package traverse provides functions for walking, rewriting, and searching
+// [parse.Tree] values without hand-recursing over their children.
+//
+// Nodes are identified by their path from the root of the tree they are in,
+// given as a slice of the indexes of the children to follow to reach them, the
+// same as is accepted by [parse.Tree.Follow] and returned by
+// [parse.Tree.PathToDiff]. The root of a tree has an empty path.
+//
+// The query language accepted by [Compile] and [Find] selects nodes by the
+// symbols of themselves and of their ancestors; see [Compile] for its syntax.
+package traverse
+
+import (
+	"github.com/dekarrin/ictiobus/parse"
+)
+
+// Action is returned by a VisitFunc to tell the walker what to do next.
+type Action int
+
+const (
+	// Continue continues the walk as normal.
+	Continue Action = iota
+
+	// SkipChildren skips the children of the node just visited. It only has
+	// an effect when returned from a function called on a node before its
+	// children are walked; otherwise, it is the same as Continue.
+	SkipChildren
+
+	// Stop ends the walk immediately. No more nodes will be visited.
+	Stop
+)
+
+// String returns the string representation of a.
+func (a Action) String() string {
+	switch a {
+	case Continue:
+		return "Continue"
+	case SkipChildren:
+		return "SkipChildren"
+	case Stop:
+		return "Stop"
+	default:
+		return "Action(unknown)"
+	}
+}
+
+// VisitFunc is called on each node of a tree that is walked. It receives the
+// node and the path to it from the root of the tree. The path is a fresh slice
+// on every call and may be retained.
+type VisitFunc func(node *parse.Tree, path []int) Action
+
+// PreOrder walks the tree rooted at root in pre-order, calling visit on every
+// node before any of its children. If visit returns SkipChildren, the children
+// of the node are not walked. If it returns Stop, the walk ends.
+func PreOrder(root *parse.Tree, visit VisitFunc) {
+	Walk(root, visit, nil)
+}
+
+// PostOrder walks the tree rooted at root in post-order, calling visit on every
+// node after all of its children. If visit returns Stop, the walk ends. As the
+// children of a node have already been walked by the time visit is called on
+// it, use Walk with an enter function to skip subtrees in post-order.
+func PostOrder(root *parse.Tree, visit VisitFunc) {
+	Walk(root, nil, visit)
+}
+
+// Walk walks the tree rooted at root depth-first. The function enter is called
+// on each node before its children are walked, and leave is called on each node
+// after. Either may be nil. If enter returns SkipChildren, the children of the
+// node are not walked, but leave is still called on it. If either returns Stop,
+// the walk ends.
+func Walk(root *parse.Tree, enter, leave VisitFunc) {
+	if root == nil {
+		return
+	}
+	walk(root, nil, enter, leave)
+}
+
+// walk does the actual walking for Walk. Returns false if the walk has been
+// stopped.
+func walk(node *parse.Tree, path []int, enter, leave VisitFunc) bool {
+	act := Continue
+	if enter != nil {
+		act = enter(node, copyPath(path))
+		if act == Stop {
+			return false
+		}
+	}
+
+	if act != SkipChildren {
+		for i := range node.Children {
+			if node.Children[i] == nil {
+				continue
+			}
+			if !walk(node.Children[i], append(path, i), enter, leave) {
+				return false
+			}
+		}
+	}
+
+	if leave != nil {
+		if leave(node, copyPath(path)) == Stop {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RewriteFunc is called on each node of a tree being rewritten to get the node
+// that should replace it. It receives the node, whose children have already
+// been rewritten, and the path to it in the original tree. It may return node
+// itself (modified or not) to keep it, a different node to replace it with, or
+// nil to remove it from its parent's children.
+type RewriteFunc func(node *parse.Tree, path []int) *parse.Tree
+
+// Rewrite creates a new tree by calling rewrite on every node of a copy of the
+// tree rooted at root, in post-order, and replacing each node with the one that
+// rewrite returns. The tree rooted at root is not modified. The new root is
+// returned; it will be nil if rewrite removes the root.
+//
+// Because nodes are rewritten from the bottom up, the paths given to rewrite
+// are those in the original tree; they will not be correct in the new tree if
+// any nodes that come before them were removed.
+func Rewrite(root *parse.Tree, rewrite RewriteFunc) *parse.Tree {
+	if root == nil {
+		return nil
+	}
+
+	newRoot := root.Copy()
+	return rewriteNode(&newRoot, nil, rewrite)
+}
+
+func rewriteNode(node *parse.Tree, path []int, rewrite RewriteFunc) *parse.Tree {
+	var newChildren []*parse.Tree
+	for i := range node.Children {
+		if node.Children[i] == nil {
+			continue
+		}
+		replacement := rewriteNode(node.Children[i], append(path, i), rewrite)
+		if replacement != nil {
+			newChildren = append(newChildren, replacement)
+		}
+	}
+	node.Children = newChildren
+
+	return rewrite(node, copyPath(path))
+}
+
+func copyPath(path []int) []int {
+	p := make([]int, len(path))
+	copy(p, path)
+	return p
+}
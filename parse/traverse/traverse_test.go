@@ -0,0 +1,208 @@
+package traverse
+
+import (
+	"testing"
+
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PreOrder(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tree   string
+		skip   string
+		stop   string
+		expect []string
+	}{
+		{
+			name:   "full walk",
+			tree:   "[S [A (a) (b)] [B (c)]]",
+			expect: []string{"S", "A", "a", "b", "B", "c"},
+		},
+		{
+			name:   "skip subtree",
+			tree:   "[S [A (a) (b)] [B (c)]]",
+			skip:   "A",
+			expect: []string{"S", "A", "B", "c"},
+		},
+		{
+			name:   "stop",
+			tree:   "[S [A (a) (b)] [B (c)]]",
+			stop:   "b",
+			expect: []string{"S", "A", "a", "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			pt := parse.MustParseTreeFromDiagram(tc.tree)
+
+			var actual []string
+			PreOrder(pt, func(node *parse.Tree, path []int) Action {
+				actual = append(actual, node.Value)
+				assert.Same(follow(pt, path), node)
+
+				if node.Value == tc.skip {
+					return SkipChildren
+				}
+				if node.Value == tc.stop {
+					return Stop
+				}
+				return Continue
+			})
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_PostOrder(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tree   string
+		stop   string
+		expect []string
+	}{
+		{
+			name:   "full walk",
+			tree:   "[S [A (a) (b)] [B (c)]]",
+			expect: []string{"a", "b", "A", "c", "B", "S"},
+		},
+		{
+			name:   "stop",
+			tree:   "[S [A (a) (b)] [B (c)]]",
+			stop:   "A",
+			expect: []string{"a", "b", "A"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			pt := parse.MustParseTreeFromDiagram(tc.tree)
+
+			var actual []string
+			PostOrder(pt, func(node *parse.Tree, path []int) Action {
+				actual = append(actual, node.Value)
+				assert.Same(follow(pt, path), node)
+
+				if node.Value == tc.stop {
+					return Stop
+				}
+				return Continue
+			})
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_Walk_SkipChildren(t *testing.T) {
+	assert := assert.New(t)
+	pt := parse.MustParseTreeFromDiagram("[S [A (a) (b)] [B (c)]]")
+
+	var actual []string
+	Walk(pt, func(node *parse.Tree, path []int) Action {
+		if node.Value == "A" {
+			return SkipChildren
+		}
+		return Continue
+	}, func(node *parse.Tree, path []int) Action {
+		actual = append(actual, node.Value)
+		return Continue
+	})
+
+	assert.Equal([]string{"A", "c", "B", "S"}, actual)
+}
+
+func Test_Rewrite(t *testing.T) {
+	testCases := []struct {
+		name    string
+		tree    string
+		rewrite RewriteFunc
+		expect  string
+		nilRoot bool
+	}{
+		{
+			name: "no changes",
+			tree: "[S [A (a) (b)] [B (c)]]",
+			rewrite: func(node *parse.Tree, path []int) *parse.Tree {
+				return node
+			},
+			expect: "[S [A (a) (b)] [B (c)]]",
+		},
+		{
+			name: "replace terminals",
+			tree: "[S [A (a) (b)] [B (c)]]",
+			rewrite: func(node *parse.Tree, path []int) *parse.Tree {
+				if node.Terminal {
+					return parse.Leaf("x")
+				}
+				return node
+			},
+			expect: "[S [A (x) (x)] [B (x)]]",
+		},
+		{
+			name: "remove nodes",
+			tree: "[S [A (a) (b)] [B (c)]]",
+			rewrite: func(node *parse.Tree, path []int) *parse.Tree {
+				if node.Value == "a" || node.Value == "B" {
+					return nil
+				}
+				return node
+			},
+			expect: "[S [A (b)]]",
+		},
+		{
+			name: "collapse single-child non-terminals",
+			tree: "[S [A [C (a)]] [B (c) (d)]]",
+			rewrite: func(node *parse.Tree, path []int) *parse.Tree {
+				if len(path) > 0 && !node.Terminal && len(node.Children) == 1 {
+					return node.Children[0]
+				}
+				return node
+			},
+			expect: "[S (a) [B (c) (d)]]",
+		},
+		{
+			name: "remove root",
+			tree: "[S (a)]",
+			rewrite: func(node *parse.Tree, path []int) *parse.Tree {
+				if len(path) == 0 {
+					return nil
+				}
+				return node
+			},
+			nilRoot: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			pt := parse.MustParseTreeFromDiagram(tc.tree)
+			orig := pt.Copy()
+
+			actual := Rewrite(pt, tc.rewrite)
+
+			assert.True(orig.Equal(pt), "original tree was modified")
+			if tc.nilRoot {
+				assert.Nil(actual)
+				return
+			}
+			expect := parse.MustParseTreeFromDiagram(tc.expect)
+			assert.True(expect.Equal(actual), "expected:\n%s\nactual:\n%s", expect.String(), actual.String())
+		})
+	}
+}
+
+// follow is the same as pt.Follow(path), but returns pt itself instead of a
+// copy of it when path is empty.
+func follow(pt *parse.Tree, path []int) *parse.Tree {
+	if len(path) == 0 {
+		return pt
+	}
+	return pt.Follow(path)
+}
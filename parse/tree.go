@@ -47,6 +47,13 @@ type Tree struct {
 	// Source is only available when Terminal is true.
 	Source lex.Token
 
+	// Span is the range of source text that the node was parsed from. For a
+	// terminal it is the span of Source; for a non-terminal it runs from the
+	// start of its first token to the end of its last. Epsilon terminals and
+	// non-terminals that derive only epsilon have an empty span located where
+	// the next token in the input starts.
+	Span lex.Span
+
 	// Children is all children of the parse tree.
 	Children []*Tree
 }
@@ -239,6 +246,7 @@ func Leaf(term string, t ...lex.Token) *Tree {
 	pt := &Tree{Terminal: true, Value: term}
 	if len(t) > 0 {
 		pt.Source = t[0]
+		pt.Span = lex.SpanOf(t[0])
 	}
 	return pt
 }
@@ -270,6 +278,29 @@ func (pt Tree) Follow(path []int) *Tree {
 	return cur
 }
 
+// spanChildren sets the Span of pt and every non-terminal under it that has
+// children to the range covered by those children. Spans of nodes without
+// children are left as-is.
+func (pt *Tree) spanChildren() {
+	if len(pt.Children) == 0 {
+		return
+	}
+
+	for i := range pt.Children {
+		pt.Children[i].spanChildren()
+	}
+	pt.Span = childrenSpan(pt.Children)
+}
+
+// childrenSpan returns the span covered by all of the given nodes.
+func childrenSpan(children []*Tree) lex.Span {
+	spans := make([]lex.Span, len(children))
+	for i := range children {
+		spans[i] = children[i].Span
+	}
+	return lex.Covering(spans...)
+}
+
 // String returns a prettified representation of the entire parse tree suitable
 // for use in line-by-line comparisons of tree structure. Two parse trees are
 // considered semantcally identical if they produce identical String() output.
@@ -283,6 +314,7 @@ func (pt Tree) Copy() Tree {
 		Terminal: pt.Terminal,
 		Value:    pt.Value,
 		Source:   pt.Source,
+		Span:     pt.Span,
 		Children: make([]*Tree, len(pt.Children)),
 	}
 
@@ -300,8 +332,8 @@ func (pt Tree) Copy() Tree {
 // given object is not a parseTree, returns false, else returns whether the two
 // parse trees have the exact same structure.
 //
-// Does not consider the Source field, ergo only the structures of the trees are
-// compared, not their contents.
+// Does not consider the Source or Span fields, ergo only the structures of the
+// trees are compared, not their contents.
 //
 // Runs in O(n) time with respect to the number of nodes in the trees.
 func (pt Tree) Equal(o any) bool {
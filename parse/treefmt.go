@@ -0,0 +1,397 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dekarrin/ictiobus/internal/textfmt"
+	"github.com/dekarrin/ictiobus/lex"
+)
+
+// File treefmt.go contains encodings of parse trees into formats other than
+// the diagram given by Tree.String(), for use by outside tools.
+
+// jsonTree is the form a Tree takes when encoded as JSON.
+type jsonTree struct {
+	Symbol   string          `json:"symbol"`
+	Terminal bool            `json:"terminal"`
+	Token    json.RawMessage `json:"token,omitempty"`
+	Span     lex.Span        `json:"span"`
+	Children []*jsonTree     `json:"children,omitempty"`
+}
+
+// MarshalJSON converts pt into a JSON object. Each node gives its symbol,
+// whether it is a terminal, and its span; terminal nodes with a Source also
+// give the token class, lexeme, and position of the token they were parsed
+// from. Epsilon terminals have an empty symbol.
+func (pt Tree) MarshalJSON() ([]byte, error) {
+	jt, err := pt.toJSONTree()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jt)
+}
+
+// UnmarshalJSON sets pt to the tree encoded in JSON produced by MarshalJSON.
+// The token classes of the decoded Source tokens are new TokenClasses created
+// from the encoded class ID and human name.
+func (pt *Tree) UnmarshalJSON(data []byte) error {
+	var jt jsonTree
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return err
+	}
+
+	decoded, err := treeFromJSONTree(&jt)
+	if err != nil {
+		return err
+	}
+	*pt = *decoded
+	return nil
+}
+
+func (pt Tree) toJSONTree() (*jsonTree, error) {
+	jt := &jsonTree{
+		Symbol:   pt.Value,
+		Terminal: pt.Terminal,
+		Span:     pt.Span,
+	}
+
+	if pt.Terminal && pt.Source != nil {
+		tokData, err := lex.MarshalTokenJSON(pt.Source)
+		if err != nil {
+			return nil, err
+		}
+		jt.Token = tokData
+	}
+
+	for i := range pt.Children {
+		child, err := pt.Children[i].toJSONTree()
+		if err != nil {
+			return nil, err
+		}
+		jt.Children = append(jt.Children, child)
+	}
+
+	return jt, nil
+}
+
+func treeFromJSONTree(jt *jsonTree) (*Tree, error) {
+	if jt == nil {
+		return nil, fmt.Errorf("null tree node")
+	}
+
+	pt := &Tree{
+		Terminal: jt.Terminal,
+		Value:    jt.Symbol,
+		Span:     jt.Span,
+	}
+
+	if len(jt.Token) > 0 && string(jt.Token) != "null" {
+		if !jt.Terminal {
+			return nil, fmt.Errorf("non-terminal %q has a token", jt.Symbol)
+		}
+		tok, err := lex.UnmarshalTokenJSON(jt.Token)
+		if err != nil {
+			return nil, fmt.Errorf("terminal %q: %w", jt.Symbol, err)
+		}
+		pt.Source = tok
+	}
+
+	if jt.Terminal && len(jt.Children) > 0 {
+		return nil, fmt.Errorf("terminal %q has children", jt.Symbol)
+	}
+
+	for i := range jt.Children {
+		child, err := treeFromJSONTree(jt.Children[i])
+		if err != nil {
+			return nil, err
+		}
+		pt.Children = append(pt.Children, child)
+	}
+
+	return pt, nil
+}
+
+// SExpr returns the S-expression representation of pt. A non-terminal node is
+// written as a list of its symbol followed by its children, and a terminal node
+// as a list of its symbol, its lexeme as a double-quoted string, and the
+// LINE:COL position of its token if it has a Source. Epsilon terminals are
+// written as the empty list. Symbols that cannot be written as a bare atom are
+// double-quoted.
+//
+// For example, the tree parsed from "-2 + 3" might be written as:
+//
+//	(S (NUM (- "-" 1:1) (int "2" 1:2)) (+ "+" 1:4) (NUM (int "3" 1:6)))
+//
+// The result can be read back into a Tree with ParseTreeFromSExpr.
+func (pt Tree) SExpr() string {
+	var sb strings.Builder
+	pt.writeSExpr(&sb)
+	return sb.String()
+}
+
+func (pt Tree) writeSExpr(sb *strings.Builder) {
+	if pt.Terminal && pt.Value == "" {
+		sb.WriteString("()")
+		return
+	}
+
+	sb.WriteRune('(')
+	sb.WriteString(sexprAtom(pt.Value))
+
+	if pt.Terminal {
+		lexeme := ""
+		if pt.Source != nil {
+			lexeme = pt.Source.Lexeme()
+		}
+		sb.WriteRune(' ')
+		sb.WriteString(strconv.Quote(lexeme))
+		if pt.Source != nil {
+			sb.WriteString(fmt.Sprintf(" %d:%d", pt.Source.Line(), pt.Source.LinePos()))
+		}
+	}
+
+	for i := range pt.Children {
+		sb.WriteRune(' ')
+		pt.Children[i].writeSExpr(sb)
+	}
+
+	sb.WriteRune(')')
+}
+
+// sexprAtom returns sym as it is written in an S-expression; as a bare atom if
+// possible, otherwise as a double-quoted string.
+func sexprAtom(sym string) string {
+	if sym == "" {
+		return `""`
+	}
+	for _, ch := range sym {
+		if unicode.IsSpace(ch) || ch == '(' || ch == ')' || ch == '"' {
+			return strconv.Quote(sym)
+		}
+	}
+	return sym
+}
+
+// ParseTreeFromSExpr reads an S-expression in the format produced by
+// Tree.SExpr and returns the Tree it represents. The Source of each terminal
+// is a token whose class has the terminal's symbol as its ID. Spans are set
+// only if the positions of terminals are given.
+func ParseTreeFromSExpr(s string) (*Tree, error) {
+	sp := &sexprParser{src: []rune(s), line: 1}
+
+	pt, err := sp.node()
+	if err != nil {
+		return nil, err
+	}
+
+	sp.skipSpace()
+	if sp.pos < len(sp.src) {
+		return nil, fmt.Errorf("line %d: unexpected %q after end of tree", sp.line, sp.src[sp.pos])
+	}
+
+	pt.spanChildren()
+	return pt, nil
+}
+
+// sexprParser reads trees from S-expressions.
+type sexprParser struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+func (sp *sexprParser) skipSpace() {
+	for sp.pos < len(sp.src) && unicode.IsSpace(sp.src[sp.pos]) {
+		if sp.src[sp.pos] == '\n' {
+			sp.line++
+		}
+		sp.pos++
+	}
+}
+
+func (sp *sexprParser) peek() (rune, bool) {
+	sp.skipSpace()
+	if sp.pos >= len(sp.src) {
+		return 0, false
+	}
+	return sp.src[sp.pos], true
+}
+
+func (sp *sexprParser) expect(ch rune) error {
+	next, ok := sp.peek()
+	if !ok {
+		return fmt.Errorf("line %d: unexpected end of input; expected %q", sp.line, ch)
+	}
+	if next != ch {
+		return fmt.Errorf("line %d: unexpected %q; expected %q", sp.line, next, ch)
+	}
+	sp.pos++
+	return nil
+}
+
+// node reads a single node and all of its children.
+func (sp *sexprParser) node() (*Tree, error) {
+	if err := sp.expect('('); err != nil {
+		return nil, err
+	}
+
+	next, ok := sp.peek()
+	if !ok {
+		return nil, fmt.Errorf("line %d: unexpected end of input in node", sp.line)
+	}
+	if next == ')' {
+		sp.pos++
+		return &Tree{Terminal: true}, nil
+	}
+
+	sym, _, err := sp.atom()
+	if err != nil {
+		return nil, err
+	}
+
+	next, ok = sp.peek()
+	if !ok {
+		return nil, fmt.Errorf("line %d: unexpected end of input in node %q", sp.line, sym)
+	}
+
+	if next == '"' {
+		return sp.terminal(sym)
+	}
+
+	pt := &Tree{Value: sym}
+	for {
+		next, ok = sp.peek()
+		if !ok {
+			return nil, fmt.Errorf("line %d: unexpected end of input in node %q", sp.line, sym)
+		}
+		if next == ')' {
+			sp.pos++
+			return pt, nil
+		}
+		if next != '(' {
+			return nil, fmt.Errorf("line %d: unexpected %q in non-terminal %q; expected child node", sp.line, next, sym)
+		}
+
+		child, err := sp.node()
+		if err != nil {
+			return nil, err
+		}
+		pt.Children = append(pt.Children, child)
+	}
+}
+
+// terminal reads the rest of a terminal node for sym after its symbol.
+func (sp *sexprParser) terminal(sym string) (*Tree, error) {
+	lexeme, _, err := sp.atom()
+	if err != nil {
+		return nil, err
+	}
+
+	var line, col int
+	next, ok := sp.peek()
+	if ok && next != ')' {
+		posStr, quoted, err := sp.atom()
+		if err != nil {
+			return nil, err
+		}
+		lineStr, colStr, found := strings.Cut(posStr, ":")
+		if quoted || !found {
+			return nil, fmt.Errorf("line %d: terminal %q position %q is not in LINE:COL form", sp.line, sym, posStr)
+		}
+		line, err = strconv.Atoi(lineStr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: terminal %q position has bad line: %w", sp.line, sym, err)
+		}
+		col, err = strconv.Atoi(colStr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: terminal %q position has bad column: %w", sp.line, sym, err)
+		}
+	}
+
+	if err := sp.expect(')'); err != nil {
+		return nil, err
+	}
+
+	tok := lex.NewToken(lex.NewTokenClass(sym, sym), lexeme, col, line, "")
+	pt := &Tree{Terminal: true, Value: sym, Source: tok}
+	if line > 0 {
+		pt.Span = lex.SpanOf(tok)
+	}
+	return pt, nil
+}
+
+// atom reads either a bare atom or a double-quoted string. quoted is whether
+// it was a double-quoted string.
+func (sp *sexprParser) atom() (val string, quoted bool, err error) {
+	sp.skipSpace()
+	start := sp.pos
+
+	if sp.pos < len(sp.src) && sp.src[sp.pos] == '"' {
+		sp.pos++
+		escaped := false
+		for sp.pos < len(sp.src) {
+			ch := sp.src[sp.pos]
+			sp.pos++
+			if escaped {
+				escaped = false
+			} else if ch == '\\' {
+				escaped = true
+			} else if ch == '"' {
+				val, err := strconv.Unquote(string(sp.src[start:sp.pos]))
+				if err != nil {
+					return "", true, fmt.Errorf("line %d: bad string %s: %w", sp.line, string(sp.src[start:sp.pos]), err)
+				}
+				return val, true, nil
+			} else if ch == '\n' {
+				sp.line++
+			}
+		}
+		return "", true, fmt.Errorf("line %d: unterminated string", sp.line)
+	}
+
+	for sp.pos < len(sp.src) {
+		ch := sp.src[sp.pos]
+		if unicode.IsSpace(ch) || ch == '(' || ch == ')' || ch == '"' {
+			break
+		}
+		sp.pos++
+	}
+	if sp.pos == start {
+		if sp.pos >= len(sp.src) {
+			return "", false, fmt.Errorf("line %d: unexpected end of input", sp.line)
+		}
+		return "", false, fmt.Errorf("line %d: unexpected %q", sp.line, sp.src[sp.pos])
+	}
+	return string(sp.src[start:sp.pos]), false, nil
+}
+
+// DOT returns a Graphviz DOT digraph that draws pt. Non-terminals are drawn as
+// ellipses, and terminals as boxes that also show the lexeme of their Source.
+func (pt Tree) DOT() string {
+	getLabel := func(n *Tree) string {
+		if n.Terminal && n.Value == "" {
+			return "ε"
+		}
+		if n.Terminal && n.Source != nil {
+			return fmt.Sprintf("%s\n%s", n.Value, strconv.Quote(n.Source.Lexeme()))
+		}
+		return n.Value
+	}
+	getShape := func(n *Tree) string {
+		if n.Terminal && n.Value == "" {
+			return "plaintext"
+		} else if n.Terminal {
+			return "box"
+		}
+		return "ellipse"
+	}
+	getChildren := func(n *Tree) []*Tree {
+		return n.Children
+	}
+
+	return textfmt.DOTTree("parsetree", &pt, getLabel, getShape, getChildren)
+}
@@ -0,0 +1,189 @@
+package parse
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/stretchr/testify/assert"
+)
+
+// treefmtTestTree returns the tree for "-2 + 3" that is used by the tests in
+// this file.
+func treefmtTestTree() Tree {
+	minusClass := lex.NewTokenClass("-", "minus sign")
+	plusClass := lex.NewTokenClass("+", "plus sign")
+	intClass := lex.NewTokenClass("int", "integer")
+
+	pt := Node("S",
+		Node("NUM",
+			Leaf("-", lex.NewToken(minusClass, "-", 1, 1, "-2 + 3")),
+			Leaf("int", lex.NewToken(intClass, "2", 2, 1, "-2 + 3")),
+		),
+		Leaf("+", lex.NewToken(plusClass, "+", 4, 1, "-2 + 3")),
+		Node("NUM",
+			Leaf("int", lex.NewToken(intClass, "3", 6, 1, "-2 + 3")),
+		),
+		Node("OPT",
+			&Tree{Terminal: true},
+		),
+	)
+	pt.spanChildren()
+	return *pt
+}
+
+func Test_Tree_JSON(t *testing.T) {
+	assert := assert.New(t)
+
+	pt := treefmtTestTree()
+
+	data, err := json.Marshal(pt)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var actual Tree
+	err = json.Unmarshal(data, &actual)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.True(pt.Equal(actual), "decoded tree does not match:\n%s", actual.String())
+	assert.Equal(lex.Span{Start: lex.Position{Line: 1, Col: 1}, End: lex.Position{Line: 1, Col: 7}}, actual.Span)
+
+	intNode := actual.Follow([]int{2, 0})
+	if !assert.NotNil(intNode.Source) {
+		return
+	}
+	assert.Equal("int", intNode.Source.Class().ID())
+	assert.Equal("integer", intNode.Source.Class().Human())
+	assert.Equal("3", intNode.Source.Lexeme())
+	assert.Equal(1, intNode.Source.Line())
+	assert.Equal(6, intNode.Source.LinePos())
+	assert.Equal("-2 + 3", intNode.Source.FullLine())
+
+	assert.Nil(actual.Follow([]int{3, 0}).Source)
+}
+
+func Test_Tree_JSON_errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "terminal with children",
+			input: `{"symbol": "a", "terminal": true, "children": [{"symbol": "b", "terminal": true}]}`,
+		},
+		{
+			name:  "non-terminal with token",
+			input: `{"symbol": "A", "token": {"class": "a", "lexeme": "a"}}`,
+		},
+		{
+			name:  "token without class",
+			input: `{"symbol": "a", "terminal": true, "token": {"lexeme": "a"}}`,
+		},
+		{
+			name:  "null child",
+			input: `{"symbol": "A", "children": [null]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pt Tree
+			err := json.Unmarshal([]byte(tc.input), &pt)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_Tree_SExpr(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tree   Tree
+		expect string
+	}{
+		{
+			name:   "terminal without source",
+			tree:   *Leaf("int"),
+			expect: `(int "")`,
+		},
+		{
+			name:   "non-terminal without children",
+			tree:   *Node("A"),
+			expect: `(A)`,
+		},
+		{
+			name:   "symbol that needs quoting",
+			tree:   *Node("A B", Leaf("(")),
+			expect: `("A B" ("(" ""))`,
+		},
+		{
+			name:   "full tree",
+			tree:   treefmtTestTree(),
+			expect: `(S (NUM (- "-" 1:1) (int "2" 1:2)) (+ "+" 1:4) (NUM (int "3" 1:6)) (OPT ()))`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := tc.tree.SExpr()
+			assert.Equal(tc.expect, actual)
+
+			decoded, err := ParseTreeFromSExpr(actual)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.True(tc.tree.Equal(decoded), "decoded tree does not match:\n%s", decoded.String())
+			assert.Equal(tc.tree.Span, decoded.Span)
+		})
+	}
+}
+
+func Test_ParseTreeFromSExpr_errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty", input: ""},
+		{name: "unclosed node", input: "(S (A)"},
+		{name: "atom child", input: "(S a)"},
+		{name: "trailing input", input: "(S) (T)"},
+		{name: "unterminated lexeme", input: `(a "abc)`},
+		{name: "bad position", input: `(a "a" 12)`},
+		{name: "quoted position", input: `(a "a" "1:2")`},
+		{name: "child of terminal", input: `(a "a" (b "b"))`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseTreeFromSExpr(tc.input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_Tree_DOT(t *testing.T) {
+	assert := assert.New(t)
+
+	pt := Node("S",
+		Leaf("int", lex.NewToken(lex.NewTokenClass("int", "integer"), "2", 1, 1, "2")),
+		Node("OPT", &Tree{Terminal: true}),
+	)
+
+	expect := `digraph "parsetree" {
+	ordering=out;
+	n0 [label="S", shape=ellipse];
+	n1 [label="int\n\"2\"", shape=box];
+	n0 -> n1;
+	n2 [label="OPT", shape=ellipse];
+	n3 [label="ε", shape=plaintext];
+	n2 -> n3;
+	n0 -> n2;
+}
+`
+
+	assert.Equal(expect, pt.DOT())
+}
@@ -0,0 +1,9 @@
+#!/bin/bash
+
+# Runs the unit tests that use components from many goroutines at once with the
+# race detector enabled, exiting if there is any error.
+
+# assumes we are in a dir called 'scripts' in the repo root:
+cd "$(dirname "$0")/.."
+
+go test -race -count 1 -run Concurrent ./... "$@"
@@ -0,0 +1,426 @@
+package syntaxerr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity is how serious a Diagnostic is.
+type Severity int
+
+const (
+	// SeverityError is for a problem that stops the input from being
+	// analyzed.
+	SeverityError Severity = iota
+
+	// SeverityWarning is for a problem that does not stop the input from
+	// being analyzed but that is probably a mistake.
+	SeverityWarning
+
+	// SeverityNote is for information that is not a problem itself.
+	SeverityNote
+)
+
+// String returns the name of the Severity as it is shown in messages.
+func (sev Severity) String() string {
+	switch sev {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(sev))
+	}
+}
+
+// MarshalText returns the name of the Severity, so that it is encoded in JSON
+// as a string.
+func (sev Severity) MarshalText() ([]byte, error) {
+	switch sev {
+	case SeverityError, SeverityWarning, SeverityNote:
+		return []byte(sev.String()), nil
+	default:
+		return nil, fmt.Errorf("not a valid severity: %d", int(sev))
+	}
+}
+
+// UnmarshalText sets the Severity to the one with the given name.
+func (sev *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "error":
+		*sev = SeverityError
+	case "warning":
+		*sev = SeverityWarning
+	case "note":
+		*sev = SeverityNote
+	default:
+		return fmt.Errorf("not a valid severity: %q", string(text))
+	}
+	return nil
+}
+
+// Codes of the Diagnostics that ictiobus itself produces. Codes of Diagnostics
+// produced by a particular language, such as from its hooks, should not start
+// with "ICT".
+const (
+	// CodeLexical is for input that the lexer could not match any pattern
+	// to.
+	CodeLexical = "ICT0001"
+
+	// CodeUnexpectedToken is for a token that the parser did not expect at
+	// its position in the input.
+	CodeUnexpectedToken = "ICT0002"
+
+	// CodeParserState is for an LR parser reaching a state that its tables do
+	// not allow for. This always indicates a problem with the parser rather
+	// than the input.
+	CodeParserState = "ICT0003"
+
+	// CodeHookFailed is for a hook that returned an error or panicked while
+	// translating a parse tree.
+	CodeHookFailed = "ICT0101"
+
+	// CodeHookMissing is for a binding whose hook was never set or is not in
+	// the hooks table of the SDTS.
+	CodeHookMissing = "ICT0102"
+
+	// CodeEvalOrder is for an SDTS whose bindings cannot be put in an order
+	// to evaluate them in for a parse tree, or that leave parts of the tree
+	// disconnected from the root.
+	CodeEvalOrder = "ICT0103"
+
+	// CodeAttrNotSet is for an attribute that was requested from the root of a
+	// parse tree but that the SDTS did not set on it.
+	CodeAttrNotSet = "ICT0104"
+)
+
+// Position is a location in source text.
+type Position struct {
+	// Line is the 1-indexed line number of the position. It is 0 if the
+	// position is not known.
+	Line int `json:"line"`
+
+	// Col is the 1-indexed character-of-line of the position.
+	Col int `json:"col"`
+}
+
+// Span is a range of source text. Start is the position of the first character
+// in the range and End is the position just after the last one.
+type Span struct {
+	// File is the name of the file that the source text is in. It is empty if
+	// the source text is not from a named source.
+	File string `json:"file,omitempty"`
+
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Known returns whether the Span has a location in source text.
+func (s Span) Known() bool {
+	return s.Start.Line > 0
+}
+
+// spanOf returns the Span of source in file that starts at start.
+func spanOf(file string, start Position, source string) Span {
+	end := start
+	for _, ch := range source {
+		if ch == '\n' {
+			end.Line++
+			end.Col = 1
+		} else {
+			end.Col++
+		}
+	}
+	return Span{File: file, Start: start, End: end}
+}
+
+// Label is a secondary location that a Diagnostic refers to, such as the place
+// where something that conflicts with the primary location was declared.
+type Label struct {
+	// Span is the location that the Label is for.
+	Span Span `json:"span"`
+
+	// SourceLine is the full text of the line that Span starts on. It is empty
+	// if it is not known.
+	SourceLine string `json:"source_line,omitempty"`
+
+	// Message describes what is at the location.
+	Message string `json:"message"`
+}
+
+// Suggestion is a possible fix for the problem that a Diagnostic is for.
+type Suggestion struct {
+	// Message describes the fix.
+	Message string `json:"message"`
+
+	// Span is the source text that would be replaced by the fix. If it is
+	// empty, Replacement is inserted at its start.
+	Span Span `json:"span"`
+
+	// Replacement is the text that would replace the source text in Span.
+	Replacement string `json:"replacement"`
+}
+
+// Diagnostic is a message about a problem with analyzed code. Unlike an Error,
+// it can have a code that identifies the kind of problem, a severity, a span
+// of source text instead of a single position, secondary locations, notes, and
+// suggested fixes.
+//
+// Diagnostic implements error; most functions in ictiobus that produce them
+// return them in a Diagnostics.
+type Diagnostic struct {
+	// Code identifies the kind of problem, such as CodeUnexpectedToken. It may
+	// be empty.
+	Code string `json:"code,omitempty"`
+
+	// Severity is how serious the problem is.
+	Severity Severity `json:"severity"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+
+	// Span is the primary location of the problem. If Span.Known() is false,
+	// the Diagnostic is not for any particular location in source text.
+	Span Span `json:"span"`
+
+	// SourceLine is the full text of the line that Span starts on. It is empty
+	// if it is not known.
+	SourceLine string `json:"source_line,omitempty"`
+
+	// Labels is secondary locations that are related to the problem.
+	Labels []Label `json:"labels,omitempty"`
+
+	// Notes is additional information about the problem.
+	Notes []string `json:"notes,omitempty"`
+
+	// Suggestions is possible fixes for the problem.
+	Suggestions []Suggestion `json:"suggestions,omitempty"`
+
+	// Cause is the error that the Diagnostic was created from, if any. It is
+	// returned by Unwrap.
+	Cause error `json:"-"`
+}
+
+// Error returns the message of the Diagnostic along with its location. For a
+// Diagnostic with SeverityError, this is the same as the message of the Error
+// that ToError returns.
+func (d Diagnostic) Error() string {
+	prefix := d.Severity.String()
+	if d.Severity == SeverityError {
+		prefix = "syntax error"
+	}
+
+	var fileStr string
+	if d.Span.File != "" {
+		fileStr = d.Span.File + ": "
+	}
+
+	if !d.Span.Known() {
+		return fmt.Sprintf("%s: %s%s", prefix, fileStr, d.Message)
+	}
+	return fmt.Sprintf("%s: %saround line %d, char %d: %s", prefix, fileStr, d.Span.Start.Line, d.Span.Start.Col, d.Message)
+}
+
+// Unwrap returns the Cause of the Diagnostic.
+func (d Diagnostic) Unwrap() error {
+	return d.Cause
+}
+
+// Source returns the source text in the primary span of the Diagnostic if it
+// is all on SourceLine, or an empty string if not.
+func (d Diagnostic) Source() string {
+	s := d.Span
+	line := []rune(d.SourceLine)
+	if !s.Known() || s.End.Line != s.Start.Line || s.Start.Col < 1 || s.End.Col < s.Start.Col || s.End.Col-1 > len(line) {
+		return ""
+	}
+	return string(line[s.Start.Col-1 : s.End.Col-1])
+}
+
+// ToError returns an Error with the message and primary location of d. The
+// other information in d is not included.
+func (d Diagnostic) ToError() *Error {
+	return NewInFile(d.Span.File, d.Message, d.SourceLine, d.Source(), d.Span.Start.Line, d.Span.Start.Col)
+}
+
+// FullMessage shows the complete message of the Diagnostic along with the
+// offending line and a cursor to the problem position for it and for each of
+// its labels, followed by its notes and suggestions.
+func (d Diagnostic) FullMessage() string {
+	var sb strings.Builder
+
+	if d.Span.Known() && d.SourceLine != "" {
+		sb.WriteString(sourceLineWithCursor(d.SourceLine, d.Span.Start.Col))
+		sb.WriteRune('\n')
+	}
+	sb.WriteString(d.Error())
+
+	for _, lbl := range d.Labels {
+		sb.WriteRune('\n')
+		if lbl.Span.Known() && lbl.SourceLine != "" {
+			sb.WriteString(sourceLineWithCursor(lbl.SourceLine, lbl.Span.Start.Col))
+			sb.WriteRune('\n')
+		}
+		sb.WriteString(Diagnostic{Severity: SeverityNote, Message: lbl.Message, Span: lbl.Span}.Error())
+	}
+	for _, note := range d.Notes {
+		sb.WriteString("\nnote: ")
+		sb.WriteString(note)
+	}
+	for _, sug := range d.Suggestions {
+		sb.WriteString("\nhelp: ")
+		sb.WriteString(sug.Message)
+	}
+
+	return sb.String()
+}
+
+// Diagnostic returns a Diagnostic with SeverityError that has the message and
+// location of se.
+func (se Error) Diagnostic() Diagnostic {
+	d := Diagnostic{
+		Severity:   SeverityError,
+		Message:    se.message,
+		SourceLine: se.sourceLine,
+		Span:       Span{File: se.file},
+	}
+	if se.line != 0 {
+		d.Span = spanOf(se.file, Position{Line: se.line, Col: se.pos}, se.source)
+	}
+	return d
+}
+
+// Diagnostics is a list of Diagnostics, such as all of the problems found in a
+// file. It implements error so that it can be returned from functions that
+// return an error.
+//
+// When used with errors.As, a Diagnostics can be converted to an *Error for its
+// first Diagnostic with SeverityError or to that Diagnostic itself, and it
+// matches anything that the Cause of any of its Diagnostics matches. It is
+// also matched by errors.Is with anything that the Cause of any of its
+// Diagnostics matches.
+type Diagnostics []Diagnostic
+
+// Error returns the message of each Diagnostic, each on its own line.
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, len(ds))
+	for i := range ds {
+		msgs[i] = ds[i].Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// FullMessage returns the full message of each Diagnostic, separated by blank
+// lines.
+func (ds Diagnostics) FullMessage() string {
+	msgs := make([]string, len(ds))
+	for i := range ds {
+		msgs[i] = ds[i].FullMessage()
+	}
+	return strings.Join(msgs, "\n\n")
+}
+
+// HasErrors returns whether any Diagnostic in ds has SeverityError.
+func (ds Diagnostics) HasErrors() bool {
+	for i := range ds {
+		if ds[i].Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns ds as an error if it has any Diagnostic with SeverityError.
+// Otherwise, it returns nil.
+func (ds Diagnostics) Err() error {
+	if ds.HasErrors() {
+		return ds
+	}
+	return nil
+}
+
+// WithFile returns a copy of ds with the File of every span in it, including
+// those of labels and suggestions, set to file.
+func (ds Diagnostics) WithFile(file string) Diagnostics {
+	updated := make(Diagnostics, len(ds))
+	for i, d := range ds {
+		d.Span.File = file
+		if d.Labels != nil {
+			lbls := make([]Label, len(d.Labels))
+			for j := range d.Labels {
+				lbls[j] = d.Labels[j]
+				lbls[j].Span.File = file
+			}
+			d.Labels = lbls
+		}
+		if d.Suggestions != nil {
+			sugs := make([]Suggestion, len(d.Suggestions))
+			for j := range d.Suggestions {
+				sugs[j] = d.Suggestions[j]
+				sugs[j].Span.File = file
+			}
+			d.Suggestions = sugs
+		}
+		updated[i] = d
+	}
+	return updated
+}
+
+// AsDiagnostics returns the Diagnostics that err is or wraps. If err instead
+// is or wraps an *Error, it is returned as a Diagnostics with a single
+// Diagnostic. If neither is the case, ok is false.
+func AsDiagnostics(err error) (ds Diagnostics, ok bool) {
+	if errors.As(err, &ds) {
+		return ds, true
+	}
+	var synErr *Error
+	if errors.As(err, &synErr) {
+		return Diagnostics{synErr.Diagnostic()}, true
+	}
+	return nil, false
+}
+
+// As sets target to the first Diagnostic with SeverityError in ds if target
+// is a *Diagnostic, or to the Error for it if target is an **Error. For any
+// other target, it sets target to the first error in the Cause of a Diagnostic
+// in ds that matches it. It returns whether target was set.
+func (ds Diagnostics) As(target interface{}) bool {
+	switch target := target.(type) {
+	case *Diagnostic:
+		for i := range ds {
+			if ds[i].Severity == SeverityError {
+				*target = ds[i]
+				return true
+			}
+		}
+		return false
+	case **Error:
+		for i := range ds {
+			if ds[i].Severity == SeverityError {
+				*target = ds[i].ToError()
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range ds {
+		if ds[i].Cause != nil && errors.As(ds[i].Cause, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is returns whether the Cause of any Diagnostic in ds matches target.
+func (ds Diagnostics) Is(target error) bool {
+	for i := range ds {
+		if ds[i].Cause != nil && errors.Is(ds[i].Cause, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package syntaxerr
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Diagnostic_Error(t *testing.T) {
+	testCases := []struct {
+		name   string
+		diag   Diagnostic
+		expect string
+	}{
+		{
+			name: "error with location",
+			diag: Diagnostic{
+				Severity: SeverityError,
+				Message:  "unexpected '+'",
+				Span:     Span{Start: Position{Line: 2, Col: 5}, End: Position{Line: 2, Col: 6}},
+			},
+			expect: "syntax error: around line 2, char 5: unexpected '+'",
+		},
+		{
+			name: "error with file",
+			diag: Diagnostic{
+				Severity: SeverityError,
+				Message:  "unexpected '+'",
+				Span:     Span{File: "src.txt", Start: Position{Line: 2, Col: 5}, End: Position{Line: 2, Col: 6}},
+			},
+			expect: "syntax error: src.txt: around line 2, char 5: unexpected '+'",
+		},
+		{
+			name: "warning with no location",
+			diag: Diagnostic{
+				Severity: SeverityWarning,
+				Message:  "disconnected segments",
+			},
+			expect: "warning: disconnected segments",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := tc.diag.Error()
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_Diagnostic_FullMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	d := Diagnostic{
+		Code:       CodeUnexpectedToken,
+		Severity:   SeverityError,
+		Message:    "duplicate name",
+		Span:       Span{Start: Position{Line: 3, Col: 5}, End: Position{Line: 3, Col: 8}},
+		SourceLine: "let foo = 2",
+		Labels: []Label{
+			{
+				Span:       Span{Start: Position{Line: 1, Col: 5}, End: Position{Line: 1, Col: 8}},
+				SourceLine: "let foo = 1",
+				Message:    "first declared here",
+			},
+		},
+		Notes:       []string{"names must be unique"},
+		Suggestions: []Suggestion{{Message: "rename it", Replacement: "bar"}},
+	}
+
+	expect := "let foo = 2\n" +
+		"    ^\n" +
+		"syntax error: around line 3, char 5: duplicate name\n" +
+		"let foo = 1\n" +
+		"    ^\n" +
+		"note: around line 1, char 5: first declared here\n" +
+		"note: names must be unique\n" +
+		"help: rename it"
+
+	assert.Equal(expect, d.FullMessage())
+}
+
+func Test_Error_Diagnostic(t *testing.T) {
+	assert := assert.New(t)
+
+	se := NewInFile("src.txt", "bad thing", "a := 27 + 3", "27", 1, 6)
+
+	// execute
+	d := se.Diagnostic()
+
+	// assert
+	assert.Equal(SeverityError, d.Severity)
+	assert.Equal(Span{File: "src.txt", Start: Position{Line: 1, Col: 6}, End: Position{Line: 1, Col: 8}}, d.Span)
+	assert.Equal("27", d.Source())
+	assert.Equal(se.Error(), d.Error())
+	assert.Equal(se, d.ToError())
+}
+
+func Test_Diagnostics_As(t *testing.T) {
+	assert := assert.New(t)
+
+	cause := &LimitError{Limit: LimitTokens, Max: 2}
+	ds := Diagnostics{
+		{Severity: SeverityWarning, Message: "just a warning"},
+		{Severity: SeverityError, Message: "bad thing", Span: Span{Start: Position{Line: 1, Col: 2}, End: Position{Line: 1, Col: 3}}, SourceLine: "abc", Cause: cause},
+	}
+	var err error = ds
+
+	var synErr *Error
+	if assert.True(errors.As(err, &synErr)) {
+		assert.Equal("bad thing", synErr.message)
+		assert.Equal(1, synErr.Line())
+		assert.Equal(2, synErr.Position())
+		assert.Equal("b", synErr.Source())
+	}
+
+	var diag Diagnostic
+	if assert.True(errors.As(err, &diag)) {
+		assert.Equal("bad thing", diag.Message)
+	}
+
+	var limErr *LimitError
+	if assert.True(errors.As(err, &limErr)) {
+		assert.Equal(cause, limErr)
+	}
+
+	assert.True(errors.Is(err, ErrTooManyTokens))
+	assert.False(errors.Is(err, ErrTreeTooDeep))
+	assert.True(ds.HasErrors())
+	assert.Nil(ds[:1].Err())
+}
+
+func Test_Severity_JSON(t *testing.T) {
+	assert := assert.New(t)
+
+	d := Diagnostic{Severity: SeverityWarning, Message: "careful"}
+
+	data, err := json.Marshal(d)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(data), `"severity":"warning"`)
+
+	var decoded Diagnostic
+	if assert.NoError(json.Unmarshal(data, &decoded)) {
+		assert.Equal(SeverityWarning, decoded.Severity)
+	}
+
+	assert.Error(json.Unmarshal([]byte(`{"severity":"fatal"}`), &decoded))
+}
@@ -0,0 +1,93 @@
+package syntaxerr
+
+import "fmt"
+
+// Limit is a resource limit that can be placed on the analysis of input, such
+// as to keep pathological input from using too much time or memory.
+type Limit int
+
+const (
+	// LimitInputSize is the maximum number of bytes of input that will be
+	// read.
+	LimitInputSize Limit = iota
+
+	// LimitTokens is the maximum number of tokens that will be lexed from the
+	// input.
+	LimitTokens
+
+	// LimitTreeDepth is the maximum depth of the parse tree built from the
+	// input. The root of the tree is at depth 1.
+	LimitTreeDepth
+
+	// LimitEvalSteps is the maximum number of hooks that will be called while
+	// translating the parse tree.
+	LimitEvalSteps
+)
+
+// String returns a description of the Limit.
+func (l Limit) String() string {
+	switch l {
+	case LimitInputSize:
+		return "input size"
+	case LimitTokens:
+		return "token count"
+	case LimitTreeDepth:
+		return "parse tree depth"
+	case LimitEvalSteps:
+		return "evaluation steps"
+	default:
+		return fmt.Sprintf("Limit(%d)", int(l))
+	}
+}
+
+// LimitError is returned when analysis of input is stopped because the input
+// exceeded one of the limits set on it. Whether an error is for a particular
+// limit can be checked with errors.Is and the matching Err variable of this
+// package, such as ErrInputTooLarge.
+type LimitError struct {
+	// Limit is the limit that was exceeded.
+	Limit Limit
+
+	// Max is the value the limit was set to.
+	Max int
+}
+
+var (
+	// ErrInputTooLarge matches any *LimitError for LimitInputSize when used
+	// with errors.Is.
+	ErrInputTooLarge = &LimitError{Limit: LimitInputSize}
+
+	// ErrTooManyTokens matches any *LimitError for LimitTokens when used with
+	// errors.Is.
+	ErrTooManyTokens = &LimitError{Limit: LimitTokens}
+
+	// ErrTreeTooDeep matches any *LimitError for LimitTreeDepth when used with
+	// errors.Is.
+	ErrTreeTooDeep = &LimitError{Limit: LimitTreeDepth}
+
+	// ErrTooManyEvalSteps matches any *LimitError for LimitEvalSteps when used
+	// with errors.Is.
+	ErrTooManyEvalSteps = &LimitError{Limit: LimitEvalSteps}
+)
+
+// Error returns the message of the error.
+func (le *LimitError) Error() string {
+	switch le.Limit {
+	case LimitInputSize:
+		return fmt.Sprintf("input is larger than the maximum of %d bytes", le.Max)
+	case LimitTokens:
+		return fmt.Sprintf("input has more than the maximum of %d tokens", le.Max)
+	case LimitTreeDepth:
+		return fmt.Sprintf("parse tree is deeper than the maximum of %d", le.Max)
+	case LimitEvalSteps:
+		return fmt.Sprintf("translation takes more than the maximum of %d steps", le.Max)
+	default:
+		return fmt.Sprintf("%s exceeds the maximum of %d", le.Limit, le.Max)
+	}
+}
+
+// Is returns whether target is a *LimitError for the same Limit as le.
+func (le *LimitError) Is(target error) bool {
+	other, ok := target.(*LimitError)
+	return ok && other.Limit == le.Limit
+}
@@ -0,0 +1,604 @@
+package syntaxerr
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// File render.go contains the Renderer, which formats Diagnostics for showing
+// to an end-user in a terminal.
+
+// ANSI escape sequences used by the Renderer when Color is enabled.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiGreen  = "\x1b[1;32m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBlue   = "\x1b[1;34m"
+)
+
+// maxSpanLines is the number of lines that a multi-line span can cover before
+// the Renderer omits the lines in the middle of it.
+const maxSpanLines = 4
+
+// Renderer formats Diagnostics in the style of rustc, with the source lines
+// that they refer to shown under a gutter of line numbers, the primary span
+// underlined with '^', and the span of each label underlined with '-' and
+// followed by its message. Spans that cover more than one line are drawn with
+// a line in the left margin that connects their start and end.
+//
+// The zero-value is a Renderer that does not use color and shows only the
+// lines that the Diagnostic has the text of. Use RendererFor to get one that
+// uses color if output is going to a terminal.
+type Renderer struct {
+	// Color is whether to use ANSI escape sequences to color the output.
+	Color bool
+
+	// Context is the number of lines to show before and after each line that
+	// is underlined. Context lines are only shown for files whose full text
+	// is in Sources.
+	Context int
+
+	// Sources is the full text of source files, by the name that is used for
+	// them in the File of a Span. The text of a file is used for a Diagnostic
+	// only if it agrees with the SourceLine of each location in that file;
+	// otherwise, only those lines are shown. Use the empty string as the name
+	// for text that is not from a named source.
+	Sources map[string]string
+}
+
+// RendererFor returns a Renderer that uses color if w is a terminal and the
+// NO_COLOR environment variable is not set, and that shows one line of context
+// around underlined lines.
+func RendererFor(w io.Writer) Renderer {
+	color := IsTerminal(w) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	return Renderer{Color: color, Context: 1}
+}
+
+// IsTerminal returns whether w is an *os.File that is a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// RenderAll returns the rendered text of each Diagnostic in ds, separated by
+// blank lines.
+func (r Renderer) RenderAll(ds Diagnostics) string {
+	msgs := make([]string, len(ds))
+	for i := range ds {
+		msgs[i] = r.Render(ds[i])
+	}
+	return strings.Join(msgs, "\n\n")
+}
+
+// Render returns the text of d formatted for showing to an end-user. The
+// returned string does not end with a newline.
+func (r Renderer) Render(d Diagnostic) string {
+	var out []string
+
+	title := d.Severity.String()
+	if d.Code != "" {
+		title += "[" + d.Code + "]"
+	}
+	out = append(out, r.paint(severityColor(d.Severity), title)+r.paint(ansiBold, ": "+d.Message))
+
+	// collect the locations and split them up by file, primary first.
+	var marks []mark
+	if d.Span.Known() {
+		marks = append(marks, mark{span: d.Span, primary: true, sourceLine: d.SourceLine, color: severityColor(d.Severity)})
+	}
+	for _, lbl := range d.Labels {
+		if lbl.Span.Known() {
+			marks = append(marks, mark{span: lbl.Span, msg: lbl.Message, sourceLine: lbl.SourceLine, color: ansiBlue})
+		}
+	}
+
+	var files []string
+	byFile := map[string][]mark{}
+	for _, m := range marks {
+		if _, ok := byFile[m.span.File]; !ok {
+			files = append(files, m.span.File)
+		}
+		byFile[m.span.File] = append(byFile[m.span.File], m)
+	}
+
+	snips := make([]snippet, len(files))
+	snipsByFile := map[string]*snippet{}
+	for i, f := range files {
+		snips[i] = r.snippet(f, byFile[f])
+		snipsByFile[f] = &snips[i]
+	}
+
+	// notes include labels that could not be placed in a snippet.
+	var notes []string
+	for _, sn := range snips {
+		for _, m := range sn.unplaced {
+			if !m.primary {
+				notes = append(notes, fmt.Sprintf("%s: %s", location(m.span), m.msg))
+			}
+		}
+	}
+	notes = append(notes, d.Notes...)
+
+	// find the suggestions that can be shown as a patched line
+	patches := make([]*patch, len(d.Suggestions))
+	for i, sug := range d.Suggestions {
+		if sn, ok := snipsByFile[sug.Span.File]; ok {
+			patches[i] = sn.patch(sug)
+		}
+	}
+
+	width := 1
+	for _, sn := range snips {
+		for _, n := range sn.show {
+			width = maxInt(width, len(fmt.Sprint(n)))
+		}
+	}
+	for _, p := range patches {
+		if p != nil {
+			width = maxInt(width, len(fmt.Sprint(p.line)))
+		}
+	}
+
+	for i, sn := range snips {
+		arrow := ":::"
+		var loc Span
+		if i == 0 && d.Span.Known() {
+			arrow = "-->"
+			loc = d.Span
+		} else if len(sn.marks) > 0 {
+			loc = sn.marks[0].span
+		} else {
+			// only has labels that are given as notes
+			continue
+		}
+		out = append(out, strings.Repeat(" ", width)+r.paint(ansiBlue, arrow)+" "+location(loc))
+		if len(sn.show) > 0 {
+			out = append(out, r.rows(sn.render(width))...)
+		}
+	}
+
+	if len(notes)+len(d.Suggestions) > 0 {
+		if len(snips) > 0 {
+			out = append(out, r.rows([]row{gutterRow(width, "")})...)
+		}
+		for _, note := range notes {
+			out = append(out, r.annotation(width, "note", note))
+		}
+		for i, sug := range d.Suggestions {
+			out = append(out, r.annotation(width, "help", sug.Message))
+			if patches[i] != nil {
+				out = append(out, r.rows(patches[i].render(width))...)
+			}
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// annotation returns a note or help line with the given kind, indented to line
+// up with the gutter.
+func (r Renderer) annotation(width int, kind, msg string) string {
+	indent := strings.Repeat(" ", width+1)
+	msg = strings.ReplaceAll(msg, "\n", "\n"+indent+strings.Repeat(" ", len(kind)+4))
+	return indent + r.paint(ansiBold, "= "+kind+":") + " " + msg
+}
+
+// paint returns s colored with the ANSI escape sequence color if r uses color.
+func (r Renderer) paint(color, s string) string {
+	if !r.Color || color == "" || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// rows returns the text of each row, with trailing spaces removed.
+func (r Renderer) rows(rws []row) []string {
+	lines := make([]string, len(rws))
+	for i, rw := range rws {
+		end := len(rw)
+		for end > 0 && rw[end-1].ch == ' ' {
+			end--
+		}
+
+		var sb strings.Builder
+		var run strings.Builder
+		var runColor string
+		flush := func() {
+			sb.WriteString(r.paint(runColor, run.String()))
+			run.Reset()
+		}
+		for _, c := range rw[:end] {
+			if c.color != runColor {
+				flush()
+				runColor = c.color
+			}
+			run.WriteRune(c.ch)
+		}
+		flush()
+		lines[i] = sb.String()
+	}
+	return lines
+}
+
+// severityColor returns the color that sev is shown in.
+func severityColor(sev Severity) string {
+	switch sev {
+	case SeverityError:
+		return ansiRed
+	case SeverityWarning:
+		return ansiYellow
+	case SeverityNote:
+		return ansiGreen
+	default:
+		return ansiBold
+	}
+}
+
+// location returns the location of the start of s in the format
+// "file:line:col", or "line:col" if s has no file.
+func location(s Span) string {
+	loc := fmt.Sprintf("%d:%d", s.Start.Line, s.Start.Col)
+	if s.File != "" {
+		loc = s.File + ":" + loc
+	}
+	return loc
+}
+
+// mark is a span that is underlined in a snippet.
+type mark struct {
+	span       Span
+	msg        string
+	primary    bool
+	sourceLine string
+	color      string
+
+	// the location of the mark after it is placed in a snippet. endCol is
+	// exclusive.
+	startLine, startCol, endLine, endCol int
+
+	// slot is the margin slot of a multi-line mark, or -1 if the mark is on
+	// one line.
+	slot int
+}
+
+func (m mark) underline() rune {
+	if m.primary {
+		return '^'
+	}
+	return '-'
+}
+
+// covers returns whether the margin line of m is drawn on the source line
+// with number n.
+func (m mark) covers(n int) bool {
+	return m.slot >= 0 && m.startLine < n && n <= m.endLine
+}
+
+// snippet is the lines of one file that are shown for a Diagnostic.
+type snippet struct {
+	lines    map[int]string
+	marks    []mark
+	unplaced []mark
+	show     []int
+	slots    int
+}
+
+// snippet creates the snippet for the marks in file. Marks whose first line
+// is not known are put in unplaced.
+func (r Renderer) snippet(file string, marks []mark) snippet {
+	sn := snippet{lines: map[int]string{}}
+
+	if src, ok := r.Sources[file]; ok && sourceAgrees(src, marks) {
+		for i, line := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+			sn.lines[i+1] = strings.TrimSuffix(line, "\r")
+		}
+	} else {
+		for _, m := range marks {
+			if m.sourceLine != "" {
+				sn.lines[m.span.Start.Line] = m.sourceLine
+			}
+		}
+	}
+
+	shown := map[int]bool{}
+	showWithContext := func(n int, ctx int) {
+		for i := n - ctx; i <= n+ctx; i++ {
+			if _, ok := sn.lines[i]; ok {
+				shown[i] = true
+			}
+		}
+	}
+
+	for _, m := range marks {
+		startText, ok := sn.lines[m.span.Start.Line]
+		if !ok {
+			sn.unplaced = append(sn.unplaced, m)
+			continue
+		}
+
+		m.startLine, m.startCol = m.span.Start.Line, maxInt(m.span.Start.Col, 1)
+		m.endLine, m.endCol = m.span.End.Line, m.span.End.Col
+		m.slot = -1
+
+		if m.endLine > m.startLine && m.endCol <= 1 {
+			// the span ends with the newline at the end of the line before.
+			m.endLine--
+			m.endCol = len([]rune(sn.lines[m.endLine])) + 1
+		}
+		if _, ok := sn.lines[m.endLine]; m.endLine > m.startLine && !ok {
+			// can't show where it ends, so underline to the end of the line.
+			m.endLine = m.startLine
+			m.endCol = len([]rune(startText)) + 1
+		}
+		if m.endLine < m.startLine || (m.endLine == m.startLine && m.endCol <= m.startCol) {
+			m.endLine = m.startLine
+			m.endCol = m.startCol + 1
+		}
+		if m.endLine > m.startLine {
+			m.slot = sn.slots
+			sn.slots++
+		}
+
+		showWithContext(m.startLine, 0)
+		showWithContext(m.endLine, 0)
+		if m.endLine-m.startLine <= maxSpanLines {
+			for n := m.startLine; n < m.endLine; n++ {
+				showWithContext(n, 0)
+			}
+		} else {
+			showWithContext(m.startLine+1, 0)
+			showWithContext(m.endLine-1, 0)
+		}
+		for i := 1; i <= r.Context; i++ {
+			showWithContext(m.startLine-i, 0)
+			showWithContext(m.endLine+i, 0)
+		}
+
+		sn.marks = append(sn.marks, m)
+	}
+
+	for n := range shown {
+		sn.show = append(sn.show, n)
+	}
+	sort.Ints(sn.show)
+
+	// a gap of a single known line is shown rather than elided.
+	for i := 1; i < len(sn.show); i++ {
+		if sn.show[i]-sn.show[i-1] == 2 {
+			if _, ok := sn.lines[sn.show[i-1]+1]; ok {
+				shown[sn.show[i-1]+1] = true
+			}
+		}
+	}
+	if len(shown) != len(sn.show) {
+		sn.show = sn.show[:0]
+		for n := range shown {
+			sn.show = append(sn.show, n)
+		}
+		sort.Ints(sn.show)
+	}
+
+	return sn
+}
+
+// sourceAgrees returns whether each mark with a SourceLine has that line in
+// src.
+func sourceAgrees(src string, marks []mark) bool {
+	lines := strings.Split(src, "\n")
+	for _, m := range marks {
+		if m.sourceLine == "" {
+			continue
+		}
+		n := m.span.Start.Line
+		if n > len(lines) || strings.TrimSuffix(lines[n-1], "\r") != m.sourceLine {
+			return false
+		}
+	}
+	return true
+}
+
+// render returns the rows of sn, with a gutter of the given width.
+func (sn snippet) render(width int) []row {
+	rows := []row{gutterRow(width, "")}
+	margin := width + 3
+	textStart := margin + 2*sn.slots
+
+	for i, n := range sn.show {
+		if i > 0 && n-sn.show[i-1] > 1 {
+			var rw row
+			rw.put("...", ansiBlue)
+			rows = append(rows, rw)
+		}
+
+		text := []rune(sn.lines[n])
+
+		rw := gutterRow(width, fmt.Sprint(n))
+		sn.putMargin(&rw, margin, n, -1)
+		rw.putAt(textStart, strings.ReplaceAll(string(text), "\t", "    "), "")
+		rows = append(rows, rw)
+
+		// marks that are all on this line, by column
+		var single []mark
+		for _, m := range sn.marks {
+			if m.slot < 0 && m.startLine == n {
+				single = append(single, m)
+			}
+		}
+		sort.SliceStable(single, func(a, b int) bool { return single[a].startCol < single[b].startCol })
+		for _, m := range single {
+			rw := gutterRow(width, "")
+			sn.putMargin(&rw, margin, n, -1)
+			start := textStart + displayCol(text, m.startCol)
+			end := textStart + displayCol(text, m.endCol)
+			rw.putAt(start, strings.Repeat(string(m.underline()), maxInt(end-start, 1)), m.color)
+			if m.msg != "" {
+				rw.putAt(maxInt(end, start+1)+1, m.msg, m.color)
+			}
+			rows = append(rows, rw)
+		}
+
+		// the ends of multi-line marks, then the starts.
+		for _, m := range sn.marks {
+			if m.slot < 0 || m.endLine != n {
+				continue
+			}
+			rw := gutterRow(width, "")
+			sn.putMargin(&rw, margin, n, m.slot)
+			at := textStart + displayCol(text, m.endCol-1)
+			rw.putAt(margin+2*m.slot, "|", m.color)
+			rw.putAt(margin+2*m.slot+1, strings.Repeat("_", maxInt(at-(margin+2*m.slot+1), 0)), m.color)
+			rw.putAt(at, string(m.underline()), m.color)
+			if m.msg != "" {
+				rw.putAt(at+2, m.msg, m.color)
+			}
+			rows = append(rows, rw)
+		}
+		for _, m := range sn.marks {
+			if m.slot < 0 || m.startLine != n {
+				continue
+			}
+			rw := gutterRow(width, "")
+			sn.putMargin(&rw, margin, n, m.slot)
+			at := textStart + displayCol(text, m.startCol)
+			rw.putAt(margin+2*m.slot+1, strings.Repeat("_", maxInt(at-(margin+2*m.slot+1), 0)), m.color)
+			rw.putAt(at, string(m.underline()), m.color)
+			rows = append(rows, rw)
+		}
+	}
+
+	return rows
+}
+
+// putMargin puts the margin line of each multi-line mark that covers line n
+// into rw, except for the one in slot skip.
+func (sn snippet) putMargin(rw *row, margin, n, skip int) {
+	for _, m := range sn.marks {
+		if m.slot != skip && m.covers(n) {
+			rw.putAt(margin+2*m.slot, "|", m.color)
+		}
+	}
+}
+
+// patch is a source line with a Suggestion applied to it.
+type patch struct {
+	line        int
+	text        []rune
+	start, end  int
+	insertion   bool
+	replacement string
+}
+
+// patch returns the line of sn with sug applied to it, or nil if sug is not
+// for a single known line.
+func (sn snippet) patch(sug Suggestion) *patch {
+	s := sug.Span
+	text, ok := sn.lines[s.Start.Line]
+	if !s.Known() || !ok || s.End.Line != s.Start.Line || strings.Contains(sug.Replacement, "\n") {
+		return nil
+	}
+	line := []rune(text)
+	if s.Start.Col < 1 || s.End.Col < s.Start.Col || s.End.Col-1 > len(line) {
+		return nil
+	}
+
+	var patched []rune
+	patched = append(patched, line[:s.Start.Col-1]...)
+	patched = append(patched, []rune(sug.Replacement)...)
+	patched = append(patched, line[s.End.Col-1:]...)
+
+	return &patch{
+		line:        s.Start.Line,
+		text:        patched,
+		start:       s.Start.Col,
+		end:         s.Start.Col + len([]rune(sug.Replacement)),
+		insertion:   s.End.Col == s.Start.Col,
+		replacement: sug.Replacement,
+	}
+}
+
+// render returns the rows that show p, with a gutter of the given width.
+func (p patch) render(width int) []row {
+	textStart := width + 3
+	rw := gutterRow(width, fmt.Sprint(p.line))
+	rw.putAt(textStart, strings.ReplaceAll(string(p.text), "\t", "    "), "")
+
+	mark := "~"
+	if p.insertion {
+		mark = "+"
+	}
+	under := gutterRow(width, "")
+	start := textStart + displayCol(p.text, p.start)
+	end := textStart + displayCol(p.text, p.end)
+	under.putAt(start, strings.Repeat(mark, maxInt(end-start, 1)), ansiGreen)
+
+	return []row{gutterRow(width, ""), rw, under}
+}
+
+// displayCol returns the number of columns that the characters of line before
+// the 1-indexed col take up when shown, with tabs shown as four spaces.
+func displayCol(line []rune, col int) int {
+	var n int
+	for i := 0; i < col-1; i++ {
+		if i < len(line) && line[i] == '\t' {
+			n += 4
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// cell is a single character of rendered output.
+type cell struct {
+	ch    rune
+	color string
+}
+
+// row is a line of rendered output.
+type row []cell
+
+// gutterRow returns a row with the gutter filled in with label right-aligned
+// in the given width.
+func gutterRow(width int, label string) row {
+	var rw row
+	rw.put(fmt.Sprintf("%*s |", width, label), ansiBlue)
+	return rw
+}
+
+// put adds s to the end of rw in the given color.
+func (rw *row) put(s string, color string) {
+	for _, ch := range s {
+		*rw = append(*rw, cell{ch: ch, color: color})
+	}
+}
+
+// putAt writes s into rw starting at column col in the given color, padding rw
+// with spaces if needed.
+func (rw *row) putAt(col int, s string, color string) {
+	for _, ch := range s {
+		for len(*rw) <= col {
+			*rw = append(*rw, cell{ch: ' '})
+		}
+		(*rw)[col] = cell{ch: ch, color: color}
+		col++
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
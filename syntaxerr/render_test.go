@@ -0,0 +1,168 @@
+package syntaxerr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Renderer_Render(t *testing.T) {
+	span := func(file string, startLine, startCol, endLine, endCol int) Span {
+		return Span{File: file, Start: Position{Line: startLine, Col: startCol}, End: Position{Line: endLine, Col: endCol}}
+	}
+
+	callSource := "fn main() {\n    foo(\n        1,\n        2,\n        3,\n        4,\n        5\n    );\n}\n"
+
+	testCases := []struct {
+		name     string
+		renderer Renderer
+		diag     Diagnostic
+		expect   []string
+	}{
+		{
+			name: "no location",
+			diag: Diagnostic{Severity: SeverityWarning, Message: "disconnected segments", Notes: []string{"check the bindings"}},
+			expect: []string{
+				"warning: disconnected segments",
+				"  = note: check the bindings",
+			},
+		},
+		{
+			name: "location without source line",
+			diag: Diagnostic{Code: CodeUnexpectedToken, Message: "unexpected end of input", Span: span("", 4, 1, 4, 1)},
+			expect: []string{
+				"error[ICT0002]: unexpected end of input",
+				" --> 4:1",
+			},
+		},
+		{
+			name: "label, note, and suggestion",
+			diag: Diagnostic{
+				Code:        CodeUnexpectedToken,
+				Message:     "duplicate name",
+				Span:        span("a.txt", 3, 5, 3, 8),
+				SourceLine:  "let foo = 2",
+				Labels:      []Label{{Span: span("a.txt", 1, 5, 1, 8), SourceLine: "let foo = 1", Message: "first declared here"}},
+				Notes:       []string{"names must be unique"},
+				Suggestions: []Suggestion{{Message: "rename it", Span: span("a.txt", 3, 5, 3, 8), Replacement: "bar"}},
+			},
+			expect: []string{
+				"error[ICT0002]: duplicate name",
+				" --> a.txt:3:5",
+				"  |",
+				"1 | let foo = 1",
+				"  |     --- first declared here",
+				"...",
+				"3 | let foo = 2",
+				"  |     ^^^",
+				"  |",
+				"  = note: names must be unique",
+				"  = help: rename it",
+				"  |",
+				"3 | let bar = 2",
+				"  |     ~~~",
+			},
+		},
+		{
+			name:     "multi-line span with context",
+			renderer: Renderer{Context: 1, Sources: map[string]string{"m.rs": callSource}},
+			diag: Diagnostic{
+				Severity:   SeverityWarning,
+				Message:    "call spans lines",
+				Span:       span("m.rs", 2, 5, 8, 6),
+				SourceLine: "    foo(",
+				Labels: []Label{
+					{Span: span("m.rs", 1, 4, 1, 8), SourceLine: "fn main() {", Message: "in this fn"},
+					{Span: span("b.rs", 10, 1, 10, 2), Message: "elsewhere"},
+				},
+			},
+			expect: []string{
+				"warning: call spans lines",
+				" --> m.rs:2:5",
+				"  |",
+				"1 |   fn main() {",
+				"  |      ---- in this fn",
+				"2 |       foo(",
+				"  |  _____^",
+				"3 | |         1,",
+				"...",
+				"7 | |         5",
+				"8 | |     );",
+				"  | |_____^",
+				"9 |   }",
+				"  |",
+				"  = note: b.rs:10:1: elsewhere",
+			},
+		},
+		{
+			name:     "sources that disagree are not used",
+			renderer: Renderer{Context: 2, Sources: map[string]string{"": "something\nelse\n"}},
+			diag:     Diagnostic{Message: "bad", Span: span("", 2, 2, 2, 3), SourceLine: "x\ty"},
+			expect: []string{
+				"error: bad",
+				" --> 2:2",
+				"  |",
+				"2 | x    y",
+				"  |  ^^^^",
+			},
+		},
+		{
+			name:     "labels in another file",
+			renderer: Renderer{},
+			diag: Diagnostic{
+				Message:    "conflict",
+				Span:       span("a.txt", 12, 1, 12, 2),
+				SourceLine: "b",
+				Labels:     []Label{{Span: span("b.txt", 2, 3, 2, 4), SourceLine: "a a", Message: "other one"}},
+			},
+			expect: []string{
+				"error: conflict",
+				"  --> a.txt:12:1",
+				"   |",
+				"12 | b",
+				"   | ^",
+				"  ::: b.txt:2:3",
+				"   |",
+				" 2 | a a",
+				"   |   - other one",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := tc.renderer.Render(tc.diag)
+
+			assert.Equal(strings.Join(tc.expect, "\n"), actual)
+		})
+	}
+}
+
+func Test_Renderer_Render_color(t *testing.T) {
+	assert := assert.New(t)
+
+	d := Diagnostic{Message: "bad", Span: Span{Start: Position{Line: 1, Col: 1}, End: Position{Line: 1, Col: 2}}, SourceLine: "x"}
+
+	plain := Renderer{}.Render(d)
+	colored := Renderer{Color: true}.Render(d)
+
+	assert.NotContains(plain, "\x1b[")
+	assert.Contains(colored, ansiRed+"error"+ansiReset)
+	assert.Contains(colored, ansiRed+"^"+ansiReset)
+}
+
+func Test_Diagnostics_WithFile(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := Diagnostics{{Message: "bad", Labels: []Label{{Message: "here"}}, Suggestions: []Suggestion{{Message: "fix"}}}}
+
+	actual := ds.WithFile("f.txt")
+
+	assert.Equal("f.txt", actual[0].Span.File)
+	assert.Equal("f.txt", actual[0].Labels[0].Span.File)
+	assert.Equal("f.txt", actual[0].Suggestions[0].Span.File)
+	assert.Equal("", ds[0].Labels[0].Span.File)
+}
@@ -22,11 +22,21 @@ type Error struct {
 	// position in line of error, 1-indexed.
 	pos     int
 	message string
+
+	// name of the file the error occured in, or empty if not known.
+	file string
 }
 
 // New creates a new SyntaxError with its properties set.
 func New(msg string, sourceLine string, source string, line int, pos int) *Error {
+	return NewInFile("", msg, sourceLine, source, line, pos)
+}
+
+// NewInFile is the same as New but also sets the name of the file that the
+// error occured in.
+func NewInFile(file string, msg string, sourceLine string, source string, line int, pos int) *Error {
 	return &Error{
+		file:       file,
 		sourceLine: sourceLine,
 		source:     source,
 		line:       line,
@@ -35,13 +45,28 @@ func New(msg string, sourceLine string, source string, line int, pos int) *Error
 	}
 }
 
-// Error returns the message of the error.
+// Error returns the message of the error. If the error has a file, it is
+// included in the message after the "syntax error: " prefix, as in
+// "syntax error: main.txt: around line 2, char 6: unexpected '+'". Code that
+// compares the text of errors for named input will see the file name there;
+// the message of an error with no file does not have it.
 func (se Error) Error() string {
+	var fileStr string
+	if se.file != "" {
+		fileStr = se.file + ": "
+	}
+
 	if se.line == 0 {
-		return fmt.Sprintf("syntax error: %s", se.message)
+		return fmt.Sprintf("syntax error: %s%s", fileStr, se.message)
 	}
 
-	return fmt.Sprintf("syntax error: around line %d, char %d: %s", se.line, se.pos, se.message)
+	return fmt.Sprintf("syntax error: %saround line %d, char %d: %s", fileStr, se.line, se.pos, se.message)
+}
+
+// File returns the name of the file that the error occured in. This will
+// return an empty string if the file is not known.
+func (se Error) File() string {
+	return se.file
 }
 
 // Source returns the exact text of the specific source code that caused the
@@ -77,14 +102,19 @@ func (se Error) FullMessage() string {
 }
 
 // MessageForFile returns the full error message in the format of
-// filename:line:pos: message, followed by the syntax error itself.
+// filename:line:pos: message, followed by the syntax error itself. If filename
+// is empty, the file that the error occured in is used.
 func (se Error) MessageForFile(filename string) string {
 	var msg string
 
+	if filename == "" {
+		filename = se.file
+	}
+
 	if se.line != 0 {
 		msg = fmt.Sprintf("%s:%d:%d: %s\n%s", filename, se.line, se.pos, se.message, se.SourceLineWithCursor())
 	} else {
-		msg = fmt.Sprintf("%s: %s", filename, msg)
+		msg = fmt.Sprintf("%s: %s", filename, se.message)
 	}
 
 	return msg
@@ -96,19 +126,25 @@ func (se Error) MessageForFile(filename string) string {
 // Returns a blank string if no source line was provided for the error (such as
 // for unexpected EOF errors).
 func (se Error) SourceLineWithCursor() string {
-	if se.sourceLine == "" {
+	return sourceLineWithCursor(se.sourceLine, se.pos)
+}
+
+// sourceLineWithCursor returns sourceLine and directly under it a cursor at the
+// 1-indexed character pos. Returns a blank string if sourceLine is blank.
+func sourceLineWithCursor(sourceLine string, pos int) string {
+	if sourceLine == "" {
 		return ""
 	}
 
 	cursorLine := ""
 	// pos will be 1-indexed.
-	for i := 0; i < se.pos-1 && i < len(se.sourceLine); i++ {
-		if se.sourceLine[i] == '\t' {
+	for i := 0; i < pos-1 && i < len(sourceLine); i++ {
+		if sourceLine[i] == '\t' {
 			cursorLine += "    "
 		} else {
 			cursorLine += " "
 		}
 	}
 
-	return strings.ReplaceAll(se.sourceLine, "\t", "    ") + "\n" + cursorLine + "^"
+	return strings.ReplaceAll(sourceLine, "\t", "    ") + "\n" + cursorLine + "^"
 }
@@ -36,3 +36,61 @@ func Test_New(t *testing.T) {
 	}
 
 }
+
+func Test_Error_file(t *testing.T) {
+	testCases := []struct {
+		name          string
+		file          string
+		line          int
+		forFile       string
+		expectError   string
+		expectForFile string
+	}{
+		{
+			name:          "no file",
+			line:          2,
+			forFile:       "given.txt",
+			expectError:   "syntax error: around line 2, char 6: bad thing",
+			expectForFile: "given.txt:2:6: bad thing\na := 27 + 3\n     ^",
+		},
+		{
+			name:          "file",
+			file:          "src.txt",
+			line:          2,
+			expectError:   "syntax error: src.txt: around line 2, char 6: bad thing",
+			expectForFile: "src.txt:2:6: bad thing\na := 27 + 3\n     ^",
+		},
+		{
+			name:          "given file takes precedence",
+			file:          "src.txt",
+			line:          2,
+			forFile:       "given.txt",
+			expectError:   "syntax error: src.txt: around line 2, char 6: bad thing",
+			expectForFile: "given.txt:2:6: bad thing\na := 27 + 3\n     ^",
+		},
+		{
+			name:          "no line",
+			forFile:       "given.txt",
+			expectError:   "syntax error: bad thing",
+			expectForFile: "given.txt: bad thing",
+		},
+		{
+			name:          "file with no line",
+			file:          "src.txt",
+			expectError:   "syntax error: src.txt: bad thing",
+			expectForFile: "src.txt: bad thing",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := NewInFile(tc.file, "bad thing", "a := 27 + 3", "27", tc.line, 6)
+
+			assert.Equal(tc.file, actual.File())
+			assert.Equal(tc.expectError, actual.Error())
+			assert.Equal(tc.expectForFile, actual.MessageForFile(tc.forFile))
+		})
+	}
+}
@@ -0,0 +1,27 @@
+#!/bin/bash
+
+script_path="$(cd "$(dirname "$0")" >/dev/null ; pwd -P)"
+
+echo "[PRE] Build diag binary:"
+./ictcc --lalr \
+	--ir 'int' \
+	-l SimpleMath -v 1.0.0 \
+	-d "$script_path/testdiag" \
+	--hooks "$script_path/.hooks" \
+	--dev \
+	-n \
+	"$script_path/simplemath.md" >/dev/null || { echo "FAIL" >&2 ; exit 1 ; }
+
+echo "(done)"
+
+echo "[1/3] Print tree of 2+3 as S-expression:"
+"$script_path"/testdiag -q -t --tree-format sexpr -C "2+3" || { echo "FAIL" >&2 ; exit 1 ; }
+echo "(done)"
+
+echo "[2/3] Print tree of 2 as JSON:"
+"$script_path"/testdiag -q -t --tree-format json -C "2" || { echo "FAIL" >&2 ; exit 1 ; }
+echo "(done)"
+
+echo "[3/3] Print tree of 2*3 as DOT:"
+"$script_path"/testdiag -q -t --tree-format dot -C "2*3" || { echo "FAIL" >&2 ; exit 1 ; }
+echo "(done)"
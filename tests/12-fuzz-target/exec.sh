@@ -0,0 +1,21 @@
+#!/bin/bash
+
+script_path="$(cd "$(dirname "$0")" >/dev/null ; pwd -P)"
+
+echo "[PRE] Generate frontend with fuzz target:"
+./ictcc --lalr \
+	--fuzz-target \
+	--ir 'int' \
+	-l SimpleMath -v 1.0.0 \
+	--dest "$script_path/testfe" \
+	--pkg testfe \
+	--sim-off \
+	"$script_path/simplemath.md" >/dev/null || { echo "FAIL" >&2 ; exit 1 ; }
+
+echo "(done)"
+
+echo "[1/1] Run fuzz target seed corpus:"
+(cd "$script_path/testfe" && go test -run FuzzParse . >/dev/null) || { echo "FAIL" >&2 ; rm -rf "$script_path/testfe" ; exit 1 ; }
+echo "(done)"
+
+rm -rf "$script_path/testfe"
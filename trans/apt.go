@@ -54,6 +54,10 @@ type AnnotatedTree struct {
 	// Source is only available when Terminal is true.
 	Source lex.Token
 
+	// Span is the range of source text that the node was parsed from. It is
+	// the same as the Span of the parse.Tree node it was annotated from.
+	Span lex.Span
+
 	// Children is all children of the parse tree.
 	Children []*AnnotatedTree
 
@@ -80,6 +84,7 @@ func Annotate(root parse.Tree) AnnotatedTree {
 		curAnnoNode.Terminal = curTreeNode.Terminal
 		curAnnoNode.Symbol = curTreeNode.Value
 		curAnnoNode.Source = curTreeNode.Source
+		curAnnoNode.Span = curTreeNode.Span
 		curAnnoNode.Children = make([]*AnnotatedTree, len(curTreeNode.Children))
 		curAnnoNode.Attributes = nodeAttrs{
 			string("$id"): idGen.Next(),
@@ -130,6 +135,7 @@ func ATLeaf(id uint64, term string, t ...lex.Token) *AnnotatedTree {
 	pt := &AnnotatedTree{Terminal: true, Symbol: term, Attributes: nodeAttrs{"$id": aptNodeID(id), "$text": ""}}
 	if len(t) > 0 {
 		pt.Source = t[0]
+		pt.Span = lex.SpanOf(t[0])
 		pt.Attributes["$text"] = t[0].Lexeme()
 		pt.Attributes["$ft"] = nil
 
@@ -142,6 +148,7 @@ func ATLeaf(id uint64, term string, t ...lex.Token) *AnnotatedTree {
 		pt.Attributes["$text"] = "dummy2"
 		pt.Attributes["$ft"] = tok
 		pt.Source = tok
+		pt.Span = lex.SpanOf(tok)
 	}
 	return pt
 }
@@ -156,6 +163,14 @@ func ATNode(id uint64, nt string, children ...*AnnotatedTree) *AnnotatedTree {
 		Children:   children,
 		Attributes: nodeAttrs{"$id": aptNodeID(id)},
 	}
+	if len(children) > 0 {
+		spans := make([]lex.Span, len(children))
+		for i := range children {
+			spans[i] = children[i].Span
+		}
+		pt.Span = lex.Covering(spans...)
+	}
+
 	// and also calculate $ft
 	pt.First()
 
@@ -3,6 +3,9 @@ package trans
 import (
 	"testing"
 
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -153,3 +156,29 @@ func Test_APT_RelativeNode(t *testing.T) {
 		})
 	}
 }
+
+func Test_Annotate_Span(t *testing.T) {
+	assert := assert.New(t)
+
+	intClass := lex.NewTokenClass("int", "integer")
+	first := lex.NewToken(intClass, "12", 1, 1, "12")
+	second := lex.NewToken(intClass, "256", 1, 2, "256")
+
+	pt := parse.Tree{
+		Value: "A",
+		Span:  lex.Covering(lex.SpanOf(first), lex.SpanOf(second)),
+		Children: []*parse.Tree{
+			parse.Leaf("int", first),
+			parse.Leaf("int", second),
+		},
+	}
+
+	actual := Annotate(pt)
+
+	assert.Equal(lex.Span{Start: lex.Position{Line: 1, Col: 1}, End: lex.Position{Line: 2, Col: 4}}, actual.Span)
+	if !assert.Len(actual.Children, 2) {
+		return
+	}
+	assert.Equal(lex.SpanOf(first), actual.Children[0].Span)
+	assert.Equal(lex.SpanOf(second), actual.Children[1].Span)
+}
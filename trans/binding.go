@@ -8,6 +8,37 @@ import (
 	"github.com/dekarrin/ictiobus/parse"
 )
 
+// Binding is a hook bound to set an attribute on nodes of the parse tree made
+// by a rule of the grammar.
+type Binding struct {
+	// Head is the head symbol of the rule the binding is on.
+	Head string
+
+	// Production is the produced symbols of the rule the binding is on.
+	Production []string
+
+	// Dest is the attribute that the binding sets. It is on the head of the
+	// rule for a synthesized attribute and on one of the produced symbols for
+	// an inherited attribute.
+	Dest AttrRef
+
+	// Hook is the name of the hook that is called to get the value.
+	Hook string
+
+	// Args is the attributes whose values are passed to the hook, in order.
+	Args []AttrRef
+}
+
+// String returns the string representation of the Binding.
+func (b Binding) String() string {
+	prodStr := strings.Join(b.Production, " ")
+	if prodStr == "" {
+		prodStr = "ε"
+	}
+	args := strings.Join(slices.Map(b.Args, AttrRef.String), ", ")
+	return fmt.Sprintf("%s -> %s: %s = %s(%s)", b.Head, prodStr, b.Dest, b.Hook, args)
+}
+
 // sddBinding represents a single binding of a syntax-directed definition to a
 // rule in the grammar. It will be executed for all nodes created for that rule.
 type sddBinding struct {
@@ -107,6 +138,9 @@ func (bind sddBinding) Invoke(apt *AnnotatedTree, hooksTable HookMap, listener f
 		return nil, hookError{msg: "binding has no setter hook defined"}
 	}
 	hookFn := hooksTable[bind.Setter]
+	if hookFn == nil {
+		hookFn = builtinHooks[bind.Setter]
+	}
 	if hookFn == nil {
 		return nil, hookError{name: bind.Setter, missingHook: true, msg: fmt.Sprintf("no implementation for hook function '%s' was provided", bind.Setter)}
 	}
@@ -119,9 +153,8 @@ func (bind sddBinding) Invoke(apt *AnnotatedTree, hooksTable HookMap, listener f
 
 	// gather info on the attribute being set
 	info := SetterInfo{
-		Name:       bind.Dest.Name,
-		Synthetic:  bind.Synthesized,
-		FirstToken: apt.First(),
+		Name:      bind.Dest.Name,
+		Synthetic: bind.Synthesized,
 	}
 
 	// symbol of who it is for
@@ -131,6 +164,10 @@ func (bind sddBinding) Invoke(apt *AnnotatedTree, hooksTable HookMap, listener f
 		// invalid dest
 		panic(fmt.Sprintf("bound-to rule does not contain a %s", bind.Dest.Rel.String()))
 	}
+	if destNode, ok := apt.RelativeNode(bind.Dest.Rel); ok {
+		info.FirstToken = destNode.First()
+		info.Span = destNode.Span
+	}
 
 	// gather args
 	args := []interface{}{}
@@ -146,7 +183,7 @@ func (bind sddBinding) Invoke(apt *AnnotatedTree, hooksTable HookMap, listener f
 			} else {
 				errFmt := "attribute %s not defined for %s in bound-to-rule"
 				errMsg := fmt.Sprintf(errFmt, req.Name, req.Rel.String())
-				return nil, hookError{name: bind.Setter, msg: errMsg}
+				return nil, hookError{name: bind.Setter, missingAttr: true, msg: errMsg}
 			}
 		}
 
@@ -219,7 +256,7 @@ func (bind sddBinding) Invoke(apt *AnnotatedTree, hooksTable HookMap, listener f
 	// after event emitted, now check the return value and error if the hook
 	// returned an error.
 	if err != nil {
-		return nil, hookError{name: bind.Setter, msg: err.Error()}
+		return nil, hookError{name: bind.Setter, msg: err.Error(), err: err}
 	}
 
 	return val, nil
@@ -230,14 +267,18 @@ func (bind sddBinding) Invoke(apt *AnnotatedTree, hooksTable HookMap, listener f
 // hook function. hook func could be missing, not set, or could have returned an
 // error. if name is empty, then the error is that the hook was set to an empty
 // string or never set. If missingHook is set, the the name was set but the
-// hook was not found in the hooks table. Otherwise the error is in msg.
+// hook was not found in the hooks table. If missingAttr is set, an attribute
+// the hook needs as an argument was never set. Otherwise the error is in msg,
+// and err is the error the hook returned, if it returned one.
 type hookError struct {
 	// the name of the hook function.
 	name string
 
 	missingHook bool
+	missingAttr bool
 
 	msg string
+	err error
 }
 
 // Error returns a message describing the error.
@@ -0,0 +1,100 @@
+package trans
+
+import (
+	"fmt"
+)
+
+// Names of the built-in hooks. Built-in hooks are available to every SDTS
+// without being given to SetHooks, although giving a hook with the same name
+// to SetHooks will use that one instead. Their names start with '$', which
+// cannot be used in a hook name in FISHI, so they never conflict with the hooks
+// of a language. They are used in the bindings that are made for the
+// non-terminals generated for EBNF operators and groups in FISHI grammars and
+// in the bindings that are rewritten when a grammar is converted for LL(1)
+// parsing.
+const (
+	// HookNil returns nil. It takes no arguments.
+	HookNil = "$nil"
+
+	// HookIdent returns its only argument as-is.
+	HookIdent = "$ident"
+
+	// HookTuple returns all of its arguments in order as an []interface{}.
+	HookTuple = "$tuple"
+
+	// HookListEmpty returns an empty, non-nil []interface{}. It takes no
+	// arguments.
+	HookListEmpty = "$list_empty"
+
+	// HookListPrepend returns a new []interface{} with its first argument
+	// followed by the items of its second argument, which must be an
+	// []interface{}.
+	HookListPrepend = "$list_prepend"
+
+	// HookListAppend returns its first argument, which must be an
+	// []interface{}, with its second argument added to the end. The first
+	// argument's backing array may be reused, so building a list one item at a
+	// time with it takes amortized constant time per item.
+	HookListAppend = "$list_append"
+
+	// HookListReverse returns a new []interface{} with the items of its only
+	// argument, which must be an []interface{}, in reverse order.
+	HookListReverse = "$list_reverse"
+)
+
+var builtinHooks = HookMap{
+	HookNil: func(_ SetterInfo, _ []interface{}) (interface{}, error) {
+		return nil, nil
+	},
+	HookIdent: func(_ SetterInfo, args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("takes 1 argument but was given %d", len(args))
+		}
+		return args[0], nil
+	},
+	HookTuple: func(_ SetterInfo, args []interface{}) (interface{}, error) {
+		tuple := make([]interface{}, len(args))
+		copy(tuple, args)
+		return tuple, nil
+	},
+	HookListEmpty: func(_ SetterInfo, _ []interface{}) (interface{}, error) {
+		return []interface{}{}, nil
+	},
+	HookListPrepend: func(_ SetterInfo, args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("takes 2 arguments but was given %d", len(args))
+		}
+		rest, ok := args[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("argument 2 is a %T, not an []interface{}", args[1])
+		}
+		list := make([]interface{}, 0, len(rest)+1)
+		list = append(list, args[0])
+		list = append(list, rest...)
+		return list, nil
+	},
+	HookListAppend: func(_ SetterInfo, args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("takes 2 arguments but was given %d", len(args))
+		}
+		list, ok := args[0].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("argument 1 is a %T, not an []interface{}", args[0])
+		}
+		return append(list, args[1]), nil
+	},
+	HookListReverse: func(_ SetterInfo, args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("takes 1 argument but was given %d", len(args))
+		}
+		list, ok := args[0].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("argument 1 is a %T, not an []interface{}", args[0])
+		}
+		reversed := make([]interface{}, len(list))
+		for i := range list {
+			reversed[len(list)-1-i] = list[i]
+		}
+		return reversed, nil
+	},
+}
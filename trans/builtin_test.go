@@ -0,0 +1,117 @@
+package trans
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_builtinHooks(t *testing.T) {
+	testCases := []struct {
+		name      string
+		hook      string
+		args      []interface{}
+		expect    interface{}
+		expectErr bool
+	}{
+		{
+			name: "nil",
+			hook: HookNil,
+		},
+		{
+			name:   "ident",
+			hook:   HookIdent,
+			args:   []interface{}{"a"},
+			expect: "a",
+		},
+		{
+			name:      "ident with too many args",
+			hook:      HookIdent,
+			args:      []interface{}{"a", "b"},
+			expectErr: true,
+		},
+		{
+			name:   "tuple",
+			hook:   HookTuple,
+			args:   []interface{}{"a", 1, nil},
+			expect: []interface{}{"a", 1, nil},
+		},
+		{
+			name:   "empty list",
+			hook:   HookListEmpty,
+			expect: []interface{}{},
+		},
+		{
+			name:   "prepend to empty list",
+			hook:   HookListPrepend,
+			args:   []interface{}{"a", []interface{}{}},
+			expect: []interface{}{"a"},
+		},
+		{
+			name:   "prepend to list",
+			hook:   HookListPrepend,
+			args:   []interface{}{"a", []interface{}{"b", "c"}},
+			expect: []interface{}{"a", "b", "c"},
+		},
+		{
+			name:      "prepend to non-list",
+			hook:      HookListPrepend,
+			args:      []interface{}{"a", "b"},
+			expectErr: true,
+		},
+		{
+			name:   "append to empty list",
+			hook:   HookListAppend,
+			args:   []interface{}{[]interface{}{}, "a"},
+			expect: []interface{}{"a"},
+		},
+		{
+			name:   "append to list",
+			hook:   HookListAppend,
+			args:   []interface{}{[]interface{}{"a", "b"}, "c"},
+			expect: []interface{}{"a", "b", "c"},
+		},
+		{
+			name:      "append to non-list",
+			hook:      HookListAppend,
+			args:      []interface{}{"a", "b"},
+			expectErr: true,
+		},
+		{
+			name:   "reverse empty list",
+			hook:   HookListReverse,
+			args:   []interface{}{[]interface{}{}},
+			expect: []interface{}{},
+		},
+		{
+			name:   "reverse list",
+			hook:   HookListReverse,
+			args:   []interface{}{[]interface{}{"a", "b", "c"}},
+			expect: []interface{}{"c", "b", "a"},
+		},
+		{
+			name:      "reverse non-list",
+			hook:      HookListReverse,
+			args:      []interface{}{"a"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := builtinHooks[tc.hook](SetterInfo{}, tc.args)
+
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package trans
+
+import (
+	"errors"
+
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// File diag.go contains functions for turning errors that occur during
+// evaluation into syntaxerr.Diagnostics.
+
+// evalDiagnostic returns a Diagnostic with the given code and severity for ee,
+// located at node. If node is nil, the Diagnostic is not for any location.
+func evalDiagnostic(ee evalError, code string, sev syntaxerr.Severity, node *AnnotatedTree) syntaxerr.Diagnostic {
+	d := syntaxerr.Diagnostic{
+		Code:     code,
+		Severity: sev,
+		Message:  ee.msg,
+		Cause:    ee,
+	}
+	if node != nil {
+		d.Span, d.SourceLine = nodeLocation(node)
+	}
+	return d
+}
+
+// evalErrorAt returns a syntaxerr.Diagnostics with a single error for ee,
+// located at node. If node is nil, the error is not for any location.
+func evalErrorAt(ee evalError, code string, node *AnnotatedTree) error {
+	return syntaxerr.Diagnostics{evalDiagnostic(ee, code, syntaxerr.SeverityError, node)}
+}
+
+// nodeLocation returns the span of source text that node was parsed from, and
+// the full text of the line that it starts on. The file and the line are taken
+// from the first token in node; if it has none, the line is empty.
+func nodeLocation(node *AnnotatedTree) (span syntaxerr.Span, sourceLine string) {
+	first := firstToken(node)
+	if first == nil {
+		return node.Span.InFile(""), ""
+	}
+	return node.Span.InFile(lex.FileOf(first)), first.FullLine()
+}
+
+// firstToken returns the first token that node was parsed from, or nil if
+// there is none.
+func firstToken(node *AnnotatedTree) lex.Token {
+	if node.Source != nil {
+		return node.Source
+	}
+	for _, child := range node.Children {
+		if tok := firstToken(child); tok != nil {
+			return tok
+		}
+	}
+	return nil
+}
+
+// hookLocation returns the location of the problem that err describes, if err
+// is or wraps a *syntaxerr.Error that has one, such as one created by a hook
+// with lex.NewSyntaxErrorFromToken.
+func hookLocation(err error) (span syntaxerr.Span, sourceLine string, ok bool) {
+	var synErr *syntaxerr.Error
+	if err == nil || !errors.As(err, &synErr) || synErr.Line() == 0 {
+		return syntaxerr.Span{}, "", false
+	}
+	d := synErr.Diagnostic()
+	return d.Span, d.SourceLine, true
+}
@@ -0,0 +1,107 @@
+package trans
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SDTS_Evaluate_diagnostics(t *testing.T) {
+	const line = "1 + x"
+
+	leaf := func(term, lexeme string, pos int) *parse.Tree {
+		tok := lex.WithFile(lex.NewToken(lex.NewTokenClass(term, term), lexeme, pos, 1, line), "src.txt")
+		return &parse.Tree{Terminal: true, Value: term, Source: tok, Span: lex.SpanOf(tok)}
+	}
+	node := func(children ...*parse.Tree) *parse.Tree {
+		pt := parse.Node("S", children...)
+		spans := make([]lex.Span, len(children))
+		for i := range children {
+			spans[i] = children[i].Span
+		}
+		pt.Span = lex.Covering(spans...)
+		return pt
+	}
+
+	// tree for "1 + x"
+	tree := node(node(leaf("int", "1", 1)), leaf("plus", "+", 3), leaf("int", "x", 5))
+
+	testCases := []struct {
+		name        string
+		hooks       HookMap
+		expectCode  string
+		expectSpan  syntaxerr.Span
+		expectLabel *syntaxerr.Span
+	}{
+		{
+			name: "hook returns error",
+			hooks: HookMap{
+				"int": func(info SetterInfo, args []interface{}) (interface{}, error) { return 1, nil },
+				"add": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					return nil, fmt.Errorf("bad add")
+				},
+			},
+			expectCode: syntaxerr.CodeHookFailed,
+			expectSpan: syntaxerr.Span{File: "src.txt", Start: syntaxerr.Position{Line: 1, Col: 1}, End: syntaxerr.Position{Line: 1, Col: 6}},
+		},
+		{
+			name: "hook returns located error",
+			hooks: HookMap{
+				"int": func(info SetterInfo, args []interface{}) (interface{}, error) { return 1, nil },
+				"add": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					return nil, lex.NewSyntaxErrorFromToken("not a number", args[1].(lex.Token))
+				},
+			},
+			expectCode:  syntaxerr.CodeHookFailed,
+			expectSpan:  syntaxerr.Span{File: "src.txt", Start: syntaxerr.Position{Line: 1, Col: 5}, End: syntaxerr.Position{Line: 1, Col: 6}},
+			expectLabel: &syntaxerr.Span{File: "src.txt", Start: syntaxerr.Position{Line: 1, Col: 1}, End: syntaxerr.Position{Line: 1, Col: 6}},
+		},
+		{
+			name: "hook missing",
+			hooks: HookMap{
+				"int": func(info SetterInfo, args []interface{}) (interface{}, error) { return 1, nil },
+			},
+			expectCode: syntaxerr.CodeHookMissing,
+			expectSpan: syntaxerr.Span{File: "src.txt", Start: syntaxerr.Position{Line: 1, Col: 1}, End: syntaxerr.Position{Line: 1, Col: 6}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sdts := NewSDTS()
+			sdts.SetHooks(tc.hooks)
+			sdts.Bind("S", []string{"S", "plus", "int"}, "val", "add", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}, {Rel: NRTerminal(1), Name: "$ft"}})
+			sdts.Bind("S", []string{"int"}, "val", "int", nil)
+
+			// execute
+			_, _, err := sdts.Evaluate(*tree, "val")
+
+			// assert
+			var diags syntaxerr.Diagnostics
+			if !assert.True(errors.As(err, &diags), "expected syntaxerr.Diagnostics, got %T", err) {
+				return
+			}
+			if !assert.Len(diags, 1) {
+				return
+			}
+			d := diags[0]
+			assert.Equal(tc.expectCode, d.Code)
+			assert.Equal(tc.expectSpan, d.Span)
+			assert.Equal(line, d.SourceLine)
+			if tc.expectLabel != nil {
+				if assert.Len(d.Labels, 1) {
+					assert.Equal(*tc.expectLabel, d.Labels[0].Span)
+				}
+			} else {
+				assert.Empty(d.Labels)
+			}
+		})
+	}
+}
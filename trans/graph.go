@@ -41,35 +41,22 @@ func (dg *directedGraph[V]) LinkFrom(other *directedGraph[V]) {
 // Copy creates a duplicate of this graph. Note that data is copied by value and
 // is *not* deeply copied.
 func (dg *directedGraph[V]) Copy() *directedGraph[V] {
-	return dg.recursiveCopy(map[*directedGraph[V]]bool{})
-}
-
-func (dg *directedGraph[V]) recursiveCopy(visited map[*directedGraph[V]]bool) *directedGraph[V] {
-	visited[dg] = true
-	dgCopy := &directedGraph[V]{Data: dg.Data}
-
-	// check out edges
-	for i := range dg.Edges {
-		to := dg.Edges[i]
-		if _, alreadyVisited := visited[to]; alreadyVisited {
-			continue
-		}
+	nodes := dg.AllNodes()
 
-		toCopy := to.recursiveCopy(visited)
-		dgCopy.LinkTo(toCopy)
+	copies := make(map[*directedGraph[V]]*directedGraph[V], len(nodes))
+	for _, n := range nodes {
+		copies[n] = &directedGraph[V]{Data: n.Data}
 	}
 
-	// check back edges
-	for i := range dg.InEdges {
-		from := dg.InEdges[i]
-		if _, alreadyVisited := visited[from]; alreadyVisited {
-			continue
+	// linking every out edge also links every in edge, so this gets all of
+	// them, including any duplicates.
+	for _, n := range nodes {
+		for _, to := range n.Edges {
+			copies[n].LinkTo(copies[to])
 		}
-		fromCopy := from.recursiveCopy(visited)
-		dgCopy.LinkFrom(fromCopy)
 	}
 
-	return dgCopy
+	return copies[dg]
 }
 
 // Contains returns whether the graph that the given node is in contains at any
@@ -185,8 +172,8 @@ func kahnSort[V any](dg *directedGraph[V], secondarySort func(l, r V) bool) ([]*
 
 		sortedL = append(sortedL, n)
 
-		for i := range n.Edges {
-			m := n.Edges[i]
+		for len(n.Edges) > 0 {
+			m := n.Edges[0]
 			// remove all edges from n to m (instead of just 'the one' bc we
 			// have no way of associating a *particular* edge with the in edge
 			// on m side and there COULD be dupes)
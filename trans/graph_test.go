@@ -333,3 +333,104 @@ func mockSBind(sym string, prod []string, dest AttrRef, reqs ...AttrRef) sddBind
 
 	return b
 }
+
+// diamondGraph returns the root of a graph of 4 nodes where a leads to b and
+// c, and both of those lead to d.
+func diamondGraph() *directedGraph[string] {
+	a := &directedGraph[string]{Data: "a"}
+	b := &directedGraph[string]{Data: "b"}
+	c := &directedGraph[string]{Data: "c"}
+	d := &directedGraph[string]{Data: "d"}
+	a.LinkTo(b)
+	a.LinkTo(c)
+	b.LinkTo(d)
+	c.LinkTo(d)
+	return a
+}
+
+func Test_directedGraph_Copy(t *testing.T) {
+	assert := assert.New(t)
+
+	orig := diamondGraph()
+
+	actual := orig.Copy()
+
+	nodes := actual.AllNodes()
+	if !assert.Len(nodes, 4) {
+		return
+	}
+	edges := map[string][]string{}
+	for _, n := range nodes {
+		assert.False(orig.Contains(n), "copy shares node %q with original", n.Data)
+		for _, to := range n.Edges {
+			edges[n.Data] = append(edges[n.Data], to.Data)
+		}
+		assert.Len(n.InEdges, map[string]int{"a": 0, "b": 1, "c": 1, "d": 2}[n.Data], "in edges of %q", n.Data)
+	}
+	assert.Equal(map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}}, edges)
+}
+
+func Test_kahnSort(t *testing.T) {
+	testCases := []struct {
+		name      string
+		graph     func() *directedGraph[string]
+		expect    []string
+		expectErr bool
+	}{
+		{
+			name: "single node",
+			graph: func() *directedGraph[string] {
+				return &directedGraph[string]{Data: "a"}
+			},
+			expect: []string{"a"},
+		},
+		{
+			name:   "node with more than one out edge",
+			graph:  diamondGraph,
+			expect: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "duplicate edges",
+			graph: func() *directedGraph[string] {
+				a := &directedGraph[string]{Data: "a"}
+				b := &directedGraph[string]{Data: "b"}
+				a.LinkTo(b)
+				a.LinkTo(b)
+				return a
+			},
+			expect: []string{"a", "b"},
+		},
+		{
+			name: "cycle",
+			graph: func() *directedGraph[string] {
+				a := &directedGraph[string]{Data: "a"}
+				b := &directedGraph[string]{Data: "b"}
+				a.LinkTo(b)
+				b.LinkTo(a)
+				return a
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sorted, err := kahnSort(tc.graph(), func(l, r string) bool { return l < r })
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			actual := make([]string, len(sorted))
+			for i := range sorted {
+				actual[i] = sorted[i].Data
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
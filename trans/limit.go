@@ -0,0 +1,24 @@
+package trans
+
+import (
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// Limits are limits on the evaluation of an SDTS. A limit of 0 means there is
+// no limit.
+type Limits struct {
+	// MaxSteps is the maximum number of hooks that will be called, counting
+	// each call of a hook for a binding on a node as one step.
+	MaxSteps int
+}
+
+// stepsExceeded returns whether steps is more than the MaxSteps of lim.
+func (lim Limits) stepsExceeded(steps int) bool {
+	return lim.MaxSteps > 0 && steps > lim.MaxSteps
+}
+
+// stepsError returns the error for evaluation taking more than the MaxSteps of
+// lim.
+func (lim Limits) stepsError() error {
+	return &syntaxerr.LimitError{Limit: syntaxerr.LimitEvalSteps, Max: lim.MaxSteps}
+}
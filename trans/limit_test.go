@@ -0,0 +1,225 @@
+package trans
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+	"github.com/stretchr/testify/assert"
+)
+
+// plainSDTS is an SDTS that has only the methods of the SDTS interface, so it
+// is not a ContextSDTS or any of the other optional interfaces.
+type plainSDTS struct {
+	SDTS
+}
+
+func Test_EvaluateContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	leaf := func(term string) *parse.Tree {
+		return parse.Leaf(term, lex.NewToken(lex.NewTokenClass(term, term), term, 1, 1, ""))
+	}
+
+	// three bindings are invoked to evaluate the tree for "int plus int plus
+	// int".
+	tree := parse.Node("S",
+		parse.Node("S",
+			parse.Node("S", leaf("int")),
+			leaf("plus"),
+			leaf("int"),
+		),
+		leaf("plus"),
+		leaf("int"),
+	)
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		lim       Limits
+		plain     bool
+		expect    []interface{}
+		expectErr error
+	}{
+		{
+			name:   "no limits",
+			ctx:    context.Background(),
+			expect: []interface{}{3},
+		},
+		{
+			name:   "steps equal to limit",
+			ctx:    context.Background(),
+			lim:    Limits{MaxSteps: 3},
+			expect: []interface{}{3},
+		},
+		{
+			name:      "steps more than limit",
+			ctx:       context.Background(),
+			lim:       Limits{MaxSteps: 2},
+			expectErr: syntaxerr.ErrTooManyEvalSteps,
+		},
+		{
+			name:      "canceled",
+			ctx:       canceled,
+			expectErr: context.Canceled,
+		},
+		{
+			name:   "not a ContextSDTS",
+			ctx:    context.Background(),
+			plain:  true,
+			expect: []interface{}{3},
+		},
+		{
+			name:   "not a ContextSDTS does not apply limits",
+			ctx:    context.Background(),
+			lim:    Limits{MaxSteps: 2},
+			plain:  true,
+			expect: []interface{}{3},
+		},
+		{
+			name:      "not a ContextSDTS canceled",
+			ctx:       canceled,
+			plain:     true,
+			expectErr: context.Canceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sdts := NewSDTS()
+			sdts.SetHooks(HookMap{
+				"count": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					n := 1
+					for _, a := range args {
+						n += a.(int)
+					}
+					return n, nil
+				},
+			})
+			sdts.Bind("S", []string{"S", "plus", "int"}, "val", "count", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}})
+			sdts.Bind("S", []string{"int"}, "val", "count", nil)
+
+			if tc.plain {
+				sdts = plainSDTS{sdts}
+			}
+
+			// execute
+			actual, _, err := EvaluateContext(tc.ctx, sdts, *tree, tc.lim, "val")
+
+			// assert
+			if tc.expectErr != nil {
+				assert.True(errors.Is(err, tc.expectErr), "expected %v, got %v", tc.expectErr, err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_EvaluateStreamContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	g := grammar.MustParse(`S -> S plus int | int ;`)
+	p, _, err := parse.GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	lx := lex.NewLexer(true)
+	for id, pat := range map[string]string{"plus": `\+`, "int": `[0-9]+`} {
+		lx.RegisterClass(lex.NewTokenClass(id, id), "")
+		lx.AddPattern(pat, lex.LexAs(id), "", 0)
+	}
+
+	// three bindings are invoked to evaluate "1+2+3", and its parse tree is
+	// four nodes deep.
+	const input = "1+2+3"
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		parseLim  parse.Limits
+		lim       Limits
+		expect    []interface{}
+		expectErr error
+	}{
+		{
+			name:   "no limits",
+			ctx:    context.Background(),
+			expect: []interface{}{3},
+		},
+		{
+			name:     "at limits",
+			ctx:      context.Background(),
+			parseLim: parse.Limits{MaxTreeDepth: 4},
+			lim:      Limits{MaxSteps: 3},
+			expect:   []interface{}{3},
+		},
+		{
+			name:      "steps more than limit",
+			ctx:       context.Background(),
+			lim:       Limits{MaxSteps: 2},
+			expectErr: syntaxerr.ErrTooManyEvalSteps,
+		},
+		{
+			name:      "tree deeper than limit",
+			ctx:       context.Background(),
+			parseLim:  parse.Limits{MaxTreeDepth: 3},
+			expectErr: syntaxerr.ErrTreeTooDeep,
+		},
+		{
+			name:      "canceled",
+			ctx:       canceled,
+			expectErr: context.Canceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sdts := NewSDTS()
+			sdts.SetHooks(HookMap{
+				"count": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					n := 1
+					for _, a := range args {
+						n += a.(int)
+					}
+					return n, nil
+				},
+			})
+			sdts.Bind("S", []string{"S", "plus", "int"}, "val", "count", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}})
+			sdts.Bind("S", []string{"int"}, "val", "count", nil)
+
+			stream, err := lx.Lex(strings.NewReader(input))
+			if !assert.NoError(err) {
+				return
+			}
+
+			// execute
+			actual, err := EvaluateStreamContext(tc.ctx, sdts, p, stream, tc.parseLim, tc.lim, "val")
+
+			// assert
+			if tc.expectErr != nil {
+				assert.True(errors.Is(err, tc.expectErr), "expected %v, got %v", tc.expectErr, err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
@@ -1,18 +1,26 @@
 package trans
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/dekarrin/ictiobus/internal/box"
 	"github.com/dekarrin/ictiobus/internal/slices"
 	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/syntaxerr"
 )
 
 // sdts.go contains the implementation of a Syntax-Directed Translation Scheme.
 
 type sdtsImpl struct {
+	// mu guards all other fields. An evaluation holds it only long enough to
+	// take a snapshot of them, so hooks and listeners may change the SDTS
+	// while it runs; such changes apply to later evaluations.
+	mu sync.RWMutex
+
 	hooks    HookMap
 	bindings map[string]map[string][]sddBinding
 	listener func(Event)
@@ -24,6 +32,9 @@ func (sdts *sdtsImpl) String() string {
 		return "<nil>"
 	}
 
+	sdts.mu.RLock()
+	defer sdts.mu.RUnlock()
+
 	var sb strings.Builder
 	sb.WriteString("sdtsImpl<")
 	if sdts.bindings == nil {
@@ -76,13 +87,18 @@ func (sdts *sdtsImpl) String() string {
 // occurs during translation. This can be used for debugging. If nil is passed
 // as the listener, it will disable sending events to it.
 func (sdts *sdtsImpl) RegisterListener(listener func(Event)) {
-	sdts.listener = listener
+	sdts.mu.Lock()
+	defer sdts.mu.Unlock()
 
+	sdts.listener = listener
 }
 
 // SetHooks sets the hook mapping containing implementations of the hooks in the
 // SDTS. It must be called before calling Evaluate.
 func (sdts *sdtsImpl) SetHooks(hooks HookMap) {
+	sdts.mu.Lock()
+	defer sdts.mu.Unlock()
+
 	// only create a new map if we don't already have one
 	if sdts.hooks == nil {
 		sdts.hooks = HookMap{}
@@ -113,6 +129,22 @@ func (sdts *sdtsImpl) SetHooks(hooks HookMap) {
 // This function requires that SetHooks be called with a valid set of
 // implementations for all hooks that will be called.
 func (sdts *sdtsImpl) Evaluate(tree parse.Tree, attributes ...string) (vals []interface{}, warns []error, err error) {
+	return sdts.EvaluateContext(context.Background(), tree, Limits{}, attributes...)
+}
+
+// EvaluateContext is the same as Evaluate but stops when ctx is done or when
+// more bindings would be invoked than lim allows.
+func (sdts *sdtsImpl) EvaluateContext(ctx context.Context, tree parse.Tree, lim Limits, attributes ...string) (vals []interface{}, warns []error, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return sdts.snapshot().evaluate(ctx, tree, lim, attributes...)
+}
+
+// evaluate does the work of EvaluateContext. It does not lock mu, so it must
+// only be called on a snapshot.
+func (sdts *sdtsImpl) evaluate(ctx context.Context, tree parse.Tree, lim Limits, attributes ...string) (vals []interface{}, warns []error, err error) {
 	// don't check for no hooks being set because it's possible we are going to
 	// be handed an empty parse tree, which will fail for other reasons first
 	// or perhaps will not fail at all.
@@ -142,9 +174,15 @@ func (sdts *sdtsImpl) Evaluate(tree parse.Tree, attributes ...string) (vals []in
 						break
 					}
 
-					// TODO GHI #101: things are wonky for inherited, check those separately,
-					// might need to not assume that Parent is the parent of the
-					// node for the rule the actual binding was set on. Synthesized should be fine though.
+					// an inherited attribute gives context to a node, and it
+					// is fine for the node to not need it.
+					if !node.Data.Synthetic {
+						continue
+					}
+
+					// a synthesized attribute is set by a binding on the rule
+					// that created the node, so its parent is the one that
+					// should be using it.
 					nodeParentSymbol := node.Data.Parent.Symbol
 
 					// check for parent in NoFlows
@@ -239,18 +277,18 @@ func (sdts *sdtsImpl) Evaluate(tree parse.Tree, attributes ...string) (vals []in
 		})
 
 		if singleAttrRoot != nil {
-			warns = append(warns, evalError{
+			warns = append(warns, evalDiagnostic(evalError{
 				msg:              "applying SDTS to tree results in evaluation dependency graph with undeclared disconnected segments",
 				depGraphs:        depGraphs,
 				unexpectedBreaks: unexpectedBreaks,
-			})
+			}, syntaxerr.CodeEvalOrder, syntaxerr.SeverityWarning, nil))
 			depGraphs = []*directedGraph[depNode]{singleAttrRoot}
 		} else {
-			return nil, warns, evalError{
+			return nil, warns, evalErrorAt(evalError{
 				msg:              "applying SDTS to tree results in evaluation dependency graph with multiple disconnected root segments",
 				depGraphs:        depGraphs,
 				unexpectedBreaks: unexpectedBreaks,
-			}
+			}, syntaxerr.CodeEvalOrder, nil)
 		}
 	}
 
@@ -261,10 +299,10 @@ func (sdts *sdtsImpl) Evaluate(tree parse.Tree, attributes ...string) (vals []in
 		return l.Tree.ID() < r.Tree.ID()
 	})
 	if err != nil {
-		return nil, warns, evalError{
+		return nil, warns, evalErrorAt(evalError{
 			msg:       fmt.Sprintf("sorting SDTS dependency graph: %s", err.Error()),
 			sortError: true,
-		}
+		}, syntaxerr.CodeEvalOrder, nil)
 	}
 
 	// we now have an annotated tree. tell listeners
@@ -274,6 +312,7 @@ func (sdts *sdtsImpl) Evaluate(tree parse.Tree, attributes ...string) (vals []in
 		Tree:      &root,
 	})
 
+	var steps int
 	for i := range visitOrder {
 		depNode := visitOrder[i].Data
 
@@ -281,58 +320,38 @@ func (sdts *sdtsImpl) Evaluate(tree parse.Tree, attributes ...string) (vals []in
 		synthetic := depNode.Synthetic
 		treeParent := depNode.Parent
 
+		// an inherited attribute is set by a binding on the rule that created
+		// the parent of the node, not the node itself.
 		var invokeOn *AnnotatedTree
 		if synthetic {
 			invokeOn = nodeTree
 		} else {
 			invokeOn = treeParent
 		}
+		if invokeOn == nil {
+			// an attribute of the root that is used but never set; there is
+			// no binding that could set it.
+			continue
+		}
 
-		nodeRuleHead, nodeRuleProd := nodeTree.Rule()
+		nodeRuleHead, nodeRuleProd := invokeOn.Rule()
 
 		bindingsToExec := sdts.bindingsForAttr(nodeRuleHead, nodeRuleProd, depNode.Dest)
 		for j := range bindingsToExec {
 			binding := bindingsToExec[j]
+
+			if err := ctx.Err(); err != nil {
+				return nil, warns, err
+			}
+			steps++
+			if lim.stepsExceeded(steps) {
+				return nil, warns, lim.stepsError()
+			}
+
 			value, err := binding.Invoke(invokeOn, sdts.hooks, sdts.emitEvent, &root, &tree)
 
 			if err != nil {
-				attrTypeStr := "synthetic"
-				if !synthetic {
-					attrTypeStr = "inherited"
-				}
-				if hookErr, ok := err.(hookError); ok {
-					hName := hookErr.name
-					if hookErr.name == "" {
-						hName = "?"
-					}
-
-					errMsg := fmt.Sprintf("%s binding %s = %s(", attrTypeStr, binding.Dest.String(), hName)
-					for k := range binding.Requirements {
-						errMsg += binding.Requirements[k].String()
-						if k+1 < len(binding.Requirements) {
-							errMsg += ", "
-						}
-					}
-					errMsg += fmt.Sprintf(") for rule %s -> %s", nodeRuleHead, nodeRuleProd)
-
-					if hookErr.name == "" {
-						return nil, warns, evalError{
-							msg: fmt.Sprintf("%s: no hook set on binding", errMsg),
-						}
-					} else if hookErr.missingHook {
-						return nil, warns, evalError{
-							missingHook: hName,
-							msg:         fmt.Sprintf("%s: '%s' is not in the provided hooks table", errMsg, hookErr.name),
-						}
-					} else {
-						return nil, warns, evalError{
-							failedHook: hName,
-							msg:        fmt.Sprintf("%s: %s", errMsg, hookErr.Error()),
-						}
-					}
-				} else {
-					return nil, warns, err
-				}
+				return nil, warns, bindingError(err, binding, nodeRuleHead, nodeRuleProd, invokeOn)
 			}
 
 			// now actually set the value on the attribute
@@ -345,10 +364,10 @@ func (sdts *sdtsImpl) Evaluate(tree parse.Tree, attributes ...string) (vals []in
 	for i := range attributes {
 		val, ok := root.Attributes[attributes[i]]
 		if !ok {
-			return nil, warns, evalError{
+			return nil, warns, evalErrorAt(evalError{
 				msg:       fmt.Sprintf("SDTS does not set attribute %q on root node", attributes[i]),
 				sortError: true,
-			}
+			}, syntaxerr.CodeAttrNotSet, &root)
 		}
 		attrValues[i] = val
 	}
@@ -359,6 +378,9 @@ func (sdts *sdtsImpl) Evaluate(tree parse.Tree, attributes ...string) (vals []in
 // BindSynthesizedAttribute adds a binding to the SDTS for a synthesized
 // attribute.
 func (sdts *sdtsImpl) Bind(head string, prod []string, attrName string, hook string, withArgs []AttrRef) error {
+	sdts.mu.Lock()
+	defer sdts.mu.Unlock()
+
 	// sanity checks; can we even call this?
 	if hook == "" {
 		return fmt.Errorf("cannot bind to empty hook")
@@ -409,9 +431,44 @@ func (sdts *sdtsImpl) Bind(head string, prod []string, attrName string, hook str
 	return nil
 }
 
+// Bindings returns every binding that has been added to the SDTS with Bind or
+// BindI. They are ordered by the head symbol and then the production of the
+// rule they are on, and then in the order they were added.
+func (sdts *sdtsImpl) Bindings() []Binding {
+	sdts.mu.RLock()
+	defer sdts.mu.RUnlock()
+
+	var binds []Binding
+
+	heads := slices.Keys(sdts.bindings)
+	sort.Strings(heads)
+	for _, head := range heads {
+		prods := slices.Keys(sdts.bindings[head])
+		sort.Strings(prods)
+		for _, prod := range prods {
+			for _, bind := range sdts.bindings[head][prod] {
+				b := Binding{
+					Head:       bind.BoundRuleSymbol,
+					Production: make([]string, len(bind.BoundRuleProduction)),
+					Dest:       bind.Dest,
+					Hook:       bind.Setter,
+					Args:       make([]AttrRef, len(bind.Requirements)),
+				}
+				copy(b.Production, bind.BoundRuleProduction)
+				copy(b.Args, bind.Requirements)
+				binds = append(binds, b)
+			}
+		}
+	}
+
+	return binds
+}
+
 // BindI adds a binding to the SDTS for an inherited attribute.
-// At this time, inherited attributes are not supported.
 func (sdts *sdtsImpl) BindI(head string, prod []string, attrName string, hook string, withArgs []AttrRef, forProd NodeRelation) error {
+	sdts.mu.Lock()
+	defer sdts.mu.Unlock()
+
 	// sanity checks; can we even call this?
 	if hook == "" {
 		return fmt.Errorf("cannot bind to empty hook")
@@ -453,7 +510,7 @@ func (sdts *sdtsImpl) BindI(head string, prod []string, attrName string, hook st
 
 	// build the binding
 	bind := sddBinding{
-		Synthesized:         true,
+		Synthesized:         false,
 		BoundRuleSymbol:     head,
 		BoundRuleProduction: make([]string, len(prod)),
 		Requirements:        make([]AttrRef, len(withArgs)),
@@ -475,6 +532,9 @@ func (sdts *sdtsImpl) BindI(head string, prod []string, attrName string, hook st
 // the value produced by it is not used by another part of the translation
 // scheme.
 func (sdts *sdtsImpl) SetNoFlow(synth bool, head string, prod []string, attrName string, forProd NodeRelation, which int, ifParent string) error {
+	sdts.mu.Lock()
+	defer sdts.mu.Unlock()
+
 	prodStr := strings.Join(prod, " ")
 
 	var attrTypeName string
@@ -555,12 +615,102 @@ func (sdts *sdtsImpl) SetNoFlow(synth bool, head string, prod []string, attrName
 	return nil
 }
 
+// snapshot returns a new sdtsImpl with a copy of the hooks, bindings, and
+// listener of sdts. Evaluations use a snapshot so that they do not hold mu
+// while calling hooks and listeners.
+func (sdts *sdtsImpl) snapshot() *sdtsImpl {
+	sdts.mu.RLock()
+	defer sdts.mu.RUnlock()
+
+	snap := &sdtsImpl{listener: sdts.listener}
+
+	if sdts.hooks != nil {
+		snap.hooks = make(HookMap, len(sdts.hooks))
+		for name, fn := range sdts.hooks {
+			snap.hooks[name] = fn
+		}
+	}
+
+	if sdts.bindings != nil {
+		snap.bindings = make(map[string]map[string][]sddBinding, len(sdts.bindings))
+		for head, byProd := range sdts.bindings {
+			snap.bindings[head] = make(map[string][]sddBinding, len(byProd))
+			for prod, binds := range byProd {
+				// SetNoFlow updates bindings in place, so the slice must be
+				// copied.
+				snap.bindings[head][prod] = append([]sddBinding(nil), binds...)
+			}
+		}
+	}
+
+	return snap
+}
+
 func (sdts *sdtsImpl) emitEvent(e Event) {
 	if sdts.listener != nil {
 		sdts.listener(e)
 	}
 }
 
+// bindingError returns the error for binding failing with err when invoked at
+// node, which was created by the rule with the given head and production.
+func bindingError(err error, binding sddBinding, head string, prod []string, node *AnnotatedTree) error {
+	hookErr, ok := err.(hookError)
+	if !ok {
+		return err
+	}
+
+	attrTypeStr := "synthetic"
+	if !binding.Synthesized {
+		attrTypeStr = "inherited"
+	}
+
+	hName := hookErr.name
+	if hookErr.name == "" {
+		hName = "?"
+	}
+
+	errMsg := fmt.Sprintf("%s binding %s = %s(", attrTypeStr, binding.Dest.String(), hName)
+	for k := range binding.Requirements {
+		errMsg += binding.Requirements[k].String()
+		if k+1 < len(binding.Requirements) {
+			errMsg += ", "
+		}
+	}
+	errMsg += fmt.Sprintf(") for rule %s -> %s", head, prod)
+
+	if hookErr.name == "" {
+		return evalErrorAt(evalError{
+			msg: fmt.Sprintf("%s: no hook set on binding", errMsg),
+		}, syntaxerr.CodeHookMissing, node)
+	} else if hookErr.missingHook {
+		return evalErrorAt(evalError{
+			missingHook: hName,
+			msg:         fmt.Sprintf("%s: '%s' is not in the provided hooks table", errMsg, hookErr.name),
+		}, syntaxerr.CodeHookMissing, node)
+	}
+
+	code := syntaxerr.CodeHookFailed
+	if hookErr.missingAttr {
+		code = syntaxerr.CodeAttrNotSet
+	}
+	d := evalDiagnostic(evalError{
+		failedHook: hName,
+		msg:        fmt.Sprintf("%s: %s", errMsg, hookErr.Error()),
+	}, code, syntaxerr.SeverityError, node)
+
+	// if the hook said where in the input the problem is, that is more useful
+	// than the whole node.
+	if span, line, ok := hookLocation(hookErr.err); ok {
+		nodeLbl := syntaxerr.Label{Span: d.Span, SourceLine: d.SourceLine, Message: "while evaluating this " + node.Symbol}
+		d.Span, d.SourceLine = span, line
+		if nodeLbl.Span.Known() {
+			d.Labels = append(d.Labels, nodeLbl)
+		}
+	}
+	return syntaxerr.Diagnostics{d}
+}
+
 // bindingsForAttr returns all bindings defined to apply when at a node in a parse
 // tree created by the rule production with head as its head symbol and prod
 // as its produced symbols, and when setting the attribute referred to by
@@ -0,0 +1,167 @@
+package trans
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SDTS_Bindings(t *testing.T) {
+	assert := assert.New(t)
+
+	sdts := NewSDTS()
+	sdts.Bind("X", []string{"plus", "int", "X"}, "val", "add", []AttrRef{{Rel: NRTerminal(1), Name: "$text"}, {Rel: NRNonTerminal(0), Name: "val"}})
+	sdts.Bind("S", []string{"int", "X"}, "val", "add", []AttrRef{{Rel: NRTerminal(0), Name: "$text"}, {Rel: NRNonTerminal(0), Name: "val"}})
+	sdts.Bind("X", []string{""}, "val", "zero", nil)
+	sdts.Bind("S", []string{"int", "X"}, "text", "identity", []AttrRef{{Rel: NRTerminal(0), Name: "$text"}})
+
+	expect := []Binding{
+		{
+			Head:       "S",
+			Production: []string{"int", "X"},
+			Dest:       AttrRef{Rel: NodeRelation{Type: RelHead}, Name: "val"},
+			Hook:       "add",
+			Args:       []AttrRef{{Rel: NRTerminal(0), Name: "$text"}, {Rel: NRNonTerminal(0), Name: "val"}},
+		},
+		{
+			Head:       "S",
+			Production: []string{"int", "X"},
+			Dest:       AttrRef{Rel: NodeRelation{Type: RelHead}, Name: "text"},
+			Hook:       "identity",
+			Args:       []AttrRef{{Rel: NRTerminal(0), Name: "$text"}},
+		},
+		{
+			Head:       "X",
+			Production: []string{""},
+			Dest:       AttrRef{Rel: NodeRelation{Type: RelHead}, Name: "val"},
+			Hook:       "zero",
+			Args:       []AttrRef{},
+		},
+		{
+			Head:       "X",
+			Production: []string{"plus", "int", "X"},
+			Dest:       AttrRef{Rel: NodeRelation{Type: RelHead}, Name: "val"},
+			Hook:       "add",
+			Args:       []AttrRef{{Rel: NRTerminal(1), Name: "$text"}, {Rel: NRNonTerminal(0), Name: "val"}},
+		},
+	}
+
+	actual := Bindings(sdts)
+
+	assert.Equal(expect, actual)
+	assert.Equal("X -> ε: {head symbol}.val = zero()", actual[2].String())
+	assert.Nil(Bindings(plainSDTS{sdts}), "bindings of SDTS that is not a BindingLister")
+}
+
+func Test_SDTS_Evaluate_InheritedAttributes(t *testing.T) {
+	leaf := func(term, text string) *parse.Tree {
+		return parse.Leaf(term, lex.NewToken(lex.NewTokenClass(term, term), text, 1, 1, ""))
+	}
+
+	// "10 - 3 - 2" with the left recursion removed; subtraction must still be
+	// left-associative, so the inherited attribute carries the total so far
+	// down to the right.
+	tree := parse.Node("E",
+		leaf("int", "10"),
+		parse.Node("R",
+			leaf("minus", "-"),
+			leaf("int", "3"),
+			parse.Node("R",
+				leaf("minus", "-"),
+				leaf("int", "2"),
+				parse.Node("R", parse.Leaf("")),
+			),
+		),
+	)
+
+	testCases := []struct {
+		name   string
+		attr   string
+		expect interface{}
+	}{
+		{
+			name:   "synthesized value uses inherited values",
+			attr:   "val",
+			expect: 5,
+		},
+		{
+			name:   "inherited hooks are given the symbol they set",
+			attr:   "sym",
+			expect: "R",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sdts := NewSDTS()
+			sdts.SetHooks(HookMap{
+				"int": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					return strconv.Atoi(args[0].(string))
+				},
+				"sub": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					n, err := strconv.Atoi(args[1].(string))
+					return args[0].(int) - n, err
+				},
+				"ident": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					return args[0], nil
+				},
+				"symbol": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					return info.GrammarSymbol, nil
+				},
+			})
+			sdts.BindI("E", []string{"int", "R"}, "acc", "int", []AttrRef{{Rel: NRTerminal(0), Name: "$text"}}, NRNonTerminal(0))
+			sdts.BindI("E", []string{"int", "R"}, "sym", "symbol", nil, NRNonTerminal(0))
+			sdts.Bind("E", []string{"int", "R"}, "val", "ident", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}})
+			sdts.Bind("E", []string{"int", "R"}, "sym", "ident", []AttrRef{{Rel: NRNonTerminal(0), Name: "sym"}})
+			sdts.BindI("R", []string{"minus", "int", "R"}, "acc", "sub", []AttrRef{{Rel: NRHead(), Name: "acc"}, {Rel: NRTerminal(1), Name: "$text"}}, NRNonTerminal(0))
+			sdts.Bind("R", []string{"minus", "int", "R"}, "val", "ident", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}})
+			sdts.Bind("R", []string{""}, "val", "ident", []AttrRef{{Rel: NRHead(), Name: "acc"}})
+
+			actual, _, err := sdts.Evaluate(*tree, tc.attr)
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal([]interface{}{tc.expect}, actual)
+		})
+	}
+}
+
+func Test_SDTS_Evaluate_HookChangesSDTS(t *testing.T) {
+	assert := assert.New(t)
+
+	tree := parse.Node("S", parse.Leaf("a", lex.NewToken(lex.NewTokenClass("a", "a"), "a", 1, 1, "")))
+
+	sdts := NewSDTS()
+	sdts.Bind("S", []string{"a"}, "val", "change", nil)
+	sdts.SetHooks(HookMap{
+		"change": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			// would never return if the SDTS were locked during evaluation.
+			sdts.SetHooks(HookMap{
+				"change": func(info SetterInfo, args []interface{}) (interface{}, error) {
+					return "second", nil
+				},
+			})
+			sdts.RegisterListener(func(e Event) {})
+			return "first", nil
+		},
+	})
+
+	actual, _, err := sdts.Evaluate(*tree, "val")
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]interface{}{"first"}, actual)
+
+	// the change applies to the next evaluation.
+	actual, _, err = sdts.Evaluate(*tree, "val")
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]interface{}{"second"}, actual)
+}
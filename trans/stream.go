@@ -0,0 +1,194 @@
+package trans
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/dekarrin/ictiobus/syntaxerr"
+)
+
+// File stream.go contains functions for applying an SDTS while input is being
+// parsed, without building a parse tree first.
+
+// EvaluateStream parses the tokens from stream with p and applies sdts to the
+// result, returning the requested attributes of the root node just like
+// Evaluate does. Instead of building a parse tree and a dependency graph for
+// it, the attributes of each node are computed as soon as the parser reduces
+// to it, and the children of the node are dropped once that is done. This
+// keeps memory use bounded by the depth of the parse instead of the size of
+// the input, which matters for large inputs.
+//
+// This requires that sdts be S-attributed, with every binding being for a
+// synthesized attribute, and that p be an LR parser. If either is not the case,
+// an error is returned without any input being read.
+//
+// Unlike Evaluate, every binding is called for every node that it applies to,
+// even those whose values never reach the root. The $id attribute of nodes is
+// assigned in the order that nodes are reduced, so it is not the same as the
+// $id the node would have in Evaluate. Listeners registered on sdts are given
+// EventHookCall events whose ParseTree is nil and whose Tree is the node being
+// evaluated. No EventAnnotation events are emitted.
+func EvaluateStream(sdts SDTS, p parse.Parser, stream lex.TokenStream, attributes ...string) (vals []interface{}, err error) {
+	return EvaluateStreamContext(context.Background(), sdts, p, stream, parse.Limits{}, Limits{}, attributes...)
+}
+
+// EvaluateStreamContext is the same as EvaluateStream but stops when ctx is
+// done, when the parse tree that the input makes up would exceed parseLim, or
+// when more bindings would be invoked than lim allows.
+func EvaluateStreamContext(ctx context.Context, sdts SDTS, p parse.Parser, stream lex.TokenStream, parseLim parse.Limits, lim Limits, attributes ...string) (vals []interface{}, err error) {
+	impl, ok := sdts.(*sdtsImpl)
+	if !ok {
+		return nil, fmt.Errorf("SDTS of type %T cannot be evaluated while parsing", sdts)
+	}
+
+	// hooks and listeners may change the SDTS while the stream is evaluated,
+	// so use a snapshot of it instead of holding its lock.
+	impl = impl.snapshot()
+
+	if err := impl.checkSAttributed(); err != nil {
+		return nil, err
+	}
+
+	idGen := newIDGenerator(0)
+	leaf := func(tok lex.Token) *AnnotatedTree {
+		return &AnnotatedTree{
+			Terminal: true,
+			Symbol:   tok.Class().ID(),
+			Source:   tok,
+			Span:     lex.SpanOf(tok),
+			Attributes: nodeAttrs{
+				"$id":   idGen.Next(),
+				"$text": tok.Lexeme(),
+				"$ft":   tok,
+			},
+		}
+	}
+
+	var steps int
+	result, err := parse.ParseReductionsContext(ctx, p, stream, parseLim, func(red parse.Reduction, values []interface{}) (interface{}, error) {
+		node := &AnnotatedTree{
+			Symbol:     red.Symbol,
+			Span:       red.Span,
+			Attributes: nodeAttrs{"$id": idGen.Next()},
+		}
+		if len(values) == 0 {
+			node.Children = []*AnnotatedTree{{
+				Terminal:   true,
+				Span:       red.Span,
+				Attributes: nodeAttrs{"$id": idGen.Next(), "$text": ""},
+			}}
+		}
+		for _, v := range values {
+			switch v := v.(type) {
+			case lex.Token:
+				node.Children = append(node.Children, leaf(v))
+			case *AnnotatedTree:
+				node.Children = append(node.Children, v)
+			}
+		}
+		node.First()
+
+		if err := impl.evaluateNode(node, lim, &steps); err != nil {
+			return nil, err
+		}
+
+		// the attributes of the children are never needed again.
+		node.Children = nil
+		return node, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	root := result.(*AnnotatedTree)
+	attrValues := make([]interface{}, len(attributes))
+	for i := range attributes {
+		val, ok := root.Attributes[attributes[i]]
+		if !ok {
+			return nil, evalErrorAt(evalError{
+				msg: fmt.Sprintf("SDTS does not set attribute %q on root node", attributes[i]),
+			}, syntaxerr.CodeAttrNotSet, root)
+		}
+		attrValues[i] = val
+	}
+
+	return attrValues, nil
+}
+
+// checkSAttributed returns an error if any binding in the SDTS is for an
+// inherited attribute.
+func (sdts *sdtsImpl) checkSAttributed() error {
+	for head := range sdts.bindings {
+		for prod := range sdts.bindings[head] {
+			for _, bind := range sdts.bindings[head][prod] {
+				if !bind.Synthesized {
+					return fmt.Errorf("SDTS is not S-attributed; binding %s is for an inherited attribute", bind.String())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// evaluateNode invokes all synthesized bindings for the rule that node was
+// created by, setting the attributes on node. The attributes of the children of
+// node must already be set. Bindings that use other attributes of node are
+// invoked after the bindings that set those attributes. steps is the number of
+// bindings invoked so far, and it is updated as each one is.
+func (sdts *sdtsImpl) evaluateNode(node *AnnotatedTree, lim Limits, steps *int) error {
+	head, prod := node.Rule()
+	pending := sdts.bindingsForRule(head, prod)
+
+	for len(pending) > 0 {
+		var waiting []sddBinding
+		for _, bind := range pending {
+			if !sdts.headReqsSet(node, bind, pending) {
+				waiting = append(waiting, bind)
+				continue
+			}
+
+			*steps++
+			if lim.stepsExceeded(*steps) {
+				return lim.stepsError()
+			}
+
+			value, err := bind.Invoke(node, sdts.hooks, sdts.emitEvent, node, nil)
+			if err != nil {
+				return bindingError(err, bind, head, prod, node)
+			}
+			node.Attributes[bind.Dest.Name] = value
+		}
+
+		if len(waiting) == len(pending) {
+			return evalErrorAt(evalError{
+				msg:       fmt.Sprintf("bindings for rule %s -> %s depend on each other", head, prod),
+				sortError: true,
+			}, syntaxerr.CodeEvalOrder, node)
+		}
+		pending = waiting
+	}
+
+	return nil
+}
+
+// headReqsSet returns whether every attribute of the head of the rule that bind
+// requires is either already set on node or is not set by any binding in
+// pending.
+func (sdts *sdtsImpl) headReqsSet(node *AnnotatedTree, bind sddBinding, pending []sddBinding) bool {
+	for _, req := range bind.Requirements {
+		if req.Rel.Type != RelHead {
+			continue
+		}
+		if _, ok := node.Attributes[req.Name]; ok {
+			continue
+		}
+		for _, other := range pending {
+			if other.Dest == req {
+				return false
+			}
+		}
+	}
+	return true
+}
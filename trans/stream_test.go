@@ -0,0 +1,244 @@
+package trans
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/grammar"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EvaluateStream(t *testing.T) {
+	g := grammar.MustParse(`
+		E -> E plus T | T ;
+		T -> T mult F | F ;
+		F -> lp E rp | neg F | A int ;
+		A -> at | ε ;
+	`)
+	p, _, err := parse.GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	lx := lex.NewLexer(true)
+	for id, pat := range map[string]string{"plus": `\+`, "mult": `\*`, "lp": `\(`, "rp": `\)`, "int": `[0-9]+`, "neg": `-`, "at": `@`} {
+		lx.RegisterClass(lex.NewTokenClass(id, id), "")
+		lx.AddPattern(pat, lex.LexAs(id), "", 0)
+	}
+	lx.AddPattern(`\s+`, lex.Discard(), "", 0)
+
+	hooks := HookMap{
+		"int": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			return strconv.Atoi(args[0].(string))
+		},
+		"identity": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			return args[0], nil
+		},
+		"add": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			return args[0].(int) + args[1].(int), nil
+		},
+		"mult": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			return args[0].(int) * args[1].(int), nil
+		},
+		"neg": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			return -args[0].(int), nil
+		},
+		"count": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			n := 1
+			for _, a := range args {
+				n += a.(int)
+			}
+			return n, nil
+		},
+		"fail": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			return nil, fmt.Errorf("can't do it")
+		},
+	}
+
+	newSDTS := func() SDTS {
+		sdts := NewSDTS()
+		sdts.SetHooks(hooks)
+		sdts.Bind("E", []string{"E", "plus", "T"}, "val", "add", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}, {Rel: NRNonTerminal(1), Name: "val"}})
+		sdts.Bind("E", []string{"T"}, "val", "identity", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}})
+		sdts.Bind("T", []string{"T", "mult", "F"}, "val", "mult", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}, {Rel: NRNonTerminal(1), Name: "val"}})
+		sdts.Bind("T", []string{"F"}, "val", "identity", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}})
+		sdts.Bind("F", []string{"lp", "E", "rp"}, "val", "identity", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}})
+		sdts.Bind("F", []string{"neg", "F"}, "val", "neg", []AttrRef{{Rel: NRNonTerminal(0), Name: "val"}})
+		sdts.Bind("F", []string{"A", "int"}, "val", "int", []AttrRef{{Rel: NRTerminal(0), Name: "$text"}})
+		return sdts
+	}
+
+	testCases := []struct {
+		name      string
+		input     string
+		modify    func(sdts SDTS)
+		attrs     []string
+		expect    []interface{}
+		expectErr bool
+
+		// notLikeTree is whether Evaluate is expected to fail where
+		// EvaluateStream does not.
+		notLikeTree bool
+	}{
+		{
+			name:   "single value",
+			input:  "8",
+			attrs:  []string{"val"},
+			expect: []interface{}{8},
+		},
+		{
+			name:   "expression",
+			input:  "2 + 3 * (4 + -1) + @5",
+			attrs:  []string{"val"},
+			expect: []interface{}{16},
+		},
+		{
+			name:  "attribute that depends on another attribute of the head",
+			input: "1 + 2 + 3",
+			modify: func(sdts SDTS) {
+				// sum is bound before the binding for double that it depends
+				// on.
+				sdts.Bind("E", []string{"E", "plus", "T"}, "sum", "count", []AttrRef{{Rel: NRHead(), Name: "double"}, {Rel: NRNonTerminal(0), Name: "sum"}})
+				sdts.Bind("E", []string{"E", "plus", "T"}, "double", "add", []AttrRef{{Rel: NRHead(), Name: "val"}, {Rel: NRHead(), Name: "val"}})
+				sdts.Bind("E", []string{"T"}, "sum", "count", nil)
+			},
+			attrs:  []string{"sum", "val"},
+			expect: []interface{}{1 + 12 + (1 + 6 + 1), 6},
+
+			// Evaluate does not order bindings that use attributes of the head.
+			notLikeTree: true,
+		},
+		{
+			name:  "hook returns error",
+			input: "1 * 2",
+			modify: func(sdts SDTS) {
+				sdts.Bind("T", []string{"T", "mult", "F"}, "other", "fail", nil)
+			},
+			attrs:     []string{"val"},
+			expectErr: true,
+		},
+		{
+			name:      "syntax error",
+			input:     "1 + + 2",
+			attrs:     []string{"val"},
+			expectErr: true,
+		},
+		{
+			name:      "attribute not set on root",
+			input:     "1",
+			attrs:     []string{"nothing"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sdts := newSDTS()
+			if tc.modify != nil {
+				tc.modify(sdts)
+			}
+
+			stream, err := lx.Lex(strings.NewReader(tc.input))
+			if !assert.NoError(err) {
+				return
+			}
+
+			// execute
+			actual, err := EvaluateStream(sdts, p, stream, tc.attrs...)
+
+			// assert
+			if tc.expectErr {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+			if tc.notLikeTree {
+				return
+			}
+
+			// make sure it's the same as with a tree
+			stream, err = lx.Lex(strings.NewReader(tc.input))
+			if !assert.NoError(err) {
+				return
+			}
+			pt, err := p.Parse(stream)
+			if !assert.NoError(err) {
+				return
+			}
+			fromTree, _, err := sdts.Evaluate(pt, tc.attrs...)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(fromTree, actual, "result does not match Evaluate")
+		})
+	}
+}
+
+func Test_EvaluateStream_notSAttributed(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> A ;
+		A -> a ;
+	`)
+	p, _, err := parse.GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sdts := NewSDTS()
+	sdts.BindI("S", []string{"A"}, "inh", "hook", nil, NRNonTerminal(0))
+
+	lx := lex.NewLexer(true)
+	lx.RegisterClass(lex.NewTokenClass("a", "a"), "")
+	lx.AddPattern(`a`, lex.LexAs("a"), "", 0)
+	stream, err := lx.Lex(strings.NewReader("a"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = EvaluateStream(sdts, p, stream, "val")
+	assert.Error(t, err)
+}
+
+func Test_EvaluateStream_HookChangesSDTS(t *testing.T) {
+	g := grammar.MustParse(`
+		S -> a ;
+	`)
+	p, _, err := parse.GenerateLALR1Parser(g, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sdts := NewSDTS()
+	sdts.Bind("S", []string{"a"}, "val", "change", nil)
+	sdts.SetHooks(HookMap{
+		"change": func(info SetterInfo, args []interface{}) (interface{}, error) {
+			// would never return if the SDTS were locked during evaluation.
+			sdts.SetHooks(HookMap{"other": nil})
+			sdts.RegisterListener(func(e Event) {})
+			return "done", nil
+		},
+	})
+
+	lx := lex.NewLexer(true)
+	lx.RegisterClass(lex.NewTokenClass("a", "a"), "")
+	lx.AddPattern(`a`, lex.LexAs("a"), "", 0)
+	stream, err := lx.Lex(strings.NewReader("a"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	actual, err := EvaluateStream(sdts, p, stream, "val")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []interface{}{"done"}, actual)
+}
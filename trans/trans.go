@@ -4,13 +4,13 @@
 // [parse.Tree] as input to produce the final result of the analysis, the
 // intermediate representation.
 //
-// At this time, while there are function stubs and supposed availability of
-// inherited attributes in an SDTS, only S-attributed attribute grammars are
-// supported at this time. Attempting to use inherited attributes will result in
-// untested and undefined behavior.
+// Both synthesized and inherited attributes are supported, as long as the
+// dependencies between them do not form a cycle. Evaluation while parsing with
+// [EvaluateStream] requires every attribute to be synthesized.
 package trans
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/dekarrin/ictiobus/lex"
@@ -23,6 +23,11 @@ import (
 //
 // This is a representation of the additions to a grammar which would make it an
 // attribute gramamr.
+//
+// The SDTS returned by NewSDTS is safe for concurrent use by multiple
+// goroutines. Bindings, hooks, and listeners can be changed while it is being
+// used to evaluate in other goroutines; such changes wait for evaluations in
+// progress to finish, so they must not be made from within a hook or listener.
 type SDTS interface {
 	// Evaluate takes a parse tree and executes the semantic actions defined as
 	// SDDBindings for a node for each node in the tree and on completion,
@@ -33,7 +38,10 @@ type SDTS interface {
 	// its value dependency graph.
 	//
 	// Warn errors are provided in the slice of error and can be populated
-	// regardless of whether the final (actual) error is non-nil.
+	// regardless of whether the final (actual) error is non-nil. Problems
+	// found while evaluating are returned as a syntaxerr.Diagnostics located
+	// at the node being evaluated, and each warning is a syntaxerr.Diagnostic
+	// with SeverityWarning.
 	Evaluate(tree parse.Tree, attributes ...string) (vals []interface{}, warns []error, err error)
 
 	// SetHooks sets the hook table for mapping SDTS hook names as used in a
@@ -55,8 +63,9 @@ type SDTS interface {
 	// something other than RelHead (inherited attributes can be set only on
 	// production symbols).
 	//
-	// Inherited properties on SDTSs are not supported or tested at this time.
-	// This function should only be called for experimental purposes.
+	// The hook can use attributes of the head of the rule as well as of the
+	// produced symbols, including inherited attributes that the parent of the
+	// head set on it.
 	//
 	// The binding applies only on nodes in the parse tree created by parsing
 	// the grammar rule productions with head symbol head and production symbols
@@ -97,6 +106,58 @@ type SDTS interface {
 	RegisterListener(func(e Event))
 }
 
+// ContextSDTS is an SDTS that can stop evaluating when a context is done or
+// when evaluation exceeds limits. The SDTS returned by NewSDTS implements it.
+// Use the EvaluateContext function to evaluate with an SDTS that might not.
+type ContextSDTS interface {
+	SDTS
+
+	// EvaluateContext is the same as Evaluate but stops when ctx is done or
+	// when evaluation would take more steps than lim allows, returning
+	// ctx.Err() or a *syntaxerr.LimitError respectively. Each call of a hook
+	// is one step.
+	EvaluateContext(ctx context.Context, tree parse.Tree, lim Limits, attributes ...string) (vals []interface{}, warns []error, err error)
+}
+
+// EvaluateContext evaluates tree with sdts, stopping when ctx is done or when
+// evaluation would take more steps than lim allows. If sdts is a ContextSDTS,
+// this is the same as calling its EvaluateContext method. Otherwise,
+// sdts.Evaluate is called unless ctx is already done; it cannot be stopped
+// once it has started, so lim is not applied.
+func EvaluateContext(ctx context.Context, sdts SDTS, tree parse.Tree, lim Limits, attributes ...string) (vals []interface{}, warns []error, err error) {
+	if csdts, ok := sdts.(ContextSDTS); ok {
+		return csdts.EvaluateContext(ctx, tree, lim, attributes...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return sdts.Evaluate(tree, attributes...)
+}
+
+// BindingLister is an SDTS that can list the bindings that were added to it.
+// The SDTS returned by NewSDTS implements it. Use the Bindings function to get
+// the bindings of an SDTS that might not.
+type BindingLister interface {
+	SDTS
+
+	// Bindings returns every binding that has been added to the SDTS with
+	// Bind or BindI. They are ordered by the head symbol and then the
+	// production of the rule they are on, and then in the order they were
+	// added.
+	Bindings() []Binding
+}
+
+// Bindings returns every binding that has been added to sdts. If sdts is a
+// BindingLister, this is the same as calling its Bindings method. Otherwise,
+// there is no way to get them and nil is returned.
+func Bindings(sdts SDTS) []Binding {
+	if bl, ok := sdts.(BindingLister); ok {
+		return bl.Bindings()
+	}
+	return nil
+}
+
 // NewSDTS creates a new, empty Syntax-Directed Translation Scheme.
 func NewSDTS() SDTS {
 	impl := sdtsImpl{
@@ -118,6 +179,11 @@ type SetterInfo struct {
 	// The first token of the grammar symbol that the attribute is being set on.
 	FirstToken lex.Token
 
+	// The range of source text of the node that the attribute is being set on.
+	// This is an empty span at the point where the node would be if it
+	// derives only epsilon.
+	Span lex.Span
+
 	// The name of the attribute being set.
 	Name string
 
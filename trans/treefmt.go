@@ -0,0 +1,247 @@
+package trans
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dekarrin/ictiobus/internal/box"
+	"github.com/dekarrin/ictiobus/internal/textfmt"
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/dekarrin/ictiobus/parse"
+)
+
+// File treefmt.go contains encodings of annotated trees into formats other
+// than the diagram given by AnnotatedTree.String(), for use by outside tools.
+
+// jsonAPT is the form an AnnotatedTree takes when encoded as JSON.
+type jsonAPT struct {
+	ID         uint64                     `json:"id"`
+	Symbol     string                     `json:"symbol"`
+	Terminal   bool                       `json:"terminal"`
+	Token      json.RawMessage            `json:"token,omitempty"`
+	Span       lex.Span                   `json:"span"`
+	Attributes map[string]json.RawMessage `json:"attributes,omitempty"`
+	Children   []*jsonAPT                 `json:"children,omitempty"`
+}
+
+// MarshalJSON converts apt into a JSON object. Each node is encoded the same
+// way as by parse.Tree.MarshalJSON, with the addition of its $id and all of its
+// attributes that are not built in. Attribute values that cannot themselves be
+// encoded as JSON are given as the string they format to with %v.
+func (apt AnnotatedTree) MarshalJSON() ([]byte, error) {
+	ja, err := apt.toJSONAPT()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ja)
+}
+
+// UnmarshalJSON sets apt to the tree encoded in JSON produced by MarshalJSON.
+// The built-in attributes are restored as they were, but the values of all
+// other attributes will be whatever type encoding/json decodes them into, which
+// may differ from the type they were before encoding.
+func (apt *AnnotatedTree) UnmarshalJSON(data []byte) error {
+	var ja jsonAPT
+	if err := json.Unmarshal(data, &ja); err != nil {
+		return err
+	}
+
+	decoded, err := aptFromJSONAPT(&ja)
+	if err != nil {
+		return err
+	}
+
+	// now that we have the tree, traverse it to set $ft
+	annotatedStack := box.NewStack([]*AnnotatedTree{decoded})
+	for annotatedStack.Len() > 0 {
+		curAnnoNode := annotatedStack.Pop()
+		curAnnoNode.First()
+		for i := len(curAnnoNode.Children) - 1; i >= 0; i-- {
+			annotatedStack.Push(curAnnoNode.Children[i])
+		}
+	}
+
+	*apt = *decoded
+	return nil
+}
+
+func (apt AnnotatedTree) toJSONAPT() (*jsonAPT, error) {
+	ja := &jsonAPT{
+		ID:       uint64(apt.ID()),
+		Symbol:   apt.Symbol,
+		Terminal: apt.Terminal,
+		Span:     apt.Span,
+	}
+
+	if apt.Terminal && apt.Source != nil {
+		tokData, err := lex.MarshalTokenJSON(apt.Source)
+		if err != nil {
+			return nil, err
+		}
+		ja.Token = tokData
+	}
+
+	for _, name := range textfmt.OrderedKeys(apt.Attributes) {
+		if strings.HasPrefix(name, "$") {
+			continue
+		}
+		if ja.Attributes == nil {
+			ja.Attributes = map[string]json.RawMessage{}
+		}
+
+		val := apt.Attributes[name]
+		valData, err := json.Marshal(val)
+		if err != nil {
+			valData, err = json.Marshal(fmt.Sprintf("%v", val))
+			if err != nil {
+				return nil, fmt.Errorf("attribute %q: %w", name, err)
+			}
+		}
+		ja.Attributes[name] = valData
+	}
+
+	for i := range apt.Children {
+		child, err := apt.Children[i].toJSONAPT()
+		if err != nil {
+			return nil, err
+		}
+		ja.Children = append(ja.Children, child)
+	}
+
+	return ja, nil
+}
+
+func aptFromJSONAPT(ja *jsonAPT) (*AnnotatedTree, error) {
+	if ja == nil {
+		return nil, fmt.Errorf("null tree node")
+	}
+
+	apt := &AnnotatedTree{
+		Terminal:   ja.Terminal,
+		Symbol:     ja.Symbol,
+		Span:       ja.Span,
+		Attributes: nodeAttrs{"$id": aptNodeID(ja.ID)},
+	}
+
+	if len(ja.Token) > 0 && string(ja.Token) != "null" {
+		if !ja.Terminal {
+			return nil, fmt.Errorf("non-terminal %q has a token", ja.Symbol)
+		}
+		tok, err := lex.UnmarshalTokenJSON(ja.Token)
+		if err != nil {
+			return nil, fmt.Errorf("terminal %q: %w", ja.Symbol, err)
+		}
+		apt.Source = tok
+	}
+
+	if ja.Terminal {
+		if len(ja.Children) > 0 {
+			return nil, fmt.Errorf("terminal %q has children", ja.Symbol)
+		}
+
+		apt.Attributes["$text"] = ""
+		if apt.Source != nil {
+			apt.Attributes["$text"] = apt.Source.Lexeme()
+		}
+	}
+
+	for name, valData := range ja.Attributes {
+		if strings.HasPrefix(name, "$") {
+			return nil, fmt.Errorf("node %d: cannot give built-in attribute %q", ja.ID, name)
+		}
+		var val interface{}
+		if err := json.Unmarshal(valData, &val); err != nil {
+			return nil, fmt.Errorf("node %d: attribute %q: %w", ja.ID, name, err)
+		}
+		apt.Attributes[name] = val
+	}
+
+	for i := range ja.Children {
+		child, err := aptFromJSONAPT(ja.Children[i])
+		if err != nil {
+			return nil, err
+		}
+		apt.Children = append(apt.Children, child)
+	}
+
+	return apt, nil
+}
+
+// ParseTree returns the parse.Tree that apt was annotated from, without any of
+// its attributes.
+func (apt AnnotatedTree) ParseTree() parse.Tree {
+	pt := parse.Tree{
+		Terminal: apt.Terminal,
+		Value:    apt.Symbol,
+		Source:   apt.Source,
+		Span:     apt.Span,
+	}
+
+	for i := range apt.Children {
+		child := apt.Children[i].ParseTree()
+		pt.Children = append(pt.Children, &child)
+	}
+
+	return pt
+}
+
+// SExpr returns the S-expression representation of apt. Attributes are not
+// included; the result is the same as calling SExpr on apt.ParseTree().
+func (apt AnnotatedTree) SExpr() string {
+	return apt.ParseTree().SExpr()
+}
+
+// AnnotatedTreeFromSExpr reads an S-expression in the format produced by
+// AnnotatedTree.SExpr and returns the annotated tree it represents, with only
+// the built-in attributes set. See [parse.ParseTreeFromSExpr].
+func AnnotatedTreeFromSExpr(s string) (AnnotatedTree, error) {
+	pt, err := parse.ParseTreeFromSExpr(s)
+	if err != nil {
+		return AnnotatedTree{}, err
+	}
+	return Annotate(*pt), nil
+}
+
+// DOT returns a Graphviz DOT digraph that draws apt. Each node is labeled with
+// its ID and symbol, and terminals also show their lexeme. If showAttrs is set,
+// each node also lists the values of all of its attributes that are not built
+// in.
+func (apt AnnotatedTree) DOT(showAttrs bool) string {
+	getLabel := func(n *AnnotatedTree) string {
+		var sb strings.Builder
+		sym := n.Symbol
+		if n.Terminal && sym == "" {
+			sym = "ε"
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s", n.ID(), sym))
+
+		if n.Terminal && n.Source != nil {
+			sb.WriteRune('\n')
+			sb.WriteString(strconv.Quote(n.Source.Lexeme()))
+		}
+
+		if showAttrs {
+			for _, name := range textfmt.OrderedKeys(n.Attributes) {
+				if strings.HasPrefix(name, "$") {
+					continue
+				}
+				sb.WriteString(fmt.Sprintf("\n%s = %v", name, n.Attributes[name]))
+			}
+		}
+
+		return sb.String()
+	}
+	getShape := func(n *AnnotatedTree) string {
+		if n.Terminal {
+			return "box"
+		}
+		return "ellipse"
+	}
+	getChildren := func(n *AnnotatedTree) []*AnnotatedTree {
+		return n.Children
+	}
+
+	return textfmt.DOTTree("annotatedtree", &apt, getLabel, getShape, getChildren)
+}
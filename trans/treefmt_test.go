@@ -0,0 +1,110 @@
+package trans
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dekarrin/ictiobus/lex"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AnnotatedTree_JSON(t *testing.T) {
+	assert := assert.New(t)
+
+	intClass := lex.NewTokenClass("int", "integer")
+	apt := ATNode(1, "SUM",
+		ATLeaf(2, "int", lex.NewToken(intClass, "2", 1, 1, "2 3")),
+		ATLeaf(3, "int", lex.NewToken(intClass, "3", 3, 1, "2 3")),
+	)
+	apt.Attributes["value"] = 5
+	apt.Attributes["name"] = "sum"
+	apt.Children[0].Attributes["value"] = 2
+	apt.Children[1].Attributes["value"] = func() {}
+
+	data, err := json.Marshal(apt)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var actual AnnotatedTree
+	err = json.Unmarshal(data, &actual)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(apt.String(), actual.String())
+	assert.Equal(apt.Span, actual.Span)
+	assert.Equal(aptNodeID(1), actual.ID())
+	assert.Equal(float64(5), actual.Attributes["value"])
+	assert.Equal("sum", actual.Attributes["name"])
+	if !assert.Len(actual.Children, 2) {
+		return
+	}
+	assert.Equal(float64(2), actual.Children[0].Attributes["value"])
+	assert.IsType("", actual.Children[1].Attributes["value"])
+	assert.Equal("3", actual.Children[1].Attributes["$text"])
+	assert.Equal("2", actual.First().Lexeme())
+}
+
+func Test_AnnotatedTree_SExpr(t *testing.T) {
+	assert := assert.New(t)
+
+	intClass := lex.NewTokenClass("int", "integer")
+	apt := Annotate(ATNode(1, "SUM",
+		ATLeaf(2, "int", lex.NewToken(intClass, "2", 1, 1, "2 3")),
+		ATLeaf(3, "int", lex.NewToken(intClass, "3", 3, 1, "2 3")),
+	).ParseTree())
+
+	sexpr := apt.SExpr()
+	assert.Equal(`(SUM (int "2" 1:1) (int "3" 1:3))`, sexpr)
+
+	actual, err := AnnotatedTreeFromSExpr(sexpr)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(apt.String(), actual.String())
+	assert.Equal(apt.Span, actual.Span)
+}
+
+func Test_AnnotatedTree_DOT(t *testing.T) {
+	testCases := []struct {
+		name      string
+		showAttrs bool
+		expect    string
+	}{
+		{
+			name: "without attributes",
+			expect: `digraph "annotatedtree" {
+	ordering=out;
+	n0 [label="1: SUM", shape=ellipse];
+	n1 [label="2: int\n\"2\"", shape=box];
+	n0 -> n1;
+}
+`,
+		},
+		{
+			name:      "with attributes",
+			showAttrs: true,
+			expect: `digraph "annotatedtree" {
+	ordering=out;
+	n0 [label="1: SUM\nname = sum\nvalue = 2", shape=ellipse];
+	n1 [label="2: int\n\"2\"\nvalue = 2", shape=box];
+	n0 -> n1;
+}
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apt := ATNode(1, "SUM",
+				ATLeaf(2, "int", lex.NewToken(lex.NewTokenClass("int", "integer"), "2", 1, 1, "2")),
+			)
+			apt.Attributes["value"] = 2
+			apt.Attributes["name"] = "sum"
+			apt.Children[0].Attributes["value"] = 2
+
+			assert.Equal(t, tc.expect, apt.DOT(tc.showAttrs))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package trans
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/dekarrin/ictiobus/grammar"
@@ -25,11 +26,9 @@ func Validate(sdts SDTS, g grammar.CFG, attribute string, debug ValidationOption
 	treeErrs := []box.Pair[error, *parse.Tree]{}
 
 	evalErrToTreeError := func(errFromEval error) error {
-		evalErr, ok := errFromEval.(evalError)
-		if !ok {
-			if errFromEval != nil {
-				return errFromEval
-			}
+		var evalErr evalError
+		if !errors.As(errFromEval, &evalErr) {
+			return errFromEval
 		}
 
 		if len(evalErr.depGraphs) > 0 {
@@ -57,7 +56,9 @@ func Validate(sdts SDTS, g grammar.CFG, attribute string, debug ValidationOption
 			return fmt.Errorf(fullMsg)
 		}
 
-		return errFromEval
+		// the location in a simulated tree is meaningless, so only give the
+		// message.
+		return evalErr
 	}
 
 	for i := range pts {